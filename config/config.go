@@ -41,6 +41,36 @@ const (
 	ClientChatModelModel       = "clients.llmModel.model"
 	ClientChatModelTemperature = "clients.llmModel.temperature"
 	ClientChatModelMaxTokens   = "clients.llmModel.maxTokens"
+	// 结构化输出使用的 response_format：json_schema / json_object / none
+	ClientChatModelResponseFormat = "clients.llmModel.responseFormat"
+	// 模型上下文窗口（token），扣除 maxTokens 后为提示词预算
+	ClientChatModelContextWindow = "clients.llmModel.contextWindow"
+	// 单次调用超时（秒）、重试与熔断
+	ClientChatModelTimeoutSeconds          = "clients.llmModel.timeoutSeconds"
+	ClientChatModelRetryMaxAttempts        = "clients.llmModel.retry.maxAttempts"
+	ClientChatModelRetryBaseDelayMs        = "clients.llmModel.retry.baseDelayMs"
+	ClientChatModelRetryMaxDelayMs         = "clients.llmModel.retry.maxDelayMs"
+	ClientChatModelRetryMaxRetryAfterSecs  = "clients.llmModel.retry.maxRetryAfterSeconds"
+	ClientChatModelBreakerFailureThreshold = "clients.llmModel.breaker.failureThreshold"
+	ClientChatModelBreakerCooldownSeconds  = "clients.llmModel.breaker.cooldownSeconds"
+
+	// 多模型路由：clients.llmProfiles.<name> 为命名模型配置，clients.llmRoles.<role> 为按顺序回退的配置名列表
+	ClientLLMProfiles           = "clients.llmProfiles"
+	ClientLLMProfileAddr        = "addr"
+	ClientLLMProfileModel       = "model"
+	ClientLLMProfileTemperature = "temperature"
+	ClientLLMProfileMaxTokens   = "maxTokens"
+	ClientLLMProfileAPIKeyEnv   = "apiKeyEnv"
+	ClientLLMProfileRespFormat  = "responseFormat"
+	ClientLLMRoles              = "clients.llmRoles"
+
+	// 模型响应缓存，只缓存 temperature 为 0 的调用
+	ClientLLMCacheEnabled        = "clients.llmCache.enabled"
+	ClientLLMCacheBackend        = "clients.llmCache.backend"
+	ClientLLMCacheTTLSeconds     = "clients.llmCache.ttlSeconds"
+	ClientLLMCacheMemoryCapacity = "clients.llmCache.memoryCapacity"
+	ClientLLMCacheKeyPrefix      = "clients.llmCache.keyPrefix"
+	ClientLLMCacheRoles          = "clients.llmCache.roles"
 
 	// Embedding 客户端配置键
 	EmbeddingConfigKeyModelName = "clients.embedding.model_name"
@@ -64,6 +94,45 @@ const (
 	MemoryChunkOverlap        = "memory.chunk_overlap"
 	MemoryChunkMinSize        = "memory.chunk_min_size"
 	MemoryChunkStrategy       = "memory.chunk_strategy"
+
+	// 提示词 token 预算
+	MemoryPromptBudgetProfile   = "memory.prompt_budget.profile"
+	MemoryPromptBudgetDocuments = "memory.prompt_budget.documents"
+	MemoryPromptBudgetSemantic  = "memory.prompt_budget.semantic"
+	MemoryPromptBudgetSummary   = "memory.prompt_budget.summary"
+	MemoryPromptBudgetHistory   = "memory.prompt_budget.history"
+	MemoryPromptBudgetTrimOrder = "memory.prompt_budget.trim_order"
+
+	// 知识文档配置
+	DocumentsChunkMaxSize    = "documents.chunk_max_size"
+	DocumentsChunkOverlap    = "documents.chunk_overlap"
+	DocumentsChunkMinSize    = "documents.chunk_min_size"
+	DocumentsChunkStrategy   = "documents.chunk_strategy"
+	DocumentsMaxContentSize  = "documents.max_content_size"
+	DocumentsSearchLimit     = "documents.search_limit"
+	DocumentsSearchThreshold = "documents.search_threshold"
+
+	// 用量统计配置
+	UsagePricing               = "usage.pricing"
+	UsageBudgetTaskTokens      = "usage.budget.task_tokens"
+	UsageBudgetUserDailyTokens = "usage.budget.user_daily_tokens"
+
+	// 提示词模板配置
+	PromptDefaultLocale = "prompt.default_locale"
+	PromptOverrideDir   = "prompt.override_dir"
+
+	// 任务执行配置
+	TaskJobWorkers   = "task.job_workers"
+	TaskJobQueueSize = "task.job_queue_size"
+
+	// 任务工具配置
+	TaskToolAllowedCommands = "task.tools.allowed_commands"
+	TaskToolTestCommand     = "task.tools.test_command"
+	TaskToolTimeoutSeconds  = "task.tools.timeout_seconds"
+	TaskToolMaxOutputBytes  = "task.tools.max_output_bytes"
+	TaskToolCPUSeconds      = "task.tools.cpu_seconds"
+	TaskToolMemoryMB        = "task.tools.memory_mb"
+	TaskToolFileSizeMB      = "task.tools.file_size_mb"
 )
 
 var instance *config
@@ -161,3 +230,11 @@ func (c *config) GetFloat64OrDefault(key string, defaultValue float64) float64 {
 
 	return defaultValue
 }
+
+func (c *config) GetStringSliceOrDefault(key string, defaultValue []string) []string {
+	if c.IsSet(key) {
+		return c.GetStringSlice(key)
+	}
+
+	return defaultValue
+}
@@ -18,6 +18,12 @@ const (
 	TaskStatusFailed TaskStatus = "failed"
 	// TaskStatusCancelled 已取消
 	TaskStatusCancelled TaskStatus = "cancelled"
+	// TaskStatusBudgetExceeded 超出 token 预算被停止
+	TaskStatusBudgetExceeded TaskStatus = "budget_exceeded"
+	// TaskStatusPaused 已暂停，可从暂停位置恢复
+	TaskStatusPaused TaskStatus = "paused"
+	// TaskStatusAwaitingInput 步骤多次失败，等待人工指导后继续
+	TaskStatusAwaitingInput TaskStatus = "awaiting_input"
 )
 
 // String 返回状态的字符串值
@@ -28,7 +34,7 @@ func (s TaskStatus) String() string {
 // IsValid 检查状态是否有效
 func (s TaskStatus) IsValid() bool {
 	switch s {
-	case TaskStatusPending, TaskStatusInProgress, TaskStatusCompleted, TaskStatusFailed, TaskStatusCancelled:
+	case TaskStatusPending, TaskStatusInProgress, TaskStatusCompleted, TaskStatusFailed, TaskStatusCancelled, TaskStatusBudgetExceeded, TaskStatusPaused, TaskStatusAwaitingInput:
 		return true
 	}
 	return false
@@ -66,6 +72,40 @@ func (s PhaseStatus) IsValid() bool {
 	return false
 }
 
+// =============================================
+// 执行作业状态常量
+// =============================================
+
+// JobStatus 后台执行作业状态
+type JobStatus string
+
+const (
+	// JobStatusQueued 排队中
+	JobStatusQueued JobStatus = "queued"
+	// JobStatusRunning 执行中
+	JobStatusRunning JobStatus = "running"
+	// JobStatusSucceeded 执行结束（任务本身是否完成见执行结果）
+	JobStatusSucceeded JobStatus = "succeeded"
+	// JobStatusFailed 执行出错
+	JobStatusFailed JobStatus = "failed"
+	// JobStatusCancelled 任务被取消，执行中的模型调用随之中断
+	JobStatusCancelled JobStatus = "cancelled"
+	// JobStatusPaused 任务被暂停，恢复时提交新的作业
+	JobStatusPaused JobStatus = "paused"
+	// JobStatusBlocked 任务未完成，等待人工指导或 token 预算超出后停止执行
+	JobStatusBlocked JobStatus = "blocked"
+)
+
+// String 返回状态的字符串值
+func (s JobStatus) String() string {
+	return string(s)
+}
+
+// IsFinished 作业是否已结束
+func (s JobStatus) IsFinished() bool {
+	return s == JobStatusSucceeded || s == JobStatusFailed || s == JobStatusCancelled || s == JobStatusPaused || s == JobStatusBlocked
+}
+
 // =============================================
 // 存储类型常量
 // =============================================
@@ -187,4 +227,12 @@ const (
 	DefaultMaxRetries = 3
 	// DefaultMaxToolResultsInContext 上下文中保留的最大工具结果数
 	DefaultMaxToolResultsInContext = 5
+	// DefaultJobWorkers 默认并发执行作业数
+	DefaultJobWorkers = 4
+	// DefaultJobQueueSize 默认排队作业上限
+	DefaultJobQueueSize = 100
+	// DefaultToolMaxReadBytes 工具单次读取文件的默认上限
+	DefaultToolMaxReadBytes = 256 * 1024
+	// DefaultToolMaxWriteBytes 工具单次写入文件的默认上限
+	DefaultToolMaxWriteBytes = 1024 * 1024
 )
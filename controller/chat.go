@@ -22,15 +22,26 @@ func Chat(ctx *gin.Context) {
 	}
 
 	req := reqBody.ChatRequest
+	req.Locale = requestLocale(ctx, req.Locale)
 	memoryOptions := &reqBody.MemoryContextOptionsRequest
 
 	// 如果记忆选项为空，设置为 nil（使用默认值）
-	if memoryOptions.SessionMemoryLimit == nil &&
-		memoryOptions.SemanticMemoryLimit == nil &&
-		memoryOptions.SemanticThreshold == nil {
+	if memoryOptions.IsEmpty() {
 		memoryOptions = nil
 	}
 
+	if req.Stream {
+		err := factory.GetServiceFactory().NewChatService().ChatStream(ctx, &req, memoryOptions)
+		if err != nil {
+			log.Errorf("Chat stream error: %v", err)
+			// 响应头已写出时只能记录日志，错误事件已在流中推送
+			if !ctx.Writer.Written() {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+		}
+		return
+	}
+
 	res, err := factory.GetServiceFactory().NewChatService().Chat(ctx, &req, memoryOptions)
 	if err != nil {
 		log.Errorf("Chat error: %v", err)
@@ -40,3 +51,11 @@ func Chat(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, res)
 }
+
+// requestLocale 请求未指定提示词语言时使用 Accept-Language 请求头
+func requestLocale(ctx *gin.Context, locale string) string {
+	if locale != "" {
+		return locale
+	}
+	return ctx.GetHeader("Accept-Language")
+}
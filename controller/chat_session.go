@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"ai_task/model"
+	"ai_task/service/factory"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// ListChatSessions 列出用户的聊天会话
+// @Summary 列出聊天会话
+// @Description 按最后活跃时间倒序列出用户的会话，附带标题和消息条数
+// @Tags ChatSession
+// @Produce json
+// @Param user_id path string true "用户ID"
+// @Param limit query int false "分页大小，默认20"
+// @Param offset query int false "偏移量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/user/{user_id}/chat-sessions [get]
+func ListChatSessions(ctx *gin.Context) {
+	userID := ctx.Param("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var req model.ListChatSessionsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sessions, total, err := factory.GetServiceFactory().NewChatService().ListSessions(ctx, userID, &req)
+	if err != nil {
+		log.Errorf("ListChatSessions error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"sessions": sessions, "total": total})
+}
+
+// ListChatSessionMessages 分页获取会话消息
+// @Summary 获取会话消息
+// @Description 按消息先后顺序分页返回会话的完整历史（含已折叠进摘要的消息）
+// @Tags ChatSession
+// @Produce json
+// @Param user_id path string true "用户ID"
+// @Param session_id path string true "会话ID"
+// @Param limit query int false "分页大小，默认20"
+// @Param offset query int false "偏移量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/user/{user_id}/chat-sessions/{session_id}/messages [get]
+func ListChatSessionMessages(ctx *gin.Context) {
+	userID := ctx.Param("user_id")
+	sessionID := ctx.Param("session_id")
+	if userID == "" || sessionID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "user_id and session_id are required"})
+		return
+	}
+
+	var req model.ListChatMessagesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	messages, total, err := factory.GetServiceFactory().NewChatService().ListSessionMessages(ctx, userID, sessionID, &req)
+	if err != nil {
+		log.Errorf("ListChatSessionMessages error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if total == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"messages": messages, "total": total})
+}
+
+// RenameChatSession 重命名会话
+// @Summary 重命名会话
+// @Description 设置会话标题，之后不再使用自动生成的标题
+// @Tags ChatSession
+// @Accept json
+// @Produce json
+// @Param user_id path string true "用户ID"
+// @Param session_id path string true "会话ID"
+// @Param request body model.RenameChatSessionRequest true "新标题"
+// @Success 200 {object} model.ChatSessionResponse
+// @Router /api/v1/user/{user_id}/chat-sessions/{session_id} [put]
+func RenameChatSession(ctx *gin.Context) {
+	userID := ctx.Param("user_id")
+	sessionID := ctx.Param("session_id")
+	if userID == "" || sessionID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "user_id and session_id are required"})
+		return
+	}
+
+	var req model.RenameChatSessionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := factory.GetServiceFactory().NewChatService().RenameSession(ctx, userID, sessionID, &req)
+	if err != nil {
+		log.Errorf("RenameChatSession error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if session == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, session)
+}
+
+// ForkChatSession 从指定消息分叉会话
+// @Summary 分叉会话
+// @Description 将会话中指定消息及之前的历史复制到新会话，原会话不变
+// @Tags ChatSession
+// @Accept json
+// @Produce json
+// @Param user_id path string true "用户ID"
+// @Param session_id path string true "会话ID"
+// @Param request body model.ForkChatSessionRequest true "分叉点"
+// @Success 200 {object} model.ChatSessionResponse
+// @Router /api/v1/user/{user_id}/chat-sessions/{session_id}/fork [post]
+func ForkChatSession(ctx *gin.Context) {
+	userID := ctx.Param("user_id")
+	sessionID := ctx.Param("session_id")
+	if userID == "" || sessionID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "user_id and session_id are required"})
+		return
+	}
+
+	var req model.ForkChatSessionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := factory.GetServiceFactory().NewChatService().ForkSession(ctx, userID, sessionID, &req)
+	if err != nil {
+		log.Errorf("ForkChatSession error: %v", err)
+		status := http.StatusInternalServerError
+		if err.Code == model.ErrorParams {
+			status = http.StatusBadRequest
+		}
+		ctx.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	if session == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "message not found in session"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, session)
+}
+
+// DeleteChatSession 删除会话
+// @Summary 删除会话
+// @Description 删除会话的全部消息、语义记忆分块和摘要，用于用户数据删除请求
+// @Tags ChatSession
+// @Produce json
+// @Param user_id path string true "用户ID"
+// @Param session_id path string true "会话ID"
+// @Success 200 {object} model.DeleteChatSessionResponse
+// @Router /api/v1/user/{user_id}/chat-sessions/{session_id} [delete]
+func DeleteChatSession(ctx *gin.Context) {
+	userID := ctx.Param("user_id")
+	sessionID := ctx.Param("session_id")
+	if userID == "" || sessionID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "user_id and session_id are required"})
+		return
+	}
+
+	result, err := factory.GetServiceFactory().NewChatService().DeleteSession(ctx, userID, sessionID)
+	if err != nil {
+		log.Errorf("DeleteChatSession error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if result == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
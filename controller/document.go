@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"ai_task/model"
+	"ai_task/service/factory"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// CreateDocument 上传知识文档
+// @Summary 上传知识文档
+// @Description 上传文本/Markdown/HTML 文档，分块向量化后可在对话中检索引用。支持 JSON 或 multipart（file 字段）
+// @Tags Document
+// @Accept json,mpfd
+// @Produce json
+// @Param request body model.CreateDocumentRequest true "文档"
+// @Success 200 {object} model.DocumentResponse
+// @Router /api/v1/documents [post]
+func CreateDocument(ctx *gin.Context) {
+	var req model.CreateDocumentRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	documentService := factory.GetServiceFactory().NewDocumentService()
+
+	// multipart 上传文件时以文件内容为准
+	if fileHeader, err := ctx.FormFile("file"); err == nil {
+		if fileHeader.Size > int64(documentService.MaxContentSize()) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("file exceeds %d bytes", documentService.MaxContentSize())})
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		data, err := io.ReadAll(file)
+		_ = file.Close()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		req.Content = string(data)
+		req.Filename = fileHeader.Filename
+	}
+
+	doc, err := documentService.Create(ctx, &req)
+	if err != nil {
+		log.Errorf("CreateDocument error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, doc)
+}
+
+// ListDocuments 列出知识文档
+// @Summary 列出知识文档
+// @Description 按用户或命名空间列出文档及索引状态，不返回原文
+// @Tags Document
+// @Produce json
+// @Param user_id query string false "用户ID"
+// @Param namespace query string false "命名空间"
+// @Param status query string false "索引状态(indexing/ready/failed)"
+// @Param limit query int false "分页大小"
+// @Param offset query int false "偏移量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/documents [get]
+func ListDocuments(ctx *gin.Context) {
+	var req model.ListDocumentsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	docs, err := factory.GetServiceFactory().NewDocumentService().List(ctx, &req)
+	if err != nil {
+		log.Errorf("ListDocuments error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"documents": docs})
+}
+
+// GetDocument 获取知识文档
+// @Summary 获取知识文档详情
+// @Description 获取文档原文及索引状态
+// @Tags Document
+// @Produce json
+// @Param doc_id path int true "文档ID"
+// @Success 200 {object} model.DocumentResponse
+// @Router /api/v1/documents/{doc_id} [get]
+func GetDocument(ctx *gin.Context) {
+	docID, ok := parseDocID(ctx)
+	if !ok {
+		return
+	}
+
+	doc, err := factory.GetServiceFactory().NewDocumentService().Get(ctx, docID)
+	if err != nil {
+		log.Errorf("GetDocument error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if doc == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, doc)
+}
+
+// ReindexDocument 重建知识文档索引
+// @Summary 重建知识文档索引
+// @Description 按当前分块配置和 embedding 模型重新分块向量化，整体替换旧分块
+// @Tags Document
+// @Produce json
+// @Param doc_id path int true "文档ID"
+// @Success 200 {object} model.DocumentResponse
+// @Router /api/v1/documents/{doc_id}/reindex [post]
+func ReindexDocument(ctx *gin.Context) {
+	docID, ok := parseDocID(ctx)
+	if !ok {
+		return
+	}
+
+	doc, err := factory.GetServiceFactory().NewDocumentService().Reindex(ctx, docID)
+	if err != nil {
+		log.Errorf("ReindexDocument error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if doc == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, doc)
+}
+
+// DeleteDocument 删除知识文档
+// @Summary 删除知识文档
+// @Description 删除文档及其全部分块
+// @Tags Document
+// @Produce json
+// @Param doc_id path int true "文档ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/documents/{doc_id} [delete]
+func DeleteDocument(ctx *gin.Context) {
+	docID, ok := parseDocID(ctx)
+	if !ok {
+		return
+	}
+
+	deleted, err := factory.GetServiceFactory().NewDocumentService().Delete(ctx, docID)
+	if err != nil {
+		log.Errorf("DeleteDocument error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !deleted {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "document deleted"})
+}
+
+// parseDocID 解析路径中的 doc_id，不合法时直接返回 400
+func parseDocID(ctx *gin.Context) (int64, bool) {
+	docID, err := strconv.ParseInt(ctx.Param("doc_id"), 10, 64)
+	if err != nil || docID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid doc_id"})
+		return 0, false
+	}
+	return docID, true
+}
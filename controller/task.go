@@ -1,9 +1,15 @@
 package controller
 
 import (
+	"ai_task/config"
+	"ai_task/pkg/clients/llm"
 	"ai_task/pkg/task"
+	"ai_task/service/factory"
+	"errors"
 	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
@@ -18,7 +24,24 @@ var (
 func getTaskService() *task.Service {
 	taskServiceOnce.Do(func() {
 		var err error
-		taskService, err = task.NewService(nil)
+		serviceFactory := factory.GetServiceFactory()
+		cfg := config.GetInstance()
+		taskConfig := task.DefaultTaskManagerConfig()
+		taskConfig.JobWorkers = cfg.GetIntOrDefault(config.TaskJobWorkers, taskConfig.JobWorkers)
+		taskConfig.JobQueueSize = cfg.GetIntOrDefault(config.TaskJobQueueSize, taskConfig.JobQueueSize)
+		tools := &taskConfig.Tools
+		tools.TestCommand = cfg.GetStringSliceOrDefault(config.TaskToolTestCommand, tools.TestCommand)
+		tools.Command.AllowedCommands = cfg.GetStringSliceOrDefault(config.TaskToolAllowedCommands, nil)
+		tools.Command.Timeout = time.Duration(cfg.GetIntOrDefault(config.TaskToolTimeoutSeconds, int(tools.Command.Timeout/time.Second))) * time.Second
+		tools.Command.MaxOutputBytes = cfg.GetIntOrDefault(config.TaskToolMaxOutputBytes, tools.Command.MaxOutputBytes)
+		tools.Command.CPUSeconds = cfg.GetIntOrDefault(config.TaskToolCPUSeconds, tools.Command.CPUSeconds)
+		tools.Command.MemoryBytes = int64(cfg.GetIntOrDefault(config.TaskToolMemoryMB, int(tools.Command.MemoryBytes>>20))) << 20
+		tools.Command.FileSizeBytes = int64(cfg.GetIntOrDefault(config.TaskToolFileSizeMB, int(tools.Command.FileSizeBytes>>20))) << 20
+		taskService, err = task.NewService(taskConfig,
+			task.WithRetriever(serviceFactory.NewDocumentRetriever()),
+			task.WithModels(serviceFactory.NewModels()),
+			task.WithBudget(serviceFactory.NewUsageBudget()),
+		)
 		if err != nil {
 			log.Fatalf("Failed to create task service: %v", err)
 		}
@@ -26,9 +49,21 @@ func getTaskService() *task.Service {
 	return taskService
 }
 
+// InitTaskService 启动时创建任务服务，恢复上次未执行完的后台作业
+func InitTaskService() {
+	getTaskService()
+}
+
+// CloseTaskService 退出前停止后台作业队列，执行中的作业被中断，下次启动时从未完成的步骤恢复
+func CloseTaskService() {
+	if taskService != nil {
+		taskService.Close()
+	}
+}
+
 // CreateTask 创建任务
 // @Summary 创建新任务
-// @Description 根据目标创建任务计划，支持 LLM 自动规划
+// @Description 根据目标创建任务计划，支持 LLM 自动规划；规划失败时任务标记为失败，模型输出的计划修复后仍无效时返回 422
 // @Tags Task
 // @Accept json
 // @Produce json
@@ -41,11 +76,17 @@ func CreateTask(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	req.Locale = requestLocale(ctx, req.Locale)
 
 	resp, err := getTaskService().CreateTask(ctx, &req)
 	if err != nil {
 		log.Errorf("CreateTask error: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		status := http.StatusInternalServerError
+		if errors.Is(err, llm.ErrInvalidStructuredOutput) {
+			// 模型给出的计划多次修复后仍不符合结构
+			status = http.StatusUnprocessableEntity
+		}
+		ctx.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -119,13 +160,19 @@ func GetTaskContext(ctx *gin.Context) {
 // @Produce json
 // @Param user_id query string false "用户ID"
 // @Param session_id query string false "会话ID"
+// @Param status query string false "任务状态，如 awaiting_input 列出等待人工指导的任务"
 // @Success 200 {array} task.Task
 // @Router /api/v1/tasks [get]
 func ListTasks(ctx *gin.Context) {
 	userID := ctx.Query("user_id")
 	sessionID := ctx.Query("session_id")
+	status := task.TaskStatus(ctx.Query("status"))
+	if status != "" && !status.IsValid() {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid status: " + status.String()})
+		return
+	}
 
-	tasks, err := getTaskService().ListTasks(ctx, userID, sessionID)
+	tasks, err := getTaskService().ListTasks(ctx, userID, sessionID, status)
 	if err != nil {
 		log.Errorf("ListTasks error: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -137,12 +184,12 @@ func ListTasks(ctx *gin.Context) {
 
 // ExecuteTask 执行任务
 // @Summary 执行任务
-// @Description 执行任务或指定阶段
+// @Description 提交后台执行作业，立即返回作业，通过 /api/v1/job/{job_id} 查询进度与结果
 // @Tags Task
 // @Accept json
 // @Produce json
 // @Param request body task.ExecuteRequest true "执行请求"
-// @Success 200 {object} task.ExecuteResponse
+// @Success 202 {object} task.Job
 // @Router /api/v1/task/execute [post]
 func ExecuteTask(ctx *gin.Context) {
 	var req task.ExecuteRequest
@@ -150,15 +197,238 @@ func ExecuteTask(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	req.Locale = requestLocale(ctx, req.Locale)
 
-	resp, err := getTaskService().ExecuteTask(ctx, &req)
+	job, err := getTaskService().SubmitExecution(ctx, &req)
 	if err != nil {
 		log.Errorf("ExecuteTask error: %v", err)
+		ctx.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, job)
+}
+
+// jobErrorStatus 提交、取消、暂停、恢复作业失败时的状态码，任务不存在时返回 404，任务状态不允许该操作时返回 409
+func jobErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, task.ErrTaskNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, task.ErrJobActive), errors.Is(err, task.ErrNoActiveJob),
+		errors.Is(err, task.ErrTaskPaused), errors.Is(err, task.ErrTaskNotPaused),
+		errors.Is(err, task.ErrTaskCancelled), errors.Is(err, task.ErrTaskFinished),
+		errors.Is(err, task.ErrTaskAwaitingInput), errors.Is(err, task.ErrTaskNotAwaitingInput):
+		return http.StatusConflict
+	case errors.Is(err, task.ErrJobQueueFull):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CancelTask 取消任务
+// @Summary 取消任务
+// @Description 取消任务，执行中的作业立即中断，进行中的模型调用随之返回；已暂停的任务也可以取消
+// @Tags Task
+// @Produce json
+// @Param task_id path string true "任务ID"
+// @Success 200 {object} task.Task
+// @Router /api/v1/task/{task_id}/cancel [post]
+func CancelTask(ctx *gin.Context) {
+	taskID := ctx.Param("task_id")
+	if taskID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "task_id is required"})
+		return
+	}
+
+	t, err := getTaskService().CancelTask(ctx, taskID)
+	if err != nil {
+		log.Errorf("CancelTask error: %v", err)
+		ctx.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, t)
+}
+
+// PauseTask 暂停任务
+// @Summary 暂停任务
+// @Description 排队中的作业立即暂停；执行中的作业在当前步骤完成后暂停并记录恢复位置，返回的作业仍为 running，轮询作业直到变为 paused
+// @Tags Task
+// @Produce json
+// @Param task_id path string true "任务ID"
+// @Success 202 {object} task.Job
+// @Router /api/v1/task/{task_id}/pause [post]
+func PauseTask(ctx *gin.Context) {
+	taskID := ctx.Param("task_id")
+	if taskID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "task_id is required"})
+		return
+	}
+
+	job, err := getTaskService().PauseTask(ctx, taskID)
+	if err != nil {
+		log.Errorf("PauseTask error: %v", err)
+		ctx.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, job)
+}
+
+// ResumeTask 恢复任务
+// @Summary 恢复任务
+// @Description 从暂停位置继续执行已暂停的任务，提交新的后台作业
+// @Tags Task
+// @Produce json
+// @Param task_id path string true "任务ID"
+// @Param locale query string false "提示词语言 zh/en，未指定时使用 Accept-Language 或用户偏好"
+// @Success 202 {object} task.Job
+// @Router /api/v1/task/{task_id}/resume [post]
+func ResumeTask(ctx *gin.Context) {
+	taskID := ctx.Param("task_id")
+	if taskID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "task_id is required"})
+		return
+	}
+
+	job, err := getTaskService().ResumeTask(ctx, taskID, requestLocale(ctx, ctx.Query("locale")))
+	if err != nil {
+		log.Errorf("ResumeTask error: %v", err)
+		ctx.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, job)
+}
+
+// SubmitGuidanceRequest 人工指导请求
+type SubmitGuidanceRequest struct {
+	Answer string `json:"answer" binding:"required"`
+	Locale string `json:"locale"`
+}
+
+// SubmitGuidance 提交人工指导
+// @Summary 提交人工指导
+// @Description 回答等待人工指导（awaiting_input）任务的问题，指导记录为决策并注入失败步骤的提示词，随后提交新的后台作业从该步骤继续执行
+// @Tags Task
+// @Accept json
+// @Produce json
+// @Param task_id path string true "任务ID"
+// @Param request body SubmitGuidanceRequest true "指导内容"
+// @Success 202 {object} task.Job
+// @Router /api/v1/task/{task_id}/guidance [post]
+func SubmitGuidance(ctx *gin.Context) {
+	taskID := ctx.Param("task_id")
+	if taskID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "task_id is required"})
+		return
+	}
+
+	var req SubmitGuidanceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := getTaskService().SubmitGuidance(ctx, taskID, req.Answer, requestLocale(ctx, req.Locale))
+	if err != nil {
+		log.Errorf("SubmitGuidance error: %v", err)
+		ctx.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, job)
+}
+
+// GetJob 获取执行作业
+// @Summary 获取执行作业
+// @Description 获取后台执行作业的状态、当前阶段与步骤，结束后包含执行结果
+// @Tags Task
+// @Produce json
+// @Param job_id path string true "作业ID"
+// @Success 200 {object} task.Job
+// @Router /api/v1/job/{job_id} [get]
+func GetJob(ctx *gin.Context) {
+	jobID := ctx.Param("job_id")
+	if jobID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "job_id is required"})
+		return
+	}
+
+	job, err := getTaskService().GetJob(ctx, jobID)
+	if err != nil {
+		log.Errorf("GetJob error: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, resp)
+	if job == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, job)
+}
+
+// ListTaskJobs 列出任务的执行作业
+// @Summary 列出任务的执行作业
+// @Description 按提交时间升序列出任务的全部执行作业
+// @Tags Task
+// @Produce json
+// @Param task_id path string true "任务ID"
+// @Success 200 {array} task.Job
+// @Router /api/v1/task/{task_id}/jobs [get]
+func ListTaskJobs(ctx *gin.Context) {
+	taskID := ctx.Param("task_id")
+	if taskID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "task_id is required"})
+		return
+	}
+
+	jobs, err := getTaskService().ListJobs(ctx, taskID)
+	if err != nil {
+		log.Errorf("ListTaskJobs error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+// ListToolCalls 列出工具调用记录
+// @Summary 列出工具调用记录
+// @Description 按调用时间升序列出任务的工具调用记录
+// @Tags Task
+// @Produce json
+// @Param task_id path string true "任务ID"
+// @Param limit query int false "只返回最近的条数，默认全部"
+// @Success 200 {array} task.ToolCall
+// @Router /api/v1/task/{task_id}/tool-calls [get]
+func ListToolCalls(ctx *gin.Context) {
+	taskID := ctx.Param("task_id")
+	if taskID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "task_id is required"})
+		return
+	}
+
+	limit := 0
+	if s := ctx.Query("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+
+	calls, err := getTaskService().ListToolCalls(ctx, taskID, limit)
+	if err != nil {
+		log.Errorf("ListToolCalls error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"tool_calls": calls})
 }
 
 // UpdatePhaseRequest 更新阶段请求
@@ -462,11 +732,11 @@ func StartSession(ctx *gin.Context) {
 
 // ExecuteSession 执行会话
 // @Summary 执行会话
-// @Description 执行会话中的任务
+// @Description 以后台作业执行会话中的任务，立即返回作业，通过 /api/v1/job/{job_id} 查询进度与结果
 // @Tags Session
 // @Produce json
 // @Param session_id path string true "会话ID"
-// @Success 200 {object} task.ExecuteResponse
+// @Success 202 {object} task.Job
 // @Router /api/v1/session/{session_id}/execute [post]
 func ExecuteSession(ctx *gin.Context) {
 	sessionID := ctx.Param("session_id")
@@ -475,14 +745,14 @@ func ExecuteSession(ctx *gin.Context) {
 		return
 	}
 
-	resp, err := getTaskService().ExecuteSession(ctx, sessionID)
+	job, err := getTaskService().SubmitSessionExecution(ctx, sessionID)
 	if err != nil {
 		log.Errorf("ExecuteSession error: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, resp)
+	ctx.JSON(http.StatusAccepted, job)
 }
 
 // CheckSessionStop 检查会话停止
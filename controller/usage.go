@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"ai_task/model"
+	"ai_task/pkg/clients/llm"
+	"ai_task/service/factory"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetUsage 查询模型用量
+// @Summary 查询模型用量
+// @Description 按用户、会话、任务、角色和日期范围过滤，按 group_by 分组汇总 token 用量与估算费用
+// @Tags Usage
+// @Produce json
+// @Param user_id query string false "用户ID"
+// @Param session_id query string false "会话ID"
+// @Param task_id query string false "任务ID"
+// @Param role query string false "调用角色(planner/executor/summarizer/extractor/chat/embedding)"
+// @Param kind query string false "调用类型(llm/embedding)"
+// @Param start query string false "开始日期(含)，YYYY-MM-DD 或 RFC3339"
+// @Param end query string false "结束日期(含)，YYYY-MM-DD 或 RFC3339"
+// @Param group_by query string false "分组维度，逗号分隔：user_id,session_id,task_id,role,kind,model,day"
+// @Success 200 {object} model.GetUsageResponse
+// @Router /api/v1/usage [get]
+func GetUsage(ctx *gin.Context) {
+	var req model.GetUsageRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := factory.GetServiceFactory().NewUsageService().Get(ctx, &req)
+	if err != nil {
+		log.Errorf("GetUsage error: %v", err)
+		if err.Code == model.ErrorParams {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// GetCacheMetrics 查询模型响应缓存命中统计
+// @Summary 查询模型响应缓存命中统计
+// @Description 返回进程启动以来的命中、未命中、跳过和存储错误次数，缓存未启用时 enabled 为 false
+// @Tags Usage
+// @Produce json
+// @Success 200 {object} llm.CacheMetrics
+// @Router /api/v1/usage/cache [get]
+func GetCacheMetrics(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, llm.GetCache().Metrics())
+}
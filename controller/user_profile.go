@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"ai_task/model"
+	"ai_task/service/factory"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// ListUserProfile 列出用户画像
+// @Summary 列出用户画像
+// @Description 列出助手记录的用户画像属性，支持 key 前缀和最低置信度过滤，返回来源信息
+// @Tags UserProfile
+// @Produce json
+// @Param user_id path string true "用户ID"
+// @Param key_prefix query string false "key 前缀"
+// @Param min_confidence query number false "最低置信度(0-1)"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/user/{user_id}/profile [get]
+func ListUserProfile(ctx *gin.Context) {
+	userID := ctx.Param("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	var req model.ListUserProfileRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profiles, err := factory.GetServiceFactory().NewProfileService().List(ctx, userID, &req)
+	if err != nil {
+		log.Errorf("ListUserProfile error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"profiles": profiles})
+}
+
+// GetUserProfile 获取用户画像属性
+// @Summary 获取用户画像属性
+// @Description 获取单个画像属性及其来源消息和历史值
+// @Tags UserProfile
+// @Produce json
+// @Param user_id path string true "用户ID"
+// @Param key path string true "属性键名"
+// @Success 200 {object} model.UserProfileResponse
+// @Router /api/v1/user/{user_id}/profile/{key} [get]
+func GetUserProfile(ctx *gin.Context) {
+	userID := ctx.Param("user_id")
+	key := ctx.Param("key")
+	if userID == "" || key == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "user_id and key are required"})
+		return
+	}
+
+	profile, err := factory.GetServiceFactory().NewProfileService().Get(ctx, userID, key)
+	if err != nil {
+		log.Errorf("GetUserProfile error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if profile == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "profile key not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, profile)
+}
+
+// UpsertUserProfile 设置用户画像属性
+// @Summary 设置或修正用户画像属性
+// @Description 人工设置画像属性，原值记录到 meta.history，来源标记为 manual
+// @Tags UserProfile
+// @Accept json
+// @Produce json
+// @Param user_id path string true "用户ID"
+// @Param key path string true "属性键名"
+// @Param request body model.UpsertUserProfileRequest true "属性值"
+// @Success 200 {object} model.UserProfileResponse
+// @Router /api/v1/user/{user_id}/profile/{key} [put]
+func UpsertUserProfile(ctx *gin.Context) {
+	userID := ctx.Param("user_id")
+	key := ctx.Param("key")
+	if userID == "" || key == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "user_id and key are required"})
+		return
+	}
+
+	var req model.UpsertUserProfileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := factory.GetServiceFactory().NewProfileService().Upsert(ctx, userID, key, &req)
+	if err != nil {
+		log.Errorf("UpsertUserProfile error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, profile)
+}
+
+// DeleteUserProfile 删除用户画像属性
+// @Summary 删除用户画像属性
+// @Description 删除单个画像属性
+// @Tags UserProfile
+// @Produce json
+// @Param user_id path string true "用户ID"
+// @Param key path string true "属性键名"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/user/{user_id}/profile/{key} [delete]
+func DeleteUserProfile(ctx *gin.Context) {
+	userID := ctx.Param("user_id")
+	key := ctx.Param("key")
+	if userID == "" || key == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "user_id and key are required"})
+		return
+	}
+
+	deleted, err := factory.GetServiceFactory().NewProfileService().Delete(ctx, userID, key)
+	if err != nil {
+		log.Errorf("DeleteUserProfile error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !deleted {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "profile key not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "profile key deleted"})
+}
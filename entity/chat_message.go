@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// ========== 聊天消息表 ==========
+
+const (
+	TableNameChatMessage = "chat_messages"
+
+	ChatMessageFieldID        = "id"
+	ChatMessageFieldUserID    = "user_id"
+	ChatMessageFieldSessionID = "session_id"
+	ChatMessageFieldRole      = "role"
+	ChatMessageFieldContent   = "content"
+	ChatMessageFieldTruncated = "truncated"
+	ChatMessageFieldCreatedAt = "created_at"
+)
+
+// ChatMessage 聊天消息数据库实体（每一轮 user/assistant 发言一条记录）
+type ChatMessage struct {
+	ID        int64     `xorm:"pk autoincr 'id'" json:"id"`
+	UserID    string    `xorm:"varchar(64) index 'user_id'" json:"user_id"`
+	SessionID string    `xorm:"varchar(64) index 'session_id'" json:"session_id"`
+	Role      string    `xorm:"varchar(32) 'role'" json:"role"`
+	Content   string    `xorm:"text 'content'" json:"content"`
+	Truncated bool      `xorm:"bool default false 'truncated'" json:"truncated"` // 流式输出中断，内容不完整
+	CreatedAt time.Time `xorm:"created 'created_at'" json:"created_at"`
+}
+
+func (e *ChatMessage) TableName() string {
+	return TableNameChatMessage
+}
@@ -0,0 +1,46 @@
+package entity
+
+import "time"
+
+// ========== 聊天会话表 ==========
+
+const (
+	TableNameChatSession = "chat_sessions"
+
+	ChatSessionFieldID                = "id"
+	ChatSessionFieldUserID            = "user_id"
+	ChatSessionFieldSessionID         = "session_id"
+	ChatSessionFieldTitle             = "title"
+	ChatSessionFieldTitleSource       = "title_source"
+	ChatSessionFieldMessageCount      = "message_count"
+	ChatSessionFieldForkedFromSession = "forked_from_session"
+	ChatSessionFieldForkedFromMsgID   = "forked_from_msg_id"
+	ChatSessionFieldLastActiveAt      = "last_active_at"
+	ChatSessionFieldCreatedAt         = "created_at"
+	ChatSessionFieldUpdatedAt         = "updated_at"
+)
+
+// 会话标题来源
+const (
+	ChatSessionTitleAuto   = "auto"   // 取首条用户消息自动生成
+	ChatSessionTitleManual = "manual" // 用户重命名
+)
+
+// ChatSession 聊天会话元信息，每个 (user_id, session_id) 一条，消息本身存储在 chat_messages
+type ChatSession struct {
+	ID                int64     `xorm:"pk autoincr 'id'" json:"id"`
+	UserID            string    `xorm:"varchar(64) index 'user_id'" json:"user_id"`
+	SessionID         string    `xorm:"varchar(64) index 'session_id'" json:"session_id"`
+	Title             string    `xorm:"varchar(255) 'title'" json:"title"`
+	TitleSource       string    `xorm:"varchar(32) 'title_source'" json:"title_source"`
+	MessageCount      int       `xorm:"int 'message_count'" json:"message_count"`
+	ForkedFromSession string    `xorm:"varchar(64) 'forked_from_session'" json:"forked_from_session"` // 分叉来源会话，非分叉会话为空
+	ForkedFromMsgID   int64     `xorm:"bigint 'forked_from_msg_id'" json:"forked_from_msg_id"`        // 分叉点消息ID（来源会话中）
+	LastActiveAt      time.Time `xorm:"'last_active_at'" json:"last_active_at"`
+	CreatedAt         time.Time `xorm:"created 'created_at'" json:"created_at"`
+	UpdatedAt         time.Time `xorm:"updated 'updated_at'" json:"updated_at"`
+}
+
+func (e *ChatSession) TableName() string {
+	return TableNameChatSession
+}
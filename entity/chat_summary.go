@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// ========== 会话摘要表 ==========
+
+const (
+	TableNameChatSummary = "chat_summaries"
+
+	ChatSummaryFieldID                = "id"
+	ChatSummaryFieldUserID            = "user_id"
+	ChatSummaryFieldSessionID         = "session_id"
+	ChatSummaryFieldContent           = "content"
+	ChatSummaryFieldCoveredUntilMsgID = "covered_until_msg_id"
+	ChatSummaryFieldMessageCount      = "message_count"
+	ChatSummaryFieldCreatedAt         = "created_at"
+	ChatSummaryFieldUpdatedAt         = "updated_at"
+)
+
+// ChatSummary 会话滚动摘要，每个会话一条，覆盖 id <= CoveredUntilMsgID 的全部消息
+type ChatSummary struct {
+	ID                int64     `xorm:"pk autoincr 'id'" json:"id"`
+	UserID            string    `xorm:"varchar(64) index 'user_id'" json:"user_id"`
+	SessionID         string    `xorm:"varchar(64) index 'session_id'" json:"session_id"`
+	Content           string    `xorm:"text 'content'" json:"content"`
+	CoveredUntilMsgID int64     `xorm:"bigint 'covered_until_msg_id'" json:"covered_until_msg_id"`
+	MessageCount      int       `xorm:"int 'message_count'" json:"message_count"`
+	CreatedAt         time.Time `xorm:"created 'created_at'" json:"created_at"`
+	UpdatedAt         time.Time `xorm:"updated 'updated_at'" json:"updated_at"`
+}
+
+func (e *ChatSummary) TableName() string {
+	return TableNameChatSummary
+}
@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// ========== 文档分块表 ==========
+
+const (
+	TableNameDocChunk = "doc_chunks"
+
+	DocChunkFieldID        = "id"
+	DocChunkFieldDocID     = "doc_id"
+	DocChunkFieldUserID    = "user_id"
+	DocChunkFieldNamespace = "namespace"
+	DocChunkFieldChunkIdx  = "chunk_idx"
+	DocChunkFieldContent   = "content"
+	DocChunkFieldEmbedding = "embedding"
+	DocChunkFieldCreatedAt = "created_at"
+)
+
+// DocChunk 文档分块及其向量（pgvector），user_id/namespace 冗余自文档，便于检索时过滤
+type DocChunk struct {
+	ID        int64     `xorm:"pk autoincr 'id'" json:"id"`
+	DocID     int64     `xorm:"bigint index 'doc_id'" json:"doc_id"`
+	UserID    string    `xorm:"varchar(64) index 'user_id'" json:"user_id"`
+	Namespace string    `xorm:"varchar(64) index 'namespace'" json:"namespace"`
+	ChunkIdx  int       `xorm:"int 'chunk_idx'" json:"chunk_idx"`
+	Content   string    `xorm:"text 'content'" json:"content"`
+	Embedding string    `xorm:"'embedding'" json:"-"` // pgvector 文本格式 [x,y,...]
+	CreatedAt time.Time `xorm:"created 'created_at'" json:"created_at"`
+}
+
+func (e *DocChunk) TableName() string {
+	return TableNameDocChunk
+}
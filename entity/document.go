@@ -0,0 +1,47 @@
+package entity
+
+import "time"
+
+// ========== 知识文档表 ==========
+
+const (
+	TableNameDocument = "documents"
+
+	DocumentFieldID          = "id"
+	DocumentFieldUserID      = "user_id"
+	DocumentFieldNamespace   = "namespace"
+	DocumentFieldTitle       = "title"
+	DocumentFieldContentType = "content_type"
+	DocumentFieldContent     = "content"
+	DocumentFieldStatus      = "status"
+	DocumentFieldChunkCount  = "chunk_count"
+	DocumentFieldError       = "error"
+	DocumentFieldCreatedAt   = "created_at"
+	DocumentFieldUpdatedAt   = "updated_at"
+)
+
+// 文档索引状态
+const (
+	DocumentStatusIndexing = "indexing" // 分块、向量化中
+	DocumentStatusReady    = "ready"    // 已建立索引，可被检索
+	DocumentStatusFailed   = "failed"   // 索引失败，见 error 字段
+)
+
+// Document 用户上传的知识文档，保留原文以便重建索引
+type Document struct {
+	ID          int64     `xorm:"pk autoincr 'id'" json:"id"`
+	UserID      string    `xorm:"varchar(64) index 'user_id'" json:"user_id"`
+	Namespace   string    `xorm:"varchar(64) index 'namespace'" json:"namespace"`
+	Title       string    `xorm:"varchar(255) 'title'" json:"title"`
+	ContentType string    `xorm:"varchar(32) 'content_type'" json:"content_type"`
+	Content     string    `xorm:"text 'content'" json:"content"`
+	Status      string    `xorm:"varchar(32) 'status'" json:"status"`
+	ChunkCount  int       `xorm:"int 'chunk_count'" json:"chunk_count"`
+	Error       string    `xorm:"text 'error'" json:"error"`
+	CreatedAt   time.Time `xorm:"created 'created_at'" json:"created_at"`
+	UpdatedAt   time.Time `xorm:"updated 'updated_at'" json:"updated_at"`
+}
+
+func (e *Document) TableName() string {
+	return TableNameDocument
+}
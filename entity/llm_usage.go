@@ -0,0 +1,44 @@
+package entity
+
+import "time"
+
+// ========== 模型用量表 ==========
+
+const (
+	TableNameLLMUsage = "llm_usage"
+
+	LLMUsageFieldID               = "id"
+	LLMUsageFieldUserID           = "user_id"
+	LLMUsageFieldSessionID        = "session_id"
+	LLMUsageFieldTaskID           = "task_id"
+	LLMUsageFieldRole             = "role"
+	LLMUsageFieldKind             = "kind"
+	LLMUsageFieldModel            = "model"
+	LLMUsageFieldPromptTokens     = "prompt_tokens"
+	LLMUsageFieldCompletionTokens = "completion_tokens"
+	LLMUsageFieldCachedTokens     = "cached_tokens"
+	LLMUsageFieldTotalTokens      = "total_tokens"
+	LLMUsageFieldCost             = "cost"
+	LLMUsageFieldCreatedAt        = "created_at"
+)
+
+// LLMUsage 一次模型或 embedding 调用的 token 用量
+type LLMUsage struct {
+	ID               int64     `xorm:"pk autoincr 'id'" json:"id"`
+	UserID           string    `xorm:"varchar(64) index 'user_id'" json:"user_id"`
+	SessionID        string    `xorm:"varchar(64) 'session_id'" json:"session_id"`
+	TaskID           string    `xorm:"varchar(64) index 'task_id'" json:"task_id"`
+	Role             string    `xorm:"varchar(32) 'role'" json:"role"` // 调用角色：planner/executor/summarizer/extractor/chat/embedding
+	Kind             string    `xorm:"varchar(32) 'kind'" json:"kind"` // 调用类型：llm/embedding
+	Model            string    `xorm:"varchar(128) 'model'" json:"model"`
+	PromptTokens     int       `xorm:"int 'prompt_tokens'" json:"prompt_tokens"`
+	CompletionTokens int       `xorm:"int 'completion_tokens'" json:"completion_tokens"`
+	CachedTokens     int       `xorm:"int 'cached_tokens'" json:"cached_tokens"` // 命中提示词缓存的 token，包含在 prompt_tokens 中
+	TotalTokens      int       `xorm:"int 'total_tokens'" json:"total_tokens"`
+	Cost             float64   `xorm:"double 'cost'" json:"cost"` // 按 usage.pricing 估算的费用，未配置价格时为 0
+	CreatedAt        time.Time `xorm:"created 'created_at'" json:"created_at"`
+}
+
+func (e *LLMUsage) TableName() string {
+	return TableNameLLMUsage
+}
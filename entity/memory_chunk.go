@@ -0,0 +1,36 @@
+package entity
+
+import "time"
+
+// ========== 语义记忆分块表 ==========
+
+const (
+	TableNameMemoryChunk = "memory_chunks"
+
+	MemoryChunkFieldID        = "id"
+	MemoryChunkFieldUserID    = "user_id"
+	MemoryChunkFieldSessionID = "session_id"
+	MemoryChunkFieldMessageID = "message_id"
+	MemoryChunkFieldRole      = "role"
+	MemoryChunkFieldChunkIdx  = "chunk_idx"
+	MemoryChunkFieldContent   = "content"
+	MemoryChunkFieldEmbedding = "embedding"
+	MemoryChunkFieldCreatedAt = "created_at"
+)
+
+// MemoryChunk 对话消息分块及其向量（pgvector），用于语义记忆检索
+type MemoryChunk struct {
+	ID        int64     `xorm:"pk autoincr 'id'" json:"id"`
+	UserID    string    `xorm:"varchar(64) index 'user_id'" json:"user_id"`
+	SessionID string    `xorm:"varchar(64) index 'session_id'" json:"session_id"`
+	MessageID int64     `xorm:"bigint index 'message_id'" json:"message_id"`
+	Role      string    `xorm:"varchar(32) 'role'" json:"role"`
+	ChunkIdx  int       `xorm:"int 'chunk_idx'" json:"chunk_idx"`
+	Content   string    `xorm:"text 'content'" json:"content"`
+	Embedding string    `xorm:"'embedding'" json:"-"` // pgvector 文本格式 [x,y,...]
+	CreatedAt time.Time `xorm:"created 'created_at'" json:"created_at"`
+}
+
+func (e *MemoryChunk) TableName() string {
+	return TableNameMemoryChunk
+}
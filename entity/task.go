@@ -17,6 +17,9 @@ const (
 	TaskFieldDecisionsJSON = "decisions_json"
 	TaskFieldErrorsJSON    = "errors_json"
 	TaskFieldStatus        = "status"
+	TaskFieldCursorJSON    = "cursor_json"
+	TaskFieldPendingJSON   = "pending_input_json"
+	TaskFieldGuidanceJSON  = "guidance_json"
 	TaskFieldToolCallCount = "tool_call_count"
 	TaskFieldNeedsReread   = "needs_reread"
 	TaskFieldCreatedAt     = "created_at"
@@ -36,6 +39,9 @@ type Task struct {
 	DecisionsJSON string     `xorm:"text 'decisions_json'" json:"decisions_json"`
 	ErrorsJSON    string     `xorm:"text 'errors_json'" json:"errors_json"`
 	Status        string     `xorm:"varchar(32) index 'status'" json:"status"`
+	CursorJSON    string     `xorm:"text 'cursor_json'" json:"cursor_json"`
+	PendingJSON   string     `xorm:"text 'pending_input_json'" json:"pending_input_json"`
+	GuidanceJSON  string     `xorm:"text 'guidance_json'" json:"guidance_json"`
 	ToolCallCount int        `xorm:"int 'tool_call_count'" json:"tool_call_count"`
 	NeedsReread   bool       `xorm:"bool 'needs_reread'" json:"needs_reread"`
 	CreatedAt     time.Time  `xorm:"created 'created_at'" json:"created_at"`
@@ -102,3 +108,77 @@ type TaskProgress struct {
 func (e *TaskProgress) TableName() string {
 	return TableNameTaskProgress
 }
+
+// ========== 任务执行作业表 ==========
+
+const (
+	TableNameTaskJob = "task_jobs"
+
+	TaskJobFieldID           = "id"
+	TaskJobFieldTaskID       = "task_id"
+	TaskJobFieldSessionID    = "session_id"
+	TaskJobFieldPhaseID      = "phase_id"
+	TaskJobFieldLocale       = "locale"
+	TaskJobFieldStatus       = "status"
+	TaskJobFieldCurrentPhase = "current_phase"
+	TaskJobFieldCurrentStep  = "current_step"
+	TaskJobFieldResultJSON   = "result_json"
+	TaskJobFieldError        = "error"
+	TaskJobFieldAttempts     = "attempts"
+	TaskJobFieldCreatedAt    = "created_at"
+	TaskJobFieldStartedAt    = "started_at"
+	TaskJobFieldFinishedAt   = "finished_at"
+	TaskJobFieldUpdatedAt    = "updated_at"
+)
+
+// TaskJob 任务后台执行作业数据库实体
+type TaskJob struct {
+	ID           string     `xorm:"pk varchar(64) 'id'" json:"id"`
+	TaskID       string     `xorm:"varchar(64) index 'task_id'" json:"task_id"`
+	SessionID    string     `xorm:"varchar(64) 'session_id'" json:"session_id"`
+	PhaseID      string     `xorm:"varchar(64) 'phase_id'" json:"phase_id"`
+	Locale       string     `xorm:"varchar(16) 'locale'" json:"locale"`
+	Status       string     `xorm:"varchar(32) index 'status'" json:"status"`
+	CurrentPhase string     `xorm:"varchar(64) 'current_phase'" json:"current_phase"`
+	CurrentStep  string     `xorm:"varchar(64) 'current_step'" json:"current_step"`
+	ResultJSON   string     `xorm:"text 'result_json'" json:"result_json"`
+	Error        string     `xorm:"text 'error'" json:"error"`
+	Attempts     int        `xorm:"int 'attempts'" json:"attempts"`
+	CreatedAt    time.Time  `xorm:"created 'created_at'" json:"created_at"`
+	StartedAt    *time.Time `xorm:"'started_at'" json:"started_at"`
+	FinishedAt   *time.Time `xorm:"'finished_at'" json:"finished_at"`
+	UpdatedAt    time.Time  `xorm:"updated 'updated_at'" json:"updated_at"`
+}
+
+func (e *TaskJob) TableName() string {
+	return TableNameTaskJob
+}
+
+// ========== 任务工具调用表 ==========
+
+const (
+	TableNameTaskToolCall = "task_tool_calls"
+
+	TaskToolCallFieldID        = "id"
+	TaskToolCallFieldTaskID    = "task_id"
+	TaskToolCallFieldName      = "name"
+	TaskToolCallFieldArgsJSON  = "args_json"
+	TaskToolCallFieldResult    = "result"
+	TaskToolCallFieldError     = "error"
+	TaskToolCallFieldCreatedAt = "created_at"
+)
+
+// TaskToolCall 任务工具调用记录数据库实体
+type TaskToolCall struct {
+	ID        string    `xorm:"pk varchar(64) 'id'" json:"id"`
+	TaskID    string    `xorm:"varchar(64) index 'task_id'" json:"task_id"`
+	Name      string    `xorm:"varchar(64) 'name'" json:"name"`
+	ArgsJSON  string    `xorm:"text 'args_json'" json:"args_json"`
+	Result    string    `xorm:"text 'result'" json:"result"`
+	Error     string    `xorm:"text 'error'" json:"error"`
+	CreatedAt time.Time `xorm:"'created_at'" json:"created_at"`
+}
+
+func (e *TaskToolCall) TableName() string {
+	return TableNameTaskToolCall
+}
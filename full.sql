@@ -3,6 +3,9 @@ drop DATABASE IF EXISTS ai_task;
 create DATABASE ai_task;
 \c ai_task;
 
+-- 向量检索依赖 pgvector 扩展
+CREATE EXTENSION IF NOT EXISTS vector;
+
 -- =============================================
 -- 用户画像表
 -- =============================================
@@ -45,7 +48,10 @@ CREATE TABLE IF NOT EXISTS tasks (
     questions_json TEXT,                                         -- 关键问题(JSON数组)
     decisions_json TEXT,                                         -- 决策记录(JSON数组)
     errors_json TEXT,                                            -- 错误记录(JSON数组)
-    status VARCHAR(32) DEFAULT 'pending',                        -- 任务状态: pending/in_progress/completed/failed/cancelled
+    status VARCHAR(32) DEFAULT 'pending',                        -- 任务状态: pending/in_progress/completed/failed/cancelled/budget_exceeded/paused/awaiting_input
+    cursor_json TEXT,                                            -- 暂停位置(JSON)
+    pending_input_json TEXT,                                     -- 等待人工指导的问题(JSON)
+    guidance_json TEXT,                                          -- 最近一次人工指导(JSON)
     tool_call_count INT DEFAULT 0,                               -- 工具调用计数
     needs_reread BOOLEAN DEFAULT FALSE,                          -- 是否需要重读计划
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,              -- 创建时间
@@ -63,7 +69,10 @@ COMMENT ON COLUMN tasks.phases_json IS '任务阶段列表，JSON格式，包含
 COMMENT ON COLUMN tasks.questions_json IS '关键问题列表，JSON数组格式';
 COMMENT ON COLUMN tasks.decisions_json IS '决策记录列表，JSON格式，包含决策内容、理由、时间戳等';
 COMMENT ON COLUMN tasks.errors_json IS '错误记录列表，JSON格式，包含错误信息、尝试次数、解决方案等';
-COMMENT ON COLUMN tasks.status IS '任务状态：pending-待处理、in_progress-进行中、completed-已完成、failed-失败、cancelled-已取消';
+COMMENT ON COLUMN tasks.status IS '任务状态：pending-待处理、in_progress-进行中、completed-已完成、failed-失败、cancelled-已取消、budget_exceeded-超出token预算、paused-已暂停、awaiting_input-等待人工指导';
+COMMENT ON COLUMN tasks.cursor_json IS '暂停位置，JSON格式，包含阶段ID和下一个要执行的步骤ID，恢复执行从此处继续，执行结束后清空';
+COMMENT ON COLUMN tasks.pending_input_json IS '等待人工指导的问题，JSON格式，包含阶段ID、步骤ID、问题和失败的各次尝试，提交指导后清空';
+COMMENT ON COLUMN tasks.guidance_json IS '最近一次人工指导，JSON格式，包含问题与指导内容，恢复执行后注入对应步骤的提示词，步骤完成后清空';
 COMMENT ON COLUMN tasks.tool_call_count IS '工具调用计数，用于判断何时需要重读计划（Manus的10次规则）';
 COMMENT ON COLUMN tasks.needs_reread IS '是否需要重读计划标记';
 COMMENT ON COLUMN tasks.created_at IS '任务创建时间';
@@ -122,3 +131,304 @@ COMMENT ON COLUMN task_progress.error_log_json IS '错误日志列表，JSON格
 COMMENT ON COLUMN task_progress.updated_at IS '最后更新时间';
 
 CREATE INDEX idx_task_progress_task_id ON task_progress(task_id);
+
+-- =============================================
+-- 任务执行作业表
+-- 存储后台执行作业的状态与结果，服务重启后恢复未结束的作业
+-- =============================================
+CREATE TABLE IF NOT EXISTS task_jobs (
+    id VARCHAR(64) PRIMARY KEY,                                  -- 作业ID
+    task_id VARCHAR(64) NOT NULL,                                -- 关联的任务ID
+    session_id VARCHAR(64),                                      -- 提交作业的会话ID
+    phase_id VARCHAR(64),                                        -- 只执行的阶段ID
+    locale VARCHAR(16),                                          -- 提示词语言
+    status VARCHAR(32) NOT NULL DEFAULT 'queued',                -- 作业状态
+    current_phase VARCHAR(64),                                   -- 正在执行的阶段ID
+    current_step VARCHAR(64),                                    -- 正在执行的步骤ID
+    result_json TEXT,                                            -- 执行结果(JSON)
+    error TEXT,                                                  -- 错误信息
+    attempts INT DEFAULT 0,                                      -- 开始执行次数
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,              -- 创建时间
+    started_at TIMESTAMP,                                        -- 最近一次开始执行时间
+    finished_at TIMESTAMP,                                       -- 结束时间
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP               -- 更新时间
+);
+
+COMMENT ON TABLE task_jobs IS '任务执行作业表，任务执行以作业形式在后台worker中运行，服务重启后排队中和执行中的作业重新入队';
+COMMENT ON COLUMN task_jobs.id IS '作业唯一标识，UUID格式';
+COMMENT ON COLUMN task_jobs.task_id IS '关联的任务ID';
+COMMENT ON COLUMN task_jobs.session_id IS '通过会话提交时的会话ID，直接提交时为空';
+COMMENT ON COLUMN task_jobs.phase_id IS '只执行指定阶段时的阶段ID，为空时执行整个任务';
+COMMENT ON COLUMN task_jobs.locale IS '提交时指定的提示词语言，为空时使用用户偏好';
+COMMENT ON COLUMN task_jobs.status IS '作业状态：queued-排队中、running-执行中、succeeded-执行结束、failed-执行出错、cancelled-任务被取消、paused-任务被暂停';
+COMMENT ON COLUMN task_jobs.current_phase IS '正在执行或最后执行的阶段ID';
+COMMENT ON COLUMN task_jobs.current_step IS '正在执行或最后执行的步骤ID';
+COMMENT ON COLUMN task_jobs.result_json IS '执行结果，JSON格式，与同步执行返回的ExecutionResult结构相同';
+COMMENT ON COLUMN task_jobs.error IS '执行出错时的错误信息';
+COMMENT ON COLUMN task_jobs.attempts IS '开始执行次数，重启恢复后重新执行时累加';
+COMMENT ON COLUMN task_jobs.created_at IS '作业提交时间';
+COMMENT ON COLUMN task_jobs.started_at IS '最近一次开始执行时间';
+COMMENT ON COLUMN task_jobs.finished_at IS '作业结束时间';
+COMMENT ON COLUMN task_jobs.updated_at IS '最后更新时间';
+
+CREATE INDEX idx_task_jobs_task_id ON task_jobs(task_id);
+CREATE INDEX idx_task_jobs_status ON task_jobs(status);
+
+-- =============================================
+-- 任务工具调用表
+-- 记录任务在工作目录中执行的每一次工具调用
+-- =============================================
+CREATE TABLE IF NOT EXISTS task_tool_calls (
+    id VARCHAR(64) PRIMARY KEY,                                  -- 调用ID
+    task_id VARCHAR(64) NOT NULL,                                -- 关联的任务ID
+    name VARCHAR(64) NOT NULL,                                   -- 工具名称
+    args_json TEXT,                                              -- 调用参数(JSON)
+    result TEXT,                                                 -- 调用结果
+    error TEXT,                                                  -- 错误信息
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP               -- 调用时间
+);
+
+COMMENT ON TABLE task_tool_calls IS '任务工具调用表，记录 read_file、write_file、run_command 等工具的每一次调用，任务删除时一并删除';
+COMMENT ON COLUMN task_tool_calls.id IS '调用唯一标识，UUID格式';
+COMMENT ON COLUMN task_tool_calls.task_id IS '关联的任务ID';
+COMMENT ON COLUMN task_tool_calls.name IS '工具名称，未注册的工具同样记录';
+COMMENT ON COLUMN task_tool_calls.args_json IS '调用参数，JSON格式';
+COMMENT ON COLUMN task_tool_calls.result IS '工具返回的文本结果，调用失败时为空';
+COMMENT ON COLUMN task_tool_calls.error IS '调用失败时的错误信息，如路径越界、命令不在白名单中';
+COMMENT ON COLUMN task_tool_calls.created_at IS '调用时间';
+
+CREATE INDEX idx_task_tool_calls_task_id ON task_tool_calls(task_id, created_at);
+
+-- =============================================
+-- 聊天消息表
+-- 存储会话中每一轮 user/assistant 发言，作为会话记忆来源
+-- =============================================
+CREATE TABLE IF NOT EXISTS chat_messages (
+    id BIGSERIAL PRIMARY KEY,                                    -- 主键ID
+    user_id VARCHAR(64) NOT NULL,                                -- 用户ID
+    session_id VARCHAR(64) NOT NULL,                             -- 会话ID
+    role VARCHAR(32) NOT NULL,                                   -- 角色(user/assistant)
+    content TEXT NOT NULL DEFAULT '',                            -- 消息内容
+    truncated BOOLEAN DEFAULT FALSE,                             -- 是否被截断(流式输出中断)
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP               -- 创建时间
+);
+
+COMMENT ON TABLE chat_messages IS '聊天消息表，按会话存储用户与助手的对话记录，用于构建会话记忆';
+COMMENT ON COLUMN chat_messages.id IS '主键ID，自增，同一会话内可作为消息先后顺序';
+COMMENT ON COLUMN chat_messages.user_id IS '用户ID';
+COMMENT ON COLUMN chat_messages.session_id IS '会话ID';
+COMMENT ON COLUMN chat_messages.role IS '消息角色：user/assistant';
+COMMENT ON COLUMN chat_messages.content IS '消息内容';
+COMMENT ON COLUMN chat_messages.truncated IS '是否被截断，流式输出过程中客户端断开或上游中断时为true，内容为已生成的部分';
+COMMENT ON COLUMN chat_messages.created_at IS '创建时间';
+
+CREATE INDEX idx_chat_messages_user_session ON chat_messages(user_id, session_id, id);
+
+-- =============================================
+-- 语义记忆分块表
+-- 对话消息按配置的策略分块后写入向量，用于跨轮次语义检索
+-- =============================================
+CREATE TABLE IF NOT EXISTS memory_chunks (
+    id BIGSERIAL PRIMARY KEY,                                    -- 主键ID
+    user_id VARCHAR(64) NOT NULL,                                -- 用户ID
+    session_id VARCHAR(64) NOT NULL,                             -- 会话ID
+    message_id BIGINT NOT NULL,                                  -- 来源消息ID
+    role VARCHAR(32) NOT NULL,                                   -- 来源消息角色
+    chunk_idx INT DEFAULT 0,                                     -- 分块序号
+    content TEXT NOT NULL,                                       -- 分块文本
+    embedding vector(1536) NOT NULL,                             -- 文本向量
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP               -- 创建时间
+);
+
+COMMENT ON TABLE memory_chunks IS '语义记忆分块表，存储对话消息分块及其向量，按相似度检索相关历史片段';
+COMMENT ON COLUMN memory_chunks.id IS '主键ID，自增';
+COMMENT ON COLUMN memory_chunks.user_id IS '用户ID';
+COMMENT ON COLUMN memory_chunks.session_id IS '会话ID';
+COMMENT ON COLUMN memory_chunks.message_id IS '来源消息ID，关联chat_messages.id';
+COMMENT ON COLUMN memory_chunks.role IS '来源消息角色：user/assistant';
+COMMENT ON COLUMN memory_chunks.chunk_idx IS '分块序号，从0开始';
+COMMENT ON COLUMN memory_chunks.content IS '分块文本';
+COMMENT ON COLUMN memory_chunks.embedding IS '文本向量，维度需与clients.embedding.model_name一致(text-embedding-v2为1536)';
+COMMENT ON COLUMN memory_chunks.created_at IS '创建时间';
+
+CREATE INDEX idx_memory_chunks_user_session ON memory_chunks(user_id, session_id);
+CREATE INDEX idx_memory_chunks_message_id ON memory_chunks(message_id);
+CREATE INDEX idx_memory_chunks_embedding ON memory_chunks USING hnsw (embedding vector_cosine_ops);
+
+-- =============================================
+-- 会话摘要表
+-- 会话消息超过压缩阈值后，最早的消息滚动折叠为摘要，原始消息仍保留在 chat_messages
+-- =============================================
+CREATE TABLE IF NOT EXISTS chat_summaries (
+    id BIGSERIAL PRIMARY KEY,                                    -- 主键ID
+    user_id VARCHAR(64) NOT NULL,                                -- 用户ID
+    session_id VARCHAR(64) NOT NULL,                             -- 会话ID
+    content TEXT NOT NULL DEFAULT '',                            -- 摘要内容
+    covered_until_msg_id BIGINT DEFAULT 0,                       -- 摘要覆盖到的最后一条消息ID
+    message_count INT DEFAULT 0,                                 -- 摘要累计覆盖的消息条数
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,              -- 创建时间
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,              -- 更新时间
+    UNIQUE(user_id, session_id)
+);
+
+COMMENT ON TABLE chat_summaries IS '会话摘要表，每个会话一条滚动摘要，替代已折叠的早期消息进入对话上下文';
+COMMENT ON COLUMN chat_summaries.id IS '主键ID，自增';
+COMMENT ON COLUMN chat_summaries.user_id IS '用户ID';
+COMMENT ON COLUMN chat_summaries.session_id IS '会话ID';
+COMMENT ON COLUMN chat_summaries.content IS '摘要内容，每次压缩时由旧摘要与新折叠消息合并生成';
+COMMENT ON COLUMN chat_summaries.covered_until_msg_id IS '摘要覆盖到的最后一条消息ID，chat_messages.id 不大于该值的消息已折叠';
+COMMENT ON COLUMN chat_summaries.message_count IS '摘要累计覆盖的消息条数';
+COMMENT ON COLUMN chat_summaries.created_at IS '创建时间';
+COMMENT ON COLUMN chat_summaries.updated_at IS '最后一次压缩时间';
+
+-- =============================================
+-- 聊天会话表
+-- 每个会话一条元信息（标题、活跃时间、分叉来源），随每轮对话刷新
+-- =============================================
+CREATE TABLE IF NOT EXISTS chat_sessions (
+    id BIGSERIAL PRIMARY KEY,                                    -- 主键ID
+    user_id VARCHAR(64) NOT NULL,                                -- 用户ID
+    session_id VARCHAR(64) NOT NULL,                             -- 会话ID
+    title VARCHAR(255) NOT NULL DEFAULT '',                      -- 会话标题
+    title_source VARCHAR(32) NOT NULL DEFAULT 'auto',            -- 标题来源(auto/manual)
+    message_count INT DEFAULT 0,                                 -- 消息条数
+    forked_from_session VARCHAR(64) NOT NULL DEFAULT '',         -- 分叉来源会话ID
+    forked_from_msg_id BIGINT DEFAULT 0,                         -- 分叉点消息ID
+    last_active_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,          -- 最后活跃时间
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,              -- 创建时间
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,              -- 更新时间
+    UNIQUE(user_id, session_id)
+);
+
+COMMENT ON TABLE chat_sessions IS '聊天会话表，记录会话标题、消息条数和最后活跃时间，消息本身存储在chat_messages';
+COMMENT ON COLUMN chat_sessions.id IS '主键ID，自增';
+COMMENT ON COLUMN chat_sessions.user_id IS '用户ID';
+COMMENT ON COLUMN chat_sessions.session_id IS '会话ID';
+COMMENT ON COLUMN chat_sessions.title IS '会话标题，默认取首条用户消息';
+COMMENT ON COLUMN chat_sessions.title_source IS '标题来源：auto（自动生成）/manual（用户重命名）';
+COMMENT ON COLUMN chat_sessions.message_count IS '会话消息条数';
+COMMENT ON COLUMN chat_sessions.forked_from_session IS '分叉来源会话ID，非分叉会话为空';
+COMMENT ON COLUMN chat_sessions.forked_from_msg_id IS '分叉点消息ID（来源会话中），该消息及之前的历史被复制到本会话';
+COMMENT ON COLUMN chat_sessions.last_active_at IS '最后一轮对话时间';
+COMMENT ON COLUMN chat_sessions.created_at IS '创建时间';
+COMMENT ON COLUMN chat_sessions.updated_at IS '最后更新时间';
+
+CREATE INDEX idx_chat_sessions_user_active ON chat_sessions(user_id, last_active_at DESC);
+
+-- 为已有消息的会话补齐会话记录
+INSERT INTO chat_sessions (user_id, session_id, title, title_source, message_count, last_active_at, created_at, updated_at)
+SELECT m.user_id, m.session_id,
+       COALESCE((SELECT LEFT(SPLIT_PART(TRIM(f.content), E'\n', 1), 30) FROM chat_messages f
+                 WHERE f.user_id = m.user_id AND f.session_id = m.session_id AND f.role = 'user'
+                 ORDER BY f.id LIMIT 1), ''),
+       'auto', COUNT(*), MAX(m.created_at), MIN(m.created_at), MAX(m.created_at)
+FROM chat_messages m
+GROUP BY m.user_id, m.session_id
+ON CONFLICT (user_id, session_id) DO NOTHING;
+
+-- =============================================
+-- 知识文档表
+-- 用户上传的文本/Markdown/HTML 文档，保留原文以便重建索引
+-- =============================================
+CREATE TABLE IF NOT EXISTS documents (
+    id BIGSERIAL PRIMARY KEY,                                    -- 主键ID
+    user_id VARCHAR(64) NOT NULL,                                -- 上传用户ID
+    namespace VARCHAR(64) NOT NULL DEFAULT '',                   -- 共享命名空间
+    title VARCHAR(255) NOT NULL DEFAULT '',                      -- 文档标题
+    content_type VARCHAR(32) NOT NULL DEFAULT 'text',            -- 内容类型(text/markdown/html)
+    content TEXT NOT NULL DEFAULT '',                            -- 文档原文
+    status VARCHAR(32) NOT NULL DEFAULT 'indexing',              -- 索引状态
+    chunk_count INT DEFAULT 0,                                   -- 分块数量
+    error TEXT DEFAULT '',                                       -- 索引失败原因
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,              -- 创建时间
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP               -- 更新时间
+);
+
+COMMENT ON TABLE documents IS '知识文档表，存储用户上传的文档原文及索引状态，分块向量见doc_chunks';
+COMMENT ON COLUMN documents.id IS '主键ID，自增';
+COMMENT ON COLUMN documents.user_id IS '上传用户ID，本人对话时可检索';
+COMMENT ON COLUMN documents.namespace IS '共享命名空间，对话请求携带该命名空间时可检索，为空表示仅本人可见';
+COMMENT ON COLUMN documents.title IS '文档标题，用于引用展示';
+COMMENT ON COLUMN documents.content_type IS '内容类型：text/markdown/html';
+COMMENT ON COLUMN documents.content IS '文档原文，重建索引时重新提取分块';
+COMMENT ON COLUMN documents.status IS '索引状态：indexing/ready/failed';
+COMMENT ON COLUMN documents.chunk_count IS '当前索引的分块数量';
+COMMENT ON COLUMN documents.error IS '最近一次索引失败的原因';
+COMMENT ON COLUMN documents.created_at IS '创建时间';
+COMMENT ON COLUMN documents.updated_at IS '最后更新时间';
+
+CREATE INDEX idx_documents_user_id ON documents(user_id);
+CREATE INDEX idx_documents_namespace ON documents(namespace);
+
+-- =============================================
+-- 文档分块表
+-- 文档按分块策略切分后写入向量，对话时按用户或命名空间检索并引用
+-- =============================================
+CREATE TABLE IF NOT EXISTS doc_chunks (
+    id BIGSERIAL PRIMARY KEY,                                    -- 主键ID
+    doc_id BIGINT NOT NULL,                                      -- 所属文档ID
+    user_id VARCHAR(64) NOT NULL,                                -- 文档上传用户ID
+    namespace VARCHAR(64) NOT NULL DEFAULT '',                   -- 文档命名空间
+    chunk_idx INT DEFAULT 0,                                     -- 分块序号
+    content TEXT NOT NULL,                                       -- 分块文本
+    embedding vector(1536) NOT NULL,                             -- 文本向量
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP               -- 创建时间
+);
+
+COMMENT ON TABLE doc_chunks IS '文档分块表，存储知识文档分块及其向量，对话时按相似度检索并作为引用来源';
+COMMENT ON COLUMN doc_chunks.id IS '主键ID，自增';
+COMMENT ON COLUMN doc_chunks.doc_id IS '所属文档ID，关联documents.id';
+COMMENT ON COLUMN doc_chunks.user_id IS '文档上传用户ID，冗余自documents便于检索过滤';
+COMMENT ON COLUMN doc_chunks.namespace IS '文档命名空间，冗余自documents便于检索过滤';
+COMMENT ON COLUMN doc_chunks.chunk_idx IS '分块序号，从0开始';
+COMMENT ON COLUMN doc_chunks.content IS '分块文本';
+COMMENT ON COLUMN doc_chunks.embedding IS '文本向量，维度需与clients.embedding.model_name一致(text-embedding-v2为1536)';
+COMMENT ON COLUMN doc_chunks.created_at IS '创建时间';
+
+CREATE INDEX idx_doc_chunks_doc_id ON doc_chunks(doc_id, chunk_idx);
+CREATE INDEX idx_doc_chunks_user_id ON doc_chunks(user_id);
+CREATE INDEX idx_doc_chunks_namespace ON doc_chunks(namespace);
+CREATE INDEX idx_doc_chunks_embedding ON doc_chunks USING hnsw (embedding vector_cosine_ops);
+
+-- 全文检索索引，与向量检索一起做混合检索（simple 配置保留错误码、ID 等标识符原样）
+CREATE INDEX idx_memory_chunks_content_fts ON memory_chunks USING gin (to_tsvector('simple', content));
+CREATE INDEX idx_doc_chunks_content_fts ON doc_chunks USING gin (to_tsvector('simple', content));
+
+-- =============================================
+-- 模型用量表
+-- 每次 LLM / embedding 调用的 token 用量，用于按用户、会话、任务统计和预算控制
+-- =============================================
+CREATE TABLE IF NOT EXISTS llm_usage (
+    id BIGSERIAL PRIMARY KEY,                                    -- 主键ID
+    user_id VARCHAR(64) NOT NULL DEFAULT '',                     -- 用户ID
+    session_id VARCHAR(64) NOT NULL DEFAULT '',                  -- 会话ID
+    task_id VARCHAR(64) NOT NULL DEFAULT '',                     -- 任务ID
+    role VARCHAR(32) NOT NULL DEFAULT '',                        -- 调用角色
+    kind VARCHAR(32) NOT NULL DEFAULT 'llm',                     -- 调用类型
+    model VARCHAR(128) NOT NULL DEFAULT '',                      -- 模型名称
+    prompt_tokens INT DEFAULT 0,                                 -- 输入token数
+    completion_tokens INT DEFAULT 0,                             -- 输出token数
+    cached_tokens INT DEFAULT 0,                                 -- 缓存命中token数
+    total_tokens INT DEFAULT 0,                                  -- 总token数
+    cost DOUBLE PRECISION DEFAULT 0,                             -- 估算费用
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP               -- 调用时间
+);
+
+COMMENT ON TABLE llm_usage IS '模型用量表，记录每次LLM和embedding调用的token用量，按用户/会话/任务汇总并用于预算控制';
+COMMENT ON COLUMN llm_usage.id IS '主键ID，自增';
+COMMENT ON COLUMN llm_usage.user_id IS '用户ID，未知时为空';
+COMMENT ON COLUMN llm_usage.session_id IS '会话ID，任务执行时为任务的session_id';
+COMMENT ON COLUMN llm_usage.task_id IS '任务ID，对话调用为空';
+COMMENT ON COLUMN llm_usage.role IS '调用角色：planner/executor/summarizer/extractor/chat/embedding';
+COMMENT ON COLUMN llm_usage.kind IS '调用类型：llm/embedding';
+COMMENT ON COLUMN llm_usage.model IS '模型名称';
+COMMENT ON COLUMN llm_usage.prompt_tokens IS '输入token数';
+COMMENT ON COLUMN llm_usage.completion_tokens IS '输出token数，embedding调用为0';
+COMMENT ON COLUMN llm_usage.cached_tokens IS '命中提示词缓存的token数，包含在prompt_tokens中';
+COMMENT ON COLUMN llm_usage.total_tokens IS '总token数';
+COMMENT ON COLUMN llm_usage.cost IS '按usage.pricing配置估算的费用，未配置价格的模型为0';
+COMMENT ON COLUMN llm_usage.created_at IS '调用时间';
+
+CREATE INDEX idx_llm_usage_user_created ON llm_usage(user_id, created_at);
+CREATE INDEX idx_llm_usage_task_id ON llm_usage(task_id);
+CREATE INDEX idx_llm_usage_created_at ON llm_usage(created_at);
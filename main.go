@@ -2,6 +2,7 @@ package main
 
 import (
 	"ai_task/config"
+	"ai_task/controller"
 	"ai_task/pkg/projectlog"
 	"ai_task/router"
 	"fmt"
@@ -30,6 +31,7 @@ func main() {
 	}()
 
 	projectlog.Init()
+	controller.InitTaskService()
 
 	go startServer()
 	waitStop()
@@ -53,6 +55,7 @@ func waitStop() {
 
 	sig := <-sc
 	log.Printf("exit: signal=<%d>.\n", sig)
+	controller.CloseTaskService()
 	switch sig {
 	case syscall.SIGTERM:
 		log.Println("exit: bye :-).")
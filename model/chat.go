@@ -4,33 +4,68 @@ import "github.com/sashabaranov/go-openai"
 
 // ChatRequest 聊天请求
 type ChatRequest struct {
-	UserID    string                        `json:"user_id" binding:"required"`
-	SessionID string                        `json:"session_id" binding:"required"`
-	Messages  []openai.ChatCompletionMessage `json:"messages" binding:"required"`
-	Stream   bool                           `json:"stream"` // 是否流式返回
+	UserID     string                         `json:"user_id" binding:"required"`
+	SessionID  string                         `json:"session_id" binding:"required"`
+	Messages   []openai.ChatCompletionMessage `json:"messages" binding:"required"`
+	Stream     bool                           `json:"stream"`     // 是否流式返回
+	Namespaces []string                       `json:"namespaces"` // 额外检索的知识文档命名空间，本人上传的文档始终参与检索
+	Locale     string                         `json:"locale"`     // 提示词语言 zh/en，未指定时使用用户画像中的语言偏好
 }
 
 // ChatResponse 聊天响应（非流式）
 type ChatResponse struct {
-	Message   string `json:"message"`
-	SessionID string `json:"session_id"`
+	Message   string         `json:"message"`
+	SessionID string         `json:"session_id"`
+	MessageID int64          `json:"message_id"`               // 助手回复对应的消息ID
+	Citations []Citation     `json:"citations,omitempty"`      // 注入上下文的知识文档片段，与回复中的 [n] 对应
+	Context   *ContextReport `json:"context_report,omitempty"` // 上下文超出 token 预算时被裁剪的内容
+}
+
+// ContextReport 上下文 token 预算裁剪报告
+type ContextReport struct {
+	Tokens  int                  `json:"tokens"`  // 估算的提示词 token 数
+	Budget  int                  `json:"budget"`  // 提示词 token 预算
+	Dropped []ContextDroppedItem `json:"dropped"` // 被丢弃或截断的内容
+}
+
+// ContextDroppedItem 被裁剪的上下文条目
+type ContextDroppedItem struct {
+	Section string `json:"section"` // 所在段：profile/documents/semantic/summary/history/required
+	Key     string `json:"key"`     // 条目标识，如 message:123、doc:12#3
+	Tokens  int    `json:"tokens"`  // 被丢弃（截断时为被截去）的 token 数
+	Reason  string `json:"reason"`  // section_budget/total_budget/truncated
 }
 
 // MemoryContextOptionsRequest 记忆上下文选项（可选）
 // 参考 LangChain：支持摘要、压缩和自动提取
 type MemoryContextOptionsRequest struct {
 	EnableSessionMemory *bool    `json:"enable_session_memory"` // 是否启用短期记忆
-	EnableChunking      *bool    `json:"enable_chunking"`        // 是否启用分块功能
+	EnableChunking      *bool    `json:"enable_chunking"`       // 是否启用分块功能
 	SessionMemoryLimit  *int     `json:"session_memory_limit"`  // 短期记忆条数
 	SemanticMemoryLimit *int     `json:"semantic_memory_limit"` // 语义记忆条数
-	SemanticThreshold   *float64 `json:"semantic_threshold"`   // 语义相似度阈值
-	CompressThreshold   *int     `json:"compress_threshold"`   // 压缩阈值（超过此数量时压缩旧记忆）
-	EnableSummary       *bool    `json:"enable_summary"`       // 是否启用摘要功能
-	EnableAutoExtract   *bool    `json:"enable_auto_extract"`  // 是否自动提取关键事实到长期记忆
+	SemanticThreshold   *float64 `json:"semantic_threshold"`    // 语义相似度阈值
+	CompressThreshold   *int     `json:"compress_threshold"`    // 压缩阈值（超过此数量时压缩旧记忆）
+	EnableSummary       *bool    `json:"enable_summary"`        // 是否启用摘要功能
+	EnableAutoExtract   *bool    `json:"enable_auto_extract"`   // 是否自动提取关键事实到长期记忆
 	// 分块配置
-	ChunkMaxSize  *int    `json:"chunk_max_size"`  // 最大块大小（字符数），0或nil表示使用默认值1000
-	ChunkOverlap  *int    `json:"chunk_overlap"`   // 重叠窗口大小（字符数），0或nil表示使用默认值100
-	ChunkMinSize  *int    `json:"chunk_min_size"`  // 最小块大小（字符数），0或nil表示使用默认值200
-	ChunkStrategy *string `json:"chunk_strategy"`   // 分块策略: "paragraph", "sentence", "fixed"，空字符串或nil表示使用默认值"paragraph"
+	ChunkMaxSize  *int    `json:"chunk_max_size"` // 最大块大小（字符数），0或nil表示使用默认值1000
+	ChunkOverlap  *int    `json:"chunk_overlap"`  // 重叠窗口大小（字符数），0或nil表示使用默认值100
+	ChunkMinSize  *int    `json:"chunk_min_size"` // 最小块大小（字符数），0或nil表示使用默认值200
+	ChunkStrategy *string `json:"chunk_strategy"` // 分块策略: "paragraph", "sentence", "fixed"，空字符串或nil表示使用默认值"paragraph"
 }
 
+// IsEmpty 判断请求中是否未携带任何记忆选项
+func (o *MemoryContextOptionsRequest) IsEmpty() bool {
+	return o.EnableSessionMemory == nil &&
+		o.EnableChunking == nil &&
+		o.SessionMemoryLimit == nil &&
+		o.SemanticMemoryLimit == nil &&
+		o.SemanticThreshold == nil &&
+		o.CompressThreshold == nil &&
+		o.EnableSummary == nil &&
+		o.EnableAutoExtract == nil &&
+		o.ChunkMaxSize == nil &&
+		o.ChunkOverlap == nil &&
+		o.ChunkMinSize == nil &&
+		o.ChunkStrategy == nil
+}
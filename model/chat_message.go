@@ -0,0 +1,28 @@
+package model
+
+// CreateChatMessageCondition 创建聊天消息条件
+type CreateChatMessageCondition struct {
+	UserID    string `json:"user_id"`
+	SessionID string `json:"session_id"`
+	Role      string `json:"role"`
+	Content   string `json:"content"`
+	Truncated bool   `json:"truncated"`
+}
+
+// GetChatMessagesCondition 聊天消息查询条件（带分页和排序）
+type GetChatMessagesCondition struct {
+	UserID    *string `json:"user_id"`
+	SessionID *string `json:"session_id"`
+	AfterID   *int64  `json:"after_id"` // 只查 id 大于该值的消息
+	UntilID   *int64  `json:"until_id"` // 只查 id 不大于该值的消息
+	*Pager
+	*Order
+}
+
+func (g *GetChatMessagesCondition) GetPager() *Pager {
+	return g.Pager
+}
+
+func (g *GetChatMessagesCondition) GetOrder() *Order {
+	return g.Order
+}
@@ -0,0 +1,96 @@
+package model
+
+import "time"
+
+// CreateChatSessionCondition 创建会话条件
+type CreateChatSessionCondition struct {
+	UserID            string `json:"user_id"`
+	SessionID         string `json:"session_id"`
+	Title             string `json:"title"`
+	TitleSource       string `json:"title_source"`
+	MessageCount      int    `json:"message_count"`
+	ForkedFromSession string `json:"forked_from_session"`
+	ForkedFromMsgID   int64  `json:"forked_from_msg_id"`
+}
+
+// TouchChatSessionCondition 会话新增消息时更新活跃信息，会话不存在时以 Title 创建
+type TouchChatSessionCondition struct {
+	UserID       string `json:"user_id"`
+	SessionID    string `json:"session_id"`
+	Title        string `json:"title"`         // 仅新建会话时使用
+	MessageCount int    `json:"message_count"` // 本次新增的消息条数
+}
+
+// UpdateChatSessionCondition 更新会话条件
+type UpdateChatSessionCondition struct {
+	Title       *string `json:"title"`
+	TitleSource *string `json:"title_source"`
+}
+
+// GetChatSessionsCondition 会话查询条件（带分页和排序）
+type GetChatSessionsCondition struct {
+	UserID *string `json:"user_id"`
+	*Pager
+	*Order
+}
+
+func (g *GetChatSessionsCondition) GetPager() *Pager {
+	return g.Pager
+}
+
+func (g *GetChatSessionsCondition) GetOrder() *Order {
+	return g.Order
+}
+
+// ListChatSessionsRequest 会话列表查询参数
+type ListChatSessionsRequest struct {
+	Limit  int `form:"limit" binding:"omitempty,min=0,max=200"`
+	Offset int `form:"offset" binding:"omitempty,min=0"`
+}
+
+// ListChatMessagesRequest 会话消息分页查询参数，按消息先后顺序返回
+type ListChatMessagesRequest struct {
+	Limit  int `form:"limit" binding:"omitempty,min=0,max=200"`
+	Offset int `form:"offset" binding:"omitempty,min=0"`
+}
+
+// RenameChatSessionRequest 重命名会话请求
+type RenameChatSessionRequest struct {
+	Title string `json:"title" binding:"required,max=255"`
+}
+
+// ForkChatSessionRequest 从指定消息分叉会话请求
+type ForkChatSessionRequest struct {
+	MessageID    int64  `json:"message_id" binding:"required"`             // 分叉点，该消息及之前的消息复制到新会话
+	NewSessionID string `json:"new_session_id" binding:"omitempty,max=64"` // 新会话ID，不传时自动生成
+	Title        string `json:"title" binding:"omitempty,max=255"`         // 新会话标题，不传时沿用原会话标题
+}
+
+// ChatSessionResponse 会话信息
+type ChatSessionResponse struct {
+	SessionID         string    `json:"session_id"`
+	Title             string    `json:"title"`
+	TitleSource       string    `json:"title_source"` // auto/manual
+	MessageCount      int       `json:"message_count"`
+	ForkedFromSession string    `json:"forked_from_session,omitempty"`
+	ForkedFromMsgID   int64     `json:"forked_from_msg_id,omitempty"`
+	LastActiveAt      time.Time `json:"last_active_at"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// ChatMessageResponse 会话中的一条消息
+type ChatMessageResponse struct {
+	ID        int64     `json:"id"`
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Truncated bool      `json:"truncated"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DeleteChatSessionResponse 删除会话结果
+type DeleteChatSessionResponse struct {
+	SessionID       string `json:"session_id"`
+	DeletedMessages int64  `json:"deleted_messages"`
+	DeletedChunks   int64  `json:"deleted_chunks"`  // 语义记忆分块
+	DeletedSummary  bool   `json:"deleted_summary"` // 是否删除了会话摘要
+}
@@ -0,0 +1,10 @@
+package model
+
+// UpsertChatSummaryCondition 插入或更新会话摘要条件
+type UpsertChatSummaryCondition struct {
+	UserID            string `json:"user_id"`
+	SessionID         string `json:"session_id"`
+	Content           string `json:"content"`
+	CoveredUntilMsgID int64  `json:"covered_until_msg_id"`
+	MessageCount      int    `json:"message_count"`
+}
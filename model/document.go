@@ -0,0 +1,113 @@
+package model
+
+import "time"
+
+// CreateDocumentCondition 写入文档条件
+type CreateDocumentCondition struct {
+	UserID      string `json:"user_id"`
+	Namespace   string `json:"namespace"`
+	Title       string `json:"title"`
+	ContentType string `json:"content_type"`
+	Content     string `json:"content"`
+	Status      string `json:"status"`
+}
+
+// UpdateDocumentCondition 更新文档索引状态条件，nil 字段不更新
+type UpdateDocumentCondition struct {
+	Status     *string `json:"status"`
+	ChunkCount *int    `json:"chunk_count"`
+	Error      *string `json:"error"`
+}
+
+// GetDocumentsCondition 文档查询条件（带分页和排序）
+type GetDocumentsCondition struct {
+	UserID    *string `json:"user_id"`
+	Namespace *string `json:"namespace"`
+	Status    *string `json:"status"`
+	*Pager
+	*Order
+}
+
+func (g *GetDocumentsCondition) GetPager() *Pager {
+	return g.Pager
+}
+
+func (g *GetDocumentsCondition) GetOrder() *Order {
+	return g.Order
+}
+
+// CreateDocChunkCondition 写入文档分块条件
+type CreateDocChunkCondition struct {
+	DocID     int64     `json:"doc_id"`
+	UserID    string    `json:"user_id"`
+	Namespace string    `json:"namespace"`
+	ChunkIdx  int       `json:"chunk_idx"`
+	Content   string    `json:"content"`
+	Embedding []float64 `json:"-"`
+}
+
+// SearchDocChunksCondition 文档分块检索条件，命中 user_id 本人的文档或 Namespaces 中的共享文档
+type SearchDocChunksCondition struct {
+	UserID     string    `json:"user_id"`
+	Namespaces []string  `json:"namespaces"`
+	Embedding  []float64 `json:"-"`         // 查询向量
+	Limit      int       `json:"limit"`     // 最多返回条数
+	Threshold  float64   `json:"threshold"` // 余弦相似度下限
+	Keywords   []string  `json:"keywords"`  // 全文检索词元，任一命中即可，仅 SearchLexical 使用
+}
+
+// DocChunkSearchResult 文档分块检索结果，附带所属文档标题
+type DocChunkSearchResult struct {
+	ID         int64   `xorm:"'id'" json:"id"`
+	DocID      int64   `xorm:"'doc_id'" json:"doc_id"`
+	Title      string  `xorm:"'title'" json:"title"`
+	Namespace  string  `xorm:"'namespace'" json:"namespace"`
+	ChunkIdx   int     `xorm:"'chunk_idx'" json:"chunk_idx"`
+	Content    string  `xorm:"'content'" json:"content"`
+	Similarity float64 `xorm:"'similarity'" json:"similarity"`
+}
+
+// CreateDocumentRequest 上传文档请求，支持 JSON 或 multipart（file 字段上传文件）
+type CreateDocumentRequest struct {
+	UserID      string `json:"user_id" form:"user_id" binding:"required"`
+	Namespace   string `json:"namespace" form:"namespace"`       // 共享命名空间，为空时仅本人可检索
+	Title       string `json:"title" form:"title"`               // 为空时使用文件名
+	ContentType string `json:"content_type" form:"content_type"` // text/markdown/html，为空时按文件扩展名推断，默认 text
+	Content     string `json:"content" form:"content"`           // 文档原文，multipart 上传文件时可不传
+	Filename    string `json:"-" form:"-"`                       // multipart 上传的文件名，由 controller 填充
+}
+
+// ListDocumentsRequest 文档列表查询参数
+type ListDocumentsRequest struct {
+	UserID    string `form:"user_id"`
+	Namespace string `form:"namespace"`
+	Status    string `form:"status"`
+	Limit     int    `form:"limit" binding:"omitempty,min=0,max=100"`
+	Offset    int    `form:"offset" binding:"omitempty,min=0"`
+}
+
+// DocumentResponse 文档信息，列表中不返回原文
+type DocumentResponse struct {
+	ID          int64     `json:"id"`
+	UserID      string    `json:"user_id"`
+	Namespace   string    `json:"namespace"`
+	Title       string    `json:"title"`
+	ContentType string    `json:"content_type"`
+	Content     string    `json:"content,omitempty"`
+	Status      string    `json:"status"`
+	ChunkCount  int       `json:"chunk_count"`
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Citation 回复引用的文档分块，Index 与提示词中的 [n] 编号一致
+type Citation struct {
+	Index      int     `json:"index"`
+	DocID      int64   `json:"doc_id"`
+	Title      string  `json:"title"`
+	ChunkID    int64   `json:"chunk_id"`
+	ChunkIdx   int     `json:"chunk_idx"`
+	Similarity float64 `json:"similarity"`
+	Snippet    string  `json:"snippet"`
+}
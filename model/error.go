@@ -23,6 +23,8 @@ const (
 	ErrorMakeToken            = 100013
 	ErrorUserPhoneNumberEmpty = 100014
 	ErrorDB                   = 100015
+	ErrorLLM                  = 100016
+	ErrorEmbedding            = 100017
 )
 
 // 自定义扩展的 http 状态码
@@ -46,6 +48,8 @@ var ErrorMessages = map[int]string{
 	ErrorMakeToken:            "生成 token 失败",
 	ErrorUserPhoneNumberEmpty: "手机号不能为空",
 	ErrorDB:                   "db error",
+	ErrorLLM:                  "大模型调用失败",
+	ErrorEmbedding:            "向量化失败",
 }
 
 type Error struct {
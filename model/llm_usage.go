@@ -0,0 +1,76 @@
+package model
+
+import "time"
+
+// CreateLLMUsageCondition 记录一次调用的用量
+type CreateLLMUsageCondition struct {
+	UserID           string  `json:"user_id"`
+	SessionID        string  `json:"session_id"`
+	TaskID           string  `json:"task_id"`
+	Role             string  `json:"role"`
+	Kind             string  `json:"kind"`
+	Model            string  `json:"model"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CachedTokens     int     `json:"cached_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// 用量分组维度
+const (
+	UsageGroupUser    = "user_id"
+	UsageGroupSession = "session_id"
+	UsageGroupTask    = "task_id"
+	UsageGroupRole    = "role"
+	UsageGroupKind    = "kind"
+	UsageGroupModel   = "model"
+	UsageGroupDay     = "day"
+)
+
+// AggregateLLMUsageCondition 用量汇总条件，GroupBy 为空时汇总为一行
+type AggregateLLMUsageCondition struct {
+	UserID    *string    `json:"user_id"`
+	SessionID *string    `json:"session_id"`
+	TaskID    *string    `json:"task_id"`
+	Role      *string    `json:"role"`
+	Kind      *string    `json:"kind"`
+	Since     *time.Time `json:"since"` // 包含
+	Until     *time.Time `json:"until"` // 不包含
+	GroupBy   []string   `json:"group_by"`
+}
+
+// LLMUsageAggregate 用量汇总，未参与分组的维度为空
+type LLMUsageAggregate struct {
+	UserID           string  `xorm:"'user_id'" json:"user_id,omitempty"`
+	SessionID        string  `xorm:"'session_id'" json:"session_id,omitempty"`
+	TaskID           string  `xorm:"'task_id'" json:"task_id,omitempty"`
+	Role             string  `xorm:"'role'" json:"role,omitempty"`
+	Kind             string  `xorm:"'kind'" json:"kind,omitempty"`
+	Model            string  `xorm:"'model'" json:"model,omitempty"`
+	Day              string  `xorm:"'day'" json:"day,omitempty"` // YYYY-MM-DD
+	Calls            int64   `xorm:"'calls'" json:"calls"`
+	PromptTokens     int64   `xorm:"'prompt_tokens'" json:"prompt_tokens"`
+	CompletionTokens int64   `xorm:"'completion_tokens'" json:"completion_tokens"`
+	CachedTokens     int64   `xorm:"'cached_tokens'" json:"cached_tokens"`
+	TotalTokens      int64   `xorm:"'total_tokens'" json:"total_tokens"`
+	Cost             float64 `xorm:"'cost'" json:"cost"`
+}
+
+// GetUsageRequest 用量查询参数
+type GetUsageRequest struct {
+	UserID    string `form:"user_id"`
+	SessionID string `form:"session_id"`
+	TaskID    string `form:"task_id"`
+	Role      string `form:"role"`
+	Kind      string `form:"kind"`
+	Start     string `form:"start"`    // 开始日期（含），YYYY-MM-DD 或 RFC3339
+	End       string `form:"end"`      // 结束日期（含），YYYY-MM-DD 或 RFC3339
+	GroupBy   string `form:"group_by"` // 逗号分隔：user_id,session_id,task_id,role,kind,model,day
+}
+
+// GetUsageResponse 用量查询结果
+type GetUsageResponse struct {
+	Groups []*LLMUsageAggregate `json:"groups"`
+	Total  *LLMUsageAggregate   `json:"total"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// CreateMemoryChunkCondition 写入语义记忆分块条件
+type CreateMemoryChunkCondition struct {
+	UserID    string    `json:"user_id"`
+	SessionID string    `json:"session_id"`
+	MessageID int64     `json:"message_id"`
+	Role      string    `json:"role"`
+	ChunkIdx  int       `json:"chunk_idx"`
+	Content   string    `json:"content"`
+	Embedding []float64 `json:"-"`
+}
+
+// SearchMemoryChunksCondition 语义记忆检索条件
+type SearchMemoryChunksCondition struct {
+	UserID            string    `json:"user_id"`
+	SessionID         *string   `json:"session_id"`          // 为空时检索该用户全部会话
+	Embedding         []float64 `json:"-"`                   // 查询向量
+	Limit             int       `json:"limit"`               // 最多返回条数
+	Threshold         float64   `json:"threshold"`           // 余弦相似度下限
+	ExcludeMessageIDs []int64   `json:"exclude_message_ids"` // 已在短期记忆中的消息，避免重复注入
+	Keywords          []string  `json:"keywords"`            // 全文检索词元，任一命中即可，仅 SearchLexical 使用
+}
+
+// MemoryChunkSearchResult 语义记忆检索结果
+type MemoryChunkSearchResult struct {
+	ID         int64     `xorm:"'id'" json:"id"`
+	UserID     string    `xorm:"'user_id'" json:"user_id"`
+	SessionID  string    `xorm:"'session_id'" json:"session_id"`
+	MessageID  int64     `xorm:"'message_id'" json:"message_id"`
+	Role       string    `xorm:"'role'" json:"role"`
+	ChunkIdx   int       `xorm:"'chunk_idx'" json:"chunk_idx"`
+	Content    string    `xorm:"'content'" json:"content"`
+	CreatedAt  time.Time `xorm:"'created_at'" json:"created_at"`
+	Similarity float64   `xorm:"'similarity'" json:"similarity"`
+}
@@ -22,6 +22,7 @@ type TaskQueryCondition struct {
 type TaskListCondition struct {
 	UserID    *string `json:"user_id"`
 	SessionID *string `json:"session_id"`
+	Status    *string `json:"status"`
 }
 
 // UpsertTaskCondition 创建/更新任务条件
@@ -36,6 +37,9 @@ type UpsertTaskCondition struct {
 	DecisionsJSON *string    `json:"decisions_json"`
 	ErrorsJSON    *string    `json:"errors_json"`
 	Status        *string    `json:"status"`
+	CursorJSON    *string    `json:"cursor_json"`
+	PendingJSON   *string    `json:"pending_input_json"`
+	GuidanceJSON  *string    `json:"guidance_json"`
 	ToolCallCount *int       `json:"tool_call_count"`
 	NeedsReread   *bool      `json:"needs_reread"`
 	CompletedAt   *time.Time `json:"completed_at"`
@@ -62,6 +66,51 @@ type UpsertTaskProgressCondition struct {
 	ErrorLogJSON    *string `json:"error_log_json"`
 }
 
+// ========== 任务执行作业查询条件 ==========
+
+// UpsertTaskJobCondition 创建/更新执行作业条件
+// TaskID、SessionID、PhaseID、Locale 只在创建时写入
+type UpsertTaskJobCondition struct {
+	ID           string     `json:"id"`
+	TaskID       string     `json:"task_id"`
+	SessionID    string     `json:"session_id"`
+	PhaseID      string     `json:"phase_id"`
+	Locale       string     `json:"locale"`
+	Status       *string    `json:"status"`
+	CurrentPhase *string    `json:"current_phase"`
+	CurrentStep  *string    `json:"current_step"`
+	ResultJSON   *string    `json:"result_json"`
+	Error        *string    `json:"error"`
+	Attempts     *int       `json:"attempts"`
+	StartedAt    *time.Time `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+}
+
+// TaskJobListCondition 执行作业列表条件
+type TaskJobListCondition struct {
+	TaskID   *string  `json:"task_id"`
+	Statuses []string `json:"statuses"`
+}
+
+// ========== 任务工具调用查询条件 ==========
+
+// InsertTaskToolCallCondition 写入工具调用记录条件，记录写入后不再修改
+type InsertTaskToolCallCondition struct {
+	ID        string    `json:"id"`
+	TaskID    string    `json:"task_id"`
+	Name      string    `json:"name"`
+	ArgsJSON  string    `json:"args_json"`
+	Result    string    `json:"result"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TaskToolCallListCondition 工具调用记录列表条件，Limit>0 时只取最近的 Limit 条
+type TaskToolCallListCondition struct {
+	TaskID string `json:"task_id"`
+	Limit  int    `json:"limit"`
+}
+
 // ========== 任务统计 ==========
 
 // TaskStats 任务统计
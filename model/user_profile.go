@@ -1,9 +1,16 @@
 package model
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // GetUserProfileCondition 查询条件
 type GetUserProfileCondition struct {
-	UserID *string `json:"user_id"`
-	Key    *string `json:"key"`
+	UserID        *string  `json:"user_id"`
+	Key           *string  `json:"key"`
+	KeyPrefix     *string  `json:"key_prefix"`     // key 前缀匹配
+	MinConfidence *float32 `json:"min_confidence"` // 最低置信度（含）
 }
 
 // UpdateUserProfileCondition 更新条件
@@ -15,12 +22,35 @@ type UpdateUserProfileCondition struct {
 }
 
 // UpsertUserProfileCondition 插入或更新条件
+// 更新时 SourceMsgID 按传入值覆盖（nil 表示清空来源，如人工修正）
 type UpsertUserProfileCondition struct {
-	UserID      string   `json:"user_id"`
-	Key         string   `json:"key"`
-	Value       string   `json:"value"`
-	Confidence  float32  `json:"confidence"`
-	SourceMsgID *int64   `json:"source_msg_id"`
-	Meta        *string  `json:"meta"`
+	UserID      string  `json:"user_id"`
+	Key         string  `json:"key"`
+	Value       string  `json:"value"`
+	Confidence  float32 `json:"confidence"`
+	SourceMsgID *int64  `json:"source_msg_id"`
+	Meta        *string `json:"meta"`
+}
+
+// ListUserProfileRequest 用户画像列表查询参数
+type ListUserProfileRequest struct {
+	KeyPrefix     string   `form:"key_prefix"`
+	MinConfidence *float32 `form:"min_confidence" binding:"omitempty,min=0,max=1"`
+}
+
+// UpsertUserProfileRequest 人工设置用户画像请求
+type UpsertUserProfileRequest struct {
+	Value      string  `json:"value" binding:"required"`
+	Confidence float32 `json:"confidence" binding:"omitempty,min=0,max=1"` // 不传时按人工确认处理（最高置信度）
+	Operator   string  `json:"operator"`                                   // 操作人，记录到 meta.updated_by
 }
 
+// UserProfileResponse 用户画像条目，附带来源信息
+type UserProfileResponse struct {
+	Key         string          `json:"key"`
+	Value       string          `json:"value"`
+	Confidence  float32         `json:"confidence"`
+	SourceMsgID *int64          `json:"source_msg_id"` // 提取自哪条对话消息，人工设置时为空
+	Meta        json.RawMessage `json:"meta"`          // 来源、提及次数、历史值等
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
@@ -2,13 +2,14 @@ package base_llm_model
 
 import (
 	"ai_task/model"
-	"ai_task/pkg/clients/httptool"
-	"ai_task/pkg/tools"
-	"bytes"
+	"ai_task/pkg/clients/llm_model"
+	"ai_task/pkg/clients/resilience"
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/usage"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"net/http"
 	"os"
 
 	"github.com/gin-gonic/gin"
@@ -20,15 +21,11 @@ const (
 	clientNameBaseLLM = "base_llm_model"
 )
 
-var (
-	streamMessageStart = []byte("data: ")
-	streamMessageEnd   = []byte("\n\n")
-)
-
 // Client 基础LLM模型客户端
 type Client struct {
 	config *Config
 	client *openai.Client
+	caller *resilience.Caller // 为 nil 时不重试、不熔断
 }
 
 // NewClient 创建新的LLM客户端
@@ -55,14 +52,7 @@ func NewClientWithParams(params ClientParams, opts ...Option) *Client {
 		opt(config)
 	}
 
-	// 创建OpenAI客户端配置
-	clientConfig := openai.DefaultConfig(config.APIKey)
-	clientConfig.BaseURL = config.BaseURL
-
-	return &Client{
-		config: config,
-		client: openai.NewClientWithConfig(clientConfig),
-	}
+	return NewClientWithConfig(config)
 }
 
 // NewClientWithConfig 使用完整配置创建客户端
@@ -70,10 +60,16 @@ func NewClientWithConfig(config *Config) *Client {
 	clientConfig := openai.DefaultConfig(config.APIKey)
 	clientConfig.BaseURL = config.BaseURL
 
-	return &Client{
-		config: config,
-		client: openai.NewClientWithConfig(clientConfig),
+	client := &Client{config: config}
+	if config.Resilience != nil {
+		client.caller = resilience.New(clientNameBaseLLM, *config.Resilience)
+		clientConfig.HTTPClient = resilience.NewHTTPClientWithBase(config.Transport)
+	} else if config.Transport != nil {
+		clientConfig.HTTPClient = &http.Client{Transport: config.Transport}
 	}
+	client.client = openai.NewClientWithConfig(clientConfig)
+
+	return client
 }
 
 // GetConfig 获取当前配置
@@ -81,6 +77,36 @@ func (c *Client) GetConfig() *Config {
 	return c.config
 }
 
+// ModelParams 影响输出的请求参数，作为响应缓存键的一部分
+func (c *Client) ModelParams() (model string, temperature float32, maxTokens int) {
+	return c.config.ModelName, c.config.Temperature, c.config.MaxTokens
+}
+
+// StreamChatCompletions 流式调用，不依赖 gin，返回增量分片通道，语义同 llm_model.ClientChatModel.StreamChatCompletions
+func (c *Client) StreamChatCompletions(ctx context.Context, messages []openai.ChatCompletionMessage) (<-chan llm_model.StreamDelta, error) {
+	// 只在建立连接前重试，已推送内容后无法重放
+	prompt.LogCall(ctx, clientNameBaseLLM, c.config.ModelName)
+	var stream *openai.ChatCompletionStream
+	err := c.caller.Open(ctx, func(ctx context.Context) error {
+		var err error
+		stream, err = c.client.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
+			Model:         c.config.ModelName,
+			Messages:      messages,
+			MaxTokens:     c.config.MaxTokens,
+			Temperature:   c.config.Temperature,
+			Stream:        true,
+			StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+		})
+		return err
+	})
+	if err != nil {
+		log.Errorf("%s stream creation error: %v", clientNameBaseLLM, err)
+		return nil, err
+	}
+
+	return llm_model.PumpStream(ctx, stream, c.config.ModelName), nil
+}
+
 // PostChatCompletions 流式调用，将响应流式写入gin.Context
 func (c *Client) PostChatCompletions(ctx *context.Context, messages []openai.ChatCompletionMessage) error {
 	ginCtx, ok := (*ctx).(*gin.Context)
@@ -88,62 +114,16 @@ func (c *Client) PostChatCompletions(ctx *context.Context, messages []openai.Cha
 		return model.NewError(model.ErrorParams, nil)
 	}
 
-	stream, err := c.client.CreateChatCompletionStream(ginCtx, openai.ChatCompletionRequest{
-		Model:       c.config.ModelName,
-		Messages:    messages,
-		MaxTokens:   c.config.MaxTokens,
-		Temperature: c.config.Temperature,
-		Stream:      true,
-	})
+	// 客户端断开时上游请求同步取消
+	streamCtx, cancel := context.WithCancel(ginCtx.Request.Context())
+	defer cancel()
 
+	deltas, err := c.StreamChatCompletions(streamCtx, messages)
 	if err != nil {
-		log.Errorf("%s stream creation error: %v", clientNameBaseLLM, err)
 		return err
 	}
-
-	ginCtx.Writer.Header().Set(httptool.HeaderContentType, httptool.HeaderContentTypeStream)
-	ginCtx.Writer.Header().Set(httptool.HeaderContentCache, httptool.HeaderContentCacheNo)
-	ginCtx.Writer.Header().Set(httptool.HeaderContentConnection, httptool.HeaderContentKeepAlive)
-	ginCtx.Writer.Header().Set(httptool.HeaderContentTransfer, httptool.HeaderContentChunked)
-
-	ginCtx.Writer.Flush()
-
-	defer tools.ErrorWithPrintContext(stream.Close, "close stream")
-
-	ginCtx.Stream(func(w io.Writer) bool {
-		var respMsg bytes.Buffer
-
-		response, err := stream.Recv()
-		if err == io.EOF {
-			return false
-		}
-		if err != nil {
-			log.Errorf("%s stream.Recv error: %v", clientNameBaseLLM, err)
-			return false
-		}
-
-		if len(response.Choices) > 0 {
-			respMsg.Write(streamMessageStart)
-			temp, err := json.Marshal(response.Choices)
-			if err != nil {
-				log.Errorf("%s: %+v json.Marshal error: %v", clientNameBaseLLM, response.Choices, err)
-				return false
-			}
-
-			respMsg.Write(temp)
-			respMsg.Write(streamMessageEnd)
-
-			_, err = w.Write(respMsg.Bytes())
-			if err != nil {
-				log.Errorf("%s: %+v w.Write error: %v", clientNameBaseLLM, respMsg.String(), err)
-				return false
-			}
-			ginCtx.Writer.Flush()
-		}
-		return true
-	})
-
-	return nil
+	_, err = llm_model.ServeSSE(ginCtx, deltas)
+	return err
 }
 
 // PostChatCompletionsNonStream 非流式调用，返回完整响应
@@ -168,12 +148,19 @@ func (c *Client) PostChatCompletionsNonStream(ctx context.Context, messages []op
 		}
 	}
 
-	response, err := c.client.CreateChatCompletion(ctx, request)
+	prompt.LogCall(ctx, clientNameBaseLLM, c.config.ModelName)
+	var response openai.ChatCompletionResponse
+	err := c.caller.Do(ctx, func(ctx context.Context) error {
+		var err error
+		response, err = c.client.CreateChatCompletion(ctx, request)
+		return err
+	})
 
 	if err != nil {
 		log.Errorf("%s chat completion error: %v", clientNameBaseLLM, err)
 		return nil, err
 	}
+	usage.ReportLLM(ctx, c.config.ModelName, response.Usage)
 
 	// debug 出完整的响应内容，json格式（仅在 debug 级别时序列化）
 	if log.GetLevel() == log.DebugLevel {
@@ -190,6 +177,32 @@ func (c *Client) PostChatCompletionsNonStream(ctx context.Context, messages []op
 	return &response, nil
 }
 
+// PostChatCompletionsJSON 非流式调用并携带 response_format，要求模型输出 JSON
+func (c *Client) PostChatCompletionsJSON(ctx context.Context, messages []openai.ChatCompletionMessage, format *openai.ChatCompletionResponseFormat) (*openai.ChatCompletionResponse, error) {
+	request := openai.ChatCompletionRequest{
+		Model:          c.config.ModelName,
+		Messages:       messages,
+		MaxTokens:      c.config.MaxTokens,
+		Temperature:    c.config.Temperature,
+		ResponseFormat: format,
+	}
+
+	prompt.LogCall(ctx, clientNameBaseLLM, c.config.ModelName)
+	var response openai.ChatCompletionResponse
+	err := c.caller.Do(ctx, func(ctx context.Context) error {
+		var err error
+		response, err = c.client.CreateChatCompletion(ctx, request)
+		return err
+	})
+	if err != nil {
+		log.Errorf("%s chat completion json error: %v", clientNameBaseLLM, err)
+		return nil, err
+	}
+	usage.ReportLLM(ctx, c.config.ModelName, response.Usage)
+
+	return &response, nil
+}
+
 // PostChatCompletionsNonStreamContent 非流式调用，只返回响应内容字符串
 func (c *Client) PostChatCompletionsNonStreamContent(ctx context.Context, messages []openai.ChatCompletionMessage) (string, error) {
 	response, err := c.PostChatCompletionsNonStream(ctx, messages)
@@ -1,11 +1,12 @@
 package base_llm_model
 
 import (
+	"ai_task/pkg/clients/replay"
 	"context"
-	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -201,19 +202,7 @@ func (s *BaseLLMClientTest) TestOptions() {
 
 // TestPostChatCompletionsNonStream_Success 测试非流式调用成功
 func (s *BaseLLMClientTest) TestPostChatCompletionsNonStream_Success() {
-	if !isTestParamsValid(s.testParams) {
-		s.T().Skip("Skipping test: TEST_LLM_BASE_URL, TEST_LLM_API_KEY, TEST_LLM_MODEL_NAME env vars not set")
-		return
-	}
-
-	params := ClientParams{
-		BaseURL:   s.testParams.BaseURL,
-		APIKey:    s.testParams.APIKey,
-		ModelName: s.testParams.ModelName,
-	}
-
-	client := NewClientWithParams(params)
-	s.NotNil(client)
+	client := s.newFixtureClient("chat_non_stream_intro")
 
 	ctx := context.Background()
 	messages := []openai.ChatCompletionMessage{
@@ -225,32 +214,14 @@ func (s *BaseLLMClientTest) TestPostChatCompletionsNonStream_Success() {
 
 	response, err := client.PostChatCompletionsNonStream(ctx, messages)
 
-	s.Nil(err, "should not return error")
-	s.NotNil(response, "response should not be nil")
-	if response != nil {
-		s.Greater(len(response.Choices), 0, "should have at least one choice")
-		if len(response.Choices) > 0 {
-			fmt.Println(response.Choices[0].Message.Content)
-			s.NotEmpty(response.Choices[0].Message.Content, "message content should not be empty")
-		}
-	}
+	s.Require().NoError(err)
+	s.Require().NotEmpty(response.Choices, "should have at least one choice")
+	s.NotEmpty(response.Choices[0].Message.Content, "message content should not be empty")
 }
 
 // TestPostChatCompletionsNonStreamContent_Success 测试非流式调用获取内容
 func (s *BaseLLMClientTest) TestPostChatCompletionsNonStreamContent_Success() {
-	if !isTestParamsValid(s.testParams) {
-		s.T().Skip("Skipping test: TEST_LLM_BASE_URL, TEST_LLM_API_KEY, TEST_LLM_MODEL_NAME env vars not set")
-		return
-	}
-
-	params := ClientParams{
-		BaseURL:   s.testParams.BaseURL,
-		APIKey:    s.testParams.APIKey,
-		ModelName: s.testParams.ModelName,
-	}
-
-	client := NewClientWithParams(params)
-	s.NotNil(client)
+	client := s.newFixtureClient("chat_content")
 
 	ctx := context.Background()
 	messages := []openai.ChatCompletionMessage{
@@ -262,71 +233,33 @@ func (s *BaseLLMClientTest) TestPostChatCompletionsNonStreamContent_Success() {
 
 	content, err := client.PostChatCompletionsNonStreamContent(ctx, messages)
 
-	s.Nil(err, "should not return error")
+	s.Require().NoError(err)
 	s.NotEmpty(content, "content should not be empty")
 }
 
 // TestChat_Success 测试简单对话方法
 func (s *BaseLLMClientTest) TestChat_Success() {
-	if !isTestParamsValid(s.testParams) {
-		s.T().Skip("Skipping test: TEST_LLM_BASE_URL, TEST_LLM_API_KEY, TEST_LLM_MODEL_NAME env vars not set")
-		return
-	}
-
-	params := ClientParams{
-		BaseURL:   s.testParams.BaseURL,
-		APIKey:    s.testParams.APIKey,
-		ModelName: s.testParams.ModelName,
-	}
+	client := s.newFixtureClient("chat_simple")
 
-	client := NewClientWithParams(params)
-	s.NotNil(client)
-
-	ctx := context.Background()
-	content, err := client.Chat(ctx, "你好")
+	content, err := client.Chat(context.Background(), "你好")
 
-	s.Nil(err, "should not return error")
+	s.Require().NoError(err)
 	s.NotEmpty(content, "content should not be empty")
 }
 
 // TestChatWithSystemPrompt_Success 测试带系统提示词的对话方法
 func (s *BaseLLMClientTest) TestChatWithSystemPrompt_Success() {
-	if !isTestParamsValid(s.testParams) {
-		s.T().Skip("Skipping test: TEST_LLM_BASE_URL, TEST_LLM_API_KEY, TEST_LLM_MODEL_NAME env vars not set")
-		return
-	}
-
-	params := ClientParams{
-		BaseURL:   s.testParams.BaseURL,
-		APIKey:    s.testParams.APIKey,
-		ModelName: s.testParams.ModelName,
-	}
+	client := s.newFixtureClient("chat_system_prompt")
 
-	client := NewClientWithParams(params)
-	s.NotNil(client)
-
-	ctx := context.Background()
-	content, err := client.ChatWithSystemPrompt(ctx, "你是一个数学助手", "1+1等于多少？")
+	content, err := client.ChatWithSystemPrompt(context.Background(), "你是一个数学助手", "1+1等于多少？")
 
-	s.Nil(err, "should not return error")
+	s.Require().NoError(err)
 	s.NotEmpty(content, "content should not be empty")
 }
 
 // TestMultipleMessages_Success 测试多轮对话
 func (s *BaseLLMClientTest) TestMultipleMessages_Success() {
-	if !isTestParamsValid(s.testParams) {
-		s.T().Skip("Skipping test: TEST_LLM_BASE_URL, TEST_LLM_API_KEY, TEST_LLM_MODEL_NAME env vars not set")
-		return
-	}
-
-	params := ClientParams{
-		BaseURL:   s.testParams.BaseURL,
-		APIKey:    s.testParams.APIKey,
-		ModelName: s.testParams.ModelName,
-	}
-
-	client := NewClientWithParams(params)
-	s.NotNil(client)
+	client := s.newFixtureClient("chat_multi_turn")
 
 	ctx := context.Background()
 	messages := []openai.ChatCompletionMessage{
@@ -350,11 +283,8 @@ func (s *BaseLLMClientTest) TestMultipleMessages_Success() {
 
 	response, err := client.PostChatCompletionsNonStream(ctx, messages)
 
-	s.Nil(err, "should not return error")
-	s.NotNil(response, "response should not be nil")
-	if response != nil {
-		s.Greater(len(response.Choices), 0, "should have at least one choice")
-	}
+	s.Require().NoError(err)
+	s.NotEmpty(response.Choices, "should have at least one choice")
 }
 
 // TestDifferentModels 测试使用不同模型参数创建多个客户端
@@ -391,42 +321,19 @@ func (s *BaseLLMClientTest) TestDifferentModels() {
 
 // TestEmptyMessages 测试空消息列表
 func (s *BaseLLMClientTest) TestEmptyMessages() {
-	if !isTestParamsValid(s.testParams) {
-		s.T().Skip("Skipping test: TEST_LLM_BASE_URL, TEST_LLM_API_KEY, TEST_LLM_MODEL_NAME env vars not set")
-		return
-	}
-
-	params := ClientParams{
-		BaseURL:   s.testParams.BaseURL,
-		APIKey:    s.testParams.APIKey,
-		ModelName: s.testParams.ModelName,
-	}
+	client := s.newFixtureClient("chat_empty_messages")
 
-	client := NewClientWithParams(params)
-	ctx := context.Background()
-	messages := []openai.ChatCompletionMessage{}
-
-	_, err := client.PostChatCompletionsNonStream(ctx, messages)
+	_, err := client.PostChatCompletionsNonStream(context.Background(), []openai.ChatCompletionMessage{})
 
 	// 空消息列表应该返回错误
-	s.NotNil(err, "should return error for empty messages")
+	s.Error(err, "should return error for empty messages")
 }
 
 // TestInvalidAPIKey 测试无效的API密钥
 func (s *BaseLLMClientTest) TestInvalidAPIKey() {
-	if s.testParams.BaseURL == "" {
-		s.T().Skip("Skipping test: TEST_LLM_BASE_URL env var not set")
-		return
-	}
+	// 录制时使用无效的密钥，上游返回鉴权错误
+	client := s.newFixtureClient("chat_invalid_key", WithAPIKey("invalid-api-key"))
 
-	params := ClientParams{
-		BaseURL:   s.testParams.BaseURL,
-		APIKey:    "invalid-api-key",
-		ModelName: "test-model",
-	}
-
-	client := NewClientWithParams(params)
-	ctx := context.Background()
 	messages := []openai.ChatCompletionMessage{
 		{
 			Role:    openai.ChatMessageRoleUser,
@@ -434,10 +341,47 @@ func (s *BaseLLMClientTest) TestInvalidAPIKey() {
 		},
 	}
 
-	_, err := client.PostChatCompletionsNonStream(ctx, messages)
+	_, err := client.PostChatCompletionsNonStream(context.Background(), messages)
 
 	// 无效的API密钥应该返回错误
-	s.NotNil(err, "should return error for invalid API key")
+	s.Error(err, "should return error for invalid API key")
+}
+
+// fixtureModel 夹具录制时使用的模型，回放时请求体中的模型名必须一致
+const fixtureModel = "glm-4-flash"
+
+// newFixtureClient 回放 testdata 下的夹具，opts 在默认选项之后应用；夹具缺失时测试失败
+// LLM_FIXTURE_MODE=record 时使用环境变量中的地址与密钥请求真实接口并重新录制
+func (s *BaseLLMClientTest) newFixtureClient(name string, opts ...Option) *Client {
+	mode := replay.ModeFromEnv()
+	baseURL, apiKey := "http://replay.invalid/v1", "test-api-key"
+	if mode == replay.ModeRecord {
+		if !isTestParamsValid(s.testParams) {
+			s.T().Skip("Skipping record: MODEL_BASE_GLM_URL, LLM_GML_API_KEY, LLM_GML_MODEL env vars not set")
+		}
+		baseURL, apiKey = s.testParams.BaseURL, s.testParams.APIKey
+	}
+
+	path := filepath.Join("testdata", name+".json")
+	transport, err := replay.Open(path, mode)
+	s.Require().NoError(err, "fixture %s is missing, run `make fixtures` to record it", path)
+	s.T().Cleanup(func() {
+		s.NoError(transport.Close())
+	})
+	return NewClient(baseURL, apiKey, fixtureModel, append([]Option{WithTemperature(0), WithTransport(transport)}, opts...)...)
+}
+
+// TestPostChatCompletionsNonStream_Replay 使用录制的响应测试非流式调用，不需要网络与密钥
+func (s *BaseLLMClientTest) TestPostChatCompletionsNonStream_Replay() {
+	client := s.newFixtureClient("chat_non_stream")
+
+	response, err := client.PostChatCompletionsNonStream(context.Background(), []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "你好，请用一句话介绍你自己"},
+	})
+	s.Require().NoError(err)
+	s.Require().NotEmpty(response.Choices)
+	s.NotEmpty(response.Choices[0].Message.Content)
+	s.Greater(response.Usage.TotalTokens, 0)
 }
 
 func TestBaseLLMClient(t *testing.T) {
@@ -1,12 +1,19 @@
 package base_llm_model
 
+import (
+	"ai_task/pkg/clients/resilience"
+	"net/http"
+)
+
 // Config 基础LLM模型配置
 type Config struct {
-	BaseURL     string  `json:"base_url"`     // API基础地址
-	APIKey      string  `json:"api_key"`      // API密钥
-	ModelName   string  `json:"model_name"`   // 模型名称
-	Temperature float32 `json:"temperature"`  // 温度参数，控制输出随机性
-	MaxTokens   int     `json:"max_tokens"`   // 最大输出token数
+	BaseURL     string             `json:"base_url"`             // API基础地址
+	APIKey      string             `json:"api_key"`              // API密钥
+	ModelName   string             `json:"model_name"`           // 模型名称
+	Temperature float32            `json:"temperature"`          // 温度参数，控制输出随机性
+	MaxTokens   int                `json:"max_tokens"`           // 最大输出token数
+	Resilience  *resilience.Policy `json:"resilience,omitempty"` // 超时、重试与熔断策略，为 nil 时只调用一次
+	Transport   http.RoundTripper  `json:"-"`                    // 发送请求的 RoundTripper，为 nil 时使用 http.DefaultTransport，测试中可注入录制回放
 }
 
 // ClientParams 客户端必填参数结构体
@@ -21,9 +28,11 @@ type Option func(*Config)
 
 // DefaultConfig 返回默认配置
 func DefaultConfig() *Config {
+	policy := resilience.DefaultPolicy()
 	return &Config{
 		Temperature: 0.7,
 		MaxTokens:   4096,
+		Resilience:  &policy,
 	}
 }
 
@@ -61,3 +70,17 @@ func WithMaxTokens(maxTokens int) Option {
 		c.MaxTokens = maxTokens
 	}
 }
+
+// WithTransport 设置发送请求的 RoundTripper
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Config) {
+		c.Transport = transport
+	}
+}
+
+// WithResilience 设置超时、重试与熔断策略，传入 nil 时关闭
+func WithResilience(policy *resilience.Policy) Option {
+	return func(c *Config) {
+		c.Resilience = policy
+	}
+}
@@ -0,0 +1,325 @@
+package base_llm_model
+
+import (
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/usage"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultMaxToolIterations 工具调用循环默认的最大模型调用轮数
+const DefaultMaxToolIterations = 8
+
+// ErrMaxToolIterations 达到最大轮数时模型仍在请求工具调用
+var ErrMaxToolIterations = errors.New("tool calling exceeded max iterations")
+
+// ToolHandler 工具处理函数，arguments 为模型生成的 JSON 参数，返回值作为工具结果回传给模型
+type ToolHandler func(ctx context.Context, arguments json.RawMessage) (string, error)
+
+// Tool 可供模型调用的工具
+type Tool struct {
+	Name        string          // 工具名，只能包含字母、数字、下划线和中划线
+	Description string          // 工具用途说明，模型据此决定是否调用
+	Parameters  json.RawMessage // 参数的 JSON Schema，为空时表示无参数
+	Handler     ToolHandler
+}
+
+// Toolset 已注册的工具集合，按注册顺序提供给模型
+type Toolset struct {
+	mu    sync.RWMutex
+	tools []*Tool
+	index map[string]*Tool
+}
+
+// NewToolset 创建工具集合
+func NewToolset(tools ...*Tool) (*Toolset, error) {
+	ts := &Toolset{index: make(map[string]*Tool)}
+	for _, tool := range tools {
+		if err := ts.Register(tool); err != nil {
+			return nil, err
+		}
+	}
+	return ts, nil
+}
+
+// Register 注册工具，名称重复或参数不是合法 JSON 时返回错误
+func (ts *Toolset) Register(tool *Tool) error {
+	if tool == nil || tool.Name == "" {
+		return fmt.Errorf("tool name is required")
+	}
+	if tool.Handler == nil {
+		return fmt.Errorf("tool %s has no handler", tool.Name)
+	}
+	if len(tool.Parameters) > 0 && !json.Valid(tool.Parameters) {
+		return fmt.Errorf("tool %s parameters is not valid json schema", tool.Name)
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if _, ok := ts.index[tool.Name]; ok {
+		return fmt.Errorf("tool %s already registered", tool.Name)
+	}
+	ts.tools = append(ts.tools, tool)
+	ts.index[tool.Name] = tool
+	return nil
+}
+
+// Get 按名称获取工具
+func (ts *Toolset) Get(name string) (*Tool, bool) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	tool, ok := ts.index[name]
+	return tool, ok
+}
+
+// Definitions 转换为请求中的 tools 参数
+func (ts *Toolset) Definitions() []openai.Tool {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	definitions := make([]openai.Tool, 0, len(ts.tools))
+	for _, tool := range ts.tools {
+		parameters := tool.Parameters
+		if len(parameters) == 0 {
+			parameters = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		definitions = append(definitions, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  parameters,
+			},
+		})
+	}
+	return definitions
+}
+
+// ToolRequest 一次带工具的补全请求
+type ToolRequest struct {
+	Messages          []openai.ChatCompletionMessage
+	Tools             []openai.Tool
+	ToolChoice        any  // "auto"、"none"、"required" 或 openai.ToolChoice，为 nil 时由模型决定
+	ParallelToolCalls bool // 是否允许模型在一轮中返回多个工具调用
+}
+
+// CompletionFunc 执行一次带工具的补全，工具调用循环通过它访问模型
+type CompletionFunc func(ctx context.Context, req *ToolRequest) (*openai.ChatCompletionResponse, error)
+
+// ToolRunOptions 工具调用循环选项
+type ToolRunOptions struct {
+	MaxIterations int  // 最大模型调用轮数，<= 0 时使用 DefaultMaxToolIterations
+	ToolChoice    any  // 仅作用于第一轮，之后由模型决定，避免 required 导致无法结束
+	Parallel      bool // 允许并行工具调用，同一轮的多个调用并发执行
+}
+
+// ToolResult 一次工具调用的结果
+type ToolResult struct {
+	CallID    string        `json:"call_id"`
+	Name      string        `json:"name"`
+	Arguments string        `json:"arguments"`
+	Output    string        `json:"output"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	Iteration int           `json:"iteration"` // 所在轮次，从 1 开始
+}
+
+// ToolRunResult 工具调用循环的结果
+type ToolRunResult struct {
+	Messages    []openai.ChatCompletionMessage // 完整记录：输入消息、模型回复与工具结果
+	ToolResults []ToolResult                   // 按调用顺序排列的工具结果
+	Content     string                         // 最终回复内容
+	Iterations  int                            // 实际模型调用轮数
+	Usage       openai.Usage                   // 各轮 token 用量之和
+}
+
+// RunToolLoop 执行 模型 -> 工具 -> 模型 的循环，直到模型不再请求工具或达到最大轮数
+// 工具不存在或执行失败时把错误作为工具结果回传给模型，由模型决定如何继续；
+// 达到最大轮数时返回已有结果和 ErrMaxToolIterations
+func RunToolLoop(ctx context.Context, complete CompletionFunc, messages []openai.ChatCompletionMessage, toolset *Toolset, opts *ToolRunOptions) (*ToolRunResult, error) {
+	if opts == nil {
+		opts = &ToolRunOptions{}
+	}
+	maxIterations := opts.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = DefaultMaxToolIterations
+	}
+
+	result := &ToolRunResult{
+		Messages: append([]openai.ChatCompletionMessage(nil), messages...),
+	}
+	var definitions []openai.Tool
+	if toolset != nil {
+		definitions = toolset.Definitions()
+	}
+
+	for result.Iterations < maxIterations {
+		result.Iterations++
+
+		req := &ToolRequest{
+			Messages:          result.Messages,
+			Tools:             definitions,
+			ParallelToolCalls: opts.Parallel,
+		}
+		if result.Iterations == 1 {
+			req.ToolChoice = opts.ToolChoice
+		}
+
+		response, err := complete(ctx, req)
+		if err != nil {
+			return result, err
+		}
+		if response == nil || len(response.Choices) == 0 {
+			return result, fmt.Errorf("chat completion response has no choices")
+		}
+		addUsage(&result.Usage, response.Usage)
+
+		message := response.Choices[0].Message
+		if message.Role == "" {
+			message.Role = openai.ChatMessageRoleAssistant
+		}
+		result.Messages = append(result.Messages, message)
+
+		if len(message.ToolCalls) == 0 {
+			result.Content = message.Content
+			return result, nil
+		}
+
+		toolResults := runToolCalls(ctx, toolset, message.ToolCalls, opts.Parallel)
+		for i := range toolResults {
+			toolResults[i].Iteration = result.Iterations
+			result.Messages = append(result.Messages, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				Content:    toolResults[i].content(),
+				Name:       toolResults[i].Name,
+				ToolCallID: toolResults[i].CallID,
+			})
+		}
+		result.ToolResults = append(result.ToolResults, toolResults...)
+	}
+
+	return result, ErrMaxToolIterations
+}
+
+// runToolCalls 执行一轮中的工具调用，结果顺序与调用顺序一致
+func runToolCalls(ctx context.Context, toolset *Toolset, calls []openai.ToolCall, parallel bool) []ToolResult {
+	results := make([]ToolResult, len(calls))
+	if !parallel || len(calls) == 1 {
+		for i, call := range calls {
+			results[i] = runToolCall(ctx, toolset, call)
+		}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func(i int, call openai.ToolCall) {
+			defer wg.Done()
+			results[i] = runToolCall(ctx, toolset, call)
+		}(i, call)
+	}
+	wg.Wait()
+	return results
+}
+
+// runToolCall 执行单个工具调用，处理函数 panic 时转换为错误
+func runToolCall(ctx context.Context, toolset *Toolset, call openai.ToolCall) (result ToolResult) {
+	result = ToolResult{
+		CallID:    call.ID,
+		Name:      call.Function.Name,
+		Arguments: call.Function.Arguments,
+	}
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			result.Error = fmt.Sprintf("tool panicked: %v", r)
+		}
+		result.Duration = time.Since(start)
+		if result.Error != "" {
+			log.Warnf("%s tool %s call %s failed: %s", clientNameBaseLLM, result.Name, result.CallID, result.Error)
+		}
+	}()
+
+	var tool *Tool
+	if toolset != nil {
+		tool, _ = toolset.Get(call.Function.Name)
+	}
+	if tool == nil {
+		result.Error = fmt.Sprintf("unknown tool: %s", call.Function.Name)
+		return result
+	}
+
+	arguments := json.RawMessage(call.Function.Arguments)
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	if !json.Valid(arguments) {
+		result.Error = "arguments is not valid json"
+		return result
+	}
+
+	output, err := tool.Handler(ctx, arguments)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Output = output
+	return result
+}
+
+// content 回传给模型的工具结果
+func (r *ToolResult) content() string {
+	if r.Error != "" {
+		return "error: " + r.Error
+	}
+	return r.Output
+}
+
+func addUsage(total *openai.Usage, usage openai.Usage) {
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+}
+
+// PostChatCompletionsWithTools 非流式调用，携带 tools/tool_choice，响应中可能包含 tool_calls
+func (c *Client) PostChatCompletionsWithTools(ctx context.Context, req *ToolRequest) (*openai.ChatCompletionResponse, error) {
+	request := openai.ChatCompletionRequest{
+		Model:       c.config.ModelName,
+		Messages:    req.Messages,
+		MaxTokens:   c.config.MaxTokens,
+		Temperature: c.config.Temperature,
+		Tools:       req.Tools,
+		ToolChoice:  req.ToolChoice,
+	}
+	if len(req.Tools) > 0 {
+		request.ParallelToolCalls = req.ParallelToolCalls
+	}
+
+	prompt.LogCall(ctx, clientNameBaseLLM, c.config.ModelName)
+	var response openai.ChatCompletionResponse
+	err := c.caller.Do(ctx, func(ctx context.Context) error {
+		var err error
+		response, err = c.client.CreateChatCompletion(ctx, request)
+		return err
+	})
+	if err != nil {
+		log.Errorf("%s chat completion with tools error: %v", clientNameBaseLLM, err)
+		return nil, err
+	}
+	usage.ReportLLM(ctx, c.config.ModelName, response.Usage)
+
+	return &response, nil
+}
+
+// RunTools 使用工具集合执行工具调用循环，返回完整记录
+func (c *Client) RunTools(ctx context.Context, messages []openai.ChatCompletionMessage, toolset *Toolset, opts *ToolRunOptions) (*ToolRunResult, error) {
+	return RunToolLoop(ctx, c.PostChatCompletionsWithTools, messages, toolset, opts)
+}
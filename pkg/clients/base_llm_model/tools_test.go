@@ -0,0 +1,235 @@
+package base_llm_model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/suite"
+)
+
+type ToolCallingTest struct {
+	suite.Suite
+}
+
+// scriptedCompletion 按顺序返回预设的模型回复，并记录每轮请求
+func scriptedCompletion(replies []openai.ChatCompletionMessage, requests *[]*ToolRequest) CompletionFunc {
+	var n int
+	return func(ctx context.Context, req *ToolRequest) (*openai.ChatCompletionResponse, error) {
+		*requests = append(*requests, req)
+		if n >= len(replies) {
+			return nil, fmt.Errorf("no more replies")
+		}
+		reply := replies[n]
+		n++
+		return &openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: reply}},
+			Usage:   openai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+		}, nil
+	}
+}
+
+func toolCall(id, name, arguments string) openai.ToolCall {
+	return openai.ToolCall{
+		ID:       id,
+		Type:     openai.ToolTypeFunction,
+		Function: openai.FunctionCall{Name: name, Arguments: arguments},
+	}
+}
+
+func weatherTool() *Tool {
+	return &Tool{
+		Name:        "get_weather",
+		Description: "查询城市天气",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}`),
+		Handler: func(ctx context.Context, arguments json.RawMessage) (string, error) {
+			var args struct {
+				City string `json:"city"`
+			}
+			if err := json.Unmarshal(arguments, &args); err != nil {
+				return "", err
+			}
+			return args.City + ": 晴", nil
+		},
+	}
+}
+
+// TestRegisterValidation 测试注册校验
+func (s *ToolCallingTest) TestRegisterValidation() {
+	ts, err := NewToolset(weatherTool())
+	s.Require().NoError(err)
+
+	s.Error(ts.Register(weatherTool()), "duplicate name should be rejected")
+	s.Error(ts.Register(&Tool{Name: "no_handler"}))
+	s.Error(ts.Register(&Tool{Name: "bad_schema", Parameters: json.RawMessage(`{`), Handler: weatherTool().Handler}))
+
+	definitions := ts.Definitions()
+	s.Len(definitions, 1)
+	s.Equal("get_weather", definitions[0].Function.Name)
+}
+
+// TestRunToolLoop 测试 模型 -> 工具 -> 模型 的完整循环
+func (s *ToolCallingTest) TestRunToolLoop() {
+	ts, err := NewToolset(weatherTool())
+	s.Require().NoError(err)
+
+	var requests []*ToolRequest
+	complete := scriptedCompletion([]openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{toolCall("call_1", "get_weather", `{"city":"北京"}`)}},
+		{Role: openai.ChatMessageRoleAssistant, Content: "北京今天晴"},
+	}, &requests)
+
+	messages := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "北京天气如何"}}
+	result, err := RunToolLoop(context.Background(), complete, messages, ts, &ToolRunOptions{ToolChoice: "required"})
+	s.Require().NoError(err)
+
+	s.Equal("北京今天晴", result.Content)
+	s.Equal(2, result.Iterations)
+	s.Equal(30, result.Usage.TotalTokens)
+	s.Len(result.Messages, 4) // user, assistant(tool_calls), tool, assistant
+	s.Equal(openai.ChatMessageRoleTool, result.Messages[2].Role)
+	s.Equal("call_1", result.Messages[2].ToolCallID)
+	s.Equal("北京: 晴", result.Messages[2].Content)
+	s.Require().Len(result.ToolResults, 1)
+	s.Equal(1, result.ToolResults[0].Iteration)
+
+	// tool_choice 只作用于第一轮
+	s.Equal("required", requests[0].ToolChoice)
+	s.Nil(requests[1].ToolChoice)
+	s.Len(requests[1].Tools, 1)
+}
+
+// TestRunToolLoopToolErrors 测试工具不存在、参数非法和执行失败时错误回传给模型
+func (s *ToolCallingTest) TestRunToolLoopToolErrors() {
+	failing := &Tool{
+		Name: "fail",
+		Handler: func(ctx context.Context, arguments json.RawMessage) (string, error) {
+			return "", fmt.Errorf("disk full")
+		},
+	}
+	ts, err := NewToolset(weatherTool(), failing)
+	s.Require().NoError(err)
+
+	var requests []*ToolRequest
+	complete := scriptedCompletion([]openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{
+			toolCall("call_1", "missing", `{}`),
+			toolCall("call_2", "get_weather", `{"city":`),
+			toolCall("call_3", "fail", ``),
+		}},
+		{Role: openai.ChatMessageRoleAssistant, Content: "done"},
+	}, &requests)
+
+	result, err := RunToolLoop(context.Background(), complete, nil, ts, nil)
+	s.Require().NoError(err)
+
+	s.Require().Len(result.ToolResults, 3)
+	s.Contains(result.ToolResults[0].Error, "unknown tool")
+	s.Contains(result.ToolResults[1].Error, "not valid json")
+	s.Equal("disk full", result.ToolResults[2].Error)
+	s.Equal("error: disk full", result.Messages[3].Content)
+}
+
+// TestRunToolLoopParallel 测试同一轮的多个工具调用并发执行且结果保持调用顺序
+func (s *ToolCallingTest) TestRunToolLoopParallel() {
+	var running, peak int32
+	slow := &Tool{
+		Name: "slow",
+		Handler: func(ctx context.Context, arguments json.RawMessage) (string, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return string(arguments), nil
+		},
+	}
+	ts, err := NewToolset(slow)
+	s.Require().NoError(err)
+
+	var requests []*ToolRequest
+	complete := scriptedCompletion([]openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{
+			toolCall("call_1", "slow", `{"n":1}`),
+			toolCall("call_2", "slow", `{"n":2}`),
+			toolCall("call_3", "slow", `{"n":3}`),
+		}},
+		{Role: openai.ChatMessageRoleAssistant, Content: "done"},
+	}, &requests)
+
+	result, err := RunToolLoop(context.Background(), complete, nil, ts, &ToolRunOptions{Parallel: true})
+	s.Require().NoError(err)
+
+	s.Greater(atomic.LoadInt32(&peak), int32(1), "tool calls should run concurrently")
+	s.True(requests[0].ParallelToolCalls)
+	for i, r := range result.ToolResults {
+		s.Equal(fmt.Sprintf(`{"n":%d}`, i+1), r.Output)
+	}
+}
+
+// TestRunToolLoopMaxIterations 测试达到最大轮数时返回已有记录和错误
+func (s *ToolCallingTest) TestRunToolLoopMaxIterations() {
+	ts, err := NewToolset(weatherTool())
+	s.Require().NoError(err)
+
+	call := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{toolCall("call", "get_weather", `{"city":"上海"}`)}}
+	var requests []*ToolRequest
+	complete := scriptedCompletion([]openai.ChatCompletionMessage{call, call, call}, &requests)
+
+	result, err := RunToolLoop(context.Background(), complete, nil, ts, &ToolRunOptions{MaxIterations: 2})
+	s.ErrorIs(err, ErrMaxToolIterations)
+	s.Equal(2, result.Iterations)
+	s.Len(result.ToolResults, 2)
+}
+
+// TestClientRunTools 测试客户端请求中携带 tools 并解析 tool_calls
+func (s *ToolCallingTest) TestClientRunTools() {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		s.NoError(json.NewDecoder(r.Body).Decode(&req))
+		s.Len(req.Tools, 1)
+
+		message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "北京今天晴"}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			s.Equal(true, req.ParallelToolCalls)
+			message = openai.ChatCompletionMessage{
+				Role:      openai.ChatMessageRoleAssistant,
+				ToolCalls: []openai.ToolCall{toolCall("call_1", "get_weather", `{"city":"北京"}`)},
+			}
+		} else {
+			s.Equal(openai.ChatMessageRoleTool, req.Messages[len(req.Messages)-1].Role)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: message, FinishReason: openai.FinishReasonToolCalls}},
+		})
+	}))
+	defer server.Close()
+
+	ts, err := NewToolset(weatherTool())
+	s.Require().NoError(err)
+
+	client := NewClient(server.URL, "test-api-key", "test-model")
+	messages := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "北京天气如何"}}
+	result, err := client.RunTools(context.Background(), messages, ts, &ToolRunOptions{Parallel: true})
+	s.Require().NoError(err)
+
+	s.Equal("北京今天晴", result.Content)
+	s.Equal(int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestToolCalling(t *testing.T) {
+	suite.Run(t, new(ToolCallingTest))
+}
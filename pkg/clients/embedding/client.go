@@ -2,6 +2,7 @@ package embedding
 
 import (
 	"ai_task/config"
+	"ai_task/pkg/usage"
 	"context"
 	"fmt"
 	"math"
@@ -160,29 +161,31 @@ func GetInstance() (*Client, error) {
 			return
 		}
 
-		baseURL := cfg.GetString(config.EmbeddingConfigKeyBaseURL)
-
-		// 创建 OpenAI 客户端
-		opts := []option.RequestOption{
-			option.WithAPIKey(apiKey),
-		}
+		instance = NewClient(apiKey, cfg.GetString(config.EmbeddingConfigKeyBaseURL), modelName)
+	})
 
-		// 如果配置了 base_url，则使用自定义的 base_url（用于兼容其他兼容 OpenAI API 的服务）
-		if baseURL != "" {
-			opts = append(opts, option.WithBaseURL(baseURL))
-		}
+	return instance, initErr
+}
 
-		client := openai.NewClient(opts...)
+// NewClient 创建 Embedding 客户端，baseURL 为空时使用 OpenAI 官方地址
+// opts 追加到默认选项之后，例如 option.WithHTTPClient 注入录制回放的 http 客户端
+func NewClient(apiKey, baseURL, modelName string, opts ...option.RequestOption) *Client {
+	// 创建 OpenAI 客户端
+	requestOpts := []option.RequestOption{
+		option.WithAPIKey(apiKey),
+	}
 
-		instance = &Client{
-			client:    client,
-			modelName: modelName,
-			cache:     NewLRUCache(LRUCacheCapacity),
-			metrics:   &Metrics{},
-		}
-	})
+	// 如果配置了 base_url，则使用自定义的 base_url（用于兼容其他兼容 OpenAI API 的服务）
+	if baseURL != "" {
+		requestOpts = append(requestOpts, option.WithBaseURL(baseURL))
+	}
 
-	return instance, initErr
+	return &Client{
+		client:    openai.NewClient(append(requestOpts, opts...)...),
+		modelName: modelName,
+		cache:     NewLRUCache(LRUCacheCapacity),
+		metrics:   &Metrics{},
+	}
 }
 
 // GetTextEmbedding 获取单个文本的 Embedding 向量（带缓存）
@@ -326,6 +329,7 @@ func (c *Client) getTextEmbeddingBatchOnce(ctx context.Context, texts []string)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create embeddings: %w", err)
 	}
+	usage.ReportEmbedding(ctx, c.modelName, int(resp.Usage.PromptTokens), int(resp.Usage.TotalTokens))
 
 	// 提取 embedding 向量（注意：OpenAI API 返回的是 []float64）
 	result := make([][]float64, 0, len(resp.Data))
@@ -2,10 +2,13 @@ package embedding
 
 import (
 	"ai_task/config"
+	"ai_task/pkg/clients/replay"
 	"context"
+	"path/filepath"
 	"sync"
 	"testing"
 
+	"github.com/openai/openai-go/v3/option"
 	"github.com/stretchr/testify/suite"
 	"github.com/wuwie1/go-tools/env"
 )
@@ -23,13 +26,13 @@ func (e *EmbeddingClientTest) SetupTest() {
 }
 
 func (e *EmbeddingClientTest) TestGetInstance_Success() {
-	// 确保配置存在
-	cfg := config.GetInstance()
-	apiKey := env.GetModelApiKey()
-	modelName := cfg.GetString(config.EmbeddingConfigKeyModelName)
+	// GetInstance 只创建客户端、不发送请求，未设置密钥时使用测试密钥
+	if env.GetModelApiKey() == "" {
+		e.T().Setenv("LLM_API_KEY", "test-api-key")
+	}
 
 	// 如果配置不存在，跳过测试
-	if apiKey == "" || modelName == "" {
+	if config.GetInstance().GetString(config.EmbeddingConfigKeyModelName) == "" {
 		e.T().Skip("Skipping test: embedding config not set")
 		return
 	}
@@ -92,47 +95,18 @@ func (e *EmbeddingClientTest) TestGetInstance_MissingModelName() {
 }
 
 func (e *EmbeddingClientTest) TestGetTextEmbedding_Success() {
-	// 确保配置存在
-	cfg := config.GetInstance()
-	apiKey := env.GetModelApiKey()
-	modelName := cfg.GetString(config.EmbeddingConfigKeyModelName)
-
-	// 如果配置不存在，跳过测试
-	if apiKey == "" || modelName == "" {
-		e.T().Skip("Skipping test: embedding config not set")
-		return
-	}
-
-	// 获取客户端
-	client, err := GetInstance()
-	e.Nil(err)
-	e.NotNil(client)
+	client := e.newFixtureClient("embedding_single")
 
 	// 测试单个文本的 Embedding
 	ctx := context.Background()
 	text := "测试文本"
 	embedding, err := client.GetTextEmbedding(ctx, text)
-	e.Nil(err)
-	e.NotNil(embedding)
+	e.Require().NoError(err)
 	e.Greater(len(embedding), 0, "embedding should have dimensions")
 }
 
 func (e *EmbeddingClientTest) TestGetTextEmbeddingBatch_Success() {
-	// 确保配置存在
-	cfg := config.GetInstance()
-	apiKey := env.GetModelApiKey()
-	modelName := cfg.GetString(config.EmbeddingConfigKeyModelName)
-
-	// 如果配置不存在，跳过测试
-	if apiKey == "" || modelName == "" {
-		e.T().Skip("Skipping test: embedding config not set")
-		return
-	}
-
-	// 获取客户端
-	client, err := GetInstance()
-	e.Nil(err)
-	e.NotNil(client)
+	client := e.newFixtureClient("embedding_batch_poem")
 
 	// 测试批量文本的 Embedding
 	ctx := context.Background()
@@ -144,8 +118,7 @@ func (e *EmbeddingClientTest) TestGetTextEmbeddingBatch_Success() {
 	}
 
 	embeddings, err := client.GetTextEmbeddingBatch(ctx, texts)
-	e.Nil(err)
-	e.NotNil(embeddings)
+	e.Require().NoError(err)
 	e.Equal(len(texts), len(embeddings), "should return embeddings for all texts")
 
 	// 验证每个 embedding 的维度
@@ -160,23 +133,9 @@ func (e *EmbeddingClientTest) TestGetTextEmbeddingBatch_Success() {
 }
 
 func (e *EmbeddingClientTest) TestGetTextEmbeddingBatch_EmptyTexts() {
-	// 确保配置存在
-	cfg := config.GetInstance()
-	apiKey := env.GetModelApiKey()
-	modelName := cfg.GetString(config.EmbeddingConfigKeyModelName)
-
-	// 如果配置不存在，跳过测试
-	if apiKey == "" || modelName == "" {
-		e.T().Skip("Skipping test: embedding config not set")
-		return
-	}
-
-	// 获取客户端
-	client, err := GetInstance()
-	e.Nil(err)
-	e.NotNil(client)
+	// 空文本列表在请求前就返回错误，不需要夹具
+	client := NewClient("test-api-key", "http://replay.invalid/v1", fixtureModel)
 
-	// 测试空文本列表
 	ctx := context.Background()
 	embeddings, err := client.GetTextEmbeddingBatch(ctx, []string{})
 	e.NotNil(err)
@@ -192,13 +151,13 @@ func (e *EmbeddingClientTest) TestGetTextEmbedding_EmptyResult() {
 }
 
 func (e *EmbeddingClientTest) TestGetInstance_Singleton() {
-	// 确保配置存在
-	cfg := config.GetInstance()
-	apiKey := env.GetModelApiKey()
-	modelName := cfg.GetString(config.EmbeddingConfigKeyModelName)
+	// GetInstance 只创建客户端、不发送请求，未设置密钥时使用测试密钥
+	if env.GetModelApiKey() == "" {
+		e.T().Setenv("LLM_API_KEY", "test-api-key")
+	}
 
 	// 如果配置不存在，跳过测试
-	if apiKey == "" || modelName == "" {
+	if config.GetInstance().GetString(config.EmbeddingConfigKeyModelName) == "" {
 		e.T().Skip("Skipping test: embedding config not set")
 		return
 	}
@@ -219,6 +178,46 @@ func (e *EmbeddingClientTest) TestGetInstance_Singleton() {
 	e.Equal(client1.modelName, client2.modelName)
 }
 
+// fixtureModel 夹具录制时使用的模型，回放时请求体中的模型名必须一致
+const fixtureModel = "text-embedding-v2"
+
+// newFixtureClient 回放 testdata 下的夹具，夹具缺失时测试失败
+// LLM_FIXTURE_MODE=record 时使用 embedding.base_url 与 LLM_API_KEY 请求真实接口并重新录制
+func (e *EmbeddingClientTest) newFixtureClient(name string) *Client {
+	mode := replay.ModeFromEnv()
+	baseURL, apiKey := "http://replay.invalid/v1", "test-api-key"
+	if mode == replay.ModeRecord {
+		baseURL, apiKey = config.GetInstance().GetString(config.EmbeddingConfigKeyBaseURL), env.GetModelApiKey()
+		if apiKey == "" {
+			e.T().Skip("Skipping record: embedding config not set")
+		}
+	}
+
+	path := filepath.Join("testdata", name+".json")
+	transport, err := replay.Open(path, mode)
+	e.Require().NoError(err, "fixture %s is missing, run `make fixtures` to record it", path)
+	e.T().Cleanup(func() {
+		e.NoError(transport.Close())
+	})
+	return NewClient(apiKey, baseURL, fixtureModel, option.WithHTTPClient(transport.Client()), option.WithMaxRetries(0))
+}
+
+// TestGetTextEmbeddingBatch_Replay 使用录制的响应测试批量 Embedding，重复文本走本地缓存不再请求
+func (e *EmbeddingClientTest) TestGetTextEmbeddingBatch_Replay() {
+	client := e.newFixtureClient("embedding_batch")
+	ctx := context.Background()
+
+	embeddings, err := client.GetTextEmbeddingBatch(ctx, []string{"测试文本1", "测试文本2"})
+	e.Require().NoError(err)
+	e.Require().Len(embeddings, 2)
+	e.NotEmpty(embeddings[0])
+	e.Equal(len(embeddings[0]), len(embeddings[1]))
+
+	cached, err := client.GetTextEmbedding(ctx, "测试文本1")
+	e.Require().NoError(err)
+	e.Equal(embeddings[0], cached)
+}
+
 func TestEmbeddingClient(t *testing.T) {
 	suite.Run(t, new(EmbeddingClientTest))
 }
@@ -0,0 +1,460 @@
+package llm
+
+import (
+	"ai_task/config"
+	"ai_task/pkg/clients/base_llm_model"
+	"ai_task/pkg/clients/llm_model"
+	redisclient "ai_task/pkg/clients/redis"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sashabaranov/go-openai"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	CacheBackendRedis  = "redis"  // Redis 存储，不可用时降级为内存
+	CacheBackendMemory = "memory" // 仅进程内存
+
+	DefaultCacheTTL       = 24 * time.Hour
+	DefaultCacheCapacity  = 1000
+	DefaultCacheKeyPrefix = "ai_task:llm_cache:"
+)
+
+// ParamsModel 能提供请求参数的模型，缓存键据此区分不同模型与采样参数
+type ParamsModel interface {
+	ModelParams() (model string, temperature float32, maxTokens int)
+}
+
+var (
+	_ ParamsModel = (*llm_model.ClientChatModel)(nil)
+	_ ParamsModel = (*base_llm_model.Client)(nil)
+	_ ParamsModel = (*FallbackModel)(nil)
+	_ ParamsModel = (*CachedModel)(nil)
+
+	_ JSONChatModel = (*CachedModel)(nil)
+)
+
+// CacheStore 缓存存储，未命中时返回 false 且 err 为 nil
+type CacheStore interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// MemoryCacheStore 进程内 LRU 缓存，超过容量时淘汰最久未使用的条目
+type MemoryCacheStore struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List // 最近使用的在前
+	now      func() time.Time
+}
+
+type memoryCacheEntry struct {
+	key      string
+	value    []byte
+	expireAt time.Time // 为零值时不过期
+}
+
+// NewMemoryCacheStore 创建内存缓存，capacity <= 0 时使用 DefaultCacheCapacity
+func NewMemoryCacheStore(capacity int) *MemoryCacheStore {
+	if capacity <= 0 {
+		capacity = DefaultCacheCapacity
+	}
+	return &MemoryCacheStore{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Get 获取未过期的条目
+func (s *MemoryCacheStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryCacheEntry)
+	if !entry.expireAt.IsZero() && !s.now().Before(entry.expireAt) {
+		s.order.Remove(element)
+		delete(s.items, key)
+		return nil, false, nil
+	}
+	s.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+// Set 写入条目，ttl <= 0 时不过期
+func (s *MemoryCacheStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = s.now().Add(ttl)
+	}
+	if element, ok := s.items[key]; ok {
+		entry := element.Value.(*memoryCacheEntry)
+		entry.value, entry.expireAt = value, expireAt
+		s.order.MoveToFront(element)
+		return nil
+	}
+
+	s.items[key] = s.order.PushFront(&memoryCacheEntry{key: key, value: value, expireAt: expireAt})
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*memoryCacheEntry).key)
+	}
+	return nil
+}
+
+// Len 当前条目数，包含尚未清理的过期条目
+func (s *MemoryCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// RedisCacheStore Redis 缓存，过期由 Redis 负责
+type RedisCacheStore struct {
+	client redis.Cmdable
+}
+
+// NewRedisCacheStore 创建 Redis 缓存
+func NewRedisCacheStore(client redis.Cmdable) *RedisCacheStore {
+	return &RedisCacheStore{client: client}
+}
+
+// Get 获取条目，键不存在时返回未命中
+func (s *RedisCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set 写入条目
+func (s *RedisCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+// FallbackCacheStore 主存储出错时改用备用存储，Redis 不可用时缓存仍在进程内生效
+type FallbackCacheStore struct {
+	primary   CacheStore
+	secondary CacheStore
+}
+
+// NewFallbackCacheStore 创建带降级的存储
+func NewFallbackCacheStore(primary, secondary CacheStore) *FallbackCacheStore {
+	return &FallbackCacheStore{primary: primary, secondary: secondary}
+}
+
+// Get 优先读主存储，出错时读备用存储
+func (s *FallbackCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, ok, err := s.primary.Get(ctx, key)
+	if err == nil {
+		return value, ok, nil
+	}
+	log.Warnf("llm cache primary store get failed, use fallback: %v", err)
+	return s.secondary.Get(ctx, key)
+}
+
+// Set 优先写主存储，出错时写备用存储
+func (s *FallbackCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	err := s.primary.Set(ctx, key, value, ttl)
+	if err == nil {
+		return nil
+	}
+	log.Warnf("llm cache primary store set failed, use fallback: %v", err)
+	return s.secondary.Set(ctx, key, value, ttl)
+}
+
+// CacheOptions 缓存选项
+type CacheOptions struct {
+	TTL       time.Duration // 条目有效期，<= 0 时使用 DefaultCacheTTL
+	KeyPrefix string        // 键前缀，为空时使用 DefaultCacheKeyPrefix
+}
+
+// CacheMetrics 缓存命中统计
+type CacheMetrics struct {
+	Enabled  bool    `json:"enabled"`
+	Hits     int64   `json:"hits"`
+	Misses   int64   `json:"misses"`
+	Bypasses int64   `json:"bypasses"` // 调用方跳过或 temperature > 0 未走缓存的次数
+	Errors   int64   `json:"errors"`   // 读写存储失败的次数，失败时直接调用模型
+	HitRate  float64 `json:"hit_rate"` // hits / (hits + misses)
+}
+
+// Cache 模型响应缓存
+// 只缓存 temperature 为 0 的非流式调用，键为模型、消息、temperature、max_tokens、tools 等请求参数的哈希；
+// 出错或没有候选的响应不会被缓存
+type Cache struct {
+	store  CacheStore
+	ttl    time.Duration
+	prefix string
+
+	hits     atomic.Int64
+	misses   atomic.Int64
+	bypasses atomic.Int64
+	errors   atomic.Int64
+}
+
+// NewCache 创建缓存
+func NewCache(store CacheStore, opts CacheOptions) *Cache {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultCacheTTL
+	}
+	if opts.KeyPrefix == "" {
+		opts.KeyPrefix = DefaultCacheKeyPrefix
+	}
+	return &Cache{store: store, ttl: opts.TTL, prefix: opts.KeyPrefix}
+}
+
+var (
+	cache     *Cache
+	cacheOnce sync.Once
+)
+
+// GetCache 按 clients.llmCache 创建的全局缓存，未启用时返回 nil
+func GetCache() *Cache {
+	cacheOnce.Do(func() {
+		cache = loadCache()
+	})
+	return cache
+}
+
+// loadCache 读取缓存配置，Redis 连接失败时只使用内存缓存
+func loadCache() *Cache {
+	conf := config.GetInstance()
+	if !conf.GetBool(config.ClientLLMCacheEnabled) {
+		return nil
+	}
+
+	memory := NewMemoryCacheStore(conf.GetIntOrDefault(config.ClientLLMCacheMemoryCapacity, DefaultCacheCapacity))
+	var store CacheStore = memory
+	if conf.GetStringOrDefault(config.ClientLLMCacheBackend, CacheBackendRedis) == CacheBackendRedis {
+		client, err := redisclient.TryGetInstance()
+		if err != nil {
+			log.Warnf("llm cache redis unavailable, use memory: %v", err)
+		} else {
+			store = NewFallbackCacheStore(NewRedisCacheStore(client.Client), memory)
+		}
+	}
+
+	return NewCache(store, CacheOptions{
+		TTL:       time.Duration(conf.GetIntOrDefault(config.ClientLLMCacheTTLSeconds, int(DefaultCacheTTL/time.Second))) * time.Second,
+		KeyPrefix: conf.GetStringOrDefault(config.ClientLLMCacheKeyPrefix, DefaultCacheKeyPrefix),
+	})
+}
+
+// Metrics 获取命中统计，缓存未启用（nil）时返回零值
+func (c *Cache) Metrics() CacheMetrics {
+	if c == nil {
+		return CacheMetrics{}
+	}
+	metrics := CacheMetrics{
+		Enabled:  true,
+		Hits:     c.hits.Load(),
+		Misses:   c.misses.Load(),
+		Bypasses: c.bypasses.Load(),
+		Errors:   c.errors.Load(),
+	}
+	if lookups := metrics.Hits + metrics.Misses; lookups > 0 {
+		metrics.HitRate = float64(metrics.Hits) / float64(lookups)
+	}
+	return metrics
+}
+
+// Wrap 为模型加上缓存，model 为 nil 时返回 nil
+func (c *Cache) Wrap(model ChatModel) *CachedModel {
+	if model == nil {
+		return nil
+	}
+	return &CachedModel{cache: c, model: model}
+}
+
+type cacheBypassKey struct{}
+
+// WithoutCache 本次调用跳过缓存，既不读取也不写入
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// cacheRequest 参与缓存键计算的请求参数
+type cacheRequest struct {
+	Model             string                               `json:"model"`
+	Temperature       float32                              `json:"temperature"`
+	MaxTokens         int                                  `json:"max_tokens"`
+	Messages          []openai.ChatCompletionMessage       `json:"messages"`
+	Tools             []openai.Tool                        `json:"tools,omitempty"`
+	ToolChoice        any                                  `json:"tool_choice,omitempty"`
+	ParallelToolCalls bool                                 `json:"parallel_tool_calls,omitempty"`
+	ResponseFormat    *openai.ChatCompletionResponseFormat `json:"response_format,omitempty"`
+}
+
+func (c *Cache) key(req *cacheRequest) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return c.prefix + hex.EncodeToString(sum[:]), nil
+}
+
+// do 命中时返回缓存的响应，否则调用模型并写入缓存
+// 命中的响应 Usage 置零，本次调用没有消耗 token
+func (c *Cache) do(ctx context.Context, req *cacheRequest, call func(ctx context.Context) (*openai.ChatCompletionResponse, error)) (*openai.ChatCompletionResponse, error) {
+	if cacheBypassed(ctx) || req.Temperature > 0 {
+		c.bypasses.Add(1)
+		return call(ctx)
+	}
+
+	key, err := c.key(req)
+	if err != nil {
+		c.errors.Add(1)
+		log.Warnf("llm cache key failed: %v", err)
+		return call(ctx)
+	}
+
+	data, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		c.errors.Add(1)
+		log.Warnf("llm cache get failed: %v", err)
+	} else if ok {
+		var response openai.ChatCompletionResponse
+		if err := json.Unmarshal(data, &response); err == nil {
+			c.hits.Add(1)
+			log.Debugf("llm cache hit, model: %s, key: %s", req.Model, key)
+			response.Usage = openai.Usage{}
+			return &response, nil
+		}
+		log.Warnf("llm cache entry %s is invalid, ignore", key)
+	}
+
+	c.misses.Add(1)
+	response, err := call(ctx)
+	if err != nil || response == nil || len(response.Choices) == 0 {
+		return response, err
+	}
+
+	if data, err = json.Marshal(response); err == nil {
+		err = c.store.Set(context.WithoutCancel(ctx), key, data, c.ttl)
+	}
+	if err != nil {
+		c.errors.Add(1)
+		log.Warnf("llm cache set failed: %v", err)
+	}
+	return response, nil
+}
+
+// CachedModel 带响应缓存的模型，流式调用不经过缓存
+type CachedModel struct {
+	cache *Cache
+	model ChatModel
+}
+
+// ModelParams 返回被包装模型的参数；未实现 ParamsModel 的模型以类型名区分，temperature 视为 0
+func (m *CachedModel) ModelParams() (model string, temperature float32, maxTokens int) {
+	if params, ok := m.model.(ParamsModel); ok {
+		return params.ModelParams()
+	}
+	return fmt.Sprintf("%T", m.model), 0, 0
+}
+
+func (m *CachedModel) request(messages []openai.ChatCompletionMessage) *cacheRequest {
+	req := &cacheRequest{Messages: messages}
+	req.Model, req.Temperature, req.MaxTokens = m.ModelParams()
+	return req
+}
+
+// PostChatCompletionsNonStream 非流式补全，temperature 为 0 时读写缓存
+func (m *CachedModel) PostChatCompletionsNonStream(ctx context.Context, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	return m.cache.do(ctx, m.request(messages), func(ctx context.Context) (*openai.ChatCompletionResponse, error) {
+		return m.model.PostChatCompletionsNonStream(ctx, messages)
+	})
+}
+
+// PostChatCompletionsNonStreamContent 非流式补全，只返回首个候选的内容
+func (m *CachedModel) PostChatCompletionsNonStreamContent(ctx context.Context, messages []openai.ChatCompletionMessage) (string, error) {
+	response, err := m.PostChatCompletionsNonStream(ctx, messages)
+	if err != nil {
+		return "", err
+	}
+	if response == nil || len(response.Choices) == 0 {
+		return "", fmt.Errorf("chat completion response has no choices")
+	}
+	return response.Choices[0].Message.Content, nil
+}
+
+// PostChatCompletionsJSON 结构化输出补全，response_format 参与缓存键
+func (m *CachedModel) PostChatCompletionsJSON(ctx context.Context, messages []openai.ChatCompletionMessage, format *openai.ChatCompletionResponseFormat) (*openai.ChatCompletionResponse, error) {
+	req := m.request(messages)
+	req.ResponseFormat = format
+	return m.cache.do(ctx, req, func(ctx context.Context) (*openai.ChatCompletionResponse, error) {
+		if jsonModel, ok := m.model.(JSONChatModel); ok {
+			return jsonModel.PostChatCompletionsJSON(ctx, messages, format)
+		}
+		return m.model.PostChatCompletionsNonStream(ctx, messages)
+	})
+}
+
+// PostChatCompletionsWithTools 带工具的补全，tools 与 tool_choice 参与缓存键，可作为 base_llm_model.CompletionFunc 使用
+func (m *CachedModel) PostChatCompletionsWithTools(ctx context.Context, toolReq *base_llm_model.ToolRequest) (*openai.ChatCompletionResponse, error) {
+	type toolModel interface {
+		PostChatCompletionsWithTools(ctx context.Context, req *base_llm_model.ToolRequest) (*openai.ChatCompletionResponse, error)
+	}
+	tm, ok := m.model.(toolModel)
+	if !ok {
+		return nil, fmt.Errorf("model %T does not support tool calling", m.model)
+	}
+
+	req := m.request(toolReq.Messages)
+	req.Tools, req.ToolChoice, req.ParallelToolCalls = toolReq.Tools, toolReq.ToolChoice, toolReq.ParallelToolCalls
+	return m.cache.do(ctx, req, func(ctx context.Context) (*openai.ChatCompletionResponse, error) {
+		return tm.PostChatCompletionsWithTools(ctx, toolReq)
+	})
+}
+
+// StreamChatCompletions 流式调用，直接交给被包装的模型
+func (m *CachedModel) StreamChatCompletions(ctx context.Context, messages []openai.ChatCompletionMessage) (<-chan llm_model.StreamDelta, error) {
+	s, ok := m.model.(StreamingModel)
+	if !ok {
+		return nil, fmt.Errorf("model %T does not support streaming", m.model)
+	}
+	return s.StreamChatCompletions(ctx, messages)
+}
+
+// PostChatCompletions 流式调用，直接交给被包装的模型
+func (m *CachedModel) PostChatCompletions(c *context.Context, messages []openai.ChatCompletionMessage) (*llm_model.StreamResult, error) {
+	type streamer interface {
+		PostChatCompletions(c *context.Context, messages []openai.ChatCompletionMessage) (*llm_model.StreamResult, error)
+	}
+	s, ok := m.model.(streamer)
+	if !ok {
+		return nil, fmt.Errorf("model %T does not support streaming", m.model)
+	}
+	return s.PostChatCompletions(c, messages)
+}
@@ -0,0 +1,140 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// temperatureModel 带采样参数的脚本模型
+type temperatureModel struct {
+	*ScriptedModel
+	temperature float32
+}
+
+func (m temperatureModel) ModelParams() (string, float32, int) {
+	return "test-model", m.temperature, 100
+}
+
+// brokenStore 所有读写都失败的存储，模拟 Redis 不可用
+type brokenStore struct{}
+
+func (brokenStore) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func (brokenStore) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func TestCachedModelHitAndMiss(t *testing.T) {
+	scripted := NewScriptedModel(Reply("first"), Reply("second"))
+	cache := NewCache(NewMemoryCacheStore(10), CacheOptions{})
+	model := cache.Wrap(scripted)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		got, err := model.PostChatCompletionsNonStreamContent(ctx, userMessage("hi"))
+		if err != nil || got != "first" {
+			t.Fatalf("Expected cached reply %q, got %q, err %v", "first", got, err)
+		}
+	}
+	if len(scripted.Calls()) != 1 {
+		t.Errorf("Expected one model call, got %d", len(scripted.Calls()))
+	}
+
+	// 消息不同时不命中
+	got, _ := model.PostChatCompletionsNonStreamContent(ctx, userMessage("hello"))
+	if got != "second" {
+		t.Errorf("Expected new reply for different messages, got %q", got)
+	}
+
+	metrics := cache.Metrics()
+	if !metrics.Enabled || metrics.Hits != 1 || metrics.Misses != 2 || metrics.HitRate < 0.33 || metrics.HitRate > 0.34 {
+		t.Errorf("Unexpected metrics: %+v", metrics)
+	}
+
+	var disabled *Cache
+	if metrics := disabled.Metrics(); metrics != (CacheMetrics{}) {
+		t.Errorf("Expected zero metrics for disabled cache, got %+v", metrics)
+	}
+}
+
+func TestCachedModelBypass(t *testing.T) {
+	cache := NewCache(NewMemoryCacheStore(10), CacheOptions{})
+	ctx := context.Background()
+
+	// 调用方显式跳过
+	scripted := NewScriptedModel(Reply("a"), Reply("b"))
+	model := cache.Wrap(scripted)
+	_, _ = model.PostChatCompletionsNonStreamContent(ctx, userMessage("hi"))
+	got, _ := model.PostChatCompletionsNonStreamContent(WithoutCache(ctx), userMessage("hi"))
+	if got != "b" {
+		t.Errorf("Expected WithoutCache to call the model, got %q", got)
+	}
+
+	// temperature > 0 的调用不确定，不缓存
+	sampled := cache.Wrap(temperatureModel{ScriptedModel: NewScriptedModel(Reply("x"), Reply("y")), temperature: 0.7})
+	_, _ = sampled.PostChatCompletionsNonStreamContent(ctx, userMessage("hi"))
+	got, _ = sampled.PostChatCompletionsNonStreamContent(ctx, userMessage("hi"))
+	if got != "y" {
+		t.Errorf("Expected sampled model not to be cached, got %q", got)
+	}
+
+	if metrics := cache.Metrics(); metrics.Bypasses != 3 {
+		t.Errorf("Expected 3 bypasses, got %+v", metrics)
+	}
+}
+
+func TestCachedModelSkipsErrors(t *testing.T) {
+	scripted := NewScriptedModel(Fail(errors.New("rate limited")), Reply("ok"))
+	model := NewCache(NewMemoryCacheStore(10), CacheOptions{}).Wrap(scripted)
+
+	if _, err := model.PostChatCompletionsNonStreamContent(context.Background(), userMessage("hi")); err == nil {
+		t.Fatal("Expected error from model")
+	}
+	got, err := model.PostChatCompletionsNonStreamContent(context.Background(), userMessage("hi"))
+	if err != nil || got != "ok" {
+		t.Errorf("Expected error not to be cached, got %q, err %v", got, err)
+	}
+}
+
+func TestMemoryCacheStoreTTLAndCapacity(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryCacheStore(2)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_ = store.Set(ctx, "a", []byte("1"), time.Minute)
+	now = now.Add(2 * time.Minute)
+	if _, ok, _ := store.Get(ctx, "a"); ok {
+		t.Error("Expected expired entry to miss")
+	}
+
+	_ = store.Set(ctx, "a", []byte("1"), 0)
+	_ = store.Set(ctx, "b", []byte("2"), 0)
+	_, _, _ = store.Get(ctx, "a")
+	_ = store.Set(ctx, "c", []byte("3"), 0)
+	if _, ok, _ := store.Get(ctx, "b"); ok {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+	if _, ok, _ := store.Get(ctx, "a"); !ok || store.Len() != 2 {
+		t.Errorf("Expected a to stay and len 2, got ok %v len %d", ok, store.Len())
+	}
+}
+
+func TestFallbackCacheStore(t *testing.T) {
+	scripted := NewScriptedModel(Reply("first"), Reply("second"))
+	cache := NewCache(NewFallbackCacheStore(brokenStore{}, NewMemoryCacheStore(10)), CacheOptions{})
+	model := cache.Wrap(scripted)
+
+	for i := 0; i < 2; i++ {
+		if got, _ := model.PostChatCompletionsNonStreamContent(context.Background(), userMessage("hi")); got != "first" {
+			t.Fatalf("Expected memory fallback to serve cached reply, got %q", got)
+		}
+	}
+	if metrics := cache.Metrics(); metrics.Hits != 1 || metrics.Errors != 0 {
+		t.Errorf("Unexpected metrics: %+v", metrics)
+	}
+}
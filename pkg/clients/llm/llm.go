@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"ai_task/pkg/clients/base_llm_model"
+	"ai_task/pkg/clients/llm_model"
+	"context"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ChatModel 对话补全模型
+// 规划、执行、摘要等只依赖非流式补全，通过该接口注入，便于替换模型或在测试中使用 ScriptedModel
+type ChatModel interface {
+	// PostChatCompletionsNonStream 非流式补全，返回完整响应
+	PostChatCompletionsNonStream(ctx context.Context, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error)
+	// PostChatCompletionsNonStreamContent 非流式补全，只返回首个候选的内容
+	PostChatCompletionsNonStreamContent(ctx context.Context, messages []openai.ChatCompletionMessage) (string, error)
+}
+
+// JSONChatModel 支持 response_format 的模型，结构化输出时优先使用
+type JSONChatModel interface {
+	// PostChatCompletionsJSON 非流式补全并要求输出 JSON，format 可能按上游能力降级
+	PostChatCompletionsJSON(ctx context.Context, messages []openai.ChatCompletionMessage, format *openai.ChatCompletionResponseFormat) (*openai.ChatCompletionResponse, error)
+}
+
+// StreamingModel 不依赖 gin 的流式补全，后台任务与测试通过它读取增量分片
+type StreamingModel interface {
+	// StreamChatCompletions 建立连接失败时返回错误，之后的错误以带 Err 的分片推送
+	StreamChatCompletions(ctx context.Context, messages []openai.ChatCompletionMessage) (<-chan llm_model.StreamDelta, error)
+}
+
+var (
+	_ StreamingModel = (*llm_model.ClientChatModel)(nil)
+	_ StreamingModel = (*base_llm_model.Client)(nil)
+	_ StreamingModel = (*FallbackModel)(nil)
+	_ StreamingModel = (*CachedModel)(nil)
+
+	_ JSONChatModel = (*llm_model.ClientChatModel)(nil)
+	_ JSONChatModel = (*base_llm_model.Client)(nil)
+	_ JSONChatModel = (*FallbackModel)(nil)
+
+	_ ChatModel = (*llm_model.ClientChatModel)(nil)
+	_ ChatModel = (*base_llm_model.Client)(nil)
+	_ ChatModel = (*ScriptedModel)(nil)
+	_ ChatModel = (*FallbackModel)(nil)
+)
+
+// OrDefault model 为 nil 时返回 llm_model 的全局单例
+func OrDefault(model ChatModel) ChatModel {
+	if model == nil {
+		return llm_model.GetInstance()
+	}
+	return model
+}
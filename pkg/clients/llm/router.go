@@ -0,0 +1,286 @@
+package llm
+
+import (
+	"ai_task/config"
+	"ai_task/pkg/clients/llm_model"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+	log "github.com/sirupsen/logrus"
+)
+
+// Role 模型用途，按用途路由到不同的模型配置
+type Role string
+
+const (
+	RoleChat          Role = "chat"          // 对话回复
+	RolePlanning      Role = "planning"      // 任务规划、阶段细化
+	RoleExecution     Role = "execution"     // 步骤执行决策、子代理
+	RoleSummarization Role = "summarization" // 对话摘要、任务上下文摘要
+	RoleExtraction    Role = "extraction"    // 关键事实提取
+)
+
+// Models 按用途选择模型
+type Models interface {
+	Model(role Role) ChatModel
+}
+
+// single 所有用途使用同一个模型
+type single struct {
+	model ChatModel
+}
+
+func (s single) Model(Role) ChatModel {
+	return s.model
+}
+
+// Single 所有用途使用同一个模型，model 为 nil 时返回 nil
+func Single(model ChatModel) Models {
+	if model == nil {
+		return nil
+	}
+	return single{model: model}
+}
+
+// Router 用途到模型的映射，未配置的用途使用默认模型
+type Router struct {
+	fallback ChatModel
+	roles    map[Role]ChatModel
+}
+
+// NewRouter 创建路由，fallback 为 nil 时使用全局模型
+func NewRouter(fallback ChatModel, roles map[Role]ChatModel) *Router {
+	return &Router{fallback: OrDefault(fallback), roles: roles}
+}
+
+// Model 获取用途对应的模型
+func (r *Router) Model(role Role) ChatModel {
+	if model, ok := r.roles[role]; ok && model != nil {
+		return model
+	}
+	return r.fallback
+}
+
+var (
+	router     *Router
+	routerOnce sync.Once
+)
+
+// GetRouter 按 clients.llmRoles 与 clients.llmProfiles 创建的全局路由
+func GetRouter() *Router {
+	routerOnce.Do(func() {
+		router = loadRouter()
+	})
+	return router
+}
+
+// OrDefaultModels models 为 nil 时返回全局路由
+func OrDefaultModels(models Models) Models {
+	if models == nil {
+		return GetRouter()
+	}
+	return models
+}
+
+// loadRouter 读取用途到模型配置的映射，每个用途对应按顺序回退的配置名列表
+// 未知或创建失败的配置名会被跳过，列表为空时该用途使用默认模型
+func loadRouter() *Router {
+	conf := config.GetInstance()
+	clients := map[string]ChatModel{llm_model.DefaultProfile: llm_model.GetInstance()}
+	resolve := func(name string) ChatModel {
+		if client, ok := clients[name]; ok {
+			return client
+		}
+		client, err := llm_model.NewProfileClient(name)
+		if err != nil {
+			log.Warnf("llm profile %s unavailable: %v", name, err)
+			clients[name] = nil
+			return nil
+		}
+		clients[name] = client
+		return client
+	}
+
+	roles := make(map[Role]ChatModel)
+	for role := range conf.GetStringMap(config.ClientLLMRoles) {
+		var candidates []NamedModel
+		for _, name := range conf.GetStringSlice(config.ClientLLMRoles + "." + role) {
+			if client := resolve(name); client != nil {
+				candidates = append(candidates, NamedModel{Name: name, Model: client})
+			}
+		}
+		switch len(candidates) {
+		case 0:
+			log.Warnf("llm role %s has no available profile, use default", role)
+		case 1:
+			roles[Role(role)] = candidates[0].Model
+		default:
+			roles[Role(role)] = NewFallbackModel(candidates...)
+		}
+	}
+
+	r := NewRouter(clients[llm_model.DefaultProfile], roles)
+	if cache := GetCache(); cache != nil {
+		for _, role := range conf.GetStringSlice(config.ClientLLMCacheRoles) {
+			if _, ok := r.roles[Role(role)].(*CachedModel); !ok {
+				r.roles[Role(role)] = cache.Wrap(r.Model(Role(role)))
+			}
+		}
+	}
+	return r
+}
+
+// NamedModel 带名称的模型，名称用于日志
+type NamedModel struct {
+	Name  string
+	Model ChatModel
+}
+
+// FallbackModel 按顺序尝试多个模型，前一个出错时使用下一个
+type FallbackModel struct {
+	candidates []NamedModel
+}
+
+// NewFallbackModel 创建回退链
+func NewFallbackModel(candidates ...NamedModel) *FallbackModel {
+	return &FallbackModel{candidates: candidates}
+}
+
+// PostChatCompletionsNonStream 依次尝试，返回第一个成功的响应
+func (f *FallbackModel) PostChatCompletionsNonStream(ctx context.Context, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	var response *openai.ChatCompletionResponse
+	err := f.try(ctx, func(model ChatModel) error {
+		var err error
+		response, err = model.PostChatCompletionsNonStream(ctx, messages)
+		return err
+	})
+	return response, err
+}
+
+// PostChatCompletionsNonStreamContent 依次尝试，返回第一个成功的内容
+func (f *FallbackModel) PostChatCompletionsNonStreamContent(ctx context.Context, messages []openai.ChatCompletionMessage) (string, error) {
+	var content string
+	err := f.try(ctx, func(model ChatModel) error {
+		var err error
+		content, err = model.PostChatCompletionsNonStreamContent(ctx, messages)
+		return err
+	})
+	return content, err
+}
+
+// PostChatCompletionsJSON 依次尝试，支持 response_format 的模型以 JSON 模式调用
+func (f *FallbackModel) PostChatCompletionsJSON(ctx context.Context, messages []openai.ChatCompletionMessage, format *openai.ChatCompletionResponseFormat) (*openai.ChatCompletionResponse, error) {
+	var response *openai.ChatCompletionResponse
+	err := f.try(ctx, func(model ChatModel) error {
+		var err error
+		if jsonModel, ok := model.(JSONChatModel); ok {
+			response, err = jsonModel.PostChatCompletionsJSON(ctx, messages, format)
+		} else {
+			response, err = model.PostChatCompletionsNonStream(ctx, messages)
+		}
+		return err
+	})
+	return response, err
+}
+
+// PostChatCompletions 流式调用，依次尝试支持流式的模型；已开始推送后不再回退
+func (f *FallbackModel) PostChatCompletions(c *context.Context, messages []openai.ChatCompletionMessage) (*llm_model.StreamResult, error) {
+	type streamer interface {
+		PostChatCompletions(c *context.Context, messages []openai.ChatCompletionMessage) (*llm_model.StreamResult, error)
+	}
+
+	var errs []error
+	for _, candidate := range f.candidates {
+		s, ok := candidate.Model.(streamer)
+		if !ok {
+			continue
+		}
+		// result 为 nil 说明连接未建立，尚未向客户端写出任何内容
+		result, err := s.PostChatCompletions(c, messages)
+		if result != nil || (*c).Err() != nil {
+			return result, err
+		}
+		log.Warnf("llm profile %s stream failed, try next: %v", candidate.Name, err)
+		errs = append(errs, fmt.Errorf("%s: %w", candidate.Name, err))
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no streaming model in fallback chain %s", f.names())
+	}
+	return nil, errors.Join(errs...)
+}
+
+// ModelParams 回退链中任一模型都可能应答，模型名取各候选的组合，temperature 取最大值
+func (f *FallbackModel) ModelParams() (model string, temperature float32, maxTokens int) {
+	models := make([]string, 0, len(f.candidates))
+	for _, candidate := range f.candidates {
+		name := fmt.Sprintf("%T", candidate.Model)
+		if params, ok := candidate.Model.(ParamsModel); ok {
+			var t float32
+			var m int
+			name, t, m = params.ModelParams()
+			if t > temperature {
+				temperature = t
+			}
+			if m > maxTokens {
+				maxTokens = m
+			}
+		}
+		models = append(models, name)
+	}
+	return strings.Join(models, "->"), temperature, maxTokens
+}
+
+// StreamChatCompletions 依次尝试支持流式的模型，只在建立连接失败时回退
+func (f *FallbackModel) StreamChatCompletions(ctx context.Context, messages []openai.ChatCompletionMessage) (<-chan llm_model.StreamDelta, error) {
+	var errs []error
+	for _, candidate := range f.candidates {
+		s, ok := candidate.Model.(StreamingModel)
+		if !ok {
+			continue
+		}
+		deltas, err := s.StreamChatCompletions(ctx, messages)
+		if err == nil || ctx.Err() != nil {
+			return deltas, err
+		}
+		log.Warnf("llm profile %s stream failed, try next: %v", candidate.Name, err)
+		errs = append(errs, fmt.Errorf("%s: %w", candidate.Name, err))
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no streaming model in fallback chain %s", f.names())
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (f *FallbackModel) try(ctx context.Context, call func(model ChatModel) error) error {
+	var errs []error
+	for i, candidate := range f.candidates {
+		err := call(candidate.Model)
+		if err == nil {
+			if i > 0 {
+				log.Infof("llm fallback chain %s served by %s", f.names(), candidate.Name)
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		log.Warnf("llm profile %s failed, try next: %v", candidate.Name, err)
+		errs = append(errs, fmt.Errorf("%s: %w", candidate.Name, err))
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("empty fallback chain")
+	}
+	return errors.Join(errs...)
+}
+
+func (f *FallbackModel) names() string {
+	names := make([]string, 0, len(f.candidates))
+	for _, candidate := range f.candidates {
+		names = append(names, candidate.Name)
+	}
+	return strings.Join(names, "->")
+}
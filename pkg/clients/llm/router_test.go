@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestRouterModel(t *testing.T) {
+	fallback := NewScriptedModel()
+	planning := NewScriptedModel()
+	router := NewRouter(fallback, map[Role]ChatModel{RolePlanning: planning})
+
+	if router.Model(RolePlanning) != ChatModel(planning) {
+		t.Error("Expected planning role to use its own model")
+	}
+	if router.Model(RoleSummarization) != ChatModel(fallback) {
+		t.Error("Expected unconfigured role to use fallback model")
+	}
+	if Single(nil) != nil {
+		t.Error("Expected Single(nil) to be nil so callers fall back to the router")
+	}
+}
+
+func TestFallbackModel(t *testing.T) {
+	primary := NewScriptedModel(Fail(errors.New("rate limited")))
+	secondary := NewScriptedModel(Reply("from secondary"))
+	chain := NewFallbackModel(NamedModel{Name: "strong", Model: primary}, NamedModel{Name: "cheap", Model: secondary})
+
+	got, err := chain.PostChatCompletionsNonStreamContent(context.Background(), userMessage("hi"))
+	if err != nil || got != "from secondary" {
+		t.Fatalf("Expected secondary reply, got %q, err %v", got, err)
+	}
+	if len(primary.Calls()) != 1 || len(secondary.Calls()) != 1 {
+		t.Errorf("Expected one call per model, got %d and %d", len(primary.Calls()), len(secondary.Calls()))
+	}
+
+	// 全部失败时返回每个模型的错误
+	_, err = chain.PostChatCompletionsNonStream(context.Background(), userMessage("hi"))
+	if !errors.Is(err, ErrScriptExhausted) {
+		t.Errorf("Expected joined errors, got %v", err)
+	}
+}
+
+func TestFallbackModelStopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	primary := NewScriptedModel(ScriptStep{Match: func(_ []openai.ChatCompletionMessage) bool { cancel(); return true }, Err: context.Canceled})
+	secondary := NewScriptedModel(Reply("unused"))
+	chain := NewFallbackModel(NamedModel{Name: "strong", Model: primary}, NamedModel{Name: "cheap", Model: secondary})
+
+	if _, err := chain.PostChatCompletionsNonStreamContent(ctx, userMessage("hi")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if secondary.Remaining() != 1 {
+		t.Error("Expected secondary not to be called after cancellation")
+	}
+}
@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const scriptedModelName = "scripted"
+
+// ErrScriptExhausted 没有可匹配的脚本步骤
+var ErrScriptExhausted = errors.New("scripted model: no matching step")
+
+// ScriptStep 脚本中的一步应答
+type ScriptStep struct {
+	Match   func(messages []openai.ChatCompletionMessage) bool // 为 nil 时匹配任意请求
+	Content string                                             // 返回的内容
+	Err     error                                              // 不为 nil 时返回该错误
+	Repeat  bool                                               // 为 true 时匹配后不消耗，可重复应答
+}
+
+// Reply 无条件返回 content 的步骤
+func Reply(content string) ScriptStep {
+	return ScriptStep{Content: content}
+}
+
+// ReplyWhen 请求中任一消息包含 substr 时返回 content 的步骤
+func ReplyWhen(substr, content string) ScriptStep {
+	return ScriptStep{Match: MatchContains(substr), Content: content}
+}
+
+// Fail 无条件返回 err 的步骤
+func Fail(err error) ScriptStep {
+	return ScriptStep{Err: err}
+}
+
+// MatchContains 请求中任一消息包含 substr
+func MatchContains(substr string) func([]openai.ChatCompletionMessage) bool {
+	return func(messages []openai.ChatCompletionMessage) bool {
+		for _, msg := range messages {
+			if strings.Contains(msg.Content, substr) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ScriptedModel 按脚本应答的内存模型，用于离线测试
+// 每次请求按顺序取第一个匹配的步骤，非 Repeat 的步骤应答后即被消耗；没有匹配的步骤时返回 ErrScriptExhausted
+type ScriptedModel struct {
+	mu    sync.Mutex
+	steps []ScriptStep
+	calls [][]openai.ChatCompletionMessage
+}
+
+// NewScriptedModel 创建脚本模型
+func NewScriptedModel(steps ...ScriptStep) *ScriptedModel {
+	return &ScriptedModel{steps: append([]ScriptStep(nil), steps...)}
+}
+
+// Add 追加脚本步骤
+func (m *ScriptedModel) Add(steps ...ScriptStep) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.steps = append(m.steps, steps...)
+}
+
+// Calls 已收到的请求，按调用顺序排列
+func (m *ScriptedModel) Calls() [][]openai.ChatCompletionMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	calls := make([][]openai.ChatCompletionMessage, len(m.calls))
+	copy(calls, m.calls)
+	return calls
+}
+
+// Remaining 尚未消耗的步骤数，包括 Repeat 步骤
+func (m *ScriptedModel) Remaining() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.steps)
+}
+
+// PostChatCompletionsNonStream 按脚本返回完整响应
+func (m *ScriptedModel) PostChatCompletionsNonStream(ctx context.Context, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	content, err := m.next(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return &openai.ChatCompletionResponse{
+		Object: "chat.completion",
+		Model:  scriptedModelName,
+		Choices: []openai.ChatCompletionChoice{
+			{
+				Message: openai.ChatCompletionMessage{
+					Role:    openai.ChatMessageRoleAssistant,
+					Content: content,
+				},
+				FinishReason: openai.FinishReasonStop,
+			},
+		},
+	}, nil
+}
+
+// PostChatCompletionsNonStreamContent 按脚本返回内容
+func (m *ScriptedModel) PostChatCompletionsNonStreamContent(ctx context.Context, messages []openai.ChatCompletionMessage) (string, error) {
+	return m.next(ctx, messages)
+}
+
+// next 记录请求并取出匹配的步骤
+func (m *ScriptedModel) next(ctx context.Context, messages []openai.ChatCompletionMessage) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	recorded := make([]openai.ChatCompletionMessage, len(messages))
+	copy(recorded, messages)
+	m.calls = append(m.calls, recorded)
+
+	for i, step := range m.steps {
+		if step.Match != nil && !step.Match(messages) {
+			continue
+		}
+		if !step.Repeat {
+			m.steps = append(m.steps[:i], m.steps[i+1:]...)
+		}
+		if step.Err != nil {
+			return "", step.Err
+		}
+		return step.Content, nil
+	}
+
+	return "", fmt.Errorf("%w, call %d", ErrScriptExhausted, len(m.calls))
+}
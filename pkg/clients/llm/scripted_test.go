@@ -0,0 +1,74 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func userMessage(content string) []openai.ChatCompletionMessage {
+	return []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: content}}
+}
+
+func TestScriptedModelInOrder(t *testing.T) {
+	m := NewScriptedModel(Reply("first"), Reply("second"))
+	ctx := context.Background()
+
+	for _, want := range []string{"first", "second"} {
+		got, err := m.PostChatCompletionsNonStreamContent(ctx, userMessage("hi"))
+		if err != nil || got != want {
+			t.Fatalf("Expected %q, got %q, err %v", want, got, err)
+		}
+	}
+
+	if _, err := m.PostChatCompletionsNonStreamContent(ctx, userMessage("hi")); !errors.Is(err, ErrScriptExhausted) {
+		t.Errorf("Expected ErrScriptExhausted, got %v", err)
+	}
+	if len(m.Calls()) != 3 {
+		t.Errorf("Expected 3 recorded calls, got %d", len(m.Calls()))
+	}
+}
+
+func TestScriptedModelMatch(t *testing.T) {
+	failure := errors.New("boom")
+	m := NewScriptedModel(
+		ReplyWhen("计划", "plan"),
+		ScriptStep{Match: MatchContains("失败"), Err: failure, Repeat: true},
+		ScriptStep{Content: "default", Repeat: true},
+	)
+	ctx := context.Background()
+
+	resp, err := m.PostChatCompletionsNonStream(ctx, userMessage("请生成计划"))
+	if err != nil || resp.Choices[0].Message.Content != "plan" {
+		t.Fatalf("Expected plan response, got %+v, err %v", resp, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := m.PostChatCompletionsNonStreamContent(ctx, userMessage("会失败")); !errors.Is(err, failure) {
+			t.Errorf("Expected repeated failure, got %v", err)
+		}
+	}
+
+	// 计划步骤已消耗，落到默认步骤
+	if got, _ := m.PostChatCompletionsNonStreamContent(ctx, userMessage("再生成计划")); got != "default" {
+		t.Errorf("Expected default reply, got %q", got)
+	}
+	if m.Remaining() != 2 {
+		t.Errorf("Expected 2 repeat steps remaining, got %d", m.Remaining())
+	}
+}
+
+func TestScriptedModelCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	m := NewScriptedModel(Reply("unused"))
+	if _, err := m.PostChatCompletionsNonStreamContent(ctx, userMessage("hi")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if m.Remaining() != 1 {
+		t.Error("Expected step not consumed on canceled context")
+	}
+}
@@ -0,0 +1,396 @@
+package llm
+
+import (
+	"ai_task/pkg/prompt"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultMaxRepairs 结构化输出校验失败后默认的修复次数
+const DefaultMaxRepairs = 2
+
+// ErrInvalidStructuredOutput 修复次数用尽后模型输出仍不符合结构
+var ErrInvalidStructuredOutput = errors.New("invalid structured output")
+
+// StructuredOutputError 结构化输出失败，记录最后一次输出和校验问题
+type StructuredOutputError struct {
+	Name     string   // 结构名称
+	Attempts int      // 模型调用次数（含修复）
+	Problems []string // 最后一次输出的校验问题
+	Content  string   // 最后一次输出
+}
+
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("%s output still invalid after %d attempts: %s", e.Name, e.Attempts, strings.Join(e.Problems, "; "))
+}
+
+func (e *StructuredOutputError) Unwrap() error {
+	return ErrInvalidStructuredOutput
+}
+
+// Validator 结构自身的业务校验，在 Schema 校验通过并解析后调用
+type Validator interface {
+	Validate() error
+}
+
+// StructuredOptions 结构化输出选项
+type StructuredOptions struct {
+	Name       string // 结构名称，用于 response_format 和日志，为空时使用类型名
+	MaxRepairs int    // 校验失败后的修复次数，0 时使用 DefaultMaxRepairs，负数表示不修复
+}
+
+// GenerateJSON 调用模型生成符合 T 结构的 JSON 并解析
+// 模型实现 JSONChatModel 时携带 response_format；输出无法解析、不符合 T 的 Schema 或 Validate 失败时，
+// 把问题回传给模型修复，修复次数用尽后返回 *StructuredOutputError。模型调用本身出错时直接返回该错误
+func GenerateJSON[T any](ctx context.Context, model ChatModel, messages []openai.ChatCompletionMessage, opts *StructuredOptions) (*T, error) {
+	if opts == nil {
+		opts = &StructuredOptions{}
+	}
+	schema := SchemaFor(reflect.TypeOf((*T)(nil)).Elem())
+	name := opts.Name
+	if name == "" {
+		name = schemaName(reflect.TypeOf((*T)(nil)).Elem())
+	}
+	maxRepairs := opts.MaxRepairs
+	if maxRepairs == 0 {
+		maxRepairs = DefaultMaxRepairs
+	}
+	if maxRepairs < 0 {
+		maxRepairs = 0
+	}
+
+	format := &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:   name,
+			Schema: schema,
+		},
+	}
+
+	messages = append([]openai.ChatCompletionMessage(nil), messages...)
+	var content string
+	var problems []string
+	for attempt := 1; attempt <= maxRepairs+1; attempt++ {
+		var err error
+		content, err = completeJSON(ctx, model, messages, format)
+		if err != nil {
+			return nil, err
+		}
+
+		var result T
+		problems = DecodeJSON(content, schema, &result)
+		if len(problems) == 0 {
+			if attempt > 1 {
+				log.Infof("structured output %s repaired after %d attempts", name, attempt)
+			}
+			return &result, nil
+		}
+		log.Warnf("structured output %s attempt %d invalid: %s", name, attempt, strings.Join(problems, "; "))
+
+		repair, err := repairPrompt(ctx, problems, schema)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages,
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content},
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: repair},
+		)
+	}
+
+	return nil, &StructuredOutputError{
+		Name:     name,
+		Attempts: maxRepairs + 1,
+		Problems: problems,
+		Content:  content,
+	}
+}
+
+// completeJSON 支持 response_format 时以 JSON 模式调用，否则按普通对话调用
+func completeJSON(ctx context.Context, model ChatModel, messages []openai.ChatCompletionMessage, format *openai.ChatCompletionResponseFormat) (string, error) {
+	jsonModel, ok := model.(JSONChatModel)
+	if !ok {
+		return model.PostChatCompletionsNonStreamContent(ctx, messages)
+	}
+
+	response, err := jsonModel.PostChatCompletionsJSON(ctx, messages, format)
+	if err != nil {
+		return "", err
+	}
+	if response == nil || len(response.Choices) == 0 {
+		return "", fmt.Errorf("chat completion response has no choices")
+	}
+	return response.Choices[0].Message.Content, nil
+}
+
+// DecodeJSON 去除代码块标记后按 Schema 校验并解析到 v，返回发现的问题，为空表示成功
+// v 实现 Validator 时在解析后调用其 Validate
+func DecodeJSON(content string, schema *jsonschema.Definition, v any) []string {
+	content = CleanJSON(content)
+	if content == "" {
+		return []string{"输出为空"}
+	}
+
+	var data any
+	if err := json.Unmarshal([]byte(content), &data); err != nil {
+		return []string{fmt.Sprintf("不是合法的 JSON: %v", err)}
+	}
+
+	var problems []string
+	validateSchema(schema, data, "$", &problems)
+	if len(problems) > 0 {
+		return problems
+	}
+
+	if err := json.Unmarshal([]byte(content), v); err != nil {
+		return []string{fmt.Sprintf("无法解析为目标结构: %v", err)}
+	}
+	if validator, ok := v.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return []string{err.Error()}
+		}
+	}
+	return nil
+}
+
+// CleanJSON 去除 markdown 代码块标记和 JSON 前后的说明文字
+func CleanJSON(content string) string {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```json")
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(strings.TrimSpace(content), "```")
+		content = strings.TrimSpace(content)
+	}
+	if json.Valid([]byte(content)) {
+		return content
+	}
+
+	// 模型在 JSON 前后附带了说明文字时，截取最外层的对象或数组
+	start := strings.IndexAny(content, "{[")
+	if start < 0 {
+		return content
+	}
+	end := strings.LastIndexByte(content, closingBracket(content[start]))
+	if end <= start {
+		return content
+	}
+	return content[start : end+1]
+}
+
+func closingBracket(open byte) byte {
+	if open == '[' {
+		return ']'
+	}
+	return '}'
+}
+
+// repairPrompt 校验失败后追加给模型的修复提示，按 context 中的语言渲染
+func repairPrompt(ctx context.Context, problems []string, schema *jsonschema.Definition) (string, error) {
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		schemaJSON = []byte("{}")
+	}
+	rendered, err := prompt.Render(ctx, prompt.LLMStructuredRepair, map[string]any{
+		"Problems": problems,
+		"Schema":   string(schemaJSON),
+	})
+	if err != nil {
+		return "", err
+	}
+	return rendered.Text, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaFor 按 Go 结构生成 JSON Schema
+// 字段名取 json tag，没有 omitempty 的字段为必填，可用 required:"false" 覆盖；
+// 支持 description、enum tag；time.Time 视为字符串，interface 与 map 不约束内容
+func SchemaFor(t reflect.Type) *jsonschema.Definition {
+	return reflectSchema(t, make(map[reflect.Type]bool))
+}
+
+func reflectSchema(t reflect.Type, visiting map[reflect.Type]bool) *jsonschema.Definition {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &jsonschema.Definition{Type: jsonschema.String, Description: "RFC3339 时间"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &jsonschema.Definition{Type: jsonschema.String}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonschema.Definition{Type: jsonschema.Integer}
+	case reflect.Float32, reflect.Float64:
+		return &jsonschema.Definition{Type: jsonschema.Number}
+	case reflect.Bool:
+		return &jsonschema.Definition{Type: jsonschema.Boolean}
+	case reflect.Slice, reflect.Array:
+		return &jsonschema.Definition{Type: jsonschema.Array, Items: reflectSchema(t.Elem(), visiting)}
+	case reflect.Map:
+		return &jsonschema.Definition{Type: jsonschema.Object}
+	case reflect.Struct:
+		// 递归结构只展开一层，内层不再约束
+		if visiting[t] {
+			return &jsonschema.Definition{Type: jsonschema.Object}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		return reflectObject(t, visiting)
+	}
+	return &jsonschema.Definition{}
+}
+
+func reflectObject(t reflect.Type, visiting map[reflect.Type]bool) *jsonschema.Definition {
+	d := &jsonschema.Definition{
+		Type:       jsonschema.Object,
+		Properties: make(map[string]jsonschema.Definition),
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitempty := field.Name, false
+		if tag := field.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			parts := strings.Split(tag, ",")
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			for _, opt := range parts[1:] {
+				omitempty = omitempty || opt == "omitempty"
+			}
+		}
+
+		item := reflectSchema(field.Type, visiting)
+		if description := field.Tag.Get("description"); description != "" {
+			item.Description = description
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			item.Enum = strings.Split(enum, ",")
+		}
+		d.Properties[name] = *item
+
+		required := !omitempty
+		if s := field.Tag.Get("required"); s != "" {
+			required, _ = strconv.ParseBool(s)
+		}
+		if required {
+			d.Required = append(d.Required, name)
+		}
+	}
+	return d
+}
+
+// validateSchema 校验数据是否符合 Schema，问题按路径记录到 problems
+func validateSchema(schema *jsonschema.Definition, data any, path string, problems *[]string) {
+	if data == nil {
+		if schema.Type != "" && schema.Type != jsonschema.Null && !schema.Nullable {
+			*problems = append(*problems, fmt.Sprintf("%s: 不能为 null，应为 %s", path, schema.Type))
+		}
+		return
+	}
+
+	switch schema.Type {
+	case jsonschema.Object:
+		object, ok := data.(map[string]any)
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: 应为 object，实际为 %s", path, jsonTypeOf(data)))
+			return
+		}
+		for _, field := range schema.Required {
+			if _, exists := object[field]; !exists {
+				*problems = append(*problems, fmt.Sprintf("%s: 缺少必填字段 %s", path, field))
+			}
+		}
+		for key, value := range object {
+			property, ok := schema.Properties[key]
+			if !ok {
+				continue
+			}
+			validateSchema(&property, value, path+"."+key, problems)
+		}
+	case jsonschema.Array:
+		array, ok := data.([]any)
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: 应为 array，实际为 %s", path, jsonTypeOf(data)))
+			return
+		}
+		if schema.Items == nil {
+			return
+		}
+		for i, item := range array {
+			validateSchema(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case jsonschema.String:
+		value, ok := data.(string)
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: 应为 string，实际为 %s", path, jsonTypeOf(data)))
+			return
+		}
+		if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, value) {
+			*problems = append(*problems, fmt.Sprintf("%s: 取值必须是 %s 之一，实际为 %q", path, strings.Join(schema.Enum, "/"), value))
+		}
+	case jsonschema.Integer:
+		value, ok := data.(float64)
+		if !ok || value != float64(int64(value)) {
+			*problems = append(*problems, fmt.Sprintf("%s: 应为 integer，实际为 %s", path, jsonTypeOf(data)))
+		}
+	case jsonschema.Number:
+		if _, ok := data.(float64); !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: 应为 number，实际为 %s", path, jsonTypeOf(data)))
+		}
+	case jsonschema.Boolean:
+		if _, ok := data.(bool); !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: 应为 boolean，实际为 %s", path, jsonTypeOf(data)))
+		}
+	}
+}
+
+func jsonTypeOf(data any) string {
+	switch v := data.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", data)
+}
+
+func schemaName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Name() != "" {
+		return t.Name()
+	}
+	return "result"
+}
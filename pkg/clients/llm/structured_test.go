@@ -0,0 +1,156 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+type testItem struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type testResult struct {
+	Kind    string     `json:"kind" enum:"a,b"`
+	Items   []testItem `json:"items"`
+	Note    string     `json:"note,omitempty"`
+	Created time.Time  `json:"created" required:"false"`
+}
+
+func (r *testResult) Validate() error {
+	if len(r.Items) == 0 {
+		return fmt.Errorf("$.items: 至少需要一项")
+	}
+	return nil
+}
+
+func TestSchemaFor(t *testing.T) {
+	schema := SchemaFor(reflect.TypeOf(testResult{}))
+
+	if !reflect.DeepEqual(schema.Required, []string{"kind", "items"}) {
+		t.Errorf("Expected kind and items to be required, got %v", schema.Required)
+	}
+	if schema.Properties["created"].Type != jsonschema.String {
+		t.Errorf("Expected time.Time to be a string, got %q", schema.Properties["created"].Type)
+	}
+	items := schema.Properties["items"]
+	if items.Type != jsonschema.Array || items.Items == nil || items.Items.Properties["count"].Type != jsonschema.Integer {
+		t.Errorf("Unexpected items schema: %+v", items)
+	}
+}
+
+func TestDecodeJSONProblems(t *testing.T) {
+	schema := SchemaFor(reflect.TypeOf(testResult{}))
+
+	cases := []struct {
+		content string
+		problem string
+	}{
+		{`not json`, "不是合法的 JSON"},
+		{`{"kind": "a"}`, "缺少必填字段 items"},
+		{`{"kind": "c", "items": [{"name": "x", "count": 1}]}`, "取值必须是 a/b 之一"},
+		{`{"kind": "a", "items": [{"name": "x", "count": 1.5}]}`, "$.items[0].count: 应为 integer"},
+		{`{"kind": "a", "items": []}`, "至少需要一项"},
+	}
+	for _, c := range cases {
+		var result testResult
+		problems := DecodeJSON(c.content, schema, &result)
+		if len(problems) == 0 || !strings.Contains(strings.Join(problems, "\n"), c.problem) {
+			t.Errorf("Expected problem %q for %s, got %v", c.problem, c.content, problems)
+		}
+	}
+
+	var result testResult
+	content := "结果如下：\n```json\n{\"kind\": \"b\", \"items\": [{\"name\": \"x\", \"count\": 2}]}\n```"
+	if problems := DecodeJSON(content, schema, &result); len(problems) != 0 {
+		t.Fatalf("Expected fenced JSON with prose to decode, got %v", problems)
+	}
+	if result.Kind != "b" || result.Items[0].Count != 2 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestGenerateJSONRepairs(t *testing.T) {
+	m := NewScriptedModel(
+		Reply(`{"kind": "a"}`),
+		Reply(`{"kind": "a", "items": [{"name": "x", "count": 1}]}`),
+	)
+
+	result, err := GenerateJSON[testResult](context.Background(), m, userMessage("生成"), nil)
+	if err != nil {
+		t.Fatalf("Expected repaired result, got %v", err)
+	}
+	if len(result.Items) != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	calls := m.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("Expected 2 calls, got %d", len(calls))
+	}
+	repair := calls[1][len(calls[1])-1]
+	if repair.Role != openai.ChatMessageRoleUser || !strings.Contains(repair.Content, "缺少必填字段 items") {
+		t.Errorf("Expected repair prompt with validation errors, got %+v", repair)
+	}
+	if calls[1][len(calls[1])-2].Content != `{"kind": "a"}` {
+		t.Error("Expected invalid output to be replayed as assistant message")
+	}
+}
+
+func TestGenerateJSONFailsAfterRepairs(t *testing.T) {
+	m := NewScriptedModel(ScriptStep{Content: `{"kind": "a"}`, Repeat: true})
+
+	_, err := GenerateJSON[testResult](context.Background(), m, userMessage("生成"), &StructuredOptions{MaxRepairs: 1})
+	if !errors.Is(err, ErrInvalidStructuredOutput) {
+		t.Fatalf("Expected ErrInvalidStructuredOutput, got %v", err)
+	}
+	var structuredErr *StructuredOutputError
+	if !errors.As(err, &structuredErr) || structuredErr.Attempts != 2 || structuredErr.Name != "testResult" {
+		t.Errorf("Unexpected error: %+v", err)
+	}
+	if len(m.Calls()) != 2 {
+		t.Errorf("Expected 2 calls, got %d", len(m.Calls()))
+	}
+
+	// 模型调用失败不进入修复
+	failure := errors.New("rate limited")
+	_, err = GenerateJSON[testResult](context.Background(), NewScriptedModel(Fail(failure)), userMessage("生成"), nil)
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected model error, got %v", err)
+	}
+}
+
+// jsonModel 记录 response_format 的 JSON 模式模型
+type jsonModel struct {
+	*ScriptedModel
+	formats []*openai.ChatCompletionResponseFormat
+}
+
+func (m *jsonModel) PostChatCompletionsJSON(ctx context.Context, messages []openai.ChatCompletionMessage, format *openai.ChatCompletionResponseFormat) (*openai.ChatCompletionResponse, error) {
+	m.formats = append(m.formats, format)
+	return m.PostChatCompletionsNonStream(ctx, messages)
+}
+
+func TestGenerateJSONUsesResponseFormat(t *testing.T) {
+	m := &jsonModel{ScriptedModel: NewScriptedModel(Reply(`{"kind": "a", "items": [{"name": "x", "count": 1}]}`))}
+	chain := NewFallbackModel(NamedModel{Name: "default", Model: m})
+
+	if _, err := GenerateJSON[testResult](context.Background(), chain, userMessage("生成"), &StructuredOptions{Name: "test_result"}); err != nil {
+		t.Fatalf("Expected result, got %v", err)
+	}
+	if len(m.formats) != 1 {
+		t.Fatalf("Expected JSON mode call, got %d", len(m.formats))
+	}
+	format := m.formats[0]
+	if format.Type != openai.ChatCompletionResponseFormatTypeJSONSchema || format.JSONSchema.Name != "test_result" {
+		t.Errorf("Unexpected response format: %+v", format)
+	}
+}
@@ -4,14 +4,17 @@ import (
 	"ai_task/config"
 	"ai_task/model"
 	"ai_task/pkg/clients/httptool"
-	"ai_task/pkg/tools"
+	"ai_task/pkg/clients/resilience"
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/usage"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"net/http"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sashabaranov/go-openai"
@@ -22,21 +25,49 @@ import (
 
 const (
 	clientNameChatModel = "chat_model"
+
+	// 结构化输出模式
+	ResponseFormatJSONSchema = "json_schema" // 携带 JSON Schema，由上游按结构约束输出
+	ResponseFormatJSONObject = "json_object" // 只要求输出合法 JSON
+	ResponseFormatNone       = "none"        // 上游不支持 response_format
+
+	// DefaultProfile clients.llmModel 对应的模型配置名
+	DefaultProfile = "default"
 )
 
 var (
 	streamMessageStart = []byte("data: ")
 	streamMessageEnd   = []byte("\n\n")
+	streamMessageDone  = []byte("[DONE]")
+	streamEventUsage   = []byte("event: usage\n")
+	streamEventError   = []byte("event: error\n")
 )
 
 type ClientChatModel struct {
-	config *Config
+	config     *Config
+	caller     *resilience.Caller // 为 nil 时不重试、不熔断
+	httpClient *http.Client
 }
 
 type ResponseMsg struct {
 	Message string `json:"message"`
 }
 
+// StreamResult 流式调用结束后的汇总结果
+type StreamResult struct {
+	Content      string            // 已推送给客户端的完整（或部分）回复
+	ToolCalls    []openai.ToolCall // 由分片拼接的完整工具调用
+	FinishReason string            // 上游返回的结束原因
+	Usage        *openai.Usage     // token 用量，上游不支持 include_usage 时为 nil
+	Truncated    bool              // 客户端断开或上游中断，回复不完整
+}
+
+// streamUsageMsg 流式结束时推送的 usage 事件
+type streamUsageMsg struct {
+	FinishReason string        `json:"finish_reason"`
+	Usage        *openai.Usage `json:"usage"`
+}
+
 var (
 	instance *ClientChatModel
 	once     sync.Once
@@ -51,88 +82,193 @@ func GetInstance() *ClientChatModel {
 			Token:       env.GetModelApiKey(),
 			Temperature: cast.ToFloat32(config.GetInstance().GetFloat64(config.ClientChatModelTemperature)),
 			MaxTokens:   config.GetInstance().GetInt(config.ClientChatModelMaxTokens),
-		}
 
-		instance = &ClientChatModel{
-			config: conf,
+			ResponseFormat: config.GetInstance().GetString(config.ClientChatModelResponseFormat),
+			Resilience:     loadResiliencePolicy(),
 		}
+
+		instance = newClientChatModel(clientNameChatModel, conf)
 	})
 	return instance
 }
 
-// @Description 封装流式调用
+// NewProfileClient 按 clients.llmProfiles.<name> 创建客户端
+// 未配置的字段沿用 clients.llmModel；apiKeyEnv 为空时使用默认的 LLM_API_KEY
+func NewProfileClient(name string) (*ClientChatModel, error) {
+	if name == DefaultProfile {
+		return GetInstance(), nil
+	}
+
+	conf := config.GetInstance()
+	key := func(field string) string {
+		return fmt.Sprintf("%s.%s.%s", config.ClientLLMProfiles, name, field)
+	}
+	if !conf.IsSet(key(config.ClientLLMProfileModel)) {
+		return nil, fmt.Errorf("llm profile %s is not configured", name)
+	}
+
+	addr := conf.GetStringOrDefault(key(config.ClientLLMProfileAddr), conf.GetString(config.ClientChatModelAddr))
+	token := env.GetModelApiKey()
+	if apiKeyEnv := conf.GetString(key(config.ClientLLMProfileAPIKeyEnv)); apiKeyEnv != "" {
+		token = os.Getenv(apiKeyEnv)
+		if token == "" {
+			return nil, fmt.Errorf("llm profile %s api key env %s is empty", name, apiKeyEnv)
+		}
+	}
+
+	return newClientChatModel(fmt.Sprintf("%s:%s", clientNameChatModel, name), &Config{
+		Addr:        addr,
+		V1Addr:      addr,
+		Model:       conf.GetString(key(config.ClientLLMProfileModel)),
+		Token:       token,
+		Temperature: cast.ToFloat32(conf.GetFloat64OrDefault(key(config.ClientLLMProfileTemperature), conf.GetFloat64(config.ClientChatModelTemperature))),
+		MaxTokens:   conf.GetIntOrDefault(key(config.ClientLLMProfileMaxTokens), conf.GetInt(config.ClientChatModelMaxTokens)),
+
+		ResponseFormat: conf.GetStringOrDefault(key(config.ClientLLMProfileRespFormat), conf.GetString(config.ClientChatModelResponseFormat)),
+		Resilience:     loadResiliencePolicy(),
+	}), nil
+}
+
+// NewClient 使用完整配置创建客户端，不读取全局配置；conf.Resilience 为 nil 时不重试、不熔断
+func NewClient(conf *Config) *ClientChatModel {
+	return newClientChatModel(clientNameChatModel, conf)
+}
+
+// newClientChatModel 按 conf.Resilience 创建带重试与熔断的客户端，每个客户端独立熔断
+func newClientChatModel(name string, conf *Config) *ClientChatModel {
+	client := &ClientChatModel{
+		config:     conf,
+		httpClient: resilience.NewHTTPClientWithBase(conf.Transport),
+	}
+	if conf.Resilience != nil {
+		client.caller = resilience.New(name, *conf.Resilience)
+	}
+	return client
+}
+
+// loadResiliencePolicy 读取 clients.llmModel 下的超时、重试与熔断配置，缺失时使用默认值
+func loadResiliencePolicy() *resilience.Policy {
+	conf := config.GetInstance()
+	policy := resilience.DefaultPolicy()
+	policy.Timeout = time.Duration(conf.GetIntOrDefault(config.ClientChatModelTimeoutSeconds, int(policy.Timeout/time.Second))) * time.Second
+	policy.MaxAttempts = conf.GetIntOrDefault(config.ClientChatModelRetryMaxAttempts, policy.MaxAttempts)
+	policy.BaseDelay = time.Duration(conf.GetIntOrDefault(config.ClientChatModelRetryBaseDelayMs, int(policy.BaseDelay/time.Millisecond))) * time.Millisecond
+	policy.MaxDelay = time.Duration(conf.GetIntOrDefault(config.ClientChatModelRetryMaxDelayMs, int(policy.MaxDelay/time.Millisecond))) * time.Millisecond
+	policy.MaxRetryAfter = time.Duration(conf.GetIntOrDefault(config.ClientChatModelRetryMaxRetryAfterSecs, int(policy.MaxRetryAfter/time.Second))) * time.Second
+	policy.BreakerThreshold = conf.GetIntOrDefault(config.ClientChatModelBreakerFailureThreshold, policy.BreakerThreshold)
+	policy.BreakerCooldown = time.Duration(conf.GetIntOrDefault(config.ClientChatModelBreakerCooldownSeconds, int(policy.BreakerCooldown/time.Second))) * time.Second
+	return &policy
+}
+
+// ModelParams 影响输出的请求参数，作为响应缓存键的一部分
+func (zc *ClientChatModel) ModelParams() (model string, temperature float32, maxTokens int) {
+	return zc.config.Model, zc.config.Temperature, zc.config.MaxTokens
+}
+
+// newOpenAIClient 创建上游客户端，配置了 httpClient 时用于记录 Retry-After
+func (zc *ClientChatModel) newOpenAIClient() *openai.Client {
+	defaultReq := openai.DefaultConfig(zc.config.Token)
+	defaultReq.BaseURL = zc.config.V1Addr
+	if zc.httpClient != nil {
+		defaultReq.HTTPClient = zc.httpClient
+	}
+	return openai.NewClientWithConfig(defaultReq)
+}
+
+// @Description 封装流式调用，以 SSE 推送增量内容，并汇总完整回复
 // @Param c context.Context
 // @Param message interface{}
-// @Success string
+// @Success *StreamResult
 // @Success error
-func (zc *ClientChatModel) PostChatCompletions(c *context.Context, messages []openai.ChatCompletionMessage) error {
+func (zc *ClientChatModel) PostChatCompletions(c *context.Context, messages []openai.ChatCompletionMessage) (*StreamResult, error) {
 	ginCtx, ok := (*c).(*gin.Context)
 	if !ok {
-		return model.NewError(model.ErrorParams, nil)
+		return nil, model.NewError(model.ErrorParams, nil)
 	}
 
-	defaultReq := openai.DefaultConfig(zc.config.Token)
-	defaultReq.BaseURL = zc.config.V1Addr
-
-	client := openai.NewClientWithConfig(defaultReq)
+	// 使用请求自身的 context，客户端断开时上游请求同步取消
+	streamCtx, cancel := context.WithCancel(ginCtx.Request.Context())
+	defer cancel()
 
-	stream, err := client.CreateChatCompletionStream(ginCtx, openai.ChatCompletionRequest{
-		Model:       zc.config.Model,
-		Messages:    messages,
-		MaxTokens:   zc.config.MaxTokens,
-		Temperature: zc.config.Temperature,
-		Stream:      true,
-	})
+	deltas, err := zc.StreamChatCompletions(streamCtx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return ServeSSE(ginCtx, deltas)
+}
 
+// WriteStreamEvent 在增量内容之前推送一个具名 SSE 事件（如引用来源），必要时先写出流式响应头
+func WriteStreamEvent(ginCtx *gin.Context, event string, data interface{}) error {
+	temp, err := json.Marshal(data)
 	if err != nil {
-		log.Errorf("%s stream creation error: %v", clientNameChatModel, err)
 		return err
 	}
 
+	var respMsg bytes.Buffer
+	respMsg.WriteString("event: " + event + "\n")
+	respMsg.Write(streamMessageStart)
+	respMsg.Write(temp)
+	respMsg.Write(streamMessageEnd)
+
+	setStreamHeaders(ginCtx)
+	if _, err := ginCtx.Writer.Write(respMsg.Bytes()); err != nil {
+		return err
+	}
+	ginCtx.Writer.Flush()
+	return nil
+}
+
+// WriteStreamError 流式响应已开始后调用失败时，推送 error 事件和 [DONE] 结束流
+func WriteStreamError(ginCtx *gin.Context, streamErr error) error {
+	return writeStreamEnd(ginCtx, nil, streamErr)
+}
+
+// setStreamHeaders 设置 SSE 响应头，响应已写出时不再修改
+func setStreamHeaders(ginCtx *gin.Context) {
+	if ginCtx.Writer.Written() {
+		return
+	}
 	ginCtx.Writer.Header().Set(httptool.HeaderContentType, httptool.HeaderContentTypeStream)
 	ginCtx.Writer.Header().Set(httptool.HeaderContentCache, httptool.HeaderContentCacheNo)
 	ginCtx.Writer.Header().Set(httptool.HeaderContentConnection, httptool.HeaderContentKeepAlive)
 	ginCtx.Writer.Header().Set(httptool.HeaderContentTransfer, httptool.HeaderContentChunked)
+}
 
-	ginCtx.Writer.Flush()
-
-	defer tools.ErrorWithPrintContext(stream.Close, "close stream")
-
-	ginCtx.Stream(func(w io.Writer) bool {
-		var respMsg bytes.Buffer
+// writeStreamEnd 写入流式响应的结束事件
+func writeStreamEnd(ginCtx *gin.Context, result *StreamResult, streamErr error) error {
+	var respMsg bytes.Buffer
 
-		response, err := stream.Recv()
-		if err == io.EOF {
-			return false
+	if streamErr != nil {
+		temp, err := json.Marshal(gin.H{"error": streamErr.Error()})
+		if err != nil {
+			return err
 		}
+		respMsg.Write(streamEventError)
+		respMsg.Write(streamMessageStart)
+		respMsg.Write(temp)
+		respMsg.Write(streamMessageEnd)
+	} else {
+		temp, err := json.Marshal(streamUsageMsg{
+			FinishReason: result.FinishReason,
+			Usage:        result.Usage,
+		})
 		if err != nil {
-			log.Errorf("%s stream.Recv error: %v", clientNameChatModel, err)
-			return false
+			return err
 		}
+		respMsg.Write(streamEventUsage)
+		respMsg.Write(streamMessageStart)
+		respMsg.Write(temp)
+		respMsg.Write(streamMessageEnd)
+	}
 
-		if len(response.Choices) > 0 {
-			respMsg.Write(streamMessageStart)
-			temp, err := json.Marshal(response.Choices)
-			if err != nil {
-				//c.Writer.WriteHeader(http.StatusInternalServerError)
-				//_, _ = c.Writer.Write([]byte("Internal Server Error"))
-				log.Errorf("%s: %+v json.Marshal error: %v", clientNameChatModel, response.Choices, err)
-				return false
-			}
-
-			respMsg.Write(temp)
-			respMsg.Write(streamMessageEnd)
-
-			_, err = w.Write(respMsg.Bytes())
-			if err != nil {
-				log.Errorf("%s: %+v w.Write error: %v", clientNameChatModel, respMsg.String(), err)
-				return false
-			}
-			ginCtx.Writer.Flush()
-		}
-		return true
-	})
+	respMsg.Write(streamMessageStart)
+	respMsg.Write(streamMessageDone)
+	respMsg.Write(streamMessageEnd)
 
+	if _, err := ginCtx.Writer.Write(respMsg.Bytes()); err != nil {
+		return err
+	}
+	ginCtx.Writer.Flush()
 	return nil
 }
 
@@ -142,11 +278,6 @@ func (zc *ClientChatModel) PostChatCompletions(c *context.Context, messages []op
 // @Success *openai.ChatCompletionResponse
 // @Success error
 func (zc *ClientChatModel) PostChatCompletionsNonStream(c context.Context, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
-	defaultReq := openai.DefaultConfig(zc.config.Token)
-	defaultReq.BaseURL = zc.config.V1Addr
-
-	client := openai.NewClientWithConfig(defaultReq)
-
 	// 创建请求结构
 	request := openai.ChatCompletionRequest{
 		Model:       zc.config.Model,
@@ -156,6 +287,40 @@ func (zc *ClientChatModel) PostChatCompletionsNonStream(c context.Context, messa
 		Stream:      false,
 	}
 
+	return zc.createChatCompletion(c, request)
+}
+
+// PostChatCompletionsJSON 非流式调用并要求模型输出 JSON
+// 按配置的 responseFormat 降级：json_object 时只保留类型，none 时不携带 response_format
+func (zc *ClientChatModel) PostChatCompletionsJSON(c context.Context, messages []openai.ChatCompletionMessage, format *openai.ChatCompletionResponseFormat) (*openai.ChatCompletionResponse, error) {
+	request := openai.ChatCompletionRequest{
+		Model:          zc.config.Model,
+		Messages:       messages,
+		MaxTokens:      zc.config.MaxTokens,
+		Temperature:    zc.config.Temperature,
+		ResponseFormat: zc.responseFormat(format),
+	}
+
+	return zc.createChatCompletion(c, request)
+}
+
+// responseFormat 按客户端支持的模式调整 response_format
+func (zc *ClientChatModel) responseFormat(format *openai.ChatCompletionResponseFormat) *openai.ChatCompletionResponseFormat {
+	switch zc.config.ResponseFormat {
+	case ResponseFormatNone:
+		return nil
+	case ResponseFormatJSONSchema:
+		if format != nil {
+			return format
+		}
+	}
+	return &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+}
+
+// createChatCompletion 发起非流式请求并记录用量
+func (zc *ClientChatModel) createChatCompletion(c context.Context, request openai.ChatCompletionRequest) (*openai.ChatCompletionResponse, error) {
+	client := zc.newOpenAIClient()
+
 	// debug 出完整的请求参数，json格式（仅在 debug 级别时序列化）
 	if log.GetLevel() == log.DebugLevel {
 		requestJson, err := json.MarshalIndent(request, "", "  ")
@@ -169,12 +334,19 @@ func (zc *ClientChatModel) PostChatCompletionsNonStream(c context.Context, messa
 		}
 	}
 
-	response, err := client.CreateChatCompletion(c, request)
+	prompt.LogCall(c, clientNameChatModel, zc.config.Model)
+	var response openai.ChatCompletionResponse
+	err := zc.caller.Do(c, func(ctx context.Context) error {
+		var err error
+		response, err = client.CreateChatCompletion(ctx, request)
+		return err
+	})
 
 	if err != nil {
 		log.Errorf("%s chat completion error: %v", clientNameChatModel, err)
 		return nil, err
 	}
+	usage.ReportLLM(c, zc.config.Model, response.Usage)
 
 	// debug 出完整的响应内容，json格式（仅在 debug 级别时序列化）
 	if log.GetLevel() == log.DebugLevel {
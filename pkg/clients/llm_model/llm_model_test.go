@@ -2,11 +2,19 @@ package llm_model
 
 import (
 	"ai_task/config"
+	"ai_task/pkg/clients/httptool"
+	"ai_task/pkg/clients/replay"
+	"ai_task/pkg/clients/resilience"
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sashabaranov/go-openai"
@@ -34,21 +42,7 @@ func createTestContext() *gin.Context {
 }
 
 func (c *ClientChatModelTest) TestPostChatCompletionsNonStream_Success() {
-	// 确保配置存在
-	cfg := config.GetInstance()
-	addr := cfg.GetString(config.ClientChatModelAddr)
-	model := cfg.GetString(config.ClientChatModelModel)
-	token := env.GetModelApiKey()
-
-	// 如果配置不存在，跳过测试
-	if addr == "" || model == "" || token == "" {
-		c.T().Skip("Skipping test: chat model config not set")
-		return
-	}
-
-	// 获取客户端实例
-	client := GetInstance()
-	c.NotNil(client)
+	client := c.newFixtureClient("chat_non_stream_intro")
 
 	// 创建测试上下文
 	ginCtx := createTestContext()
@@ -66,70 +60,26 @@ func (c *ClientChatModelTest) TestPostChatCompletionsNonStream_Success() {
 	response, err := client.PostChatCompletionsNonStream(ctx, messages)
 
 	// 验证结果
-	c.Nil(err, "should not return error")
-	c.NotNil(response, "response should not be nil")
-	if response != nil {
-		c.Greater(len(response.Choices), 0, "should have at least one choice")
-		if len(response.Choices) > 0 {
-			c.NotEmpty(response.Choices[0].Message.Content, "message content should not be empty")
-		}
-	}
+	c.Require().NoError(err)
+	c.Require().NotEmpty(response.Choices, "should have at least one choice")
+	c.NotEmpty(response.Choices[0].Message.Content, "message content should not be empty")
 }
 
 func (c *ClientChatModelTest) TestPostChatCompletionsNonStream_EmptyMessages() {
-	// 确保配置存在
-	cfg := config.GetInstance()
-	addr := cfg.GetString(config.ClientChatModelAddr)
-	model := cfg.GetString(config.ClientChatModelModel)
-	token := env.GetModelApiKey()
-
-	// 如果配置不存在，跳过测试
-	if addr == "" || model == "" || token == "" {
-		c.T().Skip("Skipping test: chat model config not set")
-		return
-	}
-
-	// 获取客户端实例
-	client := GetInstance()
-	c.NotNil(client)
+	client := c.newFixtureClient("chat_non_stream_empty_messages")
 
 	// 创建测试上下文
 	ginCtx := createTestContext()
 	var ctx context.Context = ginCtx
 
-	// 准备空消息列表
-	messages := []openai.ChatCompletionMessage{}
-
-	// 调用非流式方法
-	response, err := client.PostChatCompletionsNonStream(ctx, messages)
-
-	// 空消息列表可能会返回错误或空响应，取决于 API 的行为
-	// 这里主要验证方法不会 panic
-	if err != nil {
-		c.NotNil(err, "should return error for empty messages")
-		c.Nil(response, "response should be nil when error occurs")
-	} else {
-		// 如果 API 允许空消息，验证响应不为 nil
-		c.NotNil(response, "response should not be nil")
-	}
+	// 空消息列表会被上游拒绝
+	response, err := client.PostChatCompletionsNonStream(ctx, []openai.ChatCompletionMessage{})
+	c.Error(err, "should return error for empty messages")
+	c.Nil(response, "response should be nil when error occurs")
 }
 
 func (c *ClientChatModelTest) TestPostChatCompletionsNonStream_MultipleMessages() {
-	// 确保配置存在
-	cfg := config.GetInstance()
-	addr := cfg.GetString(config.ClientChatModelAddr)
-	model := cfg.GetString(config.ClientChatModelModel)
-	token := env.GetModelApiKey()
-
-	// 如果配置不存在，跳过测试
-	if addr == "" || model == "" || token == "" {
-		c.T().Skip("Skipping test: chat model config not set")
-		return
-	}
-
-	// 获取客户端实例
-	client := GetInstance()
-	c.NotNil(client)
+	client := c.newFixtureClient("chat_non_stream_multi_turn")
 
 	// 创建测试上下文
 	ginCtx := createTestContext()
@@ -159,36 +109,16 @@ func (c *ClientChatModelTest) TestPostChatCompletionsNonStream_MultipleMessages(
 	response, err := client.PostChatCompletionsNonStream(ctx, messages)
 
 	// 验证结果
-	c.Nil(err, "should not return error")
-	c.NotNil(response, "response should not be nil")
-	if response != nil {
-		c.Greater(len(response.Choices), 0, "should have at least one choice")
-		if len(response.Choices) > 0 {
-			c.NotEmpty(response.Choices[0].Message.Content, "message content should not be empty")
-			c.Equal(openai.ChatMessageRoleAssistant, response.Choices[0].Message.Role, "response role should be assistant")
-		}
-	}
+	c.Require().NoError(err)
+	c.Require().NotEmpty(response.Choices, "should have at least one choice")
+	c.NotEmpty(response.Choices[0].Message.Content, "message content should not be empty")
+	c.Equal(openai.ChatMessageRoleAssistant, response.Choices[0].Message.Role, "response role should be assistant")
 }
 
 func (c *ClientChatModelTest) TestPostChatCompletionsNonStream_InvalidConfig() {
-	// 这个测试主要验证当配置无效时方法的行为
-	// 由于 GetInstance 使用 sync.Once，我们需要在测试中模拟无效配置
-	// 实际场景中，如果配置无效，API 调用会返回错误
-
-	// 保存原始配置
-	cfg := config.GetInstance()
-	originalAddr := cfg.GetString(config.ClientChatModelAddr)
-	originalToken := env.GetModelApiKey()
-
-	// 如果原始配置为空，说明配置本身就不存在，跳过测试
-	if originalAddr == "" || originalToken == "" {
-		c.T().Skip("Skipping test: chat model config not set")
-		return
-	}
-
-	// 获取客户端实例（使用有效配置）
-	client := GetInstance()
-	c.NotNil(client)
+	// 录制时使用无效的密钥，上游返回鉴权错误
+	client := c.newFixtureClient("chat_non_stream_invalid_key")
+	client.config.Token = "invalid-token"
 
 	// 创建测试上下文
 	ginCtx := createTestContext()
@@ -202,37 +132,13 @@ func (c *ClientChatModelTest) TestPostChatCompletionsNonStream_InvalidConfig() {
 		},
 	}
 
-	// 调用方法 - 如果配置无效，应该返回错误
-	// 注意：由于我们无法在运行时修改 sync.Once 初始化的配置，
-	// 这个测试主要验证方法在正常配置下的行为
 	response, err := client.PostChatCompletionsNonStream(ctx, messages)
-
-	// 如果配置有效，应该成功；如果无效，应该返回错误
-	// 这里主要验证方法不会 panic
-	if err != nil {
-		c.NotNil(err, "should return error when config is invalid")
-		c.Nil(response, "response should be nil when error occurs")
-	} else {
-		c.NotNil(response, "response should not be nil when successful")
-	}
+	c.Error(err, "should return error when api key is invalid")
+	c.Nil(response, "response should be nil when error occurs")
 }
 
 func (c *ClientChatModelTest) TestPostChatCompletionsNonStream_ResponseStructure() {
-	// 确保配置存在
-	cfg := config.GetInstance()
-	addr := cfg.GetString(config.ClientChatModelAddr)
-	model := cfg.GetString(config.ClientChatModelModel)
-	token := env.GetModelApiKey()
-
-	// 如果配置不存在，跳过测试
-	if addr == "" || model == "" || token == "" {
-		c.T().Skip("Skipping test: chat model config not set")
-		return
-	}
-
-	// 获取客户端实例
-	client := GetInstance()
-	c.NotNil(client)
+	client := c.newFixtureClient("chat_non_stream_structure")
 
 	// 创建测试上下文
 	ginCtx := createTestContext()
@@ -250,38 +156,20 @@ func (c *ClientChatModelTest) TestPostChatCompletionsNonStream_ResponseStructure
 	response, err := client.PostChatCompletionsNonStream(ctx, messages)
 
 	// 验证响应结构
-	c.Nil(err, "should not return error")
-	c.NotNil(response, "response should not be nil")
-	if response != nil {
-		// 验证基本字段
-		c.NotEmpty(response.ID, "response ID should not be empty")
-		c.NotEmpty(response.Model, "response model should not be empty")
-		c.Greater(len(response.Choices), 0, "should have at least one choice")
-
-		// 验证 Choice 结构
-		choice := response.Choices[0]
-		c.Equal(openai.ChatMessageRoleAssistant, choice.Message.Role, "message role should be assistant")
-		c.NotEmpty(choice.Message.Content, "message content should not be empty")
-		c.NotEmpty(choice.FinishReason, "finish reason should not be empty")
-	}
+	c.Require().NoError(err)
+	c.NotEmpty(response.ID, "response ID should not be empty")
+	c.NotEmpty(response.Model, "response model should not be empty")
+	c.Require().NotEmpty(response.Choices, "should have at least one choice")
+
+	// 验证 Choice 结构
+	choice := response.Choices[0]
+	c.Equal(openai.ChatMessageRoleAssistant, choice.Message.Role, "message role should be assistant")
+	c.NotEmpty(choice.Message.Content, "message content should not be empty")
+	c.NotEmpty(choice.FinishReason, "finish reason should not be empty")
 }
 
 func (c *ClientChatModelTest) TestPostChatCompletionsNonStreamContent_Success() {
-	// 确保配置存在
-	cfg := config.GetInstance()
-	addr := cfg.GetString(config.ClientChatModelAddr)
-	model := cfg.GetString(config.ClientChatModelModel)
-	token := env.GetModelApiKey()
-
-	// 如果配置不存在，跳过测试
-	if addr == "" || model == "" || token == "" {
-		c.T().Skip("Skipping test: chat model config not set")
-		return
-	}
-
-	// 获取客户端实例
-	client := GetInstance()
-	c.NotNil(client)
+	client := c.newFixtureClient("chat_content_intro")
 
 	// 创建测试上下文
 	ginCtx := createTestContext()
@@ -299,27 +187,13 @@ func (c *ClientChatModelTest) TestPostChatCompletionsNonStreamContent_Success()
 	content, err := client.PostChatCompletionsNonStreamContent(ctx, messages)
 
 	// 验证结果
-	c.Nil(err, "should not return error")
+	c.Require().NoError(err)
 	c.NotEmpty(content, "content should not be empty")
 	c.IsType("", content, "content should be a string")
 }
 
 func (c *ClientChatModelTest) TestPostChatCompletionsNonStreamContent_CompareWithFullResponse() {
-	// 确保配置存在
-	cfg := config.GetInstance()
-	addr := cfg.GetString(config.ClientChatModelAddr)
-	model := cfg.GetString(config.ClientChatModelModel)
-	token := env.GetModelApiKey()
-
-	// 如果配置不存在，跳过测试
-	if addr == "" || model == "" || token == "" {
-		c.T().Skip("Skipping test: chat model config not set")
-		return
-	}
-
-	// 获取客户端实例
-	client := GetInstance()
-	c.NotNil(client)
+	client := c.newFixtureClient("chat_content_compare")
 
 	// 创建测试上下文
 	ginCtx := createTestContext()
@@ -334,77 +208,34 @@ func (c *ClientChatModelTest) TestPostChatCompletionsNonStreamContent_CompareWit
 	}
 
 	// 调用完整响应方法
-	fullResponse, err1 := client.PostChatCompletionsNonStream(ctx, messages)
-	c.Nil(err1, "full response should not return error")
-	c.NotNil(fullResponse, "full response should not be nil")
+	fullResponse, err := client.PostChatCompletionsNonStream(ctx, messages)
+	c.Require().NoError(err)
+	c.Require().NotEmpty(fullResponse.Choices)
 
-	// 调用 content 方法
-	content, err2 := client.PostChatCompletionsNonStreamContent(ctx, messages)
-
-	// 验证结果
-	c.Nil(err2, "content method should not return error")
+	// 调用 content 方法，相同请求回放第二条录制记录
+	content, err := client.PostChatCompletionsNonStreamContent(ctx, messages)
+	c.Require().NoError(err)
 	c.NotEmpty(content, "content should not be empty")
 
 	// 验证 content 与完整响应中的 content 一致
-	if fullResponse != nil && len(fullResponse.Choices) > 0 {
-		expectedContent := fullResponse.Choices[0].Message.Content
-		c.Equal(expectedContent, content, "content should match the content from full response")
-	}
+	c.Equal(fullResponse.Choices[0].Message.Content, content, "content should match the content from full response")
 }
 
 func (c *ClientChatModelTest) TestPostChatCompletionsNonStreamContent_EmptyMessages() {
-	// 确保配置存在
-	cfg := config.GetInstance()
-	addr := cfg.GetString(config.ClientChatModelAddr)
-	model := cfg.GetString(config.ClientChatModelModel)
-	token := env.GetModelApiKey()
-
-	// 如果配置不存在，跳过测试
-	if addr == "" || model == "" || token == "" {
-		c.T().Skip("Skipping test: chat model config not set")
-		return
-	}
-
-	// 获取客户端实例
-	client := GetInstance()
-	c.NotNil(client)
+	client := c.newFixtureClient("chat_content_empty_messages")
 
 	// 创建测试上下文
 	ginCtx := createTestContext()
 	var ctx context.Context = ginCtx
 
-	// 准备空消息列表
-	messages := []openai.ChatCompletionMessage{}
-
-	// 调用方法
-	content, err := client.PostChatCompletionsNonStreamContent(ctx, messages)
-
 	// 空消息列表应该返回错误
-	if err != nil {
-		c.NotNil(err, "should return error for empty messages")
-		c.Empty(content, "content should be empty when error occurs")
-	} else {
-		// 如果 API 允许空消息，验证 content 不为空
-		c.NotEmpty(content, "content should not be empty if API allows empty messages")
-	}
+	content, err := client.PostChatCompletionsNonStreamContent(ctx, []openai.ChatCompletionMessage{})
+	c.Error(err, "should return error for empty messages")
+	c.Empty(content, "content should be empty when error occurs")
 }
 
 func (c *ClientChatModelTest) TestPostChatCompletionsNonStreamContent_MultipleMessages() {
-	// 确保配置存在
-	cfg := config.GetInstance()
-	addr := cfg.GetString(config.ClientChatModelAddr)
-	model := cfg.GetString(config.ClientChatModelModel)
-	token := env.GetModelApiKey()
-
-	// 如果配置不存在，跳过测试
-	if addr == "" || model == "" || token == "" {
-		c.T().Skip("Skipping test: chat model config not set")
-		return
-	}
-
-	// 获取客户端实例
-	client := GetInstance()
-	c.NotNil(client)
+	client := c.newFixtureClient("chat_content_multi_turn")
 
 	// 创建测试上下文
 	ginCtx := createTestContext()
@@ -426,27 +257,13 @@ func (c *ClientChatModelTest) TestPostChatCompletionsNonStreamContent_MultipleMe
 	content, err := client.PostChatCompletionsNonStreamContent(ctx, messages)
 
 	// 验证结果
-	c.Nil(err, "should not return error")
+	c.Require().NoError(err)
 	c.NotEmpty(content, "content should not be empty")
 	c.Greater(len(content), 0, "content length should be greater than 0")
 }
 
 func (c *ClientChatModelTest) TestPostChatCompletionsNonStreamContent_ContentType() {
-	// 确保配置存在
-	cfg := config.GetInstance()
-	addr := cfg.GetString(config.ClientChatModelAddr)
-	model := cfg.GetString(config.ClientChatModelModel)
-	token := env.GetModelApiKey()
-
-	// 如果配置不存在，跳过测试
-	if addr == "" || model == "" || token == "" {
-		c.T().Skip("Skipping test: chat model config not set")
-		return
-	}
-
-	// 获取客户端实例
-	client := GetInstance()
-	c.NotNil(client)
+	client := c.newFixtureClient("chat_content_type")
 
 	// 创建测试上下文
 	ginCtx := createTestContext()
@@ -464,14 +281,282 @@ func (c *ClientChatModelTest) TestPostChatCompletionsNonStreamContent_ContentTyp
 	content, err := client.PostChatCompletionsNonStreamContent(ctx, messages)
 
 	// 验证结果类型和内容
-	c.Nil(err, "should not return error")
-	c.NotNil(content, "content should not be nil")
+	c.Require().NoError(err)
 	c.IsType("", content, "content should be a string")
 	c.NotEmpty(content, "content should not be empty")
 
-	// 验证 content 是有效的字符串（不是空字符串或只包含空白字符）
-	trimmedContent := content
-	c.Greater(len(trimmedContent), 0, "content should have meaningful content")
+	// 验证 content 不是空白字符
+	c.NotEmpty(strings.TrimSpace(content), "content should have meaningful content")
+}
+
+// streamRecorder 支持 CloseNotify 的 ResponseRecorder，gin.Context.Stream 依赖该接口
+type streamRecorder struct {
+	*httptest.ResponseRecorder
+	closed  chan bool
+	onWrite func([]byte)
+}
+
+func newStreamRecorder() *streamRecorder {
+	return &streamRecorder{ResponseRecorder: httptest.NewRecorder(), closed: make(chan bool, 1)}
+}
+
+func (r *streamRecorder) CloseNotify() <-chan bool {
+	return r.closed
+}
+
+func (r *streamRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseRecorder.Write(b)
+	if r.onWrite != nil {
+		r.onWrite(b)
+	}
+	return n, err
+}
+
+// newFakeStreamServer 模拟上游 SSE 接口，依次推送 chunks；hold 为 true 时推送完不结束，直到请求被取消
+func newFakeStreamServer(chunks []string, hold bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, chunk := range chunks {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", chunk)
+			flusher.Flush()
+		}
+		if hold {
+			<-r.Context().Done()
+			return
+		}
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+		flusher.Flush()
+	}))
+}
+
+func newTestClient(addr string) *ClientChatModel {
+	return &ClientChatModel{config: &Config{V1Addr: addr, Model: "test-model", Token: "test-token", MaxTokens: 100}}
+}
+
+func (c *ClientChatModelTest) TestPostChatCompletions_CaptureContentAndDone() {
+	server := newFakeStreamServer([]string{
+		`{"id":"1","choices":[{"index":0,"delta":{"role":"assistant","content":"你好"}}]}`,
+		`{"id":"1","choices":[{"index":0,"delta":{"content":"，世界"},"finish_reason":"stop"}]}`,
+		`{"id":"1","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":3,"total_tokens":8}}`,
+	}, false)
+	defer server.Close()
+
+	recorder := newStreamRecorder()
+	ginCtx, _ := gin.CreateTestContext(recorder)
+	ginCtx.Request = httptest.NewRequest(http.MethodPost, "/test", nil)
+	var ctx context.Context = ginCtx
+
+	result, err := newTestClient(server.URL).PostChatCompletions(&ctx, []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "hi"},
+	})
+
+	c.Require().NoError(err)
+	c.Equal("你好，世界", result.Content)
+	c.Equal("stop", result.FinishReason)
+	c.False(result.Truncated)
+	c.Require().NotNil(result.Usage)
+	c.Equal(8, result.Usage.TotalTokens)
+
+	body := recorder.Body.String()
+	c.Contains(body, "event: usage\ndata: ")
+	c.True(strings.HasSuffix(body, "data: [DONE]\n\n"), "stream should end with [DONE]")
+}
+
+func (c *ClientChatModelTest) TestPostChatCompletions_ClientDisconnect() {
+	server := newFakeStreamServer([]string{
+		`{"id":"1","choices":[{"index":0,"delta":{"role":"assistant","content":"你好"}}]}`,
+	}, true)
+	defer server.Close()
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 客户端收到第一段内容后断开
+	recorder := newStreamRecorder()
+	recorder.onWrite = func(b []byte) {
+		if strings.Contains(string(b), "你好") {
+			cancel()
+		}
+	}
+	ginCtx, _ := gin.CreateTestContext(recorder)
+	ginCtx.Request = httptest.NewRequest(http.MethodPost, "/test", nil).WithContext(reqCtx)
+	var ctx context.Context = ginCtx
+
+	result, err := newTestClient(server.URL).PostChatCompletions(&ctx, []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "hi"},
+	})
+
+	c.Require().NoError(err)
+	c.Equal("你好", result.Content)
+	c.True(result.Truncated)
+	c.NotContains(recorder.Body.String(), "[DONE]")
+}
+
+func (c *ClientChatModelTest) TestWriteStreamEvent_BeforeDeltas() {
+	server := newFakeStreamServer([]string{
+		`{"id":"1","choices":[{"index":0,"delta":{"role":"assistant","content":"见 [1]"},"finish_reason":"stop"}]}`,
+	}, false)
+	defer server.Close()
+
+	recorder := newStreamRecorder()
+	ginCtx, _ := gin.CreateTestContext(recorder)
+	ginCtx.Request = httptest.NewRequest(http.MethodPost, "/test", nil)
+	var ctx context.Context = ginCtx
+
+	c.Require().NoError(WriteStreamEvent(ginCtx, "citations", []gin.H{{"index": 1, "doc_id": 7}}))
+
+	result, err := newTestClient(server.URL).PostChatCompletions(&ctx, []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "hi"},
+	})
+	c.Require().NoError(err)
+	c.Equal("见 [1]", result.Content)
+
+	body := recorder.Body.String()
+	c.True(strings.HasPrefix(body, "event: citations\ndata: [{\"doc_id\":7,\"index\":1}]\n\n"), "citations should be the first event, got %q", body)
+	c.Equal(httptool.HeaderContentTypeStream, recorder.Header().Get(httptool.HeaderContentType))
+	c.True(strings.HasSuffix(body, "data: [DONE]\n\n"), "stream should end with [DONE]")
+}
+
+func (c *ClientChatModelTest) TestPostChatCompletions_RetryBeforeStreamStarts() {
+	var attempts int32
+	stream := newFakeStreamServer([]string{
+		`{"id":"1","choices":[{"index":0,"delta":{"role":"assistant","content":"恢复"},"finish_reason":"stop"}]}`,
+	}, false)
+	defer stream.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		stream.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	client.caller = resilience.New(clientNameChatModel, resilience.Policy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+	client.httpClient = resilience.NewHTTPClient()
+
+	recorder := newStreamRecorder()
+	ginCtx, _ := gin.CreateTestContext(recorder)
+	ginCtx.Request = httptest.NewRequest(http.MethodPost, "/test", nil)
+	var ctx context.Context = ginCtx
+
+	result, err := client.PostChatCompletions(&ctx, []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "hi"},
+	})
+
+	c.Require().NoError(err)
+	c.Equal("恢复", result.Content)
+	c.Equal(int32(2), atomic.LoadInt32(&attempts))
+}
+
+// TestStreamChatCompletions_Collect 测试不依赖 gin 的流式调用，工具调用片段按 index 拼接
+func (c *ClientChatModelTest) TestStreamChatCompletions_Collect() {
+	server := newFakeStreamServer([]string{
+		`{"id":"1","choices":[{"index":0,"delta":{"role":"assistant","content":"查询中"}}]}`,
+		`{"id":"1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+		`{"id":"1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}`,
+		`{"id":"1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"北京\"}"}}]},"finish_reason":"tool_calls"}]}`,
+		`{"id":"1","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":3,"total_tokens":8}}`,
+	}, false)
+	defer server.Close()
+
+	ctx := context.Background()
+	deltas, err := newTestClient(server.URL).StreamChatCompletions(ctx, []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "北京天气"},
+	})
+	c.Require().NoError(err)
+
+	result, err := CollectStream(ctx, deltas)
+	c.Require().NoError(err)
+	c.Equal("查询中", result.Content)
+	c.Equal("tool_calls", result.FinishReason)
+	c.False(result.Truncated)
+	c.Require().Len(result.ToolCalls, 1)
+	c.Equal("call_1", result.ToolCalls[0].ID)
+	c.Equal("get_weather", result.ToolCalls[0].Function.Name)
+	c.Equal(`{"city":"北京"}`, result.ToolCalls[0].Function.Arguments)
+	c.Require().NotNil(result.Usage)
+	c.Equal(8, result.Usage.TotalTokens)
+}
+
+// TestStreamChatCompletions_Cancel 测试调用方取消后通道关闭且不推送错误
+func (c *ClientChatModelTest) TestStreamChatCompletions_Cancel() {
+	server := newFakeStreamServer([]string{
+		`{"id":"1","choices":[{"index":0,"delta":{"role":"assistant","content":"你好"}}]}`,
+	}, true)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	deltas, err := newTestClient(server.URL).StreamChatCompletions(ctx, []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "hi"},
+	})
+	c.Require().NoError(err)
+
+	first := <-deltas
+	c.Equal("你好", first.Content)
+	cancel()
+
+	result, err := CollectStream(ctx, deltas)
+	c.NoError(err)
+	c.True(result.Truncated)
+}
+
+// fixtureModel 夹具录制时使用的模型，回放时请求体中的模型名必须一致
+const fixtureModel = "qwen3-max"
+
+// newFixtureClient 回放 testdata 下的夹具，不读取全局配置、不重试；夹具缺失时测试失败
+// LLM_FIXTURE_MODE=record 时使用 clients.llmModel.addr 与 LLM_API_KEY 请求真实接口并重新录制
+func (c *ClientChatModelTest) newFixtureClient(name string) *ClientChatModel {
+	mode := replay.ModeFromEnv()
+	addr, token := "http://replay.invalid/v1", "test-token"
+	if mode == replay.ModeRecord {
+		addr, token = config.GetInstance().GetString(config.ClientChatModelAddr), env.GetModelApiKey()
+		if addr == "" || token == "" {
+			c.T().Skip("Skipping record: chat model config not set")
+		}
+	}
+
+	path := filepath.Join("testdata", name+".json")
+	transport, err := replay.Open(path, mode)
+	c.Require().NoError(err, "fixture %s is missing, run `make fixtures` to record it", path)
+	c.T().Cleanup(func() {
+		c.NoError(transport.Close())
+	})
+	return NewClient(&Config{V1Addr: addr, Model: fixtureModel, Token: token, MaxTokens: 100, Transport: transport})
+}
+
+// TestPostChatCompletionsNonStream_Replay 使用录制的响应测试非流式调用
+func (c *ClientChatModelTest) TestPostChatCompletionsNonStream_Replay() {
+	client := c.newFixtureClient("chat_non_stream")
+
+	content, err := client.PostChatCompletionsNonStreamContent(context.Background(), []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "用一句话介绍杭州"},
+	})
+	c.Require().NoError(err)
+	c.Contains(content, "杭州")
+}
+
+// TestPostChatCompletions_Replay 使用录制的 SSE 流测试流式调用
+func (c *ClientChatModelTest) TestPostChatCompletions_Replay() {
+	client := c.newFixtureClient("chat_stream")
+
+	recorder := newStreamRecorder()
+	ginCtx, _ := gin.CreateTestContext(recorder)
+	ginCtx.Request = httptest.NewRequest(http.MethodPost, "/test", nil)
+	var ctx context.Context = ginCtx
+
+	result, err := client.PostChatCompletions(&ctx, []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "用一句话介绍杭州"},
+	})
+	c.Require().NoError(err)
+	c.Contains(result.Content, "杭州")
+	c.Equal("stop", result.FinishReason)
+	c.Require().NotNil(result.Usage)
+	c.Greater(result.Usage.TotalTokens, 0)
+	c.True(strings.HasSuffix(recorder.Body.String(), "data: [DONE]\n\n"), "stream should end with [DONE]")
 }
 
 func TestClientChatModel(t *testing.T) {
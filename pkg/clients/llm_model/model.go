@@ -1,5 +1,10 @@
 package llm_model
 
+import (
+	"ai_task/pkg/clients/resilience"
+	"net/http"
+)
+
 type Config struct {
 	Addr        string  `json:"addr"`
 	V1Addr      string  `json:"v1Addr"`
@@ -7,4 +12,8 @@ type Config struct {
 	Token       string  `json:"token"`
 	Temperature float32 `json:"temperature"`
 	MaxTokens   int     `json:"maxTokens"`
+
+	ResponseFormat string             `json:"responseFormat"` // 结构化输出模式，见 ResponseFormat* 常量，为空时使用 json_object
+	Transport      http.RoundTripper  `json:"-"`              // 发送请求的 RoundTripper，为 nil 时使用 http.DefaultTransport，测试中可注入录制回放
+	Resilience     *resilience.Policy `json:"-"`              // 超时、重试与熔断策略，为 nil 时不重试、不熔断
 }
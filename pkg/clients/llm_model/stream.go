@@ -0,0 +1,252 @@
+package llm_model
+
+import (
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/tools"
+	"ai_task/pkg/usage"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sashabaranov/go-openai"
+	log "github.com/sirupsen/logrus"
+)
+
+// StreamDelta 流式补全的一个增量分片，字段取自首个候选
+type StreamDelta struct {
+	Content      string            // 增量内容
+	ToolCalls    []openai.ToolCall // 工具调用片段，同一调用的多个片段 Index 相同，arguments 需按顺序拼接
+	FinishReason string            // 结束原因，只在最后一个内容分片中出现
+	Usage        *openai.Usage     // token 用量，开启 include_usage 时出现在最后一个分片
+	Err          error             // 上游中断时的错误，之后通道关闭
+
+	Choices []openai.ChatCompletionStreamChoice // 原始分片，SSE 适配器按原格式推送
+}
+
+// StreamChatCompletions 流式补全，不依赖 gin，可用于后台任务与测试
+// 建立连接失败时返回错误（此时可以重试或回退）；之后的上游错误以带 Err 的分片推送，通道在流结束后关闭。
+// ctx 取消时上游请求同步取消，调用方不再读取时应取消 ctx
+func (zc *ClientChatModel) StreamChatCompletions(ctx context.Context, messages []openai.ChatCompletionMessage) (<-chan StreamDelta, error) {
+	client := zc.newOpenAIClient()
+
+	// 只在建立连接前重试，已推送内容后无法重放
+	prompt.LogCall(ctx, clientNameChatModel, zc.config.Model)
+	var stream *openai.ChatCompletionStream
+	err := zc.caller.Open(ctx, func(ctx context.Context) error {
+		var err error
+		stream, err = client.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
+			Model:         zc.config.Model,
+			Messages:      messages,
+			MaxTokens:     zc.config.MaxTokens,
+			Temperature:   zc.config.Temperature,
+			Stream:        true,
+			StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+		})
+		return err
+	})
+	if err != nil {
+		log.Errorf("%s stream creation error: %v", clientNameChatModel, err)
+		return nil, err
+	}
+
+	return PumpStream(ctx, stream, zc.config.Model), nil
+}
+
+// PumpStream 在后台读取上游流并转换为增量分片，读完或 ctx 取消后关闭流与通道
+// 收到 usage 时按 model 记录 token 用量
+func PumpStream(ctx context.Context, stream *openai.ChatCompletionStream, model string) <-chan StreamDelta {
+	deltas := make(chan StreamDelta)
+	send := func(delta StreamDelta) bool {
+		select {
+		case deltas <- delta:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(deltas)
+		defer tools.ErrorWithPrintContext(stream.Close, "close stream")
+
+		for {
+			response, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				// 调用方取消导致的错误不再推送
+				if ctx.Err() == nil {
+					send(StreamDelta{Err: err})
+				}
+				return
+			}
+
+			// 开启 include_usage 后，最后一个分片只携带 usage，choices 为空
+			delta := StreamDelta{Usage: response.Usage, Choices: response.Choices}
+			if response.Usage != nil {
+				usage.ReportLLM(ctx, model, *response.Usage)
+			}
+			if len(response.Choices) > 0 {
+				delta.Content = response.Choices[0].Delta.Content
+				delta.ToolCalls = response.Choices[0].Delta.ToolCalls
+				delta.FinishReason = string(response.Choices[0].FinishReason)
+			}
+			if !send(delta) {
+				return
+			}
+		}
+	}()
+	return deltas
+}
+
+// CollectStream 读完通道并汇总结果，ctx 为产生分片时使用的 context，用于判断是否被取消
+func CollectStream(ctx context.Context, deltas <-chan StreamDelta) (*StreamResult, error) {
+	collector := &streamCollector{}
+	var streamErr error
+	for delta := range deltas {
+		if delta.Err != nil {
+			streamErr = delta.Err
+			continue
+		}
+		collector.add(delta)
+	}
+
+	result := collector.result()
+	if streamErr != nil || ctx.Err() != nil {
+		result.Truncated = true
+	}
+	return result, streamErr
+}
+
+// ServeSSE 以 SSE 把增量分片推送给 gin 客户端，结束时推送 usage（或 error）事件和 [DONE]
+// 客户端断开时立即停止并标记回复不完整；返回后调用方应取消产生分片的 ctx 以释放上游连接
+func ServeSSE(ginCtx *gin.Context, deltas <-chan StreamDelta) (*StreamResult, error) {
+	reqCtx := ginCtx.Request.Context()
+
+	setStreamHeaders(ginCtx)
+	ginCtx.Writer.Flush()
+
+	collector := &streamCollector{}
+	var streamErr error
+	var truncated bool
+
+	clientGone := ginCtx.Stream(func(w io.Writer) bool {
+		var delta StreamDelta
+		var ok bool
+		select {
+		case delta, ok = <-deltas:
+		case <-reqCtx.Done():
+			return false
+		}
+		if !ok {
+			return false
+		}
+		if delta.Err != nil {
+			log.Errorf("%s stream.Recv error: %v", clientNameChatModel, delta.Err)
+			streamErr = delta.Err
+			truncated = true
+			return false
+		}
+
+		collector.add(delta)
+		if len(delta.Choices) == 0 {
+			return true
+		}
+
+		var respMsg bytes.Buffer
+		respMsg.Write(streamMessageStart)
+		temp, err := json.Marshal(delta.Choices)
+		if err != nil {
+			log.Errorf("%s: %+v json.Marshal error: %v", clientNameChatModel, delta.Choices, err)
+			streamErr = err
+			truncated = true
+			return false
+		}
+		respMsg.Write(temp)
+		respMsg.Write(streamMessageEnd)
+
+		if _, err = w.Write(respMsg.Bytes()); err != nil {
+			log.Errorf("%s: %+v w.Write error: %v", clientNameChatModel, respMsg.String(), err)
+			truncated = true
+			return false
+		}
+		ginCtx.Writer.Flush()
+		return true
+	})
+
+	result := collector.result()
+	result.Truncated = truncated
+	if clientGone || reqCtx.Err() != nil {
+		log.Warnf("%s client disconnected, reply truncated at %d bytes", clientNameChatModel, len(result.Content))
+		result.Truncated = true
+		return result, nil
+	}
+
+	// 结束事件：usage（或 error）+ [DONE]，便于客户端区分正常结束与断流
+	if err := writeStreamEnd(ginCtx, result, streamErr); err != nil {
+		log.Errorf("%s write stream end error: %v", clientNameChatModel, err)
+		result.Truncated = true
+	}
+	return result, streamErr
+}
+
+// streamCollector 汇总增量分片
+type streamCollector struct {
+	content      strings.Builder
+	toolCalls    []openai.ToolCall
+	finishReason string
+	usage        *openai.Usage
+}
+
+func (c *streamCollector) add(delta StreamDelta) {
+	c.content.WriteString(delta.Content)
+	if delta.FinishReason != "" {
+		c.finishReason = delta.FinishReason
+	}
+	if delta.Usage != nil {
+		c.usage = delta.Usage
+	}
+	for _, fragment := range delta.ToolCalls {
+		c.addToolCall(fragment)
+	}
+}
+
+// addToolCall 按 Index 合并工具调用片段：首个片段携带 id 与函数名，后续片段只追加 arguments
+func (c *streamCollector) addToolCall(fragment openai.ToolCall) {
+	index := len(c.toolCalls)
+	if fragment.Index != nil {
+		index = *fragment.Index
+	}
+	for i := range c.toolCalls {
+		call := &c.toolCalls[i]
+		if call.Index == nil || *call.Index != index {
+			continue
+		}
+		if fragment.ID != "" {
+			call.ID = fragment.ID
+		}
+		if fragment.Type != "" {
+			call.Type = fragment.Type
+		}
+		call.Function.Name += fragment.Function.Name
+		call.Function.Arguments += fragment.Function.Arguments
+		return
+	}
+
+	fragment.Index = &index
+	c.toolCalls = append(c.toolCalls, fragment)
+}
+
+func (c *streamCollector) result() *StreamResult {
+	return &StreamResult{
+		Content:      c.content.String(),
+		ToolCalls:    c.toolCalls,
+		FinishReason: c.finishReason,
+		Usage:        c.usage,
+	}
+}
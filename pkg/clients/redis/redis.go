@@ -11,6 +11,7 @@ import (
 
 var (
 	instance *RedisClient
+	initErr  error
 	once     sync.Once
 )
 
@@ -143,18 +144,27 @@ func newRedisClusterApi(cfg RedisClusterConfig) (*redis.ClusterClient, error) {
 }
 
 func GetInstance() *RedisClient {
+	client, err := TryGetInstance()
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// TryGetInstance 获取单节点客户端单例，连接失败时返回错误而不是 panic，便于调用方降级
+func TryGetInstance() (*RedisClient, error) {
 	once.Do(func() {
 		conf := &RedisConfig{
-			Host:     config.GetInstance().GetString(config.RedisClientDb),
+			Host:     config.GetInstance().GetString(config.RedisClientHost),
 			Password: config.GetInstance().GetString(config.RedisClientPassword),
 			Db:       config.GetInstance().GetInt(config.RedisClientDb),
 		}
 		client, err := newRedisSingleApi(conf)
 		if err != nil {
-			panic(err)
+			initErr = err
+			return
 		}
 		instance = &RedisClient{conf: conf, Client: client}
-
 	})
-	return instance
+	return instance, initErr
 }
@@ -0,0 +1,294 @@
+// Package replay 录制与回放 OpenAI 兼容接口的 HTTP 流量，使依赖模型接口的测试可以离线、确定地运行
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Mode 运行模式
+type Mode string
+
+const (
+	ModeReplay Mode = "replay" // 只读取夹具，不访问网络
+	ModeRecord Mode = "record" // 请求真实上游，Close 时写入夹具
+
+	// EnvMode 选择模式的环境变量，未设置时为 replay
+	EnvMode = "LLM_FIXTURE_MODE"
+
+	redacted = "REDACTED"
+)
+
+// ErrNoInteraction 回放时没有匹配且未使用的录制记录
+var ErrNoInteraction = errors.New("replay: no matching interaction")
+
+// sensitiveHeaders 写入夹具前脱敏的请求头与响应头
+var sensitiveHeaders = []string{"Authorization", "Api-Key", "X-Api-Key", "Cookie", "Set-Cookie"}
+
+// versionSegment 路径中的版本段，如 /v1、/api/paas/v4
+var versionSegment = regexp.MustCompile(`^.*/v\d+/`)
+
+// Request 录制的请求
+type Request struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response 录制的响应，SSE 流按原始文本保存
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// Interaction 一次请求与响应
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette 夹具文件内容
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Matcher 判断请求与录制记录是否匹配
+type Matcher func(req *Request, recorded *Request) bool
+
+// Option 配置选项
+type Option func(*Transport)
+
+// WithBase 录制时使用的底层 RoundTripper，默认为 http.DefaultTransport
+func WithBase(base http.RoundTripper) Option {
+	return func(t *Transport) {
+		t.base = base
+	}
+}
+
+// WithMatcher 自定义请求匹配规则，默认为 DefaultMatcher
+func WithMatcher(matcher Matcher) Option {
+	return func(t *Transport) {
+		t.matcher = matcher
+	}
+}
+
+// Transport 录制或回放 HTTP 流量的 http.RoundTripper
+// 回放时按录制顺序取第一个匹配且未使用的记录，相同请求多次出现时依次回放
+type Transport struct {
+	path    string
+	mode    Mode
+	base    http.RoundTripper
+	matcher Matcher
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// Open 打开夹具，replay 模式下文件必须存在，record 模式下从空记录开始
+func Open(path string, mode Mode, opts ...Option) (*Transport, error) {
+	t := &Transport{path: path, mode: mode, base: http.DefaultTransport, matcher: DefaultMatcher}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	switch mode {
+	case ModeRecord:
+	case ModeReplay:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("replay: read fixture: %w", err)
+		}
+		if err := json.Unmarshal(data, &t.cassette); err != nil {
+			return nil, fmt.Errorf("replay: parse fixture %s: %w", path, err)
+		}
+		t.used = make([]bool, len(t.cassette.Interactions))
+	default:
+		return nil, fmt.Errorf("replay: unknown mode %q", mode)
+	}
+	return t, nil
+}
+
+// ModeFromEnv 读取 LLM_FIXTURE_MODE，未设置或无法识别时为 replay
+func ModeFromEnv() Mode {
+	if Mode(os.Getenv(EnvMode)) == ModeRecord {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// Mode 当前模式
+func (t *Transport) Mode() Mode {
+	return t.mode
+}
+
+// Client 使用该 Transport 的 http 客户端，可直接传给 go-openai 与 openai-go
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if t.mode == ModeRecord {
+		return t.record(req, recorded)
+	}
+	return t.replay(req, recorded)
+}
+
+// record 请求上游并读完整个响应体后保存，流式响应也会在返回前读完
+func (t *Transport) record(req *http.Request, recorded *Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("replay: read response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cassette.Interactions = append(t.cassette.Interactions, Interaction{
+		Request: *recorded,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     redact(resp.Header),
+			Body:       string(body),
+		},
+	})
+	return resp, nil
+}
+
+func (t *Transport) replay(req *http.Request, recorded *Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, interaction := range t.cassette.Interactions {
+		if t.used[i] || !t.matcher(recorded, &interaction.Request) {
+			continue
+		}
+		t.used[i] = true
+		header := interaction.Response.Header.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, recorded.Method, recorded.Path)
+}
+
+// Unused 回放模式下尚未被请求的记录数
+func (t *Transport) Unused() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var n int
+	for _, used := range t.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
+
+// Close record 模式下把录制结果写入夹具文件，replay 模式下不做任何事
+func (t *Transport) Close() error {
+	if t.mode != ModeRecord {
+		return nil
+	}
+
+	t.mu.Lock()
+	data, err := json.MarshalIndent(t.cassette, "", "  ")
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(t.path, append(data, '\n'), 0o644)
+}
+
+// DefaultMatcher 比较方法、去掉版本前缀的路径、查询参数和请求体
+// 路径只比较 /v1 之类版本段之后的部分，录制与回放可以使用不同的 base URL；JSON 请求体按语义比较，与字段顺序无关
+func DefaultMatcher(req *Request, recorded *Request) bool {
+	return req.Method == recorded.Method &&
+		endpoint(req.Path) == endpoint(recorded.Path) &&
+		req.Query == recorded.Query &&
+		canonicalBody(req.Body) == canonicalBody(recorded.Body)
+}
+
+// newRequest 读取请求体并脱敏请求头，读取后恢复请求体供上游使用
+func newRequest(req *http.Request) (*Request, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("replay: read request: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return &Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.RawQuery,
+		Header: redact(req.Header),
+		Body:   string(body),
+	}, nil
+}
+
+// redact 复制请求头并替换敏感字段的值
+func redact(header http.Header) http.Header {
+	header = header.Clone()
+	for _, key := range sensitiveHeaders {
+		if _, ok := header[http.CanonicalHeaderKey(key)]; ok {
+			header.Set(key, redacted)
+		}
+	}
+	return header
+}
+
+func endpoint(path string) string {
+	return strings.TrimPrefix(versionSegment.ReplaceAllString(path, ""), "/")
+}
+
+func canonicalBody(body string) string {
+	var v any
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return body
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return string(data)
+}
@@ -0,0 +1,123 @@
+package replay
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const sseBody = "data: {\"choices\":[{\"delta\":{\"content\":\"你\"}}]}\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"好\"}}]}\n\ndata: [DONE]\n\n"
+
+func post(t *testing.T, client *http.Client, url, body string) (int, string, http.Header) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer sk-secret")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data), resp.Header
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(w, sseBody)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"content":"hi"}}]}`)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "chat.json")
+	recorder, err := Open(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post(t, recorder.Client(), server.URL+"/v1/chat/completions", `{"model":"m","stream":false}`)
+	_, streamed, _ := post(t, recorder.Client(), server.URL+"/v1/chat/completions", `{"model":"m","stream":true}`)
+	if streamed != sseBody {
+		t.Fatalf("Expected recorder to pass stream through, got %q", streamed)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-secret") || !strings.Contains(string(data), redacted) {
+		t.Error("Expected Authorization header to be redacted in fixture")
+	}
+
+	// 回放时不访问网络，base URL 的版本前缀与字段顺序可以不同
+	player, err := Open(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, got, header := post(t, player.Client(), "http://replay.invalid/api/paas/v4/chat/completions", `{"stream":true,"model":"m"}`)
+	if status != http.StatusOK || got != sseBody || header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Unexpected replayed stream: %d %q %v", status, got, header)
+	}
+	_, got, _ = post(t, player.Client(), "http://replay.invalid/v1/chat/completions", `{"model":"m","stream":false}`)
+	if !strings.Contains(got, `"hi"`) {
+		t.Errorf("Unexpected replayed response: %q", got)
+	}
+	if atomic.LoadInt32(&calls) != 2 || player.Unused() != 0 {
+		t.Errorf("Expected 2 upstream calls and no unused interaction, got %d and %d", calls, player.Unused())
+	}
+
+	// 每条记录只回放一次，多出的请求报错
+	req, _ := http.NewRequest(http.MethodPost, "http://replay.invalid/v1/chat/completions", strings.NewReader(`{"model":"m","stream":false}`))
+	if _, err := player.RoundTrip(req); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("Expected ErrNoInteraction, got %v", err)
+	}
+}
+
+func TestOpenMissingFixture(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.json"), ModeReplay); err == nil {
+		t.Error("Expected error for missing fixture in replay mode")
+	}
+	if _, err := Open("any.json", Mode("unknown")); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
+
+func TestDefaultMatcher(t *testing.T) {
+	recorded := &Request{Method: http.MethodPost, Path: "/v1/embeddings", Body: `{"input":["a"],"model":"e"}`}
+
+	cases := []struct {
+		name string
+		req  Request
+		want bool
+	}{
+		{"same", Request{Method: http.MethodPost, Path: "/v1/embeddings", Body: `{"model":"e","input":["a"]}`}, true},
+		{"other base path", Request{Method: http.MethodPost, Path: "/compatible-mode/v1/embeddings", Body: `{"model":"e","input":["a"]}`}, true},
+		{"other body", Request{Method: http.MethodPost, Path: "/v1/embeddings", Body: `{"model":"e","input":["b"]}`}, false},
+		{"other method", Request{Method: http.MethodGet, Path: "/v1/embeddings", Body: `{"model":"e","input":["a"]}`}, false},
+		{"other endpoint", Request{Method: http.MethodPost, Path: "/v1/chat/completions", Body: `{"model":"e","input":["a"]}`}, false},
+	}
+	for _, c := range cases {
+		if got := DefaultMatcher(&c.req, recorded); got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
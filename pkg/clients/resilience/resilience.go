@@ -0,0 +1,194 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	log "github.com/sirupsen/logrus"
+)
+
+// Policy 重试、超时与熔断策略
+type Policy struct {
+	MaxAttempts      int           `json:"max_attempts"`      // 最多尝试次数（含首次），<= 1 时不重试
+	BaseDelay        time.Duration `json:"base_delay"`        // 首次重试的退避基数，之后按 2 倍递增
+	MaxDelay         time.Duration `json:"max_delay"`         // 单次退避上限
+	MaxRetryAfter    time.Duration `json:"max_retry_after"`   // 服务端 Retry-After 的采纳上限，超过时按上限等待
+	Timeout          time.Duration `json:"timeout"`           // 单次尝试超时，0 表示只受调用方 context 约束
+	BreakerThreshold int           `json:"breaker_threshold"` // 连续失败多少次后熔断，<= 0 表示不启用熔断
+	BreakerCooldown  time.Duration `json:"breaker_cooldown"`  // 熔断后多久放行一次探测请求
+}
+
+// DefaultPolicy 默认策略
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:      3,
+		BaseDelay:        500 * time.Millisecond,
+		MaxDelay:         8 * time.Second,
+		MaxRetryAfter:    30 * time.Second,
+		Timeout:          60 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+// Caller 按策略执行模型调用：单次超时、抖动退避重试、429 遵循 Retry-After，并在服务不可用时熔断
+// 为 nil 时直接执行，不做任何保护
+type Caller struct {
+	name    string
+	policy  Policy
+	breaker *Breaker
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+// New 创建调用器，name 用于日志和错误信息
+func New(name string, policy Policy) *Caller {
+	return &Caller{
+		name:    name,
+		policy:  policy,
+		breaker: NewBreaker(policy.BreakerThreshold, policy.BreakerCooldown),
+		sleep:   sleepContext,
+	}
+}
+
+// Breaker 调用器使用的熔断器，未启用熔断时为 nil
+func (c *Caller) Breaker() *Breaker {
+	if c == nil {
+		return nil
+	}
+	return c.breaker
+}
+
+// Do 执行 fn，fn 收到的 context 带有单次尝试的超时
+func (c *Caller) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return c.do(ctx, fn, true)
+}
+
+// Open 用于建立流式连接：与 Do 相同，但不设置单次超时，避免连接建立后的读取被超时中断
+func (c *Caller) Open(ctx context.Context, fn func(ctx context.Context) error) error {
+	return c.do(ctx, fn, false)
+}
+
+func (c *Caller) do(ctx context.Context, fn func(ctx context.Context) error, withTimeout bool) error {
+	if c == nil {
+		return fn(ctx)
+	}
+
+	attempts := max(c.policy.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		if !c.breaker.Allow() {
+			return fmt.Errorf("%s: %w", c.name, ErrCircuitOpen)
+		}
+
+		attemptCtx, hint := withHint(ctx)
+		cancel := func() {}
+		if withTimeout && c.policy.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(attemptCtx, c.policy.Timeout)
+		}
+		err := fn(attemptCtx)
+		cancel()
+
+		if err == nil {
+			c.breaker.Success()
+			return nil
+		}
+
+		// 调用方取消或超时，不计入服务健康状况，但要释放探测名额
+		if ctx.Err() != nil {
+			c.breaker.Release()
+			return err
+		}
+
+		retryable := isRetryable(err, hint)
+		if !retryable {
+			// 参数错误、鉴权失败等说明服务本身可用
+			c.breaker.Success()
+			return err
+		}
+		c.breaker.Failure()
+
+		if attempt >= attempts {
+			if attempts > 1 {
+				return fmt.Errorf("%s failed after %d attempts: %w", c.name, attempts, err)
+			}
+			return err
+		}
+
+		delay := c.backoff(attempt)
+		if hint.retryAfter > 0 {
+			delay = min(hint.retryAfter, c.policy.MaxRetryAfter)
+		}
+		log.Warnf("%s call failed (attempt %d/%d), retry after %v: %v", c.name, attempt, attempts, delay, err)
+
+		if err := c.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// backoff 第 attempt 次失败后的等待时间：指数退避并在 [d/2, d] 内随机抖动，避免多个调用同时重试
+func (c *Caller) backoff(attempt int) time.Duration {
+	d := c.policy.BaseDelay << (attempt - 1)
+	if d <= 0 || (c.policy.MaxDelay > 0 && d > c.policy.MaxDelay) {
+		d = c.policy.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// isRetryable 判断错误是否值得重试：超时、网络错误、429 以及 5xx
+func isRetryable(err error, hint *retryHint) bool {
+	if errors.Is(err, ErrCircuitOpen) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	status := hint.statusCode
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	}
+	if status > 0 {
+		return retryableStatus(status)
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	}
+	return status >= http.StatusInternalServerError && status != http.StatusNotImplemented
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
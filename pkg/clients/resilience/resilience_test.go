@@ -0,0 +1,237 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCaller 创建不真正等待的调用器，记录每次退避时长
+func newTestCaller(policy Policy, delays *[]time.Duration) *Caller {
+	c := New("test", policy)
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return ctx.Err()
+	}
+	return c
+}
+
+func TestCallerRetriesRetryableErrors(t *testing.T) {
+	var delays []time.Duration
+	c := newTestCaller(Policy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, &delays)
+
+	calls := 0
+	err := c.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return &openai.APIError{HTTPStatusCode: http.StatusBadGateway}
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	require.Len(t, delays, 2)
+	assert.True(t, delays[0] >= 50*time.Millisecond && delays[0] <= 100*time.Millisecond, "first backoff %v", delays[0])
+	assert.True(t, delays[1] >= 100*time.Millisecond && delays[1] <= 200*time.Millisecond, "second backoff %v", delays[1])
+}
+
+func TestCallerDoesNotRetryClientErrors(t *testing.T) {
+	var delays []time.Duration
+	c := newTestCaller(Policy{MaxAttempts: 3}, &delays)
+
+	calls := 0
+	err := c.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return &openai.APIError{HTTPStatusCode: http.StatusUnauthorized}
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, delays)
+}
+
+func TestCallerGivesUpAfterMaxAttempts(t *testing.T) {
+	var delays []time.Duration
+	c := newTestCaller(Policy{MaxAttempts: 2}, &delays)
+
+	upstream := &openai.RequestError{HTTPStatusCode: http.StatusServiceUnavailable}
+	err := c.Do(context.Background(), func(ctx context.Context) error { return upstream })
+
+	assert.ErrorIs(t, err, upstream)
+	assert.Contains(t, err.Error(), "after 2 attempts")
+}
+
+func TestCallerAttemptTimeout(t *testing.T) {
+	var delays []time.Duration
+	c := newTestCaller(Policy{MaxAttempts: 2, Timeout: 10 * time.Millisecond}, &delays)
+
+	calls := 0
+	err := c.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls, "attempt timeout should be retried")
+}
+
+func TestCallerStopsWhenCallerCanceled(t *testing.T) {
+	var delays []time.Duration
+	c := newTestCaller(Policy{MaxAttempts: 3}, &delays)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := c.Do(ctx, func(ctx context.Context) error {
+		calls++
+		cancel()
+		return context.Canceled
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+}
+
+func TestCallerHonorsRetryAfter(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":{"message":"rate limited"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	conf := openai.DefaultConfig("test-key")
+	conf.BaseURL = server.URL
+	conf.HTTPClient = NewHTTPClient()
+	client := openai.NewClientWithConfig(conf)
+
+	var delays []time.Duration
+	c := newTestCaller(Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxRetryAfter: 5 * time.Second}, &delays)
+
+	var content string
+	err := c.Do(context.Background(), func(ctx context.Context) error {
+		resp, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{Model: "m"})
+		if err == nil {
+			content = resp.Choices[0].Message.Content
+		}
+		return err
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "ok", content)
+	assert.Equal(t, []time.Duration{5 * time.Second}, delays, "Retry-After should be capped by MaxRetryAfter")
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3", now))
+	assert.Equal(t, 10*time.Second, parseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now))
+	assert.Zero(t, parseRetryAfter("soon", now))
+	assert.Zero(t, parseRetryAfter("", now))
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.Failure()
+	assert.Equal(t, BreakerClosed, b.State())
+	b.Failure()
+	assert.Equal(t, BreakerOpen, b.State())
+	assert.False(t, b.Allow())
+
+	// 冷却结束只放行一个探测请求
+	now = now.Add(time.Minute)
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow())
+	assert.Equal(t, BreakerHalfOpen, b.State())
+
+	// 探测失败重新熔断
+	b.Failure()
+	assert.Equal(t, BreakerOpen, b.State())
+
+	now = now.Add(time.Minute)
+	assert.True(t, b.Allow())
+	b.Success()
+	assert.Equal(t, BreakerClosed, b.State())
+	assert.True(t, b.Allow())
+}
+
+func TestCallerFailsFastWhenOpen(t *testing.T) {
+	var delays []time.Duration
+	c := newTestCaller(Policy{MaxAttempts: 1, BreakerThreshold: 1, BreakerCooldown: time.Hour}, &delays)
+
+	upstream := errors.New("unreachable")
+	_ = c.Do(context.Background(), func(ctx context.Context) error {
+		return &openai.APIError{HTTPStatusCode: http.StatusInternalServerError, Message: upstream.Error()}
+	})
+
+	calls := 0
+	err := c.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Zero(t, calls)
+}
+
+func TestCallerReleasesCanceledProbe(t *testing.T) {
+	var delays []time.Duration
+	c := newTestCaller(Policy{MaxAttempts: 1, BreakerThreshold: 1, BreakerCooldown: time.Minute}, &delays)
+	now := time.Now()
+	c.breaker.now = func() time.Time { return now }
+
+	_ = c.Do(context.Background(), func(ctx context.Context) error {
+		return &openai.APIError{HTTPStatusCode: http.StatusServiceUnavailable}
+	})
+	require.Equal(t, BreakerOpen, c.breaker.State())
+
+	// 冷却结束后的探测请求被调用方取消，不应让熔断器一直停在半开
+	now = now.Add(time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	err := c.Do(ctx, func(ctx context.Context) error {
+		cancel()
+		return context.Canceled
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, BreakerOpen, c.breaker.State())
+
+	calls := 0
+	err = c.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, BreakerClosed, c.breaker.State())
+}
+
+func TestNilCallerPassesThrough(t *testing.T) {
+	var c *Caller
+	calls := 0
+	err := c.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("boom")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,74 @@
+package resilience
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type hintKey struct{}
+
+// retryHint 单次尝试中由 Transport 记录的响应信息，openai 的错误类型不携带响应头
+type retryHint struct {
+	statusCode int
+	retryAfter time.Duration
+}
+
+func withHint(ctx context.Context) (context.Context, *retryHint) {
+	hint := &retryHint{}
+	return context.WithValue(ctx, hintKey{}, hint), hint
+}
+
+// Transport 记录 429/503 响应中的 Retry-After，供 Caller 决定等待时间
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// NewHTTPClient 创建使用 Transport 的 http 客户端，供 openai 客户端配置 HTTPClient
+func NewHTTPClient() *http.Client {
+	return NewHTTPClientWithBase(nil)
+}
+
+// NewHTTPClientWithBase 同 NewHTTPClient，base 为实际发送请求的 RoundTripper，为 nil 时使用 http.DefaultTransport
+func NewHTTPClientWithBase(base http.RoundTripper) *http.Client {
+	return &http.Client{Transport: &Transport{Base: base}}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if hint, ok := req.Context().Value(hintKey{}).(*retryHint); ok {
+		hint.statusCode = resp.StatusCode
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			hint.retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		}
+	}
+	return resp, nil
+}
+
+// parseRetryAfter 解析 Retry-After，支持秒数和 HTTP 日期两种格式
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,125 @@
+package document
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 支持的文档内容类型，对应 documents.content_type
+const (
+	ContentTypeText     = "text"
+	ContentTypeMarkdown = "markdown"
+	ContentTypeHTML     = "html"
+)
+
+var blankLinesRegexp = regexp.MustCompile(`\n{3,}`)
+
+// NormalizeContentType 规范化内容类型，为空时按文件名扩展名推断，无法推断时按纯文本处理
+func NormalizeContentType(contentType, filename string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(contentType)) {
+	case "":
+	case ContentTypeText, "txt", "text/plain":
+		return ContentTypeText, nil
+	case ContentTypeMarkdown, "md", "text/markdown":
+		return ContentTypeMarkdown, nil
+	case ContentTypeHTML, "htm", "text/html":
+		return ContentTypeHTML, nil
+	default:
+		return "", fmt.Errorf("unsupported content_type: %s", contentType)
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".md", ".markdown":
+		return ContentTypeMarkdown, nil
+	case ".html", ".htm":
+		return ContentTypeHTML, nil
+	default:
+		return ContentTypeText, nil
+	}
+}
+
+// ExtractText 提取用于分块和向量化的正文
+// HTML 去除标签、脚本和样式，块级元素之间以空行分隔，便于按段落分块；Markdown 与纯文本仅统一换行
+func ExtractText(contentType, content string) (string, error) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	var text string
+	switch contentType {
+	case ContentTypeHTML:
+		extracted, err := extractHTML(content)
+		if err != nil {
+			return "", err
+		}
+		text = extracted
+	case ContentTypeText, ContentTypeMarkdown:
+		text = content
+	default:
+		return "", fmt.Errorf("unsupported content_type: %s", contentType)
+	}
+
+	return strings.TrimSpace(blankLinesRegexp.ReplaceAllString(text, "\n\n")), nil
+}
+
+func extractHTML(content string) (string, error) {
+	root, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	var builder strings.Builder
+	lineStart := true
+	write := func(s string) {
+		builder.WriteString(s)
+		lineStart = strings.HasSuffix(s, "\n")
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			// 连续空白折叠为一个空格，换行由块级元素决定
+			if text := strings.Join(strings.Fields(n.Data), " "); text != "" {
+				if !lineStart {
+					write(" ")
+				}
+				write(text)
+			}
+			return
+		case html.ElementNode:
+			switch n.DataAtom {
+			case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Head:
+				return
+			case atom.Br:
+				write("\n")
+				return
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+
+		if n.Type == html.ElementNode && isBlockElement(n.DataAtom) {
+			write("\n\n")
+		}
+	}
+	walk(root)
+
+	return builder.String(), nil
+}
+
+func isBlockElement(a atom.Atom) bool {
+	switch a {
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Main, atom.Aside, atom.Nav,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Ul, atom.Ol, atom.Li, atom.Table, atom.Tr, atom.Blockquote, atom.Pre, atom.Hr,
+		atom.Dl, atom.Dt, atom.Dd, atom.Figure, atom.Figcaption:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeContentType(t *testing.T) {
+	cases := []struct {
+		contentType string
+		filename    string
+		expected    string
+	}{
+		{"", "guide.md", ContentTypeMarkdown},
+		{"", "index.HTML", ContentTypeHTML},
+		{"", "notes.txt", ContentTypeText},
+		{"", "", ContentTypeText},
+		{"Markdown", "page.html", ContentTypeMarkdown},
+		{"text/html", "", ContentTypeHTML},
+	}
+
+	for _, c := range cases {
+		got, err := NormalizeContentType(c.contentType, c.filename)
+		if err != nil {
+			t.Errorf("NormalizeContentType(%q, %q) unexpected error: %v", c.contentType, c.filename, err)
+			continue
+		}
+		if got != c.expected {
+			t.Errorf("NormalizeContentType(%q, %q) = %s, expected %s", c.contentType, c.filename, got, c.expected)
+		}
+	}
+
+	if _, err := NormalizeContentType("pdf", ""); err == nil {
+		t.Error("Expected error for unsupported content type")
+	}
+}
+
+func TestExtractTextHTML(t *testing.T) {
+	content := `<html><head><title>忽略</title><style>p{color:red}</style></head>
+<body><h1>退货政策</h1><p>收货后 7 天内
+可申请退货。</p><script>alert(1)</script><ul><li>保持包装完好</li><li>附带发票 &amp; 配件</li></ul></body></html>`
+
+	text, err := ExtractText(ContentTypeHTML, content)
+	if err != nil {
+		t.Fatalf("ExtractText error: %v", err)
+	}
+
+	for _, unwanted := range []string{"忽略", "color:red", "alert", "<p>"} {
+		if strings.Contains(text, unwanted) {
+			t.Errorf("Expected %q to be stripped, got %q", unwanted, text)
+		}
+	}
+	for _, wanted := range []string{"退货政策", "收货后 7 天内 可申请退货。", "附带发票 & 配件"} {
+		if !strings.Contains(text, wanted) {
+			t.Errorf("Expected %q in extracted text, got %q", wanted, text)
+		}
+	}
+	if !strings.Contains(text, "退货政策\n\n收货后") {
+		t.Errorf("Expected block elements separated by blank line, got %q", text)
+	}
+	if strings.Contains(text, "\n\n\n") {
+		t.Errorf("Expected consecutive blank lines to be collapsed, got %q", text)
+	}
+}
+
+func TestExtractTextMarkdown(t *testing.T) {
+	content := "# 标题\r\n\r\n\r\n\r\n正文段落\r\n"
+
+	text, err := ExtractText(ContentTypeMarkdown, content)
+	if err != nil {
+		t.Fatalf("ExtractText error: %v", err)
+	}
+	if text != "# 标题\n\n正文段落" {
+		t.Errorf("Unexpected markdown text: %q", text)
+	}
+}
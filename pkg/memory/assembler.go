@@ -0,0 +1,229 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// 上下文段名称
+const (
+	SectionProfile   = "profile"   // 用户画像
+	SectionDocuments = "documents" // 知识文档片段
+	SectionSemantic  = "semantic"  // 语义记忆片段
+	SectionSummary   = "summary"   // 滚动摘要
+	SectionHistory   = "history"   // 最近对话
+	SectionRequired  = "required"  // system 指令与本轮消息，不参与裁剪，只会被截断
+)
+
+// 丢弃原因
+const (
+	DropReasonSectionBudget = "section_budget" // 超出所在段的预算
+	DropReasonTotalBudget   = "total_budget"   // 超出整体提示词预算
+	DropReasonTruncated     = "truncated"      // 必需消息过长被截断，未丢弃
+)
+
+// minRequiredTokens 必需消息截断后至少保留的 token 数
+const minRequiredTokens = 64
+
+// DefaultTrimOrder 超出整体预算时的裁剪顺序，靠前的段先被裁剪
+var DefaultTrimOrder = []string{SectionSemantic, SectionDocuments, SectionHistory, SectionSummary, SectionProfile}
+
+// PromptItem 上下文段中的一条内容
+type PromptItem struct {
+	Key  string // 标识，用于报告被丢弃的内容，如 message:123
+	Text string // 计入预算的文本
+}
+
+// PromptSection 一段上下文及其预算
+// Items 按重要性从高到低排列，裁剪时从末尾开始丢弃
+type PromptSection struct {
+	Name         string
+	Budget       int // 该段最多占用的 token 数，<= 0 表示不单独限制，只受整体预算约束
+	Overhead     int // 段内至少保留一条内容时的固定开销，如标题、消息开销
+	ItemOverhead int // 每条内容的固定开销，如历史消息各自占一条消息
+	Items        []PromptItem
+}
+
+// DroppedItem 被裁剪的内容
+type DroppedItem struct {
+	Section string `json:"section"`
+	Key     string `json:"key"`
+	Tokens  int    `json:"tokens"`
+	Reason  string `json:"reason"`
+}
+
+// Assembly 组装结果
+type Assembly struct {
+	Required []openai.ChatCompletionMessage // 必需消息，超长时已截断
+	Sections map[string][]PromptItem        // 各段保留的内容，顺序与输入一致
+	Dropped  []DroppedItem
+	Tokens   int // 估算的提示词总 token 数
+	Budget   int // 提示词总预算
+}
+
+// PromptAssembler 按 token 预算组装提示词
+// 先按各段预算裁剪，再按 TrimOrder 逐段裁剪直到整体不超预算；
+// system 指令和本轮消息不会被丢弃，仍超出时截断其中最长的消息
+type PromptAssembler struct {
+	Budget    int
+	TrimOrder []string
+}
+
+// NewPromptAssembler 创建组装器，trimOrder 为空时使用 DefaultTrimOrder
+func NewPromptAssembler(budget int, trimOrder []string) *PromptAssembler {
+	if len(trimOrder) == 0 {
+		trimOrder = DefaultTrimOrder
+	}
+	return &PromptAssembler{Budget: budget, TrimOrder: trimOrder}
+}
+
+// sectionState 组装过程中一段的保留情况
+type sectionState struct {
+	section *PromptSection
+	tokens  []int // 每条内容的 token 数
+	kept    int   // 保留前 kept 条
+}
+
+func (st *sectionState) used() int {
+	if st.kept == 0 {
+		return 0
+	}
+	total := st.section.Overhead
+	for _, t := range st.tokens[:st.kept] {
+		total += t
+	}
+	return total
+}
+
+// drop 丢弃末尾一条内容
+func (st *sectionState) drop(reason string) DroppedItem {
+	st.kept--
+	item := st.section.Items[st.kept]
+	return DroppedItem{Section: st.section.Name, Key: item.Key, Tokens: st.tokens[st.kept], Reason: reason}
+}
+
+// Assemble 组装提示词，不会返回错误：超出预算时按优先级降级
+func (a *PromptAssembler) Assemble(required []openai.ChatCompletionMessage, sections []*PromptSection) *Assembly {
+	result := &Assembly{
+		Sections: make(map[string][]PromptItem, len(sections)),
+		Budget:   a.Budget,
+	}
+
+	result.Required, result.Dropped = a.fitRequired(required)
+	requiredTokens := CountMessagesTokens(result.Required)
+
+	states := make([]*sectionState, 0, len(sections))
+	byName := make(map[string]*sectionState, len(sections))
+	for _, section := range sections {
+		if section == nil || len(section.Items) == 0 {
+			continue
+		}
+		st := &sectionState{section: section, tokens: make([]int, len(section.Items)), kept: len(section.Items)}
+		for i, item := range section.Items {
+			st.tokens[i] = CountTokens(item.Text) + section.ItemOverhead
+		}
+		states = append(states, st)
+		byName[section.Name] = st
+	}
+
+	// 段内预算
+	for _, st := range states {
+		if st.section.Budget <= 0 {
+			continue
+		}
+		for st.kept > 0 && st.used() > st.section.Budget {
+			result.Dropped = append(result.Dropped, st.drop(DropReasonSectionBudget))
+		}
+	}
+
+	total := requiredTokens
+	for _, st := range states {
+		total += st.used()
+	}
+
+	// 整体预算，按裁剪顺序逐段丢弃，未列入裁剪顺序的段最后处理
+	if a.Budget > 0 && total > a.Budget {
+		order := make([]*sectionState, 0, len(states))
+		listed := make(map[string]bool, len(a.TrimOrder))
+		for _, name := range a.TrimOrder {
+			if st, ok := byName[name]; ok && !listed[name] {
+				order = append(order, st)
+				listed[name] = true
+			}
+		}
+		for _, st := range states {
+			if !listed[st.section.Name] {
+				order = append(order, st)
+			}
+		}
+
+		for _, st := range order {
+			for st.kept > 0 && total > a.Budget {
+				before := st.used()
+				result.Dropped = append(result.Dropped, st.drop(DropReasonTotalBudget))
+				total -= before - st.used()
+			}
+			if total <= a.Budget {
+				break
+			}
+		}
+	}
+
+	for _, st := range states {
+		if st.kept > 0 {
+			result.Sections[st.section.Name] = st.section.Items[:st.kept]
+		}
+	}
+	result.Tokens = total
+
+	return result
+}
+
+// fitRequired 必需消息超出整体预算时，反复截断其中最长的一条，直到放得下
+// 每条消息至少保留 minRequiredTokens，全部截到下限仍放不下时交由模型服务端处理
+func (a *PromptAssembler) fitRequired(required []openai.ChatCompletionMessage) ([]openai.ChatCompletionMessage, []DroppedItem) {
+	messages := make([]openai.ChatCompletionMessage, len(required))
+	copy(messages, required)
+
+	if a.Budget <= 0 {
+		return messages, nil
+	}
+
+	original := make(map[int]int) // 被截断消息的下标 -> 原始 token 数
+	for {
+		over := CountMessagesTokens(messages) - a.Budget
+		if over <= 0 {
+			break
+		}
+
+		longest, longestTokens := -1, 0
+		for i, msg := range messages {
+			if t := CountTokens(msg.Content); t > longestTokens {
+				longest, longestTokens = i, t
+			}
+		}
+		if longest < 0 || longestTokens <= minRequiredTokens {
+			break
+		}
+
+		if _, ok := original[longest]; !ok {
+			original[longest] = longestTokens
+		}
+		messages[longest].Content = TruncateTokens(messages[longest].Content, max(longestTokens-over, minRequiredTokens))
+	}
+
+	var dropped []DroppedItem
+	for i := range messages {
+		if tokens, ok := original[i]; ok {
+			dropped = append(dropped, DroppedItem{
+				Section: SectionRequired,
+				Key:     fmt.Sprintf("%s#%d", messages[i].Role, i),
+				Tokens:  tokens - CountTokens(messages[i].Content),
+				Reason:  DropReasonTruncated,
+			})
+		}
+	}
+
+	return messages, dropped
+}
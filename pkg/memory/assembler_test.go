@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestCountTokensCJK(t *testing.T) {
+	// 中文逐字计数，不能按字节/4 或按空格分词
+	if got := CountTokens("你好世界"); got != 4 {
+		t.Errorf("Expected 4 tokens for 4 Han characters, got %d", got)
+	}
+	if got := CountTokens("今天天气很好，我们去公园散步吧。"); got != 16 {
+		t.Errorf("Expected 16 tokens (14 Han + 2 punctuation), got %d", got)
+	}
+	if got := CountTokens("こんにちは"); got != 5 {
+		t.Errorf("Expected 5 tokens for kana, got %d", got)
+	}
+}
+
+func TestCountTokensASCII(t *testing.T) {
+	if got := CountTokens(""); got != 0 {
+		t.Errorf("Expected 0 tokens for empty text, got %d", got)
+	}
+	// hello(2) world(2) !(1)
+	if got := CountTokens("hello world!"); got != 5 {
+		t.Errorf("Expected 5 tokens, got %d", got)
+	}
+	// 混排：Go(1) 语言(2) is(1) 好(1)
+	if got := CountTokens("Go语言 is 好"); got != 5 {
+		t.Errorf("Expected 5 tokens for mixed text, got %d", got)
+	}
+}
+
+func TestTruncateTokens(t *testing.T) {
+	text := strings.Repeat("前", 500) + strings.Repeat("后", 500)
+
+	truncated := TruncateTokens(text, 100)
+	if got := CountTokens(truncated); got > 100 {
+		t.Errorf("Expected at most 100 tokens, got %d", got)
+	}
+	if !strings.HasPrefix(truncated, "前") || !strings.HasSuffix(truncated, "后") {
+		t.Errorf("Expected head and tail to be kept, got %q", truncated)
+	}
+	if TruncateTokens("short", 100) != "short" {
+		t.Error("Expected text within budget to be unchanged")
+	}
+}
+
+func items(section string, texts ...string) []PromptItem {
+	result := make([]PromptItem, 0, len(texts))
+	for i, text := range texts {
+		result = append(result, PromptItem{Key: section + string(rune('a'+i)), Text: text})
+	}
+	return result
+}
+
+func TestAssembleSectionBudget(t *testing.T) {
+	assembler := NewPromptAssembler(0, nil)
+	result := assembler.Assemble(nil, []*PromptSection{
+		{Name: SectionSemantic, Budget: 25, Items: items("s", strings.Repeat("字", 10), strings.Repeat("字", 10), strings.Repeat("字", 10))},
+	})
+
+	if got := len(result.Sections[SectionSemantic]); got != 2 {
+		t.Fatalf("Expected 2 semantic items kept, got %d", got)
+	}
+	if len(result.Dropped) != 1 || result.Dropped[0].Key != "sc" || result.Dropped[0].Reason != DropReasonSectionBudget {
+		t.Errorf("Expected last item dropped by section budget, got %+v", result.Dropped)
+	}
+}
+
+func TestAssembleTrimOrder(t *testing.T) {
+	required := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "你是助手"},
+		{Role: openai.ChatMessageRoleUser, Content: "问题"},
+	}
+	requiredTokens := CountMessagesTokens(required)
+
+	// 预算只够放下必需消息和画像
+	assembler := NewPromptAssembler(requiredTokens+10, nil)
+	result := assembler.Assemble(required, []*PromptSection{
+		{Name: SectionProfile, Items: items("p", strings.Repeat("画", 10))},
+		{Name: SectionSemantic, Items: items("s", strings.Repeat("语", 10))},
+		{Name: SectionHistory, Items: items("h", strings.Repeat("新", 10), strings.Repeat("旧", 10))},
+	})
+
+	if result.Tokens > result.Budget {
+		t.Errorf("Expected tokens %d within budget %d", result.Tokens, result.Budget)
+	}
+	if len(result.Sections[SectionProfile]) != 1 {
+		t.Errorf("Expected profile to survive, got %+v", result.Sections)
+	}
+	if _, ok := result.Sections[SectionSemantic]; ok {
+		t.Error("Expected semantic section to be trimmed first")
+	}
+
+	var order []string
+	for _, d := range result.Dropped {
+		order = append(order, d.Key)
+	}
+	if strings.Join(order, ",") != "sa,hb,ha" {
+		t.Errorf("Expected semantic then oldest history dropped, got %v", order)
+	}
+}
+
+func TestAssembleTruncatesRequired(t *testing.T) {
+	required := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "你是助手"},
+		{Role: openai.ChatMessageRoleUser, Content: strings.Repeat("长", 2000)},
+	}
+
+	assembler := NewPromptAssembler(500, nil)
+	result := assembler.Assemble(required, []*PromptSection{
+		{Name: SectionSummary, Items: items("m", "摘要")},
+	})
+
+	if result.Tokens > 500 {
+		t.Errorf("Expected tokens within budget, got %d", result.Tokens)
+	}
+	if result.Required[0].Content != "你是助手" {
+		t.Errorf("Expected system prompt untouched, got %q", result.Required[0].Content)
+	}
+	if len(result.Required[1].Content) >= len(required[1].Content) {
+		t.Error("Expected user message to be truncated")
+	}
+
+	var truncated bool
+	for _, d := range result.Dropped {
+		if d.Section == SectionRequired && d.Reason == DropReasonTruncated {
+			truncated = true
+		}
+	}
+	if !truncated {
+		t.Errorf("Expected truncation to be reported, got %+v", result.Dropped)
+	}
+}
@@ -3,6 +3,7 @@ package memory
 import (
 	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
 // ChunkStrategy 分块策略接口
@@ -452,8 +453,11 @@ func (c *FixedChunker) Chunk(text string, maxSize, overlap int) []Chunk {
 
 	for startIdx < len(text) {
 		endIdx := startIdx + maxSize
-		if endIdx > len(text) {
+		if endIdx >= len(text) {
 			endIdx = len(text)
+		} else {
+			// 回退到 UTF-8 字符边界，避免截断多字节字符
+			endIdx = alignRuneStart(text, endIdx, startIdx)
 		}
 
 		chunkText := text[startIdx:endIdx]
@@ -473,10 +477,19 @@ func (c *FixedChunker) Chunk(text string, maxSize, overlap int) []Chunk {
 		})
 
 		chunkIdx++
-		startIdx = endIdx - overlap // 应用重叠
-		if startIdx < 0 {
-			startIdx = 0
+		if endIdx >= len(text) {
+			break
+		}
+
+		// 应用重叠，但必须保证向前推进，否则会死循环
+		nextStart := endIdx - overlap
+		if nextStart <= startIdx {
+			nextStart = endIdx
+		}
+		for nextStart < endIdx && !utf8.RuneStart(text[nextStart]) {
+			nextStart++
 		}
+		startIdx = nextStart
 	}
 
 	// 更新总块数
@@ -523,9 +536,30 @@ func getOverlapText(text string, startIdx, endIdx int) string {
 	if endIdx > len(text) {
 		endIdx = len(text)
 	}
+	// 起点对齐到字符边界，避免重叠文本以半个多字节字符开头
+	for startIdx < endIdx && !utf8.RuneStart(text[startIdx]) {
+		startIdx++
+	}
 	if startIdx >= endIdx {
 		return ""
 	}
 	return text[startIdx:endIdx]
 }
 
+// alignRuneStart 将 idx 向前回退到 UTF-8 字符起始位置（不小于 floor）
+// 若回退到 floor 仍无法截断（单个字符超过块大小），则向后推进到下一个字符边界
+func alignRuneStart(text string, idx, floor int) int {
+	aligned := idx
+	for aligned > floor && !utf8.RuneStart(text[aligned]) {
+		aligned--
+	}
+	if aligned > floor {
+		return aligned
+	}
+	aligned = idx
+	for aligned < len(text) && !utf8.RuneStart(text[aligned]) {
+		aligned++
+	}
+	return aligned
+}
+
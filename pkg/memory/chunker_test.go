@@ -1,7 +1,9 @@
 package memory
 
 import (
+	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestParagraphChunker(t *testing.T) {
@@ -68,6 +70,31 @@ func TestFixedChunker(t *testing.T) {
 	}
 }
 
+func TestFixedChunkerTerminatesAndKeepsUTF8(t *testing.T) {
+	chunker := &FixedChunker{
+		config: DefaultChunkConfig(),
+	}
+
+	// 多字节字符 + 较大重叠，曾导致末尾块无限循环
+	text := strings.Repeat("中文内容测试", 20)
+
+	chunks := chunker.Chunk(text, 31, 10)
+	if len(chunks) == 0 {
+		t.Fatal("Expected chunks, got none")
+	}
+	if chunks[len(chunks)-1].EndIdx != len(text) {
+		t.Errorf("Expected last chunk to end at %d, got %d", len(text), chunks[len(chunks)-1].EndIdx)
+	}
+	for i, chunk := range chunks {
+		if !utf8.ValidString(chunk.Text) {
+			t.Errorf("Chunk %d is not valid UTF-8: %q", i, chunk.Text)
+		}
+		if i > 0 && chunk.StartIdx <= chunks[i-1].StartIdx {
+			t.Errorf("Chunk %d does not advance: start %d, previous start %d", i, chunk.StartIdx, chunks[i-1].StartIdx)
+		}
+	}
+}
+
 func TestNewChunker(t *testing.T) {
 	// 测试段落策略
 	config := ChunkConfig{
@@ -0,0 +1,197 @@
+package memory
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultFactConfidence 模型未给出置信度时使用的默认值
+	DefaultFactConfidence float32 = 0.6
+	// MaxFactConfidence 置信度上限，保留被新信息推翻的余地
+	MaxFactConfidence float32 = 0.99
+	// ConflictReplaceRatio 新值置信度不低于旧值的该比例时替换旧值，否则保留旧值
+	ConflictReplaceRatio float32 = 0.8
+	// MaxProfileHistory meta 中保留的历史值条数
+	MaxProfileHistory = 5
+)
+
+// 历史值状态
+const (
+	ProfileHistoryReplaced = "replaced" // 被新值替换的旧值
+	ProfileHistoryRejected = "rejected" // 与现有值冲突但置信度不足、未被采纳的新值
+)
+
+// 当前值来源
+const (
+	ProfileSourceAutoExtract = "auto_extract" // 对话中自动提取
+	ProfileSourceManual      = "manual"       // 人工设置或修正
+)
+
+// KeyFact 从对话中提取的关键事实
+type KeyFact struct {
+	Key        string  `json:"key"`
+	Value      string  `json:"value"`
+	Confidence float32 `json:"confidence"`
+}
+
+// ProfileHistory 画像属性的历史取值
+type ProfileHistory struct {
+	Value       string    `json:"value"`
+	Confidence  float32   `json:"confidence"`
+	SourceMsgID *int64    `json:"source_msg_id,omitempty"`
+	Status      string    `json:"status"`
+	At          time.Time `json:"at"`
+}
+
+// ProfileMeta user_profile.meta 中记录的合并信息
+type ProfileMeta struct {
+	Mentions  int              `json:"mentions"`             // 当前值被提及的次数
+	Source    string           `json:"source,omitempty"`     // 当前值来源
+	UpdatedBy string           `json:"updated_by,omitempty"` // 人工修正时的操作人
+	History   []ProfileHistory `json:"history,omitempty"`    // 冲突产生的历史值，最新的在前
+}
+
+// ProfileValue 画像属性的当前取值
+type ProfileValue struct {
+	Value       string
+	Confidence  float32
+	SourceMsgID *int64
+	Meta        ProfileMeta
+}
+
+// ParseProfileMeta 解析 meta 字段，格式不合法时返回空 meta
+func ParseProfileMeta(raw string) ProfileMeta {
+	meta := ProfileMeta{}
+	if strings.TrimSpace(raw) == "" {
+		return meta
+	}
+	_ = json.Unmarshal([]byte(raw), &meta)
+	return meta
+}
+
+// String 序列化为 JSON 字符串
+func (m ProfileMeta) String() string {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// MergeFact 将新提取的事实与已有画像值合并
+// - 无旧值：直接采用
+// - 值相同：强化，置信度按 1-(1-a)(1-b) 叠加
+// - 值冲突：新值足够可信则替换并把旧值记入历史，否则保留旧值、降低其置信度并记录被拒绝的新值
+func MergeFact(existing *ProfileValue, fact KeyFact, sourceMsgID *int64, now time.Time) ProfileValue {
+	confidence := normalizeConfidence(fact.Confidence)
+
+	if existing == nil {
+		return ProfileValue{
+			Value:       fact.Value,
+			Confidence:  confidence,
+			SourceMsgID: sourceMsgID,
+			Meta:        ProfileMeta{Mentions: 1, Source: ProfileSourceAutoExtract},
+		}
+	}
+
+	merged := *existing
+	merged.Meta.History = append([]ProfileHistory(nil), existing.Meta.History...)
+	if merged.Meta.Mentions <= 0 {
+		merged.Meta.Mentions = 1
+	}
+
+	if sameFactValue(existing.Value, fact.Value) {
+		merged.Confidence = capConfidence(1 - (1-existing.Confidence)*(1-confidence))
+		merged.SourceMsgID = sourceMsgID
+		merged.Meta.Mentions++
+		return merged
+	}
+
+	if confidence >= existing.Confidence*ConflictReplaceRatio {
+		merged.Meta.History = pushHistory(merged.Meta.History, ProfileHistory{
+			Value:       existing.Value,
+			Confidence:  existing.Confidence,
+			SourceMsgID: existing.SourceMsgID,
+			Status:      ProfileHistoryReplaced,
+			At:          now,
+		})
+		merged.Value = fact.Value
+		merged.Confidence = confidence
+		merged.SourceMsgID = sourceMsgID
+		merged.Meta.Mentions = 1
+		merged.Meta.Source = ProfileSourceAutoExtract
+		merged.Meta.UpdatedBy = ""
+		return merged
+	}
+
+	// 旧值被质疑，置信度按新证据强度衰减
+	merged.Confidence = existing.Confidence * (1 - confidence/2)
+	merged.Meta.History = pushHistory(merged.Meta.History, ProfileHistory{
+		Value:       fact.Value,
+		Confidence:  confidence,
+		SourceMsgID: sourceMsgID,
+		Status:      ProfileHistoryRejected,
+		At:          now,
+	})
+	return merged
+}
+
+// OverrideValue 人工设置画像值：值变化时旧值记入历史，来源标记为 manual
+// 人工修正不关联对话消息，source_msg_id 置空
+func OverrideValue(existing *ProfileValue, value string, confidence float32, operator string, now time.Time) ProfileValue {
+	confidence = capConfidence(confidence)
+	if confidence <= 0 {
+		confidence = MaxFactConfidence
+	}
+
+	merged := ProfileValue{
+		Value:      value,
+		Confidence: confidence,
+		Meta:       ProfileMeta{Mentions: 1, Source: ProfileSourceManual, UpdatedBy: operator},
+	}
+	if existing == nil {
+		return merged
+	}
+
+	merged.Meta.History = append([]ProfileHistory(nil), existing.Meta.History...)
+	if !sameFactValue(existing.Value, value) {
+		merged.Meta.History = pushHistory(merged.Meta.History, ProfileHistory{
+			Value:       existing.Value,
+			Confidence:  existing.Confidence,
+			SourceMsgID: existing.SourceMsgID,
+			Status:      ProfileHistoryReplaced,
+			At:          now,
+		})
+	} else if existing.Meta.Mentions > 0 {
+		merged.Meta.Mentions = existing.Meta.Mentions
+	}
+	return merged
+}
+
+func pushHistory(history []ProfileHistory, item ProfileHistory) []ProfileHistory {
+	history = append([]ProfileHistory{item}, history...)
+	if len(history) > MaxProfileHistory {
+		history = history[:MaxProfileHistory]
+	}
+	return history
+}
+
+func sameFactValue(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+func normalizeConfidence(c float32) float32 {
+	if c <= 0 {
+		return DefaultFactConfidence
+	}
+	return capConfidence(c)
+}
+
+func capConfidence(c float32) float32 {
+	if c > MaxFactConfidence {
+		return MaxFactConfidence
+	}
+	return c
+}
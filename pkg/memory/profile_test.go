@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeFactNew(t *testing.T) {
+	msgID := int64(1)
+	merged := MergeFact(nil, KeyFact{Key: "city", Value: "北京"}, &msgID, time.Now())
+
+	if merged.Value != "北京" {
+		t.Errorf("Expected value 北京, got %s", merged.Value)
+	}
+	if merged.Confidence != DefaultFactConfidence {
+		t.Errorf("Expected default confidence %v, got %v", DefaultFactConfidence, merged.Confidence)
+	}
+	if merged.Meta.Mentions != 1 {
+		t.Errorf("Expected 1 mention, got %d", merged.Meta.Mentions)
+	}
+}
+
+func TestMergeFactReinforce(t *testing.T) {
+	oldID, newID := int64(1), int64(2)
+	existing := &ProfileValue{Value: "Go", Confidence: 0.6, SourceMsgID: &oldID, Meta: ProfileMeta{Mentions: 1}}
+
+	merged := MergeFact(existing, KeyFact{Key: "language", Value: " go ", Confidence: 0.5}, &newID, time.Now())
+
+	if merged.Value != "Go" {
+		t.Errorf("Expected value to stay Go, got %s", merged.Value)
+	}
+	if merged.Confidence <= existing.Confidence {
+		t.Errorf("Expected confidence to increase from %v, got %v", existing.Confidence, merged.Confidence)
+	}
+	if merged.Meta.Mentions != 2 {
+		t.Errorf("Expected 2 mentions, got %d", merged.Meta.Mentions)
+	}
+	if merged.SourceMsgID == nil || *merged.SourceMsgID != newID {
+		t.Errorf("Expected source_msg_id %d, got %v", newID, merged.SourceMsgID)
+	}
+	if len(merged.Meta.History) != 0 {
+		t.Errorf("Expected no history, got %d", len(merged.Meta.History))
+	}
+}
+
+func TestMergeFactConflictReplace(t *testing.T) {
+	oldID, newID := int64(1), int64(2)
+	existing := &ProfileValue{Value: "北京", Confidence: 0.7, SourceMsgID: &oldID, Meta: ProfileMeta{Mentions: 3}}
+
+	merged := MergeFact(existing, KeyFact{Key: "city", Value: "上海", Confidence: 0.9}, &newID, time.Now())
+
+	if merged.Value != "上海" {
+		t.Errorf("Expected value 上海, got %s", merged.Value)
+	}
+	if merged.Meta.Mentions != 1 {
+		t.Errorf("Expected mentions reset to 1, got %d", merged.Meta.Mentions)
+	}
+	if len(merged.Meta.History) != 1 || merged.Meta.History[0].Value != "北京" || merged.Meta.History[0].Status != ProfileHistoryReplaced {
+		t.Errorf("Expected replaced history for 北京, got %+v", merged.Meta.History)
+	}
+	// 原值不应被修改
+	if existing.Value != "北京" || len(existing.Meta.History) != 0 {
+		t.Errorf("Existing value should not be mutated, got %+v", existing)
+	}
+}
+
+func TestMergeFactConflictReject(t *testing.T) {
+	oldID, newID := int64(1), int64(2)
+	existing := &ProfileValue{Value: "北京", Confidence: 0.95, SourceMsgID: &oldID, Meta: ProfileMeta{Mentions: 5}}
+
+	merged := MergeFact(existing, KeyFact{Key: "city", Value: "上海", Confidence: 0.4}, &newID, time.Now())
+
+	if merged.Value != "北京" {
+		t.Errorf("Expected value to stay 北京, got %s", merged.Value)
+	}
+	if merged.Confidence >= existing.Confidence {
+		t.Errorf("Expected confidence to decrease from %v, got %v", existing.Confidence, merged.Confidence)
+	}
+	if merged.SourceMsgID == nil || *merged.SourceMsgID != oldID {
+		t.Errorf("Expected source_msg_id to stay %d, got %v", oldID, merged.SourceMsgID)
+	}
+	if len(merged.Meta.History) != 1 || merged.Meta.History[0].Status != ProfileHistoryRejected {
+		t.Errorf("Expected rejected history entry, got %+v", merged.Meta.History)
+	}
+}
+
+func TestOverrideValue(t *testing.T) {
+	oldID := int64(1)
+	existing := &ProfileValue{Value: "北京", Confidence: 0.9, SourceMsgID: &oldID, Meta: ProfileMeta{Mentions: 4, Source: ProfileSourceAutoExtract}}
+
+	merged := OverrideValue(existing, "杭州", 0, "support_01", time.Now())
+
+	if merged.Value != "杭州" || merged.Confidence != MaxFactConfidence {
+		t.Errorf("Unexpected override result: %+v", merged)
+	}
+	if merged.SourceMsgID != nil {
+		t.Errorf("Expected source_msg_id to be cleared, got %v", *merged.SourceMsgID)
+	}
+	if merged.Meta.Source != ProfileSourceManual || merged.Meta.UpdatedBy != "support_01" {
+		t.Errorf("Unexpected meta: %+v", merged.Meta)
+	}
+	if len(merged.Meta.History) != 1 || merged.Meta.History[0].Value != "北京" || *merged.Meta.History[0].SourceMsgID != oldID {
+		t.Errorf("Expected replaced history for 北京, got %+v", merged.Meta.History)
+	}
+}
+
+func TestProfileMetaRoundTrip(t *testing.T) {
+	meta := ProfileMeta{Mentions: 2, History: []ProfileHistory{{Value: "a", Status: ProfileHistoryReplaced}}}
+
+	parsed := ParseProfileMeta(meta.String())
+	if parsed.Mentions != 2 || len(parsed.History) != 1 {
+		t.Errorf("Unexpected parsed meta: %+v", parsed)
+	}
+
+	if empty := ParseProfileMeta("not json"); empty.Mentions != 0 {
+		t.Errorf("Expected empty meta for invalid json, got %+v", empty)
+	}
+}
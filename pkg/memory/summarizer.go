@@ -1,11 +1,13 @@
 package memory
 
 import (
-	"ai_task/constant"
-	"ai_task/pkg/clients/llm_model"
+	"ai_task/pkg/clients/llm"
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/usage"
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/sashabaranov/go-openai"
@@ -14,13 +16,16 @@ import (
 
 // Summarizer 摘要生成器
 type Summarizer struct {
-	llmClient *llm_model.ClientChatModel
+	llmClient     llm.ChatModel // 摘要
+	extractClient llm.ChatModel // 关键事实提取
 }
 
-// NewSummarizer 创建摘要生成器
-func NewSummarizer() *Summarizer {
+// NewSummarizer 创建摘要生成器，按用途选择摘要和事实提取的模型，models 为 nil 时使用全局路由
+func NewSummarizer(models llm.Models) *Summarizer {
+	models = llm.OrDefaultModels(models)
 	return &Summarizer{
-		llmClient: llm_model.GetInstance(),
+		llmClient:     models.Model(llm.RoleSummarization),
+		extractClient: models.Model(llm.RoleExtraction),
 	}
 }
 
@@ -35,20 +40,27 @@ func (s *Summarizer) SummarizeConversation(ctx context.Context, messages []opena
 	conversationText := s.buildConversationText(messages)
 
 	// 构建摘要提示
-	summaryPrompt := fmt.Sprintf(constant.SummaryUserPromptTemplate, conversationText)
+	system, err := prompt.Render(ctx, prompt.MemorySummarySystem, nil)
+	if err != nil {
+		return "", err
+	}
+	user, err := prompt.Render(ctx, prompt.MemorySummaryUser, map[string]any{"Conversation": conversationText})
+	if err != nil {
+		return "", err
+	}
 
 	summaryMessages := []openai.ChatCompletionMessage{
 		{
 			Role:    openai.ChatMessageRoleSystem,
-			Content: constant.SummarySystemPrompt,
+			Content: system.Text,
 		},
 		{
 			Role:    openai.ChatMessageRoleUser,
-			Content: summaryPrompt,
+			Content: user.Text,
 		},
 	}
 
-	summary, err := s.llmClient.PostChatCompletionsNonStreamContent(ctx, summaryMessages)
+	summary, err := s.llmClient.PostChatCompletionsNonStreamContent(prompt.WithRendered(usage.WithRole(ctx, usage.RoleSummarizer), system, user), summaryMessages)
 	if err != nil {
 		log.Warnf("Failed to generate summary: %v", err)
 		return "", fmt.Errorf("failed to generate summary: %w", err)
@@ -57,57 +69,87 @@ func (s *Summarizer) SummarizeConversation(ctx context.Context, messages []opena
 	return strings.TrimSpace(summary), nil
 }
 
-// 从对话中提取关键事实（用于长期记忆）
+// 从对话中提取关键事实（用于长期记忆），每条事实附带置信度
 // 参考 LangChain 的记忆整合模式：让 LLM 决定如何扩展或整合记忆状态
-func (s *Summarizer) ExtractKeyFacts(ctx context.Context, messages []openai.ChatCompletionMessage) (map[string]string, error) {
+func (s *Summarizer) ExtractKeyFacts(ctx context.Context, messages []openai.ChatCompletionMessage) ([]KeyFact, error) {
 	if len(messages) == 0 {
 		return nil, nil
 	}
 
 	conversationText := s.buildConversationText(messages)
 
-	extractPrompt := fmt.Sprintf(constant.ExtractKeyFactsUserPromptTemplate, conversationText)
+	system, err := prompt.Render(ctx, prompt.MemoryExtractFactsSystem, nil)
+	if err != nil {
+		return nil, err
+	}
+	user, err := prompt.Render(ctx, prompt.MemoryExtractFactsUser, map[string]any{"Conversation": conversationText})
+	if err != nil {
+		return nil, err
+	}
 
 	extractMessages := []openai.ChatCompletionMessage{
 		{
 			Role:    openai.ChatMessageRoleSystem,
-			Content: constant.ExtractKeyFactsSystemPrompt,
+			Content: system.Text,
 		},
 		{
 			Role:    openai.ChatMessageRoleUser,
-			Content: extractPrompt,
+			Content: user.Text,
 		},
 	}
 
-	result, err := s.llmClient.PostChatCompletionsNonStreamContent(ctx, extractMessages)
+	result, err := s.extractClient.PostChatCompletionsNonStreamContent(prompt.WithRendered(usage.WithRole(ctx, usage.RoleExtractor), system, user), extractMessages)
 	if err != nil {
 		log.Warnf("Failed to extract key facts: %v", err)
 		return nil, fmt.Errorf("failed to extract key facts: %w", err)
 	}
 
-	// 解析 JSON 结果
-	facts := make(map[string]string)
-	if result != "" && result != "{}" {
-		// 清理响应内容，移除可能的 markdown 代码块标记
-		cleanedResult := cleanJSONResponse(result)
-
-		// 尝试解析 JSON
-		var parsedFacts map[string]interface{}
-		if err := json.Unmarshal([]byte(cleanedResult), &parsedFacts); err == nil {
-			for key, value := range parsedFacts {
-				if strValue, ok := value.(string); ok {
-					facts[key] = strValue
-				} else {
-					// 如果不是字符串，转换为字符串
-					facts[key] = fmt.Sprintf("%v", value)
-				}
-			}
-		} else {
-			log.Debugf("Failed to parse extracted facts JSON: %v, raw: %s", err, result)
+	return parseKeyFacts(result), nil
+}
+
+// parseKeyFacts 解析提取结果，兼容 {"key": "value"} 与 {"key": {"value": "...", "confidence": 0.9}} 两种格式
+func parseKeyFacts(result string) []KeyFact {
+	if result == "" || result == "{}" {
+		return nil
+	}
+
+	// 清理响应内容，移除可能的 markdown 代码块标记
+	cleanedResult := cleanJSONResponse(result)
+
+	var parsedFacts map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(cleanedResult), &parsedFacts); err != nil {
+		log.Debugf("Failed to parse extracted facts JSON: %v, raw: %s", err, result)
+		return nil
+	}
+
+	facts := make([]KeyFact, 0, len(parsedFacts))
+	for key, raw := range parsedFacts {
+		fact := KeyFact{Key: strings.TrimSpace(key)}
+
+		var detail struct {
+			Value      interface{} `json:"value"`
+			Confidence float32     `json:"confidence"`
+		}
+		var plain interface{}
+		if err := json.Unmarshal(raw, &detail); err == nil && detail.Value != nil {
+			fact.Value = fmt.Sprintf("%v", detail.Value)
+			fact.Confidence = detail.Confidence
+		} else if err := json.Unmarshal(raw, &plain); err == nil && plain != nil {
+			// 如果不是字符串，转换为字符串
+			fact.Value = fmt.Sprintf("%v", plain)
 		}
+
+		fact.Value = strings.TrimSpace(fact.Value)
+		if fact.Key == "" || fact.Value == "" {
+			continue
+		}
+		facts = append(facts, fact)
 	}
 
-	return facts, nil
+	// map 遍历无序，按 key 排序保证结果稳定
+	sort.Slice(facts, func(i, j int) bool { return facts[i].Key < facts[j].Key })
+
+	return facts
 }
 
 // cleanJSONResponse 清理响应内容，移除 markdown 代码块标记
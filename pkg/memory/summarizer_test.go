@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"testing"
+)
+
+func TestParseKeyFacts(t *testing.T) {
+	result := "```json\n{\"city\": {\"value\": \"上海\", \"confidence\": 0.9}, \"age\": 30, \"empty\": \"\"}\n```"
+
+	facts := parseKeyFacts(result)
+	if len(facts) != 2 {
+		t.Fatalf("Expected 2 facts, got %d: %+v", len(facts), facts)
+	}
+
+	// 按 key 排序
+	if facts[0].Key != "age" || facts[0].Value != "30" || facts[0].Confidence != 0 {
+		t.Errorf("Unexpected plain fact: %+v", facts[0])
+	}
+	if facts[1].Key != "city" || facts[1].Value != "上海" || facts[1].Confidence != 0.9 {
+		t.Errorf("Unexpected detailed fact: %+v", facts[1])
+	}
+
+	if facts := parseKeyFacts("{}"); len(facts) != 0 {
+		t.Errorf("Expected no facts, got %+v", facts)
+	}
+	if facts := parseKeyFacts("not json"); len(facts) != 0 {
+		t.Errorf("Expected no facts for invalid json, got %+v", facts)
+	}
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"unicode"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// token 估算参数，按 OpenAI/通义 BPE 分词器的平均表现取偏保守的值，宁可高估也不要超出上下文窗口
+const (
+	asciiCharsPerToken = 4 // 英文、数字按约 4 个字符 1 个 token
+	wideLetterWeight   = 2 // 非 ASCII 字母（拉丁扩展、西里尔等）按约 2 个字符 1 个 token
+	symbolTokens       = 2 // emoji 等其它符号通常被拆为多个字节 token
+	// MessageTokenOverhead 每条消息的角色、分隔符开销
+	MessageTokenOverhead = 4
+	// ReplyTokenOverhead 助手回复起始标记的开销，每次请求计一次
+	ReplyTokenOverhead = 3
+)
+
+// CountTokens 估算文本的 token 数
+// 中日韩文字没有空格分词，逐字计 1 个 token；英文按单词长度折算，标点各计 1 个
+func CountTokens(text string) int {
+	tokens := 0
+	wordWeight := 0 // 当前单词累计的字符权重，以 ASCII 字符为单位
+
+	flush := func() {
+		if wordWeight > 0 {
+			tokens += (wordWeight + asciiCharsPerToken - 1) / asciiCharsPerToken
+			wordWeight = 0
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flush()
+			tokens++
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			wordWeight++
+		case unicode.IsSpace(r):
+			flush()
+		case r < unicode.MaxASCII:
+			flush()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			wordWeight += asciiCharsPerToken / wideLetterWeight
+		default:
+			flush()
+			tokens += symbolTokens
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// CountMessageTokens 估算单条消息的 token 数，含消息开销
+func CountMessageTokens(msg openai.ChatCompletionMessage) int {
+	return CountTokens(msg.Content) + MessageTokenOverhead
+}
+
+// CountMessagesTokens 估算整组消息的 token 数，含回复起始开销
+func CountMessagesTokens(messages []openai.ChatCompletionMessage) int {
+	tokens := ReplyTokenOverhead
+	for _, msg := range messages {
+		tokens += CountMessageTokens(msg)
+	}
+	return tokens
+}
+
+// isCJK 中日韩文字及全角标点
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r) ||
+		(r >= 0x3000 && r <= 0x303F) || // CJK 标点
+		(r >= 0xFF00 && r <= 0xFFEF) // 全角字符
+}
+
+// truncateMarker 截断处插入的提示
+const truncateMarker = "\n……（内容过长，已截断）……\n"
+
+// TruncateTokens 将文本截断到不超过 maxTokens，保留开头和结尾，中间以提示替代
+// 开头通常是问题背景，结尾通常是真正的提问，两者都保留比只留一端更有用
+func TruncateTokens(text string, maxTokens int) string {
+	if CountTokens(text) <= maxTokens {
+		return text
+	}
+
+	available := maxTokens - CountTokens(truncateMarker)
+	if available <= 0 {
+		return ""
+	}
+
+	runes := []rune(text)
+	build := func(keep int) string {
+		head := keep * 2 / 3
+		tail := keep - head
+		return string(runes[:head]) + truncateMarker + string(runes[len(runes)-tail:])
+	}
+
+	// 二分查找能容纳的最多字符数
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if CountTokens(build(mid)) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	return build(lo)
+}
@@ -0,0 +1,109 @@
+package prompt
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type localeKey struct{}
+
+type renderedKey struct{}
+
+// WithLocale 设置本次请求使用的提示词语言，无法识别的语言不生效
+func WithLocale(ctx context.Context, locale string) context.Context {
+	if locale = NormalizeLocale(locale); locale == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFrom 读取 ctx 中的提示词语言，未设置时返回空字符串（使用默认语言）
+func LocaleFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
+
+// NormalizeLocale 把 zh-CN、en_US、English、中文 等写法归一为 zh / en，无法识别时返回空字符串
+// 支持 Accept-Language 形式的输入，取第一个语言
+func NormalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, ",;"); i >= 0 {
+		locale = strings.TrimSpace(locale[:i])
+	}
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+
+	switch locale {
+	case LocaleZH, "chinese", "中文", "简体中文", "汉语":
+		return LocaleZH
+	case LocaleEN, "english", "英文", "英语":
+		return LocaleEN
+	default:
+		return ""
+	}
+}
+
+// UserLocaleResolver 查询用户偏好的提示词语言，没有偏好时返回空字符串
+type UserLocaleResolver func(ctx context.Context, userID string) string
+
+var (
+	resolverMu   sync.RWMutex
+	userResolver UserLocaleResolver
+)
+
+// SetUserLocaleResolver 设置用户语言偏好的查询方式，由服务层在启动时注入
+func SetUserLocaleResolver(resolver UserLocaleResolver) {
+	resolverMu.Lock()
+	userResolver = resolver
+	resolverMu.Unlock()
+}
+
+// ResolveLocale 确定请求使用的语言：请求显式指定的语言优先，其次为用户偏好，都没有时返回空字符串（使用默认语言）
+func ResolveLocale(ctx context.Context, userID, requested string) string {
+	if locale := NormalizeLocale(requested); locale != "" {
+		return locale
+	}
+	if userID == "" {
+		return ""
+	}
+
+	resolverMu.RLock()
+	resolver := userResolver
+	resolverMu.RUnlock()
+	if resolver == nil {
+		return ""
+	}
+	return NormalizeLocale(resolver(ctx, userID))
+}
+
+// WithRendered 在 ctx 中记录本次模型调用使用的提示词，模型客户端据此在日志中输出模板名与版本
+func WithRendered(ctx context.Context, rendered ...Rendered) context.Context {
+	refs := make([]string, 0, len(rendered))
+	for _, r := range rendered {
+		refs = append(refs, r.Ref())
+	}
+	return context.WithValue(ctx, renderedKey{}, refs)
+}
+
+// Refs 读取 ctx 中记录的提示词，形如 task.planner.system@1/zh
+func Refs(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	refs, _ := ctx.Value(renderedKey{}).([]string)
+	return refs
+}
+
+// LogCall 记录模型调用使用的提示词模板与版本，ctx 中没有记录时不输出
+func LogCall(ctx context.Context, client, model string) {
+	if refs := Refs(ctx); len(refs) > 0 {
+		log.Infof("%s call %s with prompts %s", client, model, strings.Join(refs, ","))
+	}
+}
@@ -0,0 +1,49 @@
+package prompt
+
+// 内置模板名，对应 templates/<locale>/<name>.tmpl，覆盖目录中使用相同文件名
+const (
+	// 任务规划：{Goal, Context, Constraints, Preferences, References}
+	TaskPlannerSystem = "task.planner.system"
+	TaskPlannerUser   = "task.planner.user"
+
+	// 阶段细化：{Goal, PhaseName, PhaseDescription, Steps}
+	TaskRefinePhaseSystem = "task.refine_phase.system"
+	TaskRefinePhaseUser   = "task.refine_phase.user"
+
+	// 步骤决策：{Goal, PhaseID, PhaseName, Step, Errors, Decisions, Findings, Tools, Guidance}，Guidance 为 {Question, Answer}
+	// 工具观察：{Tool, Result, Error, Plan}，Plan 不为空时要求模型重读计划
+	TaskExecutorSystem      = "task.executor.system"
+	TaskExecutorDecision    = "task.executor.decision"
+	TaskExecutorObservation = "task.executor.observation"
+
+	// 上下文摘要：{Content, MaxChars}
+	TaskContextSummarySystem = "task.context_summary.system"
+	TaskContextSummaryUser   = "task.context_summary.user"
+
+	// 系统提示词：稳定前缀无参数，动态提示词为 {TaskID, Goal, Status}
+	TaskStableSystemPrefix = "task.stable_system_prefix"
+	TaskDynamicSystem      = "task.dynamic_system"
+	TaskKVCachePrefix      = "task.kv_cache_prefix"
+
+	// 子代理：系统提示词为 task.agent.<role>，未知角色使用 TaskAgentDefault
+	// 用户提示词为 {Description, HasParent, Goal, CurrentPhase, Input}
+	TaskAgentPrefix  = "task.agent."
+	TaskAgentDefault = "task.agent.default"
+	TaskAgentUser    = "task.agent.user"
+
+	// 对话摘要与事实提取：{Conversation}
+	MemorySummarySystem      = "memory.summary.system"
+	MemorySummaryUser        = "memory.summary.user"
+	MemoryExtractFactsSystem = "memory.extract_facts.system"
+	MemoryExtractFactsUser   = "memory.extract_facts.user"
+
+	// 对话上下文段落：{Content}
+	ChatLongTermMemory      = "chat.long_term_memory"
+	ChatSemanticMemory      = "chat.semantic_memory"
+	ChatConversationSummary = "chat.conversation_summary"
+	ChatDocumentContext     = "chat.document_context"
+	ChatPreviousSummary     = "chat.previous_summary"
+
+	// 结构化输出修复：{Problems, Schema}，Schema 为 JSON 字符串
+	LLMStructuredRepair = "llm.structured_repair"
+)
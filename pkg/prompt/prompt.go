@@ -0,0 +1,264 @@
+// Package prompt 提示词模板注册表
+// 模板按 <locale>/<name>.tmpl 组织，使用 text/template 语法，首行以注释声明版本：{{/* version: 1 */ -}}
+// 内置模板随程序编译，覆盖目录下的同名文件覆盖内置模板，修改提示词无需重新编译
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	LocaleZH = "zh"
+	LocaleEN = "en"
+
+	// DefaultLocale 未指定语言或指定的语言没有对应模板时使用
+	DefaultLocale = LocaleZH
+
+	templateExt    = ".tmpl"
+	sourceEmbedded = "embedded"
+)
+
+//go:embed templates
+var embedded embed.FS
+
+// versionHeader 模板首行的版本声明
+var versionHeader = regexp.MustCompile(`^\{\{-?\s*/\*\s*version:\s*(\S+)\s*\*/\s*-?\}\}`)
+
+// Template 一个语言下的具名模板
+type Template struct {
+	Name    string
+	Version string
+	Locale  string
+	Source  string // embedded 或覆盖文件所在目录
+
+	tmpl     *template.Template
+	fallback *Template // 被覆盖的内置模板，覆盖模板执行失败时使用
+}
+
+// Rendered 渲染结果，Name 与 Version 用于记录模型调用使用的提示词
+type Rendered struct {
+	Name    string
+	Version string
+	Locale  string
+	Text    string
+}
+
+// Ref 形如 task.planner.system@2/zh，写入日志便于对照提示词变更与效果
+func (r Rendered) Ref() string {
+	return fmt.Sprintf("%s@%s/%s", r.Name, r.Version, r.Locale)
+}
+
+// Registry 提示词模板注册表
+type Registry struct {
+	mu            sync.RWMutex
+	defaultLocale string
+	templates     map[string]map[string]*Template // name -> locale -> 模板
+}
+
+// NewRegistry 创建空注册表，defaultLocale 为空时使用 DefaultLocale
+func NewRegistry(defaultLocale string) *Registry {
+	if defaultLocale = NormalizeLocale(defaultLocale); defaultLocale == "" {
+		defaultLocale = DefaultLocale
+	}
+	return &Registry{defaultLocale: defaultLocale, templates: make(map[string]map[string]*Template)}
+}
+
+// Load 加载 fsys 下的全部模板，后加载的同名模板覆盖先加载的
+// 目录结构为 <locale>/<name>.tmpl；缺少版本声明或解析失败的文件返回错误，已加载的模板不受影响
+func (r *Registry) Load(fsys fs.FS, source string) error {
+	var loaded []*Template
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(p) != templateExt {
+			return nil
+		}
+		locale, file := path.Split(p)
+		locale = NormalizeLocale(path.Clean(locale))
+		if locale == "" {
+			return fmt.Errorf("prompt %s: unknown locale directory", p)
+		}
+
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		t, err := parse(strings.TrimSuffix(file, templateExt), locale, source, string(data))
+		if err != nil {
+			return fmt.Errorf("prompt %s: %w", p, err)
+		}
+		loaded = append(loaded, t)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range loaded {
+		locales, ok := r.templates[t.Name]
+		if !ok {
+			locales = make(map[string]*Template)
+			r.templates[t.Name] = locales
+		}
+		if previous, ok := locales[t.Locale]; ok {
+			t.fallback = previous
+			if previous.fallback != nil {
+				t.fallback = previous.fallback
+			}
+			log.Infof("prompt %s/%s overridden by %s, version %s -> %s", t.Name, t.Locale, source, previous.Version, t.Version)
+		}
+		locales[t.Locale] = t
+	}
+	return nil
+}
+
+// parse 解析模板，文件末尾的一个换行不计入内容
+func parse(name, locale, source, text string) (*Template, error) {
+	match := versionHeader.FindStringSubmatch(text)
+	if match == nil {
+		return nil, fmt.Errorf("missing version header")
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(strings.TrimSuffix(text, "\n"))
+	if err != nil {
+		return nil, err
+	}
+	return &Template{Name: name, Version: match[1], Locale: locale, Source: source, tmpl: tmpl}, nil
+}
+
+// SetDefaultLocale 设置默认语言，无法识别时不做修改
+func (r *Registry) SetDefaultLocale(locale string) {
+	if locale = NormalizeLocale(locale); locale == "" {
+		return
+	}
+	r.mu.Lock()
+	r.defaultLocale = locale
+	r.mu.Unlock()
+}
+
+// Lookup 查找模板，locale 没有对应模板时使用默认语言
+func (r *Registry) Lookup(name, locale string) (*Template, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	locales, ok := r.templates[name]
+	if !ok {
+		return nil, false
+	}
+	if t, ok := locales[NormalizeLocale(locale)]; ok {
+		return t, true
+	}
+	t, ok := locales[r.defaultLocale]
+	return t, ok
+}
+
+// Templates 已加载的全部模板，按名称和语言排序
+func (r *Registry) Templates() []*Template {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	templates := make([]*Template, 0, len(r.templates)*2)
+	for _, locales := range r.templates {
+		for _, t := range locales {
+			templates = append(templates, t)
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].Name != templates[j].Name {
+			return templates[i].Name < templates[j].Name
+		}
+		return templates[i].Locale < templates[j].Locale
+	})
+	return templates
+}
+
+// Render 按 ctx 中的语言渲染模板，覆盖模板执行失败时退回内置模板
+func (r *Registry) Render(ctx context.Context, name string, data any) (Rendered, error) {
+	t, ok := r.Lookup(name, LocaleFrom(ctx))
+	if !ok {
+		return Rendered{}, fmt.Errorf("prompt %s not found", name)
+	}
+
+	text, err := t.execute(data)
+	if err != nil && t.fallback != nil {
+		log.Errorf("prompt %s@%s/%s from %s failed, use %s: %v", t.Name, t.Version, t.Locale, t.Source, t.fallback.Source, err)
+		t = t.fallback
+		text, err = t.execute(data)
+	}
+	if err != nil {
+		return Rendered{}, fmt.Errorf("prompt %s@%s/%s: %w", t.Name, t.Version, t.Locale, err)
+	}
+	return Rendered{Name: t.Name, Version: t.Version, Locale: t.Locale, Text: text}, nil
+}
+
+func (t *Template) execute(data any) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+var (
+	registry     *Registry
+	registryOnce sync.Once
+)
+
+// Default 全局注册表，初始只包含内置模板，由 Setup 设置默认语言并加载覆盖目录
+func Default() *Registry {
+	registryOnce.Do(func() {
+		registry = NewRegistry(DefaultLocale)
+		templates, err := fs.Sub(embedded, "templates")
+		if err == nil {
+			err = registry.Load(templates, sourceEmbedded)
+		}
+		if err != nil {
+			panic(fmt.Errorf("load embedded prompts: %w", err))
+		}
+	})
+	return registry
+}
+
+// Setup 设置全局注册表的默认语言并加载覆盖目录，overrideDir 为空时只使用内置模板
+// 覆盖目录中任一文件无效时整个目录不生效，避免只覆盖了部分提示词
+func Setup(defaultLocale, overrideDir string) error {
+	r := Default()
+	r.SetDefaultLocale(defaultLocale)
+	if overrideDir == "" {
+		return nil
+	}
+	if err := r.Load(os.DirFS(overrideDir), overrideDir); err != nil {
+		return fmt.Errorf("load prompt overrides from %s: %w", overrideDir, err)
+	}
+	return nil
+}
+
+// Render 使用全局注册表渲染模板
+func Render(ctx context.Context, name string, data any) (Rendered, error) {
+	return Default().Render(ctx, name, data)
+}
+
+// MustRender 同 Render，失败时 panic
+// 内置模板由测试保证可以渲染，只用于模板名与参数在代码中固定的场景
+func MustRender(ctx context.Context, name string, data any) Rendered {
+	rendered, err := Render(ctx, name, data)
+	if err != nil {
+		panic(err)
+	}
+	return rendered
+}
@@ -0,0 +1,248 @@
+package prompt
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type step struct {
+	Description string
+	Completed   bool
+}
+
+type errorRecord struct {
+	Error      string
+	Attempt    int
+	Resolution string
+}
+
+type decision struct {
+	Decision  string
+	Rationale string
+}
+
+type finding struct {
+	Category string
+	Content  string
+}
+
+type guidance struct {
+	Question string
+	Answer   string
+}
+
+type tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]string
+}
+
+// sampleData 内置模板的示例参数，与调用方传入的字段一致
+var sampleData = map[string]any{
+	TaskPlannerUser: map[string]any{
+		"Goal":        "实现一个缓存",
+		"Context":     "已有 Redis",
+		"Constraints": []string{"不引入新依赖"},
+		"Preferences": []string{"先写测试"},
+		"References":  "- 《缓存规范》使用统一前缀",
+	},
+	TaskRefinePhaseUser: map[string]any{
+		"Goal":             "实现一个缓存",
+		"PhaseName":        "实现",
+		"PhaseDescription": "编写代码",
+		"Steps":            []step{{Description: "实现接口", Completed: true}, {Description: "补充测试"}},
+	},
+	TaskExecutorDecision: map[string]any{
+		"Goal":      "实现一个缓存",
+		"PhaseID":   "phase_1",
+		"PhaseName": "实现",
+		"Step":      "实现接口",
+		"Errors":    []errorRecord{{Error: "连接失败", Attempt: 2, Resolution: "重试"}},
+		"Decisions": []decision{{Decision: "使用 LRU", Rationale: "实现简单"}},
+		"Findings":  []finding{{Category: "technical", Content: "已有过期策略"}},
+		"Guidance":  &guidance{Question: "步骤失败 3 次", Answer: "改用内存实现"},
+		"Tools":     []tool{{Name: "read_file", Description: "读取文件内容", Parameters: map[string]string{"path": "相对路径"}}},
+	},
+	TaskExecutorObservation: map[string]any{
+		"Tool":   "read_file",
+		"Result": "package cache",
+		"Error":  "",
+		"Plan":   "## 任务计划\n目标: 实现一个缓存",
+	},
+	TaskContextSummaryUser: map[string]any{"Content": "目标: 实现一个缓存", "MaxChars": 500},
+	TaskDynamicSystem:      map[string]any{"TaskID": "task_1", "Goal": "实现一个缓存", "Status": "in_progress"},
+	TaskAgentUser: map[string]any{
+		"Description":  "调研缓存方案",
+		"HasParent":    true,
+		"Goal":         "实现一个缓存",
+		"CurrentPhase": "phase_1",
+		"Input":        map[string]any{"scope": "redis", "limit": 3},
+	},
+	MemorySummaryUser:       map[string]any{"Conversation": "user: 你好"},
+	MemoryExtractFactsUser:  map[string]any{"Conversation": "user: 我住在杭州"},
+	ChatLongTermMemory:      map[string]any{"Content": "- city: 杭州"},
+	ChatSemanticMemory:      map[string]any{"Content": "user: 你好"},
+	ChatConversationSummary: map[string]any{"Content": "用户询问了缓存"},
+	ChatDocumentContext:     map[string]any{"Content": "[1] 缓存规范"},
+	ChatPreviousSummary:     map[string]any{"Content": "用户询问了缓存"},
+	LLMStructuredRepair:     map[string]any{"Problems": []string{"$: 缺少必填字段 phases"}, "Schema": `{"type":"object"}`},
+}
+
+func TestEmbeddedTemplatesRender(t *testing.T) {
+	templates := Default().Templates()
+	require.NotEmpty(t, templates)
+
+	locales := make(map[string][]string)
+	for _, tmpl := range templates {
+		locales[tmpl.Name] = append(locales[tmpl.Name], tmpl.Locale)
+		assert.NotEmpty(t, tmpl.Version, tmpl.Name)
+
+		ctx := WithLocale(context.Background(), tmpl.Locale)
+		rendered, err := Default().Render(ctx, tmpl.Name, sampleData[tmpl.Name])
+		require.NoError(t, err, "%s/%s", tmpl.Name, tmpl.Locale)
+		assert.Equal(t, tmpl.Locale, rendered.Locale)
+		assert.NotEmpty(t, strings.TrimSpace(rendered.Text), tmpl.Name)
+		assert.NotContains(t, rendered.Text, "<no value>", tmpl.Name)
+		assert.NotContains(t, rendered.Text, "version:", tmpl.Name)
+	}
+
+	// 每个模板都提供中英文两个版本
+	for name, got := range locales {
+		assert.ElementsMatch(t, []string{LocaleEN, LocaleZH}, got, name)
+	}
+}
+
+func TestRenderPlannerUser(t *testing.T) {
+	rendered := MustRender(context.Background(), TaskPlannerUser, map[string]any{
+		"Goal":        "实现一个缓存",
+		"Context":     "",
+		"Constraints": []string{"不引入新依赖", "兼容旧接口"},
+		"Preferences": []string(nil),
+		"References":  "",
+	})
+
+	assert.Equal(t, "请为以下目标创建详细的执行计划：\n\n目标: 实现一个缓存\n\n约束条件:\n- 不引入新依赖\n- 兼容旧接口", rendered.Text)
+	assert.Equal(t, "task.planner.user@1/zh", rendered.Ref())
+}
+
+func TestRegistryOverride(t *testing.T) {
+	registry := NewRegistry(LocaleZH)
+	require.NoError(t, registry.Load(fstest.MapFS{
+		"zh/greeting.tmpl": {Data: []byte("{{/* version: 1 */ -}}\n你好，{{.Name}}\n")},
+		"en/greeting.tmpl": {Data: []byte("{{/* version: 1 */ -}}\nHello, {{.Name}}\n")},
+	}, sourceEmbedded))
+
+	require.NoError(t, registry.Load(fstest.MapFS{
+		"en/greeting.tmpl": {Data: []byte("{{/* version: 2 */ -}}\nHi {{.Name}}!\n")},
+	}, "overrides"))
+
+	ctx := WithLocale(context.Background(), "en-US")
+	rendered, err := registry.Render(ctx, "greeting", map[string]any{"Name": "Ann"})
+	require.NoError(t, err)
+	assert.Equal(t, Rendered{Name: "greeting", Version: "2", Locale: LocaleEN, Text: "Hi Ann!"}, rendered)
+
+	// 未被覆盖的语言仍使用内置模板
+	rendered, err = registry.Render(context.Background(), "greeting", map[string]any{"Name": "Ann"})
+	require.NoError(t, err)
+	assert.Equal(t, "你好，Ann", rendered.Text)
+	assert.Equal(t, "1", rendered.Version)
+}
+
+func TestRegistryInvalidOverride(t *testing.T) {
+	registry := NewRegistry(LocaleZH)
+	require.NoError(t, registry.Load(fstest.MapFS{
+		"zh/greeting.tmpl": {Data: []byte("{{/* version: 1 */ -}}\n你好，{{.Name}}")},
+	}, sourceEmbedded))
+
+	// 缺少版本声明时整个目录不生效
+	err := registry.Load(fstest.MapFS{
+		"zh/greeting.tmpl": {Data: []byte("hello {{.Name}}")},
+		"zh/other.tmpl":    {Data: []byte("{{/* version: 1 */ -}}\nother")},
+	}, "overrides")
+	assert.Error(t, err)
+	_, ok := registry.Lookup("other", LocaleZH)
+	assert.False(t, ok)
+
+	// 语法错误与未知语言目录同样拒绝加载
+	assert.Error(t, registry.Load(fstest.MapFS{"zh/greeting.tmpl": {Data: []byte("{{/* version: 2 */ -}}\n{{.Name")}}, "overrides"))
+	assert.Error(t, registry.Load(fstest.MapFS{"fr/greeting.tmpl": {Data: []byte("{{/* version: 2 */ -}}\nbonjour")}}, "overrides"))
+
+	// 覆盖模板执行失败时退回内置模板
+	require.NoError(t, registry.Load(fstest.MapFS{
+		"zh/greeting.tmpl": {Data: []byte("{{/* version: 2 */ -}}\n{{.Nickname}}")},
+	}, "overrides"))
+	rendered, err := registry.Render(context.Background(), "greeting", map[string]any{"Name": "Ann"})
+	require.NoError(t, err)
+	assert.Equal(t, "你好，Ann", rendered.Text)
+	assert.Equal(t, "1", rendered.Version)
+}
+
+func TestRegistryLocaleFallback(t *testing.T) {
+	registry := NewRegistry("english")
+	require.NoError(t, registry.Load(fstest.MapFS{
+		"en/only.tmpl": {Data: []byte("{{/* version: 3 */ -}}\nenglish only")},
+	}, sourceEmbedded))
+
+	rendered, err := registry.Render(WithLocale(context.Background(), LocaleZH), "only", nil)
+	require.NoError(t, err)
+	assert.Equal(t, LocaleEN, rendered.Locale)
+	assert.Equal(t, "english only", rendered.Text)
+
+	_, err = registry.Render(context.Background(), "missing", nil)
+	assert.Error(t, err)
+}
+
+func TestNormalizeLocale(t *testing.T) {
+	cases := map[string]string{
+		"zh":                  LocaleZH,
+		"zh-CN":               LocaleZH,
+		"zh_TW":               LocaleZH,
+		"中文":                  LocaleZH,
+		"Chinese":             LocaleZH,
+		"en":                  LocaleEN,
+		"en-US,en;q=0.9":      LocaleEN,
+		"English":             LocaleEN,
+		"英文":                  LocaleEN,
+		"fr-FR":               "",
+		"":                    "",
+		" zh-Hans-CN;q=0.8 ":  LocaleZH,
+		"ja,en-US;q=0.9,en;q": "",
+	}
+	for input, want := range cases {
+		assert.Equal(t, want, NormalizeLocale(input), input)
+	}
+}
+
+func TestResolveLocale(t *testing.T) {
+	SetUserLocaleResolver(func(_ context.Context, userID string) string {
+		if userID == "user_en" {
+			return "English"
+		}
+		return ""
+	})
+	defer SetUserLocaleResolver(nil)
+
+	ctx := context.Background()
+	assert.Equal(t, LocaleZH, ResolveLocale(ctx, "user_en", "zh-CN"))
+	assert.Equal(t, LocaleEN, ResolveLocale(ctx, "user_en", ""))
+	assert.Equal(t, LocaleEN, ResolveLocale(ctx, "user_en", "fr"))
+	assert.Equal(t, "", ResolveLocale(ctx, "user_zh", ""))
+	assert.Equal(t, "", ResolveLocale(ctx, "", ""))
+
+	// 无法识别的语言不写入 ctx，渲染时使用默认语言
+	assert.Equal(t, "", LocaleFrom(WithLocale(ctx, "fr")))
+}
+
+func TestWithRendered(t *testing.T) {
+	system := MustRender(context.Background(), TaskPlannerSystem, nil)
+	user := MustRender(context.Background(), MemorySummaryUser, map[string]any{"Conversation": "user: 你好"})
+
+	ctx := WithRendered(context.Background(), system, user)
+	assert.Equal(t, []string{"task.planner.system@1/zh", "memory.summary.user@1/zh"}, Refs(ctx))
+	assert.Empty(t, Refs(context.Background()))
+}
@@ -0,0 +1,72 @@
+package retrieval
+
+import (
+	"ai_task/model"
+	"ai_task/repository/factory"
+	"context"
+	"fmt"
+)
+
+// DocumentRetriever 知识文档的混合检索器
+type DocumentRetriever struct {
+	repositoryFactory factory.Factory
+	embedder          Embedder
+}
+
+// NewDocumentRetriever 创建知识文档检索器，embedder 为 nil 时只能使用 Query.Embedding
+func NewDocumentRetriever(repositoryFactory factory.Factory, embedder Embedder) *DocumentRetriever {
+	return &DocumentRetriever{repositoryFactory: repositoryFactory, embedder: embedder}
+}
+
+// Retrieve 检索本人上传的文档及 Namespaces 下的共享文档
+func (r *DocumentRetriever) Retrieve(ctx context.Context, query *Query) ([]*Result, error) {
+	if query == nil || (query.UserID == "" && len(query.Namespaces) == 0) {
+		return nil, fmt.Errorf("user_id or namespaces is required")
+	}
+
+	session := r.repositoryFactory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	chunkRepo, err := r.repositoryFactory.NewDocChunkRepository(session)
+	if err != nil {
+		return nil, err
+	}
+
+	condition := model.SearchDocChunksCondition{
+		UserID:     query.UserID,
+		Namespaces: query.Namespaces,
+		Threshold:  noThreshold,
+	}
+
+	return hybridSearch(ctx, r.embedder, query,
+		func(vector []float64, n int) ([]*Result, error) {
+			c := condition
+			c.Embedding, c.Limit = vector, n
+			rows, err := chunkRepo.Search(&c)
+			return fromDocChunks(rows), err
+		},
+		func(vector []float64, keywords []string, n int) ([]*Result, error) {
+			c := condition
+			c.Embedding, c.Limit, c.Keywords = vector, n, keywords
+			rows, err := chunkRepo.SearchLexical(&c)
+			return fromDocChunks(rows), err
+		},
+	)
+}
+
+func fromDocChunks(rows []*model.DocChunkSearchResult) []*Result {
+	results := make([]*Result, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, &Result{
+			Source:     SourceDocument,
+			ID:         row.ID,
+			DocID:      row.DocID,
+			Title:      row.Title,
+			Namespace:  row.Namespace,
+			ChunkIdx:   row.ChunkIdx,
+			Content:    row.Content,
+			Similarity: row.Similarity,
+		})
+	}
+	return results
+}
@@ -0,0 +1,105 @@
+package retrieval
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// identifierRegexp 形如错误码、任务ID、变量名的 ASCII 词元
+var identifierRegexp = regexp.MustCompile(`[A-Za-z0-9][A-Za-z0-9_\-.]*[A-Za-z0-9]`)
+
+// FuseRRF 按倒数排名融合多路有序结果：score = Σ 1/(k + rank)，rank 从 1 开始
+// 同一分块在多路中出现时得分累加，相似度取最大值；按得分降序，得分相同时按相似度降序
+func FuseRRF(k int, lists ...[]*Result) []*Result {
+	if k <= 0 {
+		k = DefaultRRFK
+	}
+
+	fused := make(map[int64]*Result)
+	order := make([]int64, 0)
+	for _, list := range lists {
+		for rank, r := range list {
+			score := 1 / float64(k+rank+1)
+			if existing, ok := fused[r.ID]; ok {
+				existing.Score += score
+				if r.Similarity > existing.Similarity {
+					existing.Similarity = r.Similarity
+				}
+				continue
+			}
+			merged := *r
+			merged.Score = score
+			fused[r.ID] = &merged
+			order = append(order, r.ID)
+		}
+	}
+
+	results := make([]*Result, 0, len(order))
+	for _, id := range order {
+		results = append(results, fused[id])
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Similarity > results[j].Similarity
+	})
+	return results
+}
+
+// filterByThreshold 过滤相似度低于阈值的结果并截断到 limit，保持原有顺序
+func filterByThreshold(results []*Result, threshold float64, limit int) []*Result {
+	filtered := make([]*Result, 0, limit)
+	for _, r := range results {
+		if len(filtered) >= limit {
+			break
+		}
+		if r.Similarity < threshold {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+// LexicalTerms 提取适合全文检索的词元：含数字、下划线或连字符的标识符（如 E1043、task_9f2c），
+// 以及全大写缩写（如 OOM）。普通词语交给向量检索，避免常见词在全文检索中大量误命中
+func LexicalTerms(text string) []string {
+	seen := make(map[string]struct{})
+	var terms []string
+	for _, token := range identifierRegexp.FindAllString(text, -1) {
+		if len(token) < 3 || !isIdentifierLike(token) {
+			continue
+		}
+		key := strings.ToLower(token)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		terms = append(terms, token)
+	}
+	return terms
+}
+
+func isIdentifierLike(token string) bool {
+	hasLower := false
+	for _, c := range token {
+		if unicode.IsDigit(c) || c == '_' || c == '-' {
+			return true
+		}
+		if unicode.IsLower(c) {
+			hasLower = true
+		}
+	}
+	return !hasLower
+}
+
+// candidateLimit 每路召回的候选条数
+func candidateLimit(limit int) int {
+	if n := limit * candidateMultiplier; n > minCandidates {
+		return n
+	}
+	return minCandidates
+}
@@ -0,0 +1,92 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestFuseRRF(t *testing.T) {
+	vector := []*Result{{ID: 1, Similarity: 0.9}, {ID: 2, Similarity: 0.8}, {ID: 3, Similarity: 0.7}}
+	lexical := []*Result{{ID: 3, Similarity: 0.7}, {ID: 4, Similarity: 0.4}}
+
+	fused := FuseRRF(DefaultRRFK, vector, lexical)
+
+	ids := make([]int64, 0, len(fused))
+	for _, r := range fused {
+		ids = append(ids, r.ID)
+	}
+	// 3 同时出现在两路中，得分最高
+	if !reflect.DeepEqual(ids, []int64{3, 1, 2, 4}) {
+		t.Fatalf("Unexpected fused order: %v", ids)
+	}
+
+	expected := 1/float64(DefaultRRFK+3) + 1/float64(DefaultRRFK+1)
+	if fused[0].Score != expected {
+		t.Errorf("Expected score %v, got %v", expected, fused[0].Score)
+	}
+	// 输入不应被修改
+	if vector[2].Score != 0 {
+		t.Errorf("Input result should not be mutated, got score %v", vector[2].Score)
+	}
+}
+
+func TestLexicalTerms(t *testing.T) {
+	terms := LexicalTerms("任务 task_9f2c 报错 E1043，还有 OOM 和 e1043，请问 how to fix it")
+
+	if !reflect.DeepEqual(terms, []string{"task_9f2c", "E1043", "OOM"}) {
+		t.Errorf("Unexpected terms: %v", terms)
+	}
+	if terms := LexicalTerms("今天天气怎么样"); len(terms) != 0 {
+		t.Errorf("Expected no terms for plain text, got %v", terms)
+	}
+}
+
+func TestHybridSearchThresholdAfterFusion(t *testing.T) {
+	query := &Query{Text: "E1043 是什么错误", Embedding: []float64{1}, Limit: 2, Threshold: 0.5}
+
+	var gotKeywords []string
+	results, err := hybridSearch(context.Background(), nil, query,
+		func(vector []float64, n int) ([]*Result, error) {
+			if n != minCandidates {
+				t.Errorf("Expected %d candidates, got %d", minCandidates, n)
+			}
+			return []*Result{{ID: 1, Similarity: 0.9}, {ID: 2, Similarity: 0.45}, {ID: 3, Similarity: 0.6}}, nil
+		},
+		func(vector []float64, keywords []string, n int) ([]*Result, error) {
+			gotKeywords = keywords
+			return []*Result{{ID: 3, Similarity: 0.6}, {ID: 2, Similarity: 0.45}}, nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("hybridSearch error: %v", err)
+	}
+
+	if !reflect.DeepEqual(gotKeywords, []string{"E1043"}) {
+		t.Errorf("Unexpected keywords: %v", gotKeywords)
+	}
+	// 2 融合得分靠前但相似度低于阈值，被过滤
+	if len(results) != 2 || results[0].ID != 3 || results[1].ID != 1 {
+		t.Errorf("Unexpected results: %+v, %+v", results[0], results[len(results)-1])
+	}
+}
+
+func TestHybridSearchLexicalFailureFallback(t *testing.T) {
+	query := &Query{Text: "task_9f2c", Embedding: []float64{1}, Limit: 5}
+
+	results, err := hybridSearch(context.Background(), nil, query,
+		func(vector []float64, n int) ([]*Result, error) {
+			return []*Result{{ID: 1, Similarity: 0.9}}, nil
+		},
+		func(vector []float64, keywords []string, n int) ([]*Result, error) {
+			return nil, fmt.Errorf("websearch_to_tsquery does not exist")
+		},
+	)
+	if err != nil {
+		t.Fatalf("Expected lexical failure to be ignored, got %v", err)
+	}
+	if len(results) != 1 || results[0].ID != 1 {
+		t.Errorf("Expected vector results only, got %+v", results)
+	}
+}
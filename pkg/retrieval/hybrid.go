@@ -0,0 +1,56 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// noThreshold 召回候选时不过滤相似度（余弦相似度下限为 -1），阈值在融合后统一应用
+const noThreshold = -1
+
+// vectorSearchFunc 按向量召回 n 条候选，按相似度降序
+type vectorSearchFunc func(vector []float64, n int) ([]*Result, error)
+
+// lexicalSearchFunc 按全文检索召回 n 条候选，按文本相关度降序，结果需带上与 vector 的相似度
+type lexicalSearchFunc func(vector []float64, keywords []string, n int) ([]*Result, error)
+
+// hybridSearch 向量检索与全文检索各召回一路候选，倒数排名融合后按相似度阈值过滤并截断
+// 查询中没有可用于全文检索的标识符时退化为纯向量检索；全文检索失败只记录日志
+func hybridSearch(ctx context.Context, embedder Embedder, query *Query, vectorSearch vectorSearchFunc, lexicalSearch lexicalSearchFunc) ([]*Result, error) {
+	if query.Limit <= 0 {
+		return nil, nil
+	}
+
+	vector := query.Embedding
+	if len(vector) == 0 {
+		if query.Text == "" || embedder == nil {
+			return nil, nil
+		}
+		var err error
+		vector, err = embedder.GetTextEmbedding(ctx, query.Text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed query: %w", err)
+		}
+	}
+
+	n := candidateLimit(query.Limit)
+
+	vectorResults, err := vectorSearch(vector, n)
+	if err != nil {
+		return nil, err
+	}
+
+	var lexicalResults []*Result
+	if keywords := LexicalTerms(query.Text); len(keywords) > 0 {
+		lexicalResults, err = lexicalSearch(vector, keywords, n)
+		if err != nil {
+			log.Warnf("lexical search error, fallback to vector only, user_id:%s, err:%v", query.UserID, err)
+			lexicalResults = nil
+		}
+	}
+
+	fused := FuseRRF(DefaultRRFK, vectorResults, lexicalResults)
+	return filterByThreshold(fused, query.Threshold, query.Limit), nil
+}
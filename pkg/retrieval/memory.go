@@ -0,0 +1,74 @@
+package retrieval
+
+import (
+	"ai_task/model"
+	"ai_task/repository/factory"
+	"context"
+	"fmt"
+)
+
+// MemoryRetriever 对话语义记忆的混合检索器
+type MemoryRetriever struct {
+	repositoryFactory factory.Factory
+	embedder          Embedder
+}
+
+// NewMemoryRetriever 创建语义记忆检索器，embedder 为 nil 时只能使用 Query.Embedding
+func NewMemoryRetriever(repositoryFactory factory.Factory, embedder Embedder) *MemoryRetriever {
+	return &MemoryRetriever{repositoryFactory: repositoryFactory, embedder: embedder}
+}
+
+// Retrieve 检索该用户（可限定会话）的历史对话分块
+func (r *MemoryRetriever) Retrieve(ctx context.Context, query *Query) ([]*Result, error) {
+	if query == nil || query.UserID == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	session := r.repositoryFactory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	chunkRepo, err := r.repositoryFactory.NewMemoryChunkRepository(session)
+	if err != nil {
+		return nil, err
+	}
+
+	condition := model.SearchMemoryChunksCondition{
+		UserID:            query.UserID,
+		SessionID:         query.SessionID,
+		Threshold:         noThreshold,
+		ExcludeMessageIDs: query.ExcludeMessageIDs,
+	}
+
+	return hybridSearch(ctx, r.embedder, query,
+		func(vector []float64, n int) ([]*Result, error) {
+			c := condition
+			c.Embedding, c.Limit = vector, n
+			rows, err := chunkRepo.Search(&c)
+			return fromMemoryChunks(rows), err
+		},
+		func(vector []float64, keywords []string, n int) ([]*Result, error) {
+			c := condition
+			c.Embedding, c.Limit, c.Keywords = vector, n, keywords
+			rows, err := chunkRepo.SearchLexical(&c)
+			return fromMemoryChunks(rows), err
+		},
+	)
+}
+
+func fromMemoryChunks(rows []*model.MemoryChunkSearchResult) []*Result {
+	results := make([]*Result, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, &Result{
+			Source:     SourceMemory,
+			ID:         row.ID,
+			UserID:     row.UserID,
+			SessionID:  row.SessionID,
+			MessageID:  row.MessageID,
+			Role:       row.Role,
+			ChunkIdx:   row.ChunkIdx,
+			Content:    row.Content,
+			Similarity: row.Similarity,
+		})
+	}
+	return results
+}
@@ -0,0 +1,57 @@
+package retrieval
+
+import "context"
+
+// 检索来源
+const (
+	SourceMemory   = "memory"   // 对话语义记忆 memory_chunks
+	SourceDocument = "document" // 知识文档 doc_chunks
+)
+
+const (
+	// DefaultRRFK 倒数排名融合常数，越大越弱化头部排名的优势
+	DefaultRRFK = 60
+	// candidateMultiplier 每路召回的候选条数为 Limit 的倍数，融合和阈值过滤后再截断
+	candidateMultiplier = 4
+	// minCandidates 每路召回的最少候选条数
+	minCandidates = 20
+)
+
+// Embedder 查询向量化，embedding.Client 实现了该接口
+type Embedder interface {
+	GetTextEmbedding(ctx context.Context, text string) ([]float64, error)
+}
+
+// Query 检索请求
+type Query struct {
+	Text              string    // 查询文本，用于全文检索，Embedding 为空时同时用于向量化
+	Embedding         []float64 // 预先计算的查询向量，可为空
+	UserID            string    // 用户ID
+	SessionID         *string   // 仅语义记忆：限定会话，为空时检索该用户全部会话
+	Namespaces        []string  // 仅知识文档：额外检索的共享命名空间
+	ExcludeMessageIDs []int64   // 仅语义记忆：已在短期记忆中的消息
+	Limit             int       // 最多返回条数
+	Threshold         float64   // 余弦相似度下限，在融合之后应用
+}
+
+// Result 检索结果，Score 为融合得分，Similarity 为与查询的余弦相似度
+type Result struct {
+	Source     string  `json:"source"`
+	ID         int64   `json:"id"` // 分块ID
+	UserID     string  `json:"user_id,omitempty"`
+	SessionID  string  `json:"session_id,omitempty"` // 语义记忆来源会话
+	MessageID  int64   `json:"message_id,omitempty"` // 语义记忆来源消息
+	Role       string  `json:"role,omitempty"`
+	DocID      int64   `json:"doc_id,omitempty"` // 知识文档ID
+	Title      string  `json:"title,omitempty"`  // 知识文档标题
+	Namespace  string  `json:"namespace,omitempty"`
+	ChunkIdx   int     `json:"chunk_idx"`
+	Content    string  `json:"content"`
+	Similarity float64 `json:"similarity"`
+	Score      float64 `json:"score"`
+}
+
+// Retriever 检索器接口，对话和任务规划共用
+type Retriever interface {
+	Retrieve(ctx context.Context, query *Query) ([]*Result, error)
+}
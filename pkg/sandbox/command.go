@@ -0,0 +1,179 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrCommandNotAllowed 命令不在白名单中
+var ErrCommandNotAllowed = errors.New("sandbox: command not allowed")
+
+const (
+	DefaultTimeout        = 60 * time.Second
+	DefaultMaxOutputBytes = 64 * 1024
+	DefaultCPUSeconds     = 60
+	DefaultMemoryBytes    = 2 << 30
+	DefaultFileSizeBytes  = 64 << 20
+)
+
+// Policy 命令执行策略
+// 资源限制在类 Unix 系统上通过 ulimit 作用于命令进程，取值 <=0 时不限制
+type Policy struct {
+	AllowedCommands []string      // 允许执行的程序名，只匹配不带路径的名称，从 PATH 中查找
+	Timeout         time.Duration // 超时后结束整个进程组
+	MaxOutputBytes  int           // stdout、stderr 各自保留的最大字节数
+	CPUSeconds      int           // CPU 时间上限（秒）
+	MemoryBytes     int64         // 虚拟内存上限
+	FileSizeBytes   int64         // 单个写入文件的大小上限
+	Env             []string      // 额外的环境变量，KEY=VALUE
+}
+
+// DefaultPolicy 默认策略，不允许任何命令
+func DefaultPolicy() Policy {
+	return Policy{
+		Timeout:        DefaultTimeout,
+		MaxOutputBytes: DefaultMaxOutputBytes,
+		CPUSeconds:     DefaultCPUSeconds,
+		MemoryBytes:    DefaultMemoryBytes,
+		FileSizeBytes:  DefaultFileSizeBytes,
+	}
+}
+
+// CommandResult 命令执行结果，命令以非零状态退出或超时不视为错误
+type CommandResult struct {
+	ExitCode  int
+	Stdout    string
+	Stderr    string
+	Truncated bool // 输出超过 MaxOutputBytes 被截断
+	TimedOut  bool
+	Duration  time.Duration
+}
+
+// Run 在工作目录的 dir 子目录中执行命令，argv[0] 必须在白名单中
+// 命令不经过 shell 解释，参数原样传递；作为路径解析时跳出工作目录的参数返回 ErrPathEscape
+func (p *Policy) Run(ctx context.Context, w *Workspace, dir string, argv []string) (*CommandResult, error) {
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	name := argv[0]
+	if strings.ContainsAny(name, `/\`) || !slices.Contains(p.AllowedCommands, name) {
+		return nil, fmt.Errorf("%w: %s", ErrCommandNotAllowed, name)
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err = w.Clean(dir)
+	if err != nil {
+		return nil, err
+	}
+	info, err := w.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	if err := checkArgs(w, dir, argv[1:]); err != nil {
+		return nil, err
+	}
+
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	stdout := &cappedBuffer{max: p.MaxOutputBytes}
+	stderr := &cappedBuffer{max: p.MaxOutputBytes}
+	cmd := exec.CommandContext(ctx, path, argv[1:]...)
+	cmd.Dir = filepath.Join(w.Dir(), dir)
+	cmd.Env = append([]string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + w.Dir(),
+		"LANG=C.UTF-8",
+	}, p.Env...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = time.Second
+	limitCommand(cmd, p)
+
+	start := time.Now()
+	err = cmd.Run()
+	result := &CommandResult{
+		ExitCode:  cmd.ProcessState.ExitCode(),
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Truncated: stdout.truncated || stderr.truncated,
+		TimedOut:  errors.Is(ctx.Err(), context.DeadlineExceeded),
+		Duration:  time.Since(start),
+	}
+
+	// 超时返回结果，调用方取消则返回错误
+	var exitErr *exec.ExitError
+	switch {
+	case result.TimedOut:
+		return result, nil
+	case ctx.Err() != nil:
+		return nil, context.Cause(ctx)
+	case err == nil, errors.As(err, &exitErr):
+		return result, nil
+	default:
+		return nil, err
+	}
+}
+
+// checkArgs 参数按相对 dir 的路径解析，绝对路径与跳出工作目录的路径返回 ErrPathEscape
+// 以 - 开头的参数只检查 = 之后的取值，如 --file=../a
+func checkArgs(w *Workspace, dir string, args []string) error {
+	for _, arg := range args {
+		value := arg
+		if strings.HasPrefix(arg, "-") {
+			var ok bool
+			if _, value, ok = strings.Cut(arg, "="); !ok {
+				continue
+			}
+		}
+		if value == "" {
+			continue
+		}
+		if filepath.IsAbs(value) || strings.HasPrefix(value, "/") || strings.HasPrefix(value, `\`) {
+			return fmt.Errorf("%w: %s", ErrPathEscape, arg)
+		}
+		if _, err := w.Clean(filepath.Join(dir, value)); err != nil {
+			return fmt.Errorf("%w: %s", ErrPathEscape, arg)
+		}
+	}
+	return nil
+}
+
+// cappedBuffer 只保留前 max 个字节，超出部分丢弃，max<=0 时不限制
+type cappedBuffer struct {
+	max       int
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.max > 0 {
+		if remaining := b.max - b.buf.Len(); len(p) > remaining {
+			b.truncated = true
+			b.buf.Write(p[:max(remaining, 0)])
+			return len(p), nil
+		}
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package sandbox
+
+import "os/exec"
+
+// limitCommand 非类 Unix 系统不支持资源限制，只保留超时与输出上限
+func limitCommand(cmd *exec.Cmd, p *Policy) {}
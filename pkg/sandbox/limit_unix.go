@@ -0,0 +1,34 @@
+//go:build unix
+
+package sandbox
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// limitCommand 通过 /bin/sh 的 ulimit 设置资源限制后 exec 目标程序，并让命令独占进程组，超时时结束整个进程组
+func limitCommand(cmd *exec.Cmd, p *Policy) {
+	var limits []string
+	if p.CPUSeconds > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -t %d", p.CPUSeconds))
+	}
+	if p.MemoryBytes > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -v %d", max(p.MemoryBytes/1024, 1)))
+	}
+	if p.FileSizeBytes > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -f %d", max(p.FileSizeBytes/512, 1)))
+	}
+	if len(limits) > 0 {
+		script := strings.Join(limits, " && ") + ` && exec "$@"`
+		cmd.Args = append([]string{"sh", "-c", script, "sandbox", cmd.Path}, cmd.Args[1:]...)
+		cmd.Path = "/bin/sh"
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,157 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openWorkspace(t *testing.T) *Workspace {
+	w, err := OpenWorkspace(filepath.Join(t.TempDir(), "workspace"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	return w
+}
+
+func TestWorkspaceFiles(t *testing.T) {
+	w := openWorkspace(t)
+
+	require.NoError(t, w.WriteFile("src/cache/cache.go", []byte("package cache\n")))
+	require.NoError(t, w.WriteFile("README.md", []byte("# cache")))
+
+	data, truncated, err := w.ReadFile("src/cache/cache.go", 0)
+	require.NoError(t, err)
+	assert.False(t, truncated)
+	assert.Equal(t, "package cache\n", string(data))
+
+	data, truncated, err = w.ReadFile("./src/../src/cache/cache.go", 7)
+	require.NoError(t, err)
+	assert.True(t, truncated)
+	assert.Equal(t, "package", string(data))
+
+	entries, err := w.ListDir("")
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{{Name: "README.md", Size: 7}, {Name: "src", IsDir: true}}, entries)
+
+	_, _, err = w.ReadFile("src", 0)
+	assert.Error(t, err)
+}
+
+func TestWorkspaceBlocksEscape(t *testing.T) {
+	w := openWorkspace(t)
+	outside := filepath.Join(filepath.Dir(w.Dir()), "secret.txt")
+	require.NoError(t, os.WriteFile(outside, []byte("secret"), 0644))
+
+	for _, name := range []string{"../secret.txt", "src/../../secret.txt", outside} {
+		_, _, err := w.ReadFile(name, 0)
+		assert.ErrorIs(t, err, ErrPathEscape, name)
+		assert.ErrorIs(t, w.WriteFile(name, []byte("x")), ErrPathEscape, name)
+	}
+	_, err := w.ListDir("..")
+	assert.ErrorIs(t, err, ErrPathEscape)
+
+	// 经由符号链接跳出工作目录同样被拒绝
+	require.NoError(t, os.Symlink(filepath.Dir(w.Dir()), filepath.Join(w.Dir(), "parent")))
+	_, _, err = w.ReadFile("parent/secret.txt", 0)
+	assert.Error(t, err)
+	assert.Error(t, w.WriteFile("parent/secret.txt", []byte("x")))
+
+	data, err := os.ReadFile(outside)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(data))
+}
+
+func TestPolicyRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires unix commands")
+	}
+	w := openWorkspace(t)
+	require.NoError(t, w.WriteFile("sub/a.txt", []byte("hello")))
+
+	policy := DefaultPolicy()
+	policy.AllowedCommands = []string{"cat", "pwd", "sh", "seq", "sleep", "dd"}
+	ctx := context.Background()
+
+	result, err := policy.Run(ctx, w, "sub", []string{"cat", "a.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "hello", result.Stdout)
+
+	result, err = policy.Run(ctx, w, "", []string{"pwd"})
+	require.NoError(t, err)
+	assert.Equal(t, w.Dir(), strings.TrimSpace(result.Stdout))
+
+	// 不经过 shell，参数原样传递
+	result, err = policy.Run(ctx, w, "sub", []string{"cat", "a.txt; rm -rf /"})
+	require.NoError(t, err)
+	assert.NotEqual(t, 0, result.ExitCode)
+	assert.Contains(t, result.Stderr, "No such file")
+
+	// 白名单只匹配程序名
+	_, err = policy.Run(ctx, w, "", []string{"rm", "sub/a.txt"})
+	assert.ErrorIs(t, err, ErrCommandNotAllowed)
+	_, err = policy.Run(ctx, w, "", []string{"/bin/cat", "sub/a.txt"})
+	assert.ErrorIs(t, err, ErrCommandNotAllowed)
+	_, err = policy.Run(ctx, w, "..", []string{"pwd"})
+	assert.ErrorIs(t, err, ErrPathEscape)
+
+	// 参数中的路径同样不能跳出工作目录
+	for _, argv := range [][]string{
+		{"cat", "/etc/passwd"},
+		{"cat", "../../etc/passwd"},
+		{"cat", "a.txt", "sub/../../x"},
+		{"sh", "--rcfile=/etc/profile"},
+	} {
+		_, err = policy.Run(ctx, w, "", argv)
+		assert.ErrorIs(t, err, ErrPathEscape, argv)
+	}
+	result, err = policy.Run(ctx, w, "sub", []string{"cat", "../sub/a.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", result.Stdout)
+}
+
+func TestPolicyRunLimits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires unix commands")
+	}
+	w := openWorkspace(t)
+	policy := DefaultPolicy()
+	policy.AllowedCommands = []string{"seq", "sh", "dd"}
+	policy.MaxOutputBytes = 10
+	policy.Timeout = 200 * time.Millisecond
+	policy.FileSizeBytes = 4096
+	ctx := context.Background()
+
+	result, err := policy.Run(ctx, w, "", []string{"seq", "1", "1000"})
+	require.NoError(t, err)
+	assert.True(t, result.Truncated)
+	assert.Equal(t, "1\n2\n3\n4\n5\n", result.Stdout)
+
+	// 超时结束整个进程组，包括子进程
+	start := time.Now()
+	result, err = policy.Run(ctx, w, "", []string{"sh", "-c", "sleep 10 & sleep 10"})
+	require.NoError(t, err)
+	assert.True(t, result.TimedOut)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// 超出文件大小限制的写入失败
+	result, err = policy.Run(ctx, w, "", []string{"dd", "if=/dev/zero", "of=big", "bs=1024", "count=64"})
+	require.NoError(t, err)
+	assert.NotEqual(t, 0, result.ExitCode)
+	info, err := w.Stat("big")
+	require.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), int64(4096))
+
+	// 调用方取消时返回错误
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = policy.Run(cancelled, w, "", []string{"seq", "1"})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,154 @@
+// Package sandbox 任务工具的受限运行环境
+// 文件操作限制在工作目录内，命令只能执行白名单中的程序，并限制运行时间、输出大小与资源用量
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ErrPathEscape 路径指向工作目录之外
+var ErrPathEscape = errors.New("sandbox: path escapes workspace")
+
+// Workspace 工作目录，所有路径都相对于工作目录解析
+// 文件操作通过 os.Root 进行，经由符号链接指向目录外的路径同样被拒绝
+type Workspace struct {
+	dir  string
+	root *os.Root
+}
+
+// Entry 目录项
+type Entry struct {
+	Name  string
+	IsDir bool
+	Size  int64
+}
+
+// OpenWorkspace 打开工作目录，目录不存在时创建
+func OpenWorkspace(dir string) (*Workspace, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workspace: %w", err)
+	}
+	return &Workspace{dir: dir, root: root}, nil
+}
+
+// Dir 工作目录的绝对路径
+func (w *Workspace) Dir() string {
+	return w.dir
+}
+
+// Close 关闭工作目录
+func (w *Workspace) Close() error {
+	return w.root.Close()
+}
+
+// Clean 规范化相对路径，空路径表示工作目录本身；绝对路径与跳出工作目录的路径返回 ErrPathEscape
+func (w *Workspace) Clean(name string) (string, error) {
+	if name == "" {
+		return ".", nil
+	}
+	if filepath.IsAbs(name) || !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%w: %s", ErrPathEscape, name)
+	}
+	return filepath.Clean(name), nil
+}
+
+// ReadFile 读取文件，超过 maxBytes 的部分被截断，maxBytes<=0 时不限制
+func (w *Workspace) ReadFile(name string, maxBytes int64) (data []byte, truncated bool, err error) {
+	if name, err = w.Clean(name); err != nil {
+		return nil, false, err
+	}
+	f, err := w.root.Open(name)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	if info.IsDir() {
+		return nil, false, fmt.Errorf("%s is a directory", name)
+	}
+
+	if maxBytes <= 0 {
+		data, err = io.ReadAll(f)
+		return data, false, err
+	}
+	data, err = io.ReadAll(io.LimitReader(f, maxBytes+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(data)) > maxBytes {
+		return data[:maxBytes], true, nil
+	}
+	return data, false, nil
+}
+
+// WriteFile 写入文件，自动创建上级目录
+func (w *Workspace) WriteFile(name string, data []byte) error {
+	name, err := w.Clean(name)
+	if err != nil {
+		return err
+	}
+	if name == "." {
+		return fmt.Errorf("%w: cannot write the workspace itself", ErrPathEscape)
+	}
+	if dir := filepath.Dir(name); dir != "." {
+		if err := w.root.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return w.root.WriteFile(name, data, 0644)
+}
+
+// ListDir 列出目录，按名称排序
+func (w *Workspace) ListDir(name string) ([]Entry, error) {
+	name, err := w.Clean(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := w.root.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	dirEntries, err := f.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(dirEntries))
+	for _, d := range dirEntries {
+		entry := Entry{Name: d.Name(), IsDir: d.IsDir()}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			entry.Size = info.Size()
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// Stat 获取文件信息
+func (w *Workspace) Stat(name string) (fs.FileInfo, error) {
+	name, err := w.Clean(name)
+	if err != nil {
+		return nil, err
+	}
+	return w.root.Stat(name)
+}
@@ -1,7 +1,9 @@
 package task
 
 import (
-	"ai_task/pkg/clients/llm_model"
+	"ai_task/pkg/clients/llm"
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/usage"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -18,7 +20,7 @@ import (
 // 2. 上下文隔离（多代理）
 // 3. 上下文卸载（工具设计）
 type ContextEngineer struct {
-	llmClient   *llm_model.ClientChatModel
+	llmClient   llm.ChatModel
 	config      *ContextEngineerConfig
 	compressor  *ContextCompressor
 	summarizer  *ContextSummarizer
@@ -53,17 +55,17 @@ func DefaultContextEngineerConfig() *ContextEngineerConfig {
 	}
 }
 
-// NewContextEngineer 创建上下文工程师
-func NewContextEngineer(config *ContextEngineerConfig) *ContextEngineer {
+// NewContextEngineer 创建上下文工程师，llmClient 为 nil 时使用全局模型
+func NewContextEngineer(config *ContextEngineerConfig, llmClient llm.ChatModel) *ContextEngineer {
 	if config == nil {
 		config = DefaultContextEngineerConfig()
 	}
 
 	return &ContextEngineer{
-		llmClient:  llm_model.GetInstance(),
+		llmClient:  llm.OrDefault(llmClient),
 		config:     config,
 		compressor: NewContextCompressor(config),
-		summarizer: NewContextSummarizer(config),
+		summarizer: NewContextSummarizer(config, llmClient),
 	}
 }
 
@@ -141,14 +143,14 @@ func (cc *ContextCompressor) extractReferences(content string) []string {
 // ContextSummarizer 上下文摘要器
 type ContextSummarizer struct {
 	config    *ContextEngineerConfig
-	llmClient *llm_model.ClientChatModel
+	llmClient llm.ChatModel
 }
 
-// NewContextSummarizer 创建摘要器
-func NewContextSummarizer(config *ContextEngineerConfig) *ContextSummarizer {
+// NewContextSummarizer 创建摘要器，llmClient 为 nil 时使用全局模型
+func NewContextSummarizer(config *ContextEngineerConfig, llmClient llm.ChatModel) *ContextSummarizer {
 	return &ContextSummarizer{
 		config:    config,
-		llmClient: llm_model.GetInstance(),
+		llmClient: llm.OrDefault(llmClient),
 	}
 }
 
@@ -167,20 +169,30 @@ func (cs *ContextSummarizer) SummarizeContext(ctx context.Context, taskCtx *Task
 	}
 
 	// 使用 LLM 生成摘要
-	summaryPrompt := fmt.Sprintf(PromptContextSummaryUserTemplate, content, cs.config.SummaryMaxTokens)
+	system, err := prompt.Render(ctx, prompt.TaskContextSummarySystem, nil)
+	if err != nil {
+		return "", err
+	}
+	user, err := prompt.Render(ctx, prompt.TaskContextSummaryUser, map[string]any{
+		"Content":  content,
+		"MaxChars": cs.config.SummaryMaxTokens,
+	})
+	if err != nil {
+		return "", err
+	}
 
 	messages := []openai.ChatCompletionMessage{
 		{
 			Role:    openai.ChatMessageRoleSystem,
-			Content: PromptContextSummarizerSystem,
+			Content: system.Text,
 		},
 		{
 			Role:    openai.ChatMessageRoleUser,
-			Content: summaryPrompt,
+			Content: user.Text,
 		},
 	}
 
-	summary, err := cs.llmClient.PostChatCompletionsNonStreamContent(ctx, messages)
+	summary, err := cs.llmClient.PostChatCompletionsNonStreamContent(prompt.WithRendered(usage.WithRole(ctx, usage.RoleSummarizer), system, user), messages)
 	if err != nil {
 		log.Warnf("Failed to summarize context: %v", err)
 		// 返回原始内容的截断版本
@@ -247,9 +259,9 @@ func (ce *ContextEngineer) BuildOptimizedContext(ctx context.Context, taskCtx *T
 
 	// 2. 构建系统提示（稳定，用于KV缓存）
 	if ce.config.StablePromptPrefix {
-		result.SystemPrompt = ce.buildStableSystemPrompt()
+		result.SystemPrompt = ce.buildStableSystemPrompt(ctx)
 	} else {
-		result.SystemPrompt = ce.buildDynamicSystemPrompt(taskCtx)
+		result.SystemPrompt = ce.buildDynamicSystemPrompt(ctx, taskCtx)
 	}
 
 	// 3. 构建任务上下文
@@ -286,17 +298,21 @@ type OptimizedContext struct {
 
 // buildStableSystemPrompt 构建稳定的系统提示
 // 用于 KV 缓存优化
-func (ce *ContextEngineer) buildStableSystemPrompt() string {
-	return PromptStableSystemPrefix
+func (ce *ContextEngineer) buildStableSystemPrompt(ctx context.Context) string {
+	return prompt.MustRender(ctx, prompt.TaskStableSystemPrefix, nil).Text
 }
 
 // buildDynamicSystemPrompt 构建动态系统提示
-func (ce *ContextEngineer) buildDynamicSystemPrompt(taskCtx *TaskContext) string {
+func (ce *ContextEngineer) buildDynamicSystemPrompt(ctx context.Context, taskCtx *TaskContext) string {
 	if taskCtx == nil || taskCtx.Task == nil {
-		return ce.buildStableSystemPrompt()
+		return ce.buildStableSystemPrompt(ctx)
 	}
 
-	return fmt.Sprintf(PromptDynamicSystemTemplate, taskCtx.Task.ID, taskCtx.Task.Goal, taskCtx.Task.Status)
+	return prompt.MustRender(ctx, prompt.TaskDynamicSystem, map[string]any{
+		"TaskID": taskCtx.Task.ID,
+		"Goal":   taskCtx.Task.Goal,
+		"Status": taskCtx.Task.Status,
+	}).Text
 }
 
 // buildTaskContext 构建任务上下文
@@ -405,14 +421,14 @@ func (ce *ContextEngineer) extractAllReferences(taskCtx *TaskContext, toolCalls
 // 实现策略2：上下文隔离
 type MultiAgentCoordinator struct {
 	manager    *Manager
-	llmClient  *llm_model.ClientChatModel
+	llmClient  llm.ChatModel
 }
 
-// NewMultiAgentCoordinator 创建多代理协调器
-func NewMultiAgentCoordinator(manager *Manager) *MultiAgentCoordinator {
+// NewMultiAgentCoordinator 创建多代理协调器，llmClient 为 nil 时使用全局模型
+func NewMultiAgentCoordinator(manager *Manager, llmClient llm.ChatModel) *MultiAgentCoordinator {
 	return &MultiAgentCoordinator{
 		manager:   manager,
-		llmClient: llm_model.GetInstance(),
+		llmClient: llm.OrDefault(llmClient),
 	}
 }
 
@@ -445,20 +461,27 @@ func (mac *MultiAgentCoordinator) DelegateTask(ctx context.Context, parentTaskID
 	}
 
 	// 构建子代理提示
-	prompt := mac.buildAgentPrompt(agentTask, parentCtx)
+	system, err := mac.getAgentSystemPrompt(ctx, agentTask.Role)
+	if err != nil {
+		return nil, err
+	}
+	user, err := mac.buildAgentPrompt(ctx, agentTask, parentCtx)
+	if err != nil {
+		return nil, err
+	}
 
 	messages := []openai.ChatCompletionMessage{
 		{
 			Role:    openai.ChatMessageRoleSystem,
-			Content: mac.getAgentSystemPrompt(agentTask.Role),
+			Content: system.Text,
 		},
 		{
 			Role:    openai.ChatMessageRoleUser,
-			Content: prompt,
+			Content: user.Text,
 		},
 	}
 
-	result, err := mac.llmClient.PostChatCompletionsNonStreamContent(ctx, messages)
+	result, err := mac.llmClient.PostChatCompletionsNonStreamContent(prompt.WithRendered(usage.WithRole(ctx, usage.RoleExecutor), system, user), messages)
 	if err != nil {
 		return nil, fmt.Errorf("agent execution failed: %w", err)
 	}
@@ -474,31 +497,29 @@ func (mac *MultiAgentCoordinator) DelegateTask(ctx context.Context, parentTaskID
 	return agentTask, nil
 }
 
-// getAgentSystemPrompt 获取代理系统提示
-func (mac *MultiAgentCoordinator) getAgentSystemPrompt(role AgentRole) string {
-	return GetAgentPrompt(role)
+// getAgentSystemPrompt 获取代理系统提示，未知角色使用默认提示词
+func (mac *MultiAgentCoordinator) getAgentSystemPrompt(ctx context.Context, role AgentRole) (prompt.Rendered, error) {
+	if _, ok := prompt.Default().Lookup(prompt.TaskAgentPrefix+string(role), prompt.LocaleFrom(ctx)); ok {
+		return prompt.Render(ctx, prompt.TaskAgentPrefix+string(role), nil)
+	}
+	return prompt.Render(ctx, prompt.TaskAgentDefault, nil)
 }
 
 // buildAgentPrompt 构建代理提示
-func (mac *MultiAgentCoordinator) buildAgentPrompt(task *AgentTask, parentCtx *TaskContext) string {
-	var sb strings.Builder
-
-	sb.WriteString(fmt.Sprintf("## 任务\n%s\n\n", task.Description))
-
-	if parentCtx != nil && parentCtx.Task != nil {
-		sb.WriteString("## 上下文\n")
-		sb.WriteString(fmt.Sprintf("父任务目标: %s\n", parentCtx.Task.Goal))
-		sb.WriteString(fmt.Sprintf("当前阶段: %s\n", parentCtx.Task.CurrentPhase))
+func (mac *MultiAgentCoordinator) buildAgentPrompt(ctx context.Context, task *AgentTask, parentCtx *TaskContext) (prompt.Rendered, error) {
+	data := map[string]any{
+		"Description":  task.Description,
+		"HasParent":    false,
+		"Goal":         "",
+		"CurrentPhase": "",
+		"Input":        task.Input,
 	}
-
-	if len(task.Input) > 0 {
-		sb.WriteString("\n## 输入\n")
-		for k, v := range task.Input {
-			sb.WriteString(fmt.Sprintf("- %s: %v\n", k, v))
-		}
+	if parentCtx != nil && parentCtx.Task != nil {
+		data["HasParent"] = true
+		data["Goal"] = parentCtx.Task.Goal
+		data["CurrentPhase"] = parentCtx.Task.CurrentPhase
 	}
-
-	return sb.String()
+	return prompt.Render(ctx, prompt.TaskAgentUser, data)
 }
 
 // ToolLoader 工具加载器
@@ -525,9 +546,9 @@ type ToolDefinition struct {
 func (tl *ToolLoader) GetAvailableTools(phase PhaseStatus) []ToolDefinition {
 	// 基础工具（始终可用）
 	baseTools := []ToolDefinition{
-		{Name: "read_file", Description: "读取文件内容", Category: "file_"},
-		{Name: "write_file", Description: "写入文件内容", Category: "file_"},
-		{Name: "list_dir", Description: "列出目录内容", Category: "file_"},
+		{Name: "read_file", Description: "读取文件内容", Category: "file_", Parameters: map[string]string{"path": "工作目录内的相对路径"}},
+		{Name: "write_file", Description: "写入文件内容", Category: "file_", Parameters: map[string]string{"path": "工作目录内的相对路径", "content": "完整的文件内容"}},
+		{Name: "list_dir", Description: "列出目录内容", Category: "file_", Parameters: map[string]string{"path": "工作目录内的相对路径，默认为工作目录"}},
 	}
 
 	// 根据阶段添加工具
@@ -541,14 +562,14 @@ func (tl *ToolLoader) GetAvailableTools(phase PhaseStatus) []ToolDefinition {
 	case PhaseStatusInProgress:
 		// 执行阶段：添加执行工具
 		return append(baseTools, []ToolDefinition{
-			{Name: "run_command", Description: "执行命令", Category: "shell_"},
-			{Name: "edit_file", Description: "编辑文件", Category: "file_"},
+			{Name: "run_command", Description: "执行命令", Category: "shell_", Parameters: map[string]string{"command": "白名单中的命令及参数，不经过 shell 解释", "dir": "执行目录，默认为工作目录"}},
+			{Name: "edit_file", Description: "编辑文件", Category: "file_", Parameters: map[string]string{"path": "工作目录内的相对路径", "old_text": "要替换的原文，必须在文件中唯一", "new_text": "替换后的文本"}},
 		}...)
 	case PhaseStatusComplete:
 		// 验证阶段：添加测试工具
 		return append(baseTools, []ToolDefinition{
-			{Name: "run_test", Description: "运行测试", Category: "shell_"},
-			{Name: "verify", Description: "验证结果", Category: "shell_"},
+			{Name: "run_test", Description: "运行测试", Category: "shell_", Parameters: map[string]string{"command": "测试命令，默认使用配置的测试命令", "dir": "执行目录，默认为工作目录"}},
+			{Name: "verify", Description: "验证结果", Category: "shell_", Parameters: map[string]string{"paths": "需要存在的文件列表", "contains": "文件需要包含的文本，可选"}},
 		}...)
 	}
 
@@ -595,8 +616,8 @@ func NewKVCacheOptimizer() *KVCacheOptimizer {
 
 // buildStablePrefix 构建稳定前缀
 func buildStablePrefix() string {
-	// 这个前缀应该保持稳定，不包含时间戳等变化内容
-	return PromptKVCacheStablePrefix
+	// 这个前缀应该保持稳定，不包含时间戳等变化内容，使用默认语言
+	return prompt.MustRender(context.Background(), prompt.TaskKVCachePrefix, nil).Text
 }
 
 // BuildOptimizedMessages 构建优化的消息
@@ -1,10 +1,15 @@
 package task
 
 import (
-	"ai_task/pkg/clients/llm_model"
+	"ai_task/pkg/clients/llm"
+	"ai_task/pkg/memory"
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/usage"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -12,24 +17,41 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+var (
+	// ErrTaskNotFound 任务不存在
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrTaskCancelled 任务已取消，执行中的作业以该错误为原因中断
+	ErrTaskCancelled = errors.New("task cancelled")
+	// ErrTaskPaused 任务已暂停，需通过恢复接口继续执行
+	ErrTaskPaused = errors.New("task paused")
+	// ErrTaskAwaitingInput 任务等待人工指导，需通过指导接口继续执行
+	ErrTaskAwaitingInput = errors.New("task awaiting input")
+	// ErrTaskNotAwaitingInput 只有等待人工指导的任务可以提交指导
+	ErrTaskNotAwaitingInput = errors.New("task is not awaiting input")
+)
+
 // Executor 任务执行器
 // 实现 Manus 的执行原则：
 // 1. 决策前阅读计划
 // 2. 3次打击错误协议
 // 3. 永不重复失败
 type Executor struct {
-	llmClient *llm_model.ClientChatModel
+	llmClient llm.ChatModel
 	manager   *Manager
 	planner   *Planner
 	config    *ExecutorConfig
+	tools     *ToolRegistry
+	budget    usage.Budget // 为 nil 时不限制 token
 }
 
 // ExecutorConfig 执行器配置
 type ExecutorConfig struct {
-	MaxRetries          int  // 最大重试次数（3次打击规则）
-	RereadBeforeAction  bool // 执行前重读计划
-	AutoSaveFindings    bool // 自动保存发现
-	EnableThreeStrike   bool // 启用3次打击规则
+	MaxRetries         int  // 最大重试次数（3次打击规则）
+	RereadBeforeAction bool // 执行前重读计划
+	AutoSaveFindings   bool // 自动保存发现
+	EnableThreeStrike  bool // 启用3次打击规则
+	MaxStepIterations  int  // 单次尝试中模型决策的最大轮数
+	MaxStepTokens      int  // 单次尝试的对话估算 token 上限，<=0 时不限制
 }
 
 // DefaultExecutorConfig 默认执行器配置
@@ -39,37 +61,43 @@ func DefaultExecutorConfig() *ExecutorConfig {
 		RereadBeforeAction: true,
 		AutoSaveFindings:   true,
 		EnableThreeStrike:  true,
+		MaxStepIterations:  10,
+		MaxStepTokens:      32000,
 	}
 }
 
-// NewExecutor 创建执行器
-func NewExecutor(manager *Manager, config *ExecutorConfig) *Executor {
+// NewExecutor 创建执行器，llmClient 为 nil 时使用全局模型
+func NewExecutor(manager *Manager, config *ExecutorConfig, llmClient llm.ChatModel) *Executor {
 	if config == nil {
 		config = DefaultExecutorConfig()
 	}
 
 	return &Executor{
-		llmClient: llm_model.GetInstance(),
+		llmClient: llm.OrDefault(llmClient),
 		manager:   manager,
-		planner:   NewPlanner(),
+		planner:   NewPlanner(llmClient),
 		config:    config,
+		tools:     NewToolRegistry(manager),
 	}
 }
 
 // ExecutionResult 执行结果
 type ExecutionResult struct {
-	Success    bool                   `json:"success"`
-	Message    string                 `json:"message"`
-	Output     map[string]interface{} `json:"output,omitempty"`
-	NextAction string                 `json:"next_action,omitempty"`
-	Error      string                 `json:"error,omitempty"`
-	Attempt    int                    `json:"attempt"`
+	Success        bool                   `json:"success"`
+	Message        string                 `json:"message"`
+	Output         map[string]interface{} `json:"output,omitempty"`
+	NextAction     string                 `json:"next_action,omitempty"`
+	Error          string                 `json:"error,omitempty"`
+	Attempt        int                    `json:"attempt"`
+	Paused         bool                   `json:"paused,omitempty"`          // 执行在步骤之间被暂停，暂停位置记录在任务中
+	AwaitingInput  bool                   `json:"awaiting_input,omitempty"`  // 步骤重试用尽，等待人工指导，问题记录在任务中
+	BudgetExceeded bool                   `json:"budget_exceeded,omitempty"` // token 预算超出，任务已置为 budget_exceeded
 }
 
 // ExecuteStep 执行单个步骤
 func (e *Executor) ExecuteStep(ctx context.Context, taskID, phaseID, stepID string, action func(ctx context.Context) (*ExecutionResult, error)) (*ExecutionResult, error) {
 	// 获取任务上下文（用于验证任务存在）
-	_, err := e.manager.GetTaskContext(ctx, taskID)
+	taskCtx, err := e.manager.GetTaskContext(ctx, taskID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get task context: %w", err)
 	}
@@ -80,54 +108,104 @@ func (e *Executor) ExecuteStep(ctx context.Context, taskID, phaseID, stepID stri
 		// 这里只是标记已读，实际的计划内容会在需要时被引用
 	}
 
-	// 实现3次打击错误协议
-	var lastResult *ExecutionResult
-	for attempt := 1; attempt <= e.config.MaxRetries; attempt++ {
+	// 实现3次打击错误协议：返回错误或失败结果都计为一次失败，记录后重试
+	maxRetries := max(e.config.MaxRetries, 1)
+	lastErr := "Unknown error"
+	var attempts []ErrorRecord
+	for attempt := 1; attempt <= maxRetries; attempt++ {
 		result, err := action(ctx)
 		if err != nil {
-			// 记录错误
-			_ = e.manager.RecordError(ctx, taskID, err.Error(), attempt, "")
-
-			if attempt < e.config.MaxRetries {
-				log.Warnf("Step %s failed (attempt %d/%d): %v, retrying with different approach",
-					stepID, attempt, e.config.MaxRetries, err)
-				continue
+			// 被取消或暂停时直接中断，不计入失败
+			if ctx.Err() != nil {
+				return nil, context.Cause(ctx)
 			}
-
-			// 3次失败后升级给用户
-			return &ExecutionResult{
-				Success: false,
-				Error:   fmt.Sprintf("Step failed after %d attempts: %v", attempt, err),
-				Attempt: attempt,
-				Message: "请提供进一步指导",
-			}, nil
-		}
-
-		if result != nil && result.Success {
+			lastErr = err.Error()
+		} else if result != nil && result.BudgetExceeded {
+			// 预算超出时停止，不计入失败
+			return result, nil
+		} else if result != nil && result.Success {
 			// 成功完成步骤
+			result.Attempt = attempt
 			_ = e.manager.CompleteStep(ctx, taskID, phaseID, stepID, result.Message)
 			return result, nil
+		} else if result != nil && result.Error != "" {
+			lastErr = result.Error
 		}
 
-		lastResult = result
-		if result != nil && result.Error != "" {
-			_ = e.manager.RecordError(ctx, taskID, result.Error, attempt, "")
+		// 记录错误
+		attempts = append(attempts, ErrorRecord{Error: lastErr, Attempt: attempt, Timestamp: time.Now(), PhaseID: phaseID})
+		_ = e.manager.RecordError(ctx, taskID, lastErr, attempt, "")
+		if attempt < maxRetries {
+			log.Warnf("Step %s failed (attempt %d/%d): %s, retrying with different approach",
+				stepID, attempt, maxRetries, lastErr)
 		}
 	}
 
-	if lastResult == nil {
-		lastResult = &ExecutionResult{
-			Success: false,
-			Error:   "Unknown error",
-			Attempt: e.config.MaxRetries,
-		}
+	// 3次失败后升级给用户，任务等待人工指导后从该步骤继续
+	stepName := stepID
+	if step := findStep(taskCtx.Task, phaseID, stepID); step != nil {
+		stepName = step.Description
 	}
+	pending := &PendingInput{
+		PhaseID:   phaseID,
+		StepID:    stepID,
+		Question:  fmt.Sprintf("步骤「%s」尝试 %d 次仍未完成，最后一次错误: %s。请提供进一步指导", stepName, maxRetries, lastErr),
+		Attempts:  attempts,
+		CreatedAt: time.Now(),
+	}
+	if err := e.manager.AwaitInput(ctx, taskID, pending); err != nil {
+		return nil, fmt.Errorf("failed to await input: %w", err)
+	}
+	log.Warnf("Task %s awaiting guidance for step %s/%s", taskID, phaseID, stepID)
 
-	return lastResult, nil
+	return &ExecutionResult{
+		Success:       false,
+		Error:         fmt.Sprintf("Step failed after %d attempts: %s", maxRetries, lastErr),
+		Attempt:       maxRetries,
+		Message:       "请提供进一步指导",
+		AwaitingInput: true,
+	}, nil
+}
+
+// findStep 查找阶段中的步骤，不存在时返回 nil
+func findStep(task *Task, phaseID, stepID string) *TaskStep {
+	for i := range task.Phases {
+		if task.Phases[i].ID != phaseID {
+			continue
+		}
+		for j := range task.Phases[i].Steps {
+			if task.Phases[i].Steps[j].ID == stepID {
+				return &task.Phases[i].Steps[j]
+			}
+		}
+	}
+	return nil
 }
 
 // ExecutePhase 执行整个阶段
-func (e *Executor) ExecutePhase(ctx context.Context, taskID, phaseID string) (*ExecutionResult, error) {
+func (e *Executor) ExecutePhase(ctx context.Context, taskID, phaseID string) (result *ExecutionResult, err error) {
+	task, err := e.manager.GetTask(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	if err := checkRunnable(task); err != nil {
+		return nil, err
+	}
+
+	// 暂停位置在该阶段时从暂停的步骤继续
+	fromStepID := ""
+	if cursor := task.Cursor; cursor != nil && cursor.PhaseID == phaseID {
+		log.Infof("Task %s resumes at %s/%s", taskID, cursor.PhaseID, cursor.StepID)
+		fromStepID = cursor.StepID
+		defer func() { e.clearCursor(ctx, taskID, result) }()
+	}
+
+	return e.executePhase(ctx, taskID, phaseID, fromStepID)
+}
+
+// executePhase 执行阶段，fromStepID 不为空时跳过该步骤之前的步骤
+// 每个步骤开始前检查是否被取消或暂停，暂停时记录该步骤为恢复位置
+func (e *Executor) executePhase(ctx context.Context, taskID, phaseID, fromStepID string) (*ExecutionResult, error) {
 	taskCtx, err := e.manager.GetTaskContext(ctx, taskID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get task context: %w", err)
@@ -150,15 +228,35 @@ func (e *Executor) ExecutePhase(ctx context.Context, taskID, phaseID string) (*E
 	if targetPhase == nil {
 		return nil, fmt.Errorf("phase not found: %s", phaseID)
 	}
+	if fromStepID != "" && !hasStep(targetPhase, fromStepID) {
+		// 暂停后计划被修改，暂停的步骤已不存在
+		log.Warnf("Task %s cursor step %s/%s not found, start from the first incomplete step", taskID, phaseID, fromStepID)
+		fromStepID = ""
+	}
 
 	// 执行每个步骤
 	for _, step := range targetPhase.Steps {
+		if fromStepID != "" {
+			if step.ID != fromStepID {
+				continue
+			}
+			fromStepID = ""
+		}
 		if step.Completed {
 			continue
 		}
 
-		// 这里使用 LLM 决定如何执行步骤
-		result, err := e.decideAndExecuteStep(ctx, taskCtx, targetPhase, &step)
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+		if !beforeStep(ctx, phaseID, step.ID) {
+			return e.pause(ctx, taskID, &TaskCursor{PhaseID: phaseID, StepID: step.ID})
+		}
+
+		// 由模型循环调用工具执行步骤，失败时按3次打击规则重试
+		result, err := e.ExecuteStep(ctx, taskID, phaseID, step.ID, func(ctx context.Context) (*ExecutionResult, error) {
+			return e.runStep(ctx, taskID, targetPhase, &step)
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -180,129 +278,226 @@ func (e *Executor) ExecutePhase(ctx context.Context, taskID, phaseID string) (*E
 	}, nil
 }
 
-// decideAndExecuteStep 决定并执行步骤
-func (e *Executor) decideAndExecuteStep(ctx context.Context, taskCtx *TaskContext, phase *TaskPhase, step *TaskStep) (*ExecutionResult, error) {
-	// 构建决策提示
-	decisionPrompt := e.buildDecisionPrompt(taskCtx, phase, step)
+// runStep 以 ReAct 循环执行步骤的一次尝试
+// 模型每轮输出一个决策：调用工具时执行工具并把结果作为观察追加到对话中，声明完成或失败时结束；
+// 超过 MaxStepIterations 轮或对话超过 MaxStepTokens 时本次尝试失败。
+// 工具调用次数达到重读阈值（Task.NeedsReread）时，在观察中复述任务计划
+func (e *Executor) runStep(ctx context.Context, taskID string, phase *TaskPhase, step *TaskStep) (*ExecutionResult, error) {
+	// 每次尝试重新读取上下文，之前尝试记录的错误会出现在提示词中
+	taskCtx, err := e.manager.GetTaskContext(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task context: %w", err)
+	}
+
+	system, err := prompt.Render(ctx, prompt.TaskExecutorSystem, nil)
+	if err != nil {
+		return nil, err
+	}
+	data := decisionPromptData(taskCtx, phase, step)
+	data["Tools"] = e.tools.Available(NewToolLoader(e.manager).GetAvailableTools(phase.Status))
+	user, err := prompt.Render(ctx, prompt.TaskExecutorDecision, data)
+	if err != nil {
+		return nil, err
+	}
 
 	messages := []openai.ChatCompletionMessage{
 		{
 			Role:    openai.ChatMessageRoleSystem,
-			Content: PromptExecutorSystem,
+			Content: system.Text,
 		},
 		{
 			Role:    openai.ChatMessageRoleUser,
-			Content: decisionPrompt,
+			Content: user.Text,
 		},
 	}
+	genCtx := prompt.WithRendered(usage.WithRole(ctx, usage.RoleExecutor), system, user)
 
-	result, err := e.llmClient.PostChatCompletionsNonStreamContent(ctx, messages)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get decision: %w", err)
-	}
+	for iteration := 1; iteration <= e.config.MaxStepIterations; iteration++ {
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+		// 每次调用模型前检查预算，步骤执行中超出后不再继续调用
+		if result := e.checkBudget(ctx, taskCtx.Task); result != nil {
+			return result, nil
+		}
+		if e.config.MaxStepTokens > 0 && memory.CountMessagesTokens(messages) > e.config.MaxStepTokens {
+			return &ExecutionResult{
+				Success: false,
+				Message: fmt.Sprintf("Step %s stopped: context too large", step.ID),
+				Error:   fmt.Sprintf("step context exceeded %d tokens after %d iterations", e.config.MaxStepTokens, iteration-1),
+			}, nil
+		}
 
-	// 解析决策结果
-	decision, err := e.parseDecision(result)
-	if err != nil {
-		log.Warnf("Failed to parse decision: %v, treating as completed", err)
-		decision = &StepDecision{
-			Action:  "complete",
-			Message: "步骤已标记完成",
+		// 决策不符合 StepDecision 时由模型修复，仍失败则本次尝试失败
+		decision, err := llm.GenerateJSON[StepDecision](genCtx, e.llmClient, messages, nil)
+		if errors.Is(err, llm.ErrInvalidStructuredOutput) {
+			log.Warnf("Invalid decision for step %s of task %s: %v", step.ID, taskID, err)
+			return &ExecutionResult{
+				Success: false,
+				Message: fmt.Sprintf("Step %s not executed: invalid decision output", step.ID),
+				Error:   fmt.Sprintf("invalid decision output: %v", err),
+			}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get decision: %w", err)
 		}
-	}
 
-	// 记录决策
-	if decision.Rationale != "" {
-		_ = e.manager.AddDecision(ctx, taskCtx.Task.ID, decision.Action, decision.Rationale)
-	}
+		// 记录发现
+		for _, finding := range decision.Findings {
+			_ = e.manager.AddFinding(ctx, taskID, finding.Category, finding.Content, finding.Source)
+		}
 
-	// 记录发现
-	for _, finding := range decision.Findings {
-		_ = e.manager.AddFinding(ctx, taskCtx.Task.ID, finding.Category, finding.Content, finding.Source)
-	}
+		switch decision.Action {
+		case StepActionComplete, StepActionFail:
+			// 记录决策
+			if decision.Rationale != "" {
+				_ = e.manager.AddDecision(ctx, taskID, decision.Message, decision.Rationale)
+			}
+			if decision.Action == StepActionFail {
+				return &ExecutionResult{
+					Success: false,
+					Message: decision.Message,
+					Error:   fmt.Sprintf("步骤 %s 执行失败: %s", step.ID, decision.Message),
+				}, nil
+			}
+			return &ExecutionResult{
+				Success: true,
+				Message: decision.Message,
+				Output: map[string]interface{}{
+					"action":     decision.Action,
+					"rationale":  decision.Rationale,
+					"iterations": iteration,
+				},
+			}, nil
+		}
 
-	// 标记步骤完成
-	_ = e.manager.CompleteStep(ctx, taskCtx.Task.ID, phase.ID, step.ID, decision.Message)
+		observation, err := e.invokeTool(ctx, taskID, decision)
+		if err != nil {
+			return nil, err
+		}
+		content, _ := json.Marshal(decision)
+		messages = append(messages,
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: string(content)},
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: observation},
+		)
+	}
 
 	return &ExecutionResult{
-		Success: true,
-		Message: decision.Message,
-		Output: map[string]interface{}{
-			"action":    decision.Action,
-			"rationale": decision.Rationale,
-		},
+		Success: false,
+		Message: fmt.Sprintf("Step %s not finished", step.ID),
+		Error:   fmt.Sprintf("step not finished after %d iterations", e.config.MaxStepIterations),
 	}, nil
 }
 
-// StepDecision 步骤决策
-type StepDecision struct {
-	Action    string    `json:"action"`
-	Message   string    `json:"message"`
-	Rationale string    `json:"rationale,omitempty"`
-	Findings  []Finding `json:"findings,omitempty"`
-}
-
-// 提示词常量已移至 prompts.go
-
-// buildDecisionPrompt 构建决策提示
-func (e *Executor) buildDecisionPrompt(taskCtx *TaskContext, phase *TaskPhase, step *TaskStep) string {
-	// 获取任务摘要（用于上下文压缩）
-	var errorsSection string
-	if len(taskCtx.Task.Errors) > 0 {
-		errorsSection = "\n\n## 已知错误（避免重复）:\n"
-		for _, err := range taskCtx.Task.Errors {
-			errorsSection += fmt.Sprintf("- %s (尝试 %d 次): %s\n", err.Error, err.Attempt, err.Resolution)
-		}
+// invokeTool 执行决策中的工具调用并渲染观察
+// 调用后任务需要重读计划时，在观察中附上任务计划并清除重读标记
+func (e *Executor) invokeTool(ctx context.Context, taskID string, decision *StepDecision) (string, error) {
+	call, err := e.tools.Invoke(ctx, taskID, decision.Tool, decision.Args)
+	if err != nil {
+		return "", fmt.Errorf("failed to invoke tool %s: %w", decision.Tool, err)
 	}
 
-	var decisionsSection string
-	if len(taskCtx.Task.Decisions) > 0 {
-		decisionsSection = "\n\n## 已做决策:\n"
-		for _, d := range taskCtx.Task.Decisions {
-			decisionsSection += fmt.Sprintf("- %s: %s\n", d.Decision, d.Rationale)
-		}
+	data := map[string]any{
+		"Tool":   call.Name,
+		"Result": call.Result,
+		"Error":  call.Error,
+		"Plan":   "",
 	}
 
-	var findingsSection string
-	if taskCtx.Findings != nil && len(taskCtx.Findings.Findings) > 0 {
-		findingsSection = "\n\n## 相关发现:\n"
-		// 只取最近5个发现
-		start := 0
-		if len(taskCtx.Findings.Findings) > 5 {
-			start = len(taskCtx.Findings.Findings) - 5
+	taskCtx, err := e.manager.GetTaskContext(ctx, taskID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get task context: %w", err)
+	}
+	if taskCtx.Task.NeedsReread {
+		recent, err := e.manager.storage.ListToolCalls(taskID, 5)
+		if err != nil {
+			log.Warnf("Failed to list tool calls of task %s: %v", taskID, err)
 		}
-		for i := start; i < len(taskCtx.Findings.Findings); i++ {
-			f := taskCtx.Findings.Findings[i]
-			findingsSection += fmt.Sprintf("- [%s] %s\n", f.Category, f.Content)
+		toolCalls := make([]ToolCall, 0, len(recent))
+		for _, tc := range recent {
+			toolCalls = append(toolCalls, *tc)
 		}
+		data["Plan"] = NewContextBuilder(0, nil).BuildContext(taskCtx, toolCalls)
+		_ = e.manager.ClearNeedsReread(ctx, taskID)
+		log.Infof("Task %s rereads plan after %d tool calls", taskID, taskCtx.Task.ToolCallCount)
 	}
 
-	return fmt.Sprintf(`## 任务信息
+	observation, err := prompt.Render(ctx, prompt.TaskExecutorObservation, data)
+	if err != nil {
+		return "", err
+	}
+	return observation.Text, nil
+}
+
+// 步骤决策的动作
+const (
+	StepActionTool     = "tool"     // 调用工具，结果在下一轮返回
+	StepActionComplete = "complete" // 步骤已完成
+	StepActionFail     = "fail"     // 步骤无法完成
+)
 
-目标: %s
-当前阶段: %s - %s
-当前步骤: %s
-%s%s%s
+// StepDecision 步骤决策，每轮 ReAct 循环输出一个
+type StepDecision struct {
+	Action    string                 `json:"action" enum:"tool,complete,fail"`
+	Tool      string                 `json:"tool,omitempty"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+	Message   string                 `json:"message"`
+	Rationale string                 `json:"rationale,omitempty"`
+	Findings  []Finding              `json:"findings,omitempty"`
+}
 
-请决定如何执行这个步骤，并提供你的发现。`,
-		taskCtx.Task.Goal,
-		phase.ID, phase.Name,
-		step.Description,
-		errorsSection,
-		decisionsSection,
-		findingsSection)
+// Validate 动作和结果描述不能为空，调用工具时需要工具名，发现需要类别和内容
+func (d *StepDecision) Validate() error {
+	if strings.TrimSpace(d.Action) == "" {
+		return fmt.Errorf("$.action: 不能为空")
+	}
+	if strings.TrimSpace(d.Message) == "" {
+		return fmt.Errorf("$.message: 不能为空")
+	}
+	if d.Action == StepActionTool && strings.TrimSpace(d.Tool) == "" {
+		return fmt.Errorf("$.tool: action 为 tool 时不能为空")
+	}
+	for i, finding := range d.Findings {
+		if strings.TrimSpace(finding.Category) == "" || strings.TrimSpace(finding.Content) == "" {
+			return fmt.Errorf("$.findings[%d]: category 和 content 不能为空", i)
+		}
+	}
+	return nil
 }
 
-// parseDecision 解析决策结果
-func (e *Executor) parseDecision(result string) (*StepDecision, error) {
-	result = cleanJSONResponse(result)
+// decisionPromptData 决策提示词参数，发现只取最近5个，人工指导只在对应步骤注入
+func decisionPromptData(taskCtx *TaskContext, phase *TaskPhase, step *TaskStep) map[string]any {
+	var findings []Finding
+	if taskCtx.Findings != nil {
+		findings = taskCtx.Findings.Findings
+		if len(findings) > 5 {
+			findings = findings[len(findings)-5:]
+		}
+	}
+
+	return map[string]any{
+		"Goal":      taskCtx.Task.Goal,
+		"PhaseID":   phase.ID,
+		"PhaseName": phase.Name,
+		"Step":      step.Description,
+		"Errors":    taskCtx.Task.Errors,
+		"Decisions": taskCtx.Task.Decisions,
+		"Findings":  findings,
+		"Guidance":  stepGuidance(taskCtx.Task, phase.ID, step.ID),
+	}
+}
 
-	var decision StepDecision
-	if err := json.Unmarshal([]byte(result), &decision); err != nil {
-		return nil, fmt.Errorf("failed to parse decision JSON: %w", err)
+// stepGuidance 步骤对应的人工指导，没有时返回 nil
+func stepGuidance(task *Task, phaseID, stepID string) *Guidance {
+	if g := task.Guidance; g != nil && g.PhaseID == phaseID && g.StepID == stepID {
+		return g
 	}
+	return nil
+}
 
-	return &decision, nil
+// hasStep 阶段中是否存在该步骤
+func hasStep(phase *TaskPhase, stepID string) bool {
+	return slices.ContainsFunc(phase.Steps, func(step TaskStep) bool { return step.ID == stepID })
 }
 
 // getNextPhaseID 获取下一个阶段ID
@@ -316,22 +511,65 @@ func (e *Executor) getNextPhaseID(task *Task, currentPhaseID string) string {
 }
 
 // ExecuteTask 执行整个任务
-func (e *Executor) ExecuteTask(ctx context.Context, taskID string) (*ExecutionResult, error) {
+// 任务有暂停位置时从该位置继续，之前的阶段与步骤不再执行；执行结束（暂停或中断除外）后清除暂停位置
+func (e *Executor) ExecuteTask(ctx context.Context, taskID string) (result *ExecutionResult, err error) {
 	taskCtx, err := e.manager.GetTaskContext(ctx, taskID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get task context: %w", err)
 	}
 
+	task := taskCtx.Task
+	if err := checkRunnable(task); err != nil {
+		return nil, err
+	}
+	ctx = usage.WithTags(ctx, usage.Tags{UserID: task.UserID, SessionID: task.SessionID, TaskID: task.ID})
+
+	cursor := task.Cursor
+	if cursor != nil {
+		// 暂停后计划被修改，暂停位置已不存在时从第一个未完成的阶段或步骤开始
+		phaseIndex := slices.IndexFunc(task.Phases, func(p TaskPhase) bool { return p.ID == cursor.PhaseID })
+		switch {
+		case phaseIndex < 0:
+			log.Warnf("Task %s cursor phase %s not found, start from the first incomplete phase", taskID, cursor.PhaseID)
+			cursor = nil
+		case cursor.StepID != "" && !hasStep(&task.Phases[phaseIndex], cursor.StepID):
+			log.Warnf("Task %s cursor step %s/%s not found, start from the first incomplete step", taskID, cursor.PhaseID, cursor.StepID)
+			cursor = &TaskCursor{PhaseID: cursor.PhaseID}
+		}
+	}
+	if cursor != nil {
+		log.Infof("Task %s resumes at %s/%s", taskID, cursor.PhaseID, cursor.StepID)
+		defer func() { e.clearCursor(ctx, taskID, result) }()
+	}
+
 	// 更新任务状态为进行中
 	taskCtx.Task.Status = TaskStatusInProgress
 
 	// 执行每个未完成的阶段
 	for _, phase := range taskCtx.Task.Phases {
+		fromStepID := ""
+		if cursor != nil {
+			// 跳过暂停位置之前的阶段
+			if phase.ID != cursor.PhaseID {
+				continue
+			}
+			fromStepID = cursor.StepID
+			cursor = nil
+		}
 		if phase.Status == PhaseStatusComplete {
 			continue
 		}
 
-		result, err := e.ExecutePhase(ctx, taskID, phase.ID)
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+
+		// 每个阶段开始前检查预算，超出后停止执行
+		if result := e.checkBudget(ctx, task); result != nil {
+			return result, nil
+		}
+
+		result, err := e.executePhase(ctx, taskID, phase.ID, fromStepID)
 		if err != nil {
 			return nil, err
 		}
@@ -360,10 +598,82 @@ func (e *Executor) ExecuteTask(ctx context.Context, taskID string) (*ExecutionRe
 	}, nil
 }
 
+// clearCursor 从暂停位置继续的执行结束后清除该位置
+// 被暂停或等待人工指导时已记录新的位置；被取消或进程退出时保留位置，重启恢复后仍从此处继续
+func (e *Executor) clearCursor(ctx context.Context, taskID string, result *ExecutionResult) {
+	if (result != nil && (result.Paused || result.AwaitingInput)) || ctx.Err() != nil {
+		return
+	}
+	if err := e.manager.ClearCursor(ctx, taskID); err != nil {
+		log.Warnf("Failed to clear cursor of task %s: %v", taskID, err)
+	}
+}
+
+// checkRunnable 已取消、已暂停或等待人工指导的任务不能直接执行，
+// 暂停的任务需通过恢复接口继续，等待指导的任务需通过指导接口继续
+func checkRunnable(task *Task) error {
+	if task == nil {
+		return ErrTaskNotFound
+	}
+	switch task.Status {
+	case TaskStatusCancelled:
+		return fmt.Errorf("%w: %s", ErrTaskCancelled, task.ID)
+	case TaskStatusPaused:
+		return fmt.Errorf("%w: %s", ErrTaskPaused, task.ID)
+	case TaskStatusAwaitingInput:
+		return fmt.Errorf("%w: %s", ErrTaskAwaitingInput, task.ID)
+	}
+	return nil
+}
+
+// pause 在 cursor 指向的步骤之前暂停，记录恢复位置
+func (e *Executor) pause(ctx context.Context, taskID string, cursor *TaskCursor) (*ExecutionResult, error) {
+	if err := e.manager.PauseTask(ctx, taskID, cursor); err != nil {
+		return nil, fmt.Errorf("failed to pause task: %w", err)
+	}
+	log.Infof("Task %s paused before %s/%s", taskID, cursor.PhaseID, cursor.StepID)
+
+	return &ExecutionResult{
+		Success: false,
+		Message: fmt.Sprintf("Task paused before step %s of phase %s", cursor.StepID, cursor.PhaseID),
+		Paused:  true,
+	}, nil
+}
+
+// checkBudget 检查 token 预算，超出时将任务置为 budget_exceeded 并返回停止结果
+// 统计失败时只记录日志，不阻塞执行
+func (e *Executor) checkBudget(ctx context.Context, task *Task) *ExecutionResult {
+	if e.budget == nil {
+		return nil
+	}
+
+	err := e.budget.Check(ctx, task.UserID, task.ID)
+	if err == nil {
+		return nil
+	}
+
+	var exceeded *usage.ExceededError
+	if !errors.As(err, &exceeded) {
+		log.Warnf("Failed to check token budget for task %s: %v", task.ID, err)
+		return nil
+	}
+
+	if err := e.manager.UpdateTaskStatus(ctx, task.ID, TaskStatusBudgetExceeded); err != nil {
+		log.Errorf("Failed to update task %s status to %s: %v", task.ID, TaskStatusBudgetExceeded, err)
+	}
+
+	return &ExecutionResult{
+		Success:        false,
+		Message:        fmt.Sprintf("Task stopped: %s", exceeded.Error()),
+		Error:          exceeded.Error(),
+		BudgetExceeded: true,
+	}
+}
+
 // ContextBuilder 上下文构建器
 // 用于构建发送给 LLM 的上下文
 type ContextBuilder struct {
-	maxTokens int
+	maxTokens   int
 	compression *ContextCompression
 }
 
@@ -651,12 +961,12 @@ type CompletionStatus struct {
 
 // RebootCheck 5问题重启检查
 type RebootCheck struct {
-	WhereAmI     string `json:"where_am_i"`
-	WhereGoing   string `json:"where_going"`
-	WhatIsGoal   string `json:"what_is_goal"`
-	WhatLearned  string `json:"what_learned"`
-	WhatDone     string `json:"what_done"`
-	AllAnswered  bool   `json:"all_answered"`
+	WhereAmI    string `json:"where_am_i"`
+	WhereGoing  string `json:"where_going"`
+	WhatIsGoal  string `json:"what_is_goal"`
+	WhatLearned string `json:"what_learned"`
+	WhatDone    string `json:"what_done"`
+	AllAnswered bool   `json:"all_answered"`
 }
 
 // performRebootCheck 执行5问题重启检查
@@ -717,24 +1027,21 @@ func (cc *CompletionChecker) performRebootCheck(taskCtx *TaskContext) RebootChec
 
 // Session 会话管理
 type Session struct {
-	ID        string
-	TaskID    string
-	StartedAt time.Time
-	manager   *Manager
-	executor  *Executor
-	tracker   *ActionTracker
+	ID         string
+	TaskID     string
+	StartedAt  time.Time
+	manager    *Manager
+	tracker    *ActionTracker
 	errTracker *ErrorTracker
-	checker   *CompletionChecker
+	checker    *CompletionChecker
 }
 
-// NewSession 创建会话
+// NewSession 创建会话，会话中的任务通过后台作业执行
 func NewSession(manager *Manager) *Session {
-	executor := NewExecutor(manager, nil)
 	return &Session{
 		ID:         fmt.Sprintf("session_%d", time.Now().UnixNano()),
 		StartedAt:  time.Now(),
 		manager:    manager,
-		executor:   executor,
 		tracker:    NewActionTracker(manager),
 		errTracker: NewErrorTracker(manager),
 		checker:    NewCompletionChecker(manager),
@@ -754,15 +1061,6 @@ func (s *Session) Start(ctx context.Context, req *PlanRequest) (*Task, error) {
 	return task, nil
 }
 
-// Execute 执行任务
-func (s *Session) Execute(ctx context.Context) (*ExecutionResult, error) {
-	if s.TaskID == "" {
-		return nil, fmt.Errorf("no task associated with session")
-	}
-
-	return s.executor.ExecuteTask(ctx, s.TaskID)
-}
-
 // CheckStop 检查是否可以停止
 func (s *Session) CheckStop(ctx context.Context) (*CompletionStatus, error) {
 	if s.TaskID == "" {
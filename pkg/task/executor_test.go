@@ -0,0 +1,190 @@
+package task
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ai_task/pkg/clients/llm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newScriptedExecutor 创建使用脚本模型的执行器与默认阶段的任务，工具调用每 2 次触发重读
+func newScriptedExecutor(t *testing.T, config *ExecutorConfig, steps ...llm.ScriptStep) (*Executor, *llm.ScriptedModel, *Task) {
+	tmpDir, err := os.MkdirTemp("", "task_test_*")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(tmpDir) })
+
+	manager, err := NewManager(&TaskManagerConfig{StoragePath: tmpDir, RereadThreshold: 2})
+	require.NoError(t, err)
+	task, err := manager.CreateTask(context.Background(), &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.NoError(t, err)
+
+	model := llm.NewScriptedModel(steps...)
+	return NewExecutor(manager, config, model), model, task
+}
+
+func TestExecutorStepToolLoop(t *testing.T) {
+	executor, model, task := newScriptedExecutor(t, nil,
+		llm.ReplyWhen("理解用户意图", `{"action": "tool", "tool": "write_file", "args": {"path": "notes.md", "content": "# 需求\n缓存"}, "message": "记录需求"}`),
+		llm.ReplyWhen("理解用户意图", `{"action": "tool", "tool": "read_file", "args": {"path": "notes.md"}, "message": "确认内容"}`),
+		llm.ReplyWhen("理解用户意图", `{"action": "complete", "message": "需求已记录", "rationale": "文件内容正确", "findings": [{"category": "research", "content": "需要缓存"}]}`),
+		llm.ScriptStep{Content: `{"action": "complete", "message": "完成"}`, Repeat: true},
+	)
+	ctx := context.Background()
+
+	result, err := executor.ExecutePhase(ctx, task.ID, "phase_1")
+	require.NoError(t, err)
+	assert.True(t, result.Success, result.Error)
+
+	data, err := os.ReadFile(filepath.Join(executor.tools.WorkspaceDir(task.ID), "notes.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# 需求\n缓存", string(data))
+
+	// 工具结果作为观察追加到对话中
+	calls := model.Calls()
+	require.Len(t, calls, 3+2)
+	assert.Len(t, calls[1], 4)
+	assert.Equal(t, "assistant", calls[1][2].Role)
+	assert.Contains(t, calls[1][2].Content, `"tool":"write_file"`)
+	assert.Contains(t, calls[1][3].Content, "工具 write_file 的执行结果")
+	assert.NotContains(t, calls[1][3].Content, "重读任务计划")
+
+	// 第 2 次工具调用达到重读阈值，观察中复述计划并清除标记
+	assert.Contains(t, calls[2][5].Content, "# 需求\n缓存")
+	assert.Contains(t, calls[2][5].Content, "重读任务计划")
+	assert.Contains(t, calls[2][5].Content, "目标: 实现一个缓存")
+
+	taskCtx, err := executor.manager.GetTaskContext(ctx, task.ID)
+	require.NoError(t, err)
+	assert.False(t, taskCtx.Task.NeedsReread)
+	assert.True(t, taskCtx.Task.Phases[0].Steps[0].Completed)
+	assert.Equal(t, "需求已记录", taskCtx.Task.Phases[0].Steps[0].Result)
+	assert.Empty(t, taskCtx.Task.Errors)
+	require.NotNil(t, taskCtx.Findings)
+	assert.Len(t, taskCtx.Findings.Findings, 1)
+
+	toolCalls, err := executor.manager.storage.ListToolCalls(task.ID, 0)
+	require.NoError(t, err)
+	assert.Len(t, toolCalls, 2)
+}
+
+func TestExecutorStepFailureUsesThreeStrike(t *testing.T) {
+	executor, model, task := newScriptedExecutor(t, nil,
+		llm.ReplyWhen("理解用户意图", `{"action": "fail", "message": "缺少需求文档"}`),
+		llm.ReplyWhen("理解用户意图", `{"action": "tool", "tool": "web_search", "args": {"query": "缓存"}, "message": "搜索需求"}`),
+		llm.ReplyWhen("理解用户意图", `{"action": "fail", "message": "无法搜索"}`),
+		llm.ReplyWhen("理解用户意图", `{"action": "fail", "message": "仍然缺少需求文档"}`),
+	)
+	ctx := context.Background()
+
+	result, err := executor.ExecutePhase(ctx, task.ID, "phase_1")
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Equal(t, "请提供进一步指导", result.Message)
+	assert.Equal(t, 3, result.Attempt)
+	assert.Contains(t, result.Error, "仍然缺少需求文档")
+
+	// 重试时提示词带上之前的失败，工具错误作为观察返回给模型
+	calls := model.Calls()
+	require.Len(t, calls, 4)
+	assert.Contains(t, calls[1][1].Content, "缺少需求文档")
+	assert.Contains(t, calls[2][3].Content, ErrUnknownTool.Error())
+
+	taskCtx, err := executor.manager.GetTaskContext(ctx, task.ID)
+	require.NoError(t, err)
+	assert.False(t, taskCtx.Task.Phases[0].Steps[0].Completed)
+	require.Len(t, taskCtx.Task.Errors, 3)
+	assert.Equal(t, 3, taskCtx.Task.Errors[2].Attempt)
+}
+
+func TestExecutorStepIterationLimit(t *testing.T) {
+	config := DefaultExecutorConfig()
+	config.MaxRetries = 1
+	config.MaxStepIterations = 3
+	executor, model, task := newScriptedExecutor(t, config,
+		llm.ScriptStep{Content: `{"action": "tool", "tool": "list_dir", "message": "查看目录"}`, Repeat: true},
+	)
+
+	result, err := executor.ExecutePhase(context.Background(), task.ID, "phase_1")
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "step not finished after 3 iterations")
+	assert.Len(t, model.Calls(), 3)
+
+	// 对话超过 token 上限时提前结束
+	config.MaxStepTokens = 1
+	executor, model, task = newScriptedExecutor(t, config,
+		llm.ScriptStep{Content: `{"action": "complete", "message": "完成"}`, Repeat: true},
+	)
+	result, err = executor.ExecutePhase(context.Background(), task.ID, "phase_1")
+	require.NoError(t, err)
+	assert.Contains(t, result.Error, "step context exceeded 1 tokens after 0 iterations")
+	assert.Empty(t, model.Calls())
+}
+
+func TestExecutorStepAwaitsInput(t *testing.T) {
+	executor, _, task := newScriptedExecutor(t, nil,
+		llm.ScriptStep{Match: llm.MatchContains("理解用户意图"), Content: `{"action": "fail", "message": "缺少需求文档"}`, Repeat: true},
+	)
+	ctx := context.Background()
+
+	result, err := executor.ExecuteTask(ctx, task.ID)
+	require.NoError(t, err)
+	assert.True(t, result.AwaitingInput)
+	assert.Equal(t, "请提供进一步指导", result.Message)
+
+	// 问题与失败的尝试一起保存，恢复位置指向失败的步骤
+	updated, err := executor.manager.GetTask(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusAwaitingInput, updated.Status)
+	require.NotNil(t, updated.PendingInput)
+	assert.Equal(t, "step_1_1", updated.PendingInput.StepID)
+	assert.Contains(t, updated.PendingInput.Question, "理解用户意图")
+	require.Len(t, updated.PendingInput.Attempts, 3)
+	assert.Equal(t, 3, updated.PendingInput.Attempts[2].Attempt)
+	assert.Equal(t, &TaskCursor{PhaseID: "phase_1", StepID: "step_1_1"}, updated.Cursor)
+
+	_, err = executor.ExecuteTask(ctx, task.ID)
+	assert.ErrorIs(t, err, ErrTaskAwaitingInput)
+
+	// 提交指导后任务暂停在失败的步骤，指导记录为决策
+	_, err = executor.manager.SubmitGuidance(ctx, "missing", "改用内存实现")
+	assert.Error(t, err)
+	guidance, err := executor.manager.SubmitGuidance(ctx, task.ID, "需求见 README")
+	require.NoError(t, err)
+	assert.Equal(t, "step_1_1", guidance.StepID)
+
+	updated, err = executor.manager.GetTask(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusPaused, updated.Status)
+	assert.Nil(t, updated.PendingInput)
+	assert.Equal(t, guidance, updated.Guidance)
+	assert.Equal(t, "需求见 README", updated.Decisions[len(updated.Decisions)-1].Decision)
+
+	_, err = executor.manager.SubmitGuidance(ctx, task.ID, "需求见 README")
+	assert.ErrorIs(t, err, ErrTaskNotAwaitingInput)
+}
+
+func TestExecutorStepStopsOnCancel(t *testing.T) {
+	executor, model, task := newScriptedExecutor(t, nil,
+		llm.ScriptStep{Content: `{"action": "tool", "tool": "stop", "message": "停止"}`, Repeat: true},
+	)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	executor.tools.Register(Tool{Name: "stop", Action: ActionTypeExecute, Run: func(ctx context.Context, env *ToolEnv, args map[string]interface{}) (string, error) {
+		cancel(ErrTaskCancelled)
+		return "", nil
+	}})
+
+	_, err := executor.ExecutePhase(ctx, task.ID, "phase_1")
+	assert.ErrorIs(t, err, ErrTaskCancelled)
+	assert.Len(t, model.Calls(), 1)
+
+	taskCtx, err := executor.manager.GetTaskContext(context.Background(), task.ID)
+	require.NoError(t, err)
+	assert.Empty(t, taskCtx.Task.Errors, "cancellation must not count as a strike")
+}
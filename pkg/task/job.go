@@ -0,0 +1,430 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrJobQueueFull 排队作业达到上限，稍后重试
+	ErrJobQueueFull = errors.New("job queue is full")
+	// ErrJobActive 任务已有排队中或执行中的作业，同一任务不并发执行
+	ErrJobActive = errors.New("task already has an active job")
+	// ErrNoActiveJob 任务没有排队中或执行中的作业，无法暂停
+	ErrNoActiveJob = errors.New("task has no active job")
+	// ErrTaskNotPaused 只有已暂停的任务可以恢复
+	ErrTaskNotPaused = errors.New("task is not paused")
+	// ErrTaskFinished 已完成或已取消的任务不能再取消
+	ErrTaskFinished = errors.New("task already finished")
+)
+
+// Job 后台执行作业
+// 执行接口提交作业后立即返回作业ID，通过作业查询接口获取执行进度与结果
+type Job struct {
+	ID           string           `json:"id"`                      // 作业ID
+	TaskID       string           `json:"task_id"`                 // 任务ID
+	SessionID    string           `json:"session_id,omitempty"`    // 通过会话提交时的会话ID
+	PhaseID      string           `json:"phase_id,omitempty"`      // 只执行指定阶段，为空时执行整个任务
+	Locale       string           `json:"locale,omitempty"`        // 提示词语言，为空时使用用户偏好
+	Status       JobStatus        `json:"status"`                  // 作业状态（queued/running/succeeded/failed/cancelled/paused/blocked）
+	CurrentPhase string           `json:"current_phase,omitempty"` // 正在执行或最后执行的阶段ID
+	CurrentStep  string           `json:"current_step,omitempty"`  // 正在执行或最后执行的步骤ID
+	Result       *ExecutionResult `json:"result,omitempty"`        // 执行结束后的结果
+	Error        string           `json:"error,omitempty"`         // 执行出错时的错误信息
+	Attempts     int              `json:"attempts"`                // 开始执行次数，重启恢复后重新执行时累加
+	CreatedAt    time.Time        `json:"created_at"`              // 提交时间
+	StartedAt    *time.Time       `json:"started_at,omitempty"`    // 最近一次开始执行时间
+	FinishedAt   *time.Time       `json:"finished_at,omitempty"`   // 结束时间
+	UpdatedAt    time.Time        `json:"updated_at"`              // 最后更新时间
+}
+
+// JobRunner 执行作业，返回的结果写入作业
+type JobRunner func(ctx context.Context, job *Job) (*ExecutionResult, error)
+
+// JobQueue 后台执行作业队列
+// 作业先写入存储再排队，由固定数量的 worker 执行；排队作业超过上限时拒绝提交
+// 启动时从存储恢复排队中与执行中的作业，执行器跳过已完成的步骤，从中断处继续
+// 执行中的作业可以被取消（中断 ctx）或暂停（当前步骤完成后停止）
+// 作业状态在 mu 内修改，写入存储使用释放锁后的副本，慢速存储不阻塞其他任务的提交、取消与暂停
+type JobQueue struct {
+	storage  Storage
+	run      JobRunner
+	workers  int
+	capacity int
+
+	mu      sync.Mutex
+	pending []*Job
+	saving  int                    // 已通过检查、正在写入存储的提交数，计入排队上限
+	active  map[string]string      // taskID -> 排队中或执行中的作业ID
+	running map[string]*runningJob // jobID -> 执行中的作业
+	notify  chan struct{}
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewJobQueue 创建作业队列，workers 与 capacity 小于等于 0 时使用默认值
+func NewJobQueue(storage Storage, run JobRunner, workers, capacity int) *JobQueue {
+	defaults := DefaultTaskManagerConfig()
+	if workers <= 0 {
+		workers = defaults.JobWorkers
+	}
+	if capacity <= 0 {
+		capacity = defaults.JobQueueSize
+	}
+
+	return &JobQueue{
+		storage:  storage,
+		run:      run,
+		workers:  workers,
+		capacity: capacity,
+		active:   make(map[string]string),
+		running:  make(map[string]*runningJob),
+		notify:   make(chan struct{}, workers),
+	}
+}
+
+// Start 恢复存储中未结束的作业并启动 worker
+// 恢复的作业不受排队上限限制；恢复失败只记录日志，不影响新作业提交
+func (q *JobQueue) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+
+	if err := q.recover(); err != nil {
+		log.Errorf("Failed to recover task jobs: %v", err)
+	}
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.worker(ctx)
+	}
+}
+
+// Close 停止 worker 并等待执行中的作业返回
+// 被中断的作业保持 running 状态，下次启动时重新执行
+func (q *JobQueue) Close() {
+	if q.cancel != nil {
+		q.cancel()
+	}
+	q.wg.Wait()
+}
+
+// recover 把排队中与执行中的作业按提交顺序重新排队
+func (q *JobQueue) recover() error {
+	jobs, err := q.storage.ListJobs("", JobStatusQueued, JobStatusRunning)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	q.mu.Lock()
+	snapshots := make([]Job, 0, len(jobs))
+	for _, job := range jobs {
+		if _, ok := q.active[job.TaskID]; ok {
+			// 同一任务只保留最早的作业，其余视为重复提交
+			job.Status = JobStatusFailed
+			job.Error = ErrJobActive.Error()
+			snapshots = append(snapshots, q.finish(job))
+			continue
+		}
+		if job.Status == JobStatusRunning {
+			log.Infof("Task job %s for task %s was interrupted at %s/%s, requeued", job.ID, job.TaskID, job.CurrentPhase, job.CurrentStep)
+		}
+		job.Status = JobStatusQueued
+		snapshots = append(snapshots, *job)
+		q.pending = append(q.pending, job)
+		q.active[job.TaskID] = job.ID
+	}
+	recovered := len(q.pending)
+	q.mu.Unlock()
+
+	for i := range snapshots {
+		q.save(&snapshots[i])
+	}
+	if len(jobs) > 0 {
+		log.Infof("Recovered %d task jobs", recovered)
+	}
+
+	q.mu.Lock()
+	q.signal()
+	q.mu.Unlock()
+	return nil
+}
+
+// Submit 提交作业，保存成功后排队并返回
+// 作业ID、状态与提交时间由队列填写；worker 执行的是作业的副本，调用方持有的 job 不会被修改
+func (q *JobQueue) Submit(job *Job) error {
+	q.mu.Lock()
+	if jobID, ok := q.active[job.TaskID]; ok {
+		q.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrJobActive, jobID)
+	}
+	if len(q.pending)+q.saving >= q.capacity {
+		q.mu.Unlock()
+		return ErrJobQueueFull
+	}
+
+	// 先占用任务，写入存储期间同一任务的重复提交被拒绝
+	job.ID = uuid.New().String()
+	job.Status = JobStatusQueued
+	job.CreatedAt = time.Now()
+	q.active[job.TaskID] = job.ID
+	q.saving++
+	q.mu.Unlock()
+
+	err := q.storage.SaveJob(job)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.saving--
+	if err != nil {
+		if q.active[job.TaskID] == job.ID {
+			delete(q.active, job.TaskID)
+		}
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+
+	queued := *job
+	q.pending = append(q.pending, &queued)
+	q.signal()
+	return nil
+}
+
+// signal 唤醒空闲 worker，调用方持有 q.mu
+func (q *JobQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// next 取出下一个排队作业并登记为执行中，没有时返回 nil
+func (q *JobQueue) next(ctx context.Context) *runningJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.pending) == 0 {
+		return nil
+	}
+	job := q.pending[0]
+	q.pending = q.pending[1:]
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	r := &runningJob{job: job, ctx: jobCtx, cancel: cancel}
+	q.running[job.ID] = r
+	return r
+}
+
+func (q *JobQueue) worker(ctx context.Context) {
+	defer q.wg.Done()
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		r := q.next(ctx)
+		if r == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.notify:
+			}
+			continue
+		}
+
+		q.execute(ctx, r)
+	}
+}
+
+// runningJob 执行中的作业，cancel 以 ErrTaskCancelled 为原因中断执行
+type runningJob struct {
+	job    *Job
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	pause  bool // 当前步骤完成后暂停
+}
+
+// execute 执行作业，每个步骤开始前记录当前阶段与步骤，并检查是否需要暂停
+func (q *JobQueue) execute(ctx context.Context, r *runningJob) {
+	job := r.job
+	defer r.cancel(nil)
+
+	q.mu.Lock()
+	now := time.Now()
+	job.Status = JobStatusRunning
+	job.Attempts++
+	job.StartedAt = &now
+	job.Error = ""
+	snapshot := *job
+	q.mu.Unlock()
+	q.save(&snapshot)
+
+	jobCtx := withStepHook(r.ctx, func(phaseID, stepID string) bool {
+		q.mu.Lock()
+		job.CurrentPhase = phaseID
+		job.CurrentStep = stepID
+		snapshot := *job
+		proceed := !r.pause
+		q.mu.Unlock()
+
+		q.save(&snapshot)
+		return proceed
+	})
+
+	result, err := q.runSafely(jobCtx, job)
+
+	q.mu.Lock()
+	delete(q.running, job.ID)
+
+	switch {
+	case errors.Is(context.Cause(jobCtx), ErrTaskCancelled):
+		job.Status = JobStatusCancelled
+		job.Error = ErrTaskCancelled.Error()
+	case ctx.Err() != nil:
+		// 队列关闭导致中断，保持 running 状态，下次启动时从未完成的步骤继续
+		q.mu.Unlock()
+		log.Warnf("Task job %s interrupted by shutdown", job.ID)
+		return
+	case err != nil:
+		log.Errorf("Task job %s for task %s failed: %v", job.ID, job.TaskID, err)
+		job.Status = JobStatusFailed
+		job.Error = err.Error()
+	case result != nil && result.Paused:
+		job.Status = JobStatusPaused
+		job.Result = result
+	case result != nil && (result.AwaitingInput || result.BudgetExceeded):
+		job.Status = JobStatusBlocked
+		job.Result = result
+	default:
+		job.Status = JobStatusSucceeded
+		job.Result = result
+	}
+	snapshot = q.finish(job)
+	q.mu.Unlock()
+	q.save(&snapshot)
+}
+
+// Cancel 取消任务的作业：排队中的作业移出队列，执行中的作业中断 ctx，进行中的模型调用随之返回
+// 返回作业当前状态的副本，任务没有未结束的作业时返回 nil
+func (q *JobQueue) Cancel(taskID string) *Job {
+	q.mu.Lock()
+	jobID, ok := q.active[taskID]
+	if !ok {
+		q.mu.Unlock()
+		return nil
+	}
+	if r, ok := q.running[jobID]; ok {
+		r.cancel(ErrTaskCancelled)
+		snapshot := *r.job
+		q.mu.Unlock()
+		return &snapshot
+	}
+
+	job := q.dequeue(jobID)
+	if job == nil {
+		q.mu.Unlock()
+		return nil
+	}
+	job.Status = JobStatusCancelled
+	job.Error = ErrTaskCancelled.Error()
+	snapshot := q.finish(job)
+	q.mu.Unlock()
+
+	q.save(&snapshot)
+	return &snapshot
+}
+
+// Pause 暂停任务的作业：排队中的作业移出队列并置为 paused，执行中的作业在当前步骤完成后暂停
+// 返回作业当前状态的副本与作业是否仍在执行，任务没有未结束的作业时返回 nil
+func (q *JobQueue) Pause(taskID string) (*Job, bool) {
+	q.mu.Lock()
+	jobID, ok := q.active[taskID]
+	if !ok {
+		q.mu.Unlock()
+		return nil, false
+	}
+	if r, ok := q.running[jobID]; ok {
+		r.pause = true
+		snapshot := *r.job
+		q.mu.Unlock()
+		return &snapshot, true
+	}
+
+	job := q.dequeue(jobID)
+	if job == nil {
+		q.mu.Unlock()
+		return nil, false
+	}
+	job.Status = JobStatusPaused
+	snapshot := q.finish(job)
+	q.mu.Unlock()
+
+	q.save(&snapshot)
+	return &snapshot, false
+}
+
+// dequeue 从排队列表中移除作业，调用方持有 q.mu
+func (q *JobQueue) dequeue(jobID string) *Job {
+	for i, job := range q.pending {
+		if job.ID == jobID {
+			q.pending = append(q.pending[:i:i], q.pending[i+1:]...)
+			return job
+		}
+	}
+	return nil
+}
+
+// runSafely 执行作业，panic 转为错误，避免 worker 退出
+func (q *JobQueue) runSafely(ctx context.Context, job *Job) (result *ExecutionResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panic: %v", r)
+		}
+	}()
+	return q.run(ctx, job)
+}
+
+// finish 记录结束时间并释放任务，返回待保存的副本，调用方持有 q.mu，释放锁后保存
+func (q *JobQueue) finish(job *Job) Job {
+	now := time.Now()
+	job.FinishedAt = &now
+	if q.active[job.TaskID] == job.ID {
+		delete(q.active, job.TaskID)
+	}
+	return *job
+}
+
+// save 保存作业状态，失败只记录日志，执行不因状态写入失败中断；调用方不持有 q.mu
+func (q *JobQueue) save(job *Job) {
+	if err := q.storage.SaveJob(job); err != nil {
+		log.Warnf("Failed to save task job %s: %v", job.ID, err)
+	}
+}
+
+// stepHook 执行器开始每个步骤前调用，返回 false 时在该步骤之前暂停
+type stepHook func(phaseID, stepID string) bool
+
+type stepHookKey struct{}
+
+// withStepHook 在 ctx 中设置步骤回调
+func withStepHook(ctx context.Context, hook stepHook) context.Context {
+	return context.WithValue(ctx, stepHookKey{}, hook)
+}
+
+// beforeStep 报告即将执行的步骤并返回是否继续，ctx 中没有回调时总是继续
+func beforeStep(ctx context.Context, phaseID, stepID string) bool {
+	if hook, ok := ctx.Value(stepHookKey{}).(stepHook); ok {
+		return hook(phaseID, stepID)
+	}
+	return true
+}
@@ -0,0 +1,567 @@
+package task
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"ai_task/pkg/clients/llm"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitJob 等待作业结束并返回最终状态
+func waitJob(t *testing.T, service *Service, jobID string) *Job {
+	var job *Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = service.GetJob(context.Background(), jobID)
+		require.NoError(t, err)
+		return job != nil && job.Status.IsFinished()
+	}, 5*time.Second, 10*time.Millisecond)
+	return job
+}
+
+// gatedModel 请求包含 gate 时阻塞，直到 release 关闭或 ctx 结束，用于模拟进行中的模型调用
+type gatedModel struct {
+	*llm.ScriptedModel
+	gate    string
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newGatedModel(gate string, steps ...llm.ScriptStep) *gatedModel {
+	return &gatedModel{
+		ScriptedModel: llm.NewScriptedModel(steps...),
+		gate:          gate,
+		entered:       make(chan struct{}, 1),
+		release:       make(chan struct{}),
+	}
+}
+
+func (m *gatedModel) wait(ctx context.Context, messages []openai.ChatCompletionMessage) error {
+	if !llm.MatchContains(m.gate)(messages) {
+		return nil
+	}
+	select {
+	case m.entered <- struct{}{}:
+	default:
+	}
+	select {
+	case <-m.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *gatedModel) PostChatCompletionsNonStream(ctx context.Context, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
+	if err := m.wait(ctx, messages); err != nil {
+		return nil, err
+	}
+	return m.ScriptedModel.PostChatCompletionsNonStream(ctx, messages)
+}
+
+func (m *gatedModel) PostChatCompletionsNonStreamContent(ctx context.Context, messages []openai.ChatCompletionMessage) (string, error) {
+	if err := m.wait(ctx, messages); err != nil {
+		return "", err
+	}
+	return m.ScriptedModel.PostChatCompletionsNonStreamContent(ctx, messages)
+}
+
+func TestServiceSubmitExecution(t *testing.T) {
+	model := llm.NewScriptedModel(
+		llm.Reply(scriptedPlan),
+		llm.ScriptStep{Content: `{"action": "complete", "message": "完成"}`, Repeat: true},
+	)
+	service := newScriptedService(t, model)
+	ctx := context.Background()
+
+	resp, err := service.CreateTask(ctx, &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.NoError(t, err)
+
+	job, err := service.SubmitExecution(ctx, &ExecuteRequest{TaskID: resp.TaskID})
+	require.NoError(t, err)
+	assert.NotEmpty(t, job.ID)
+	assert.Equal(t, JobStatusQueued, job.Status)
+
+	job = waitJob(t, service, job.ID)
+	assert.Equal(t, JobStatusSucceeded, job.Status)
+	require.NotNil(t, job.Result)
+	assert.True(t, job.Result.Success)
+	assert.Equal(t, "Task completed successfully", job.Result.Message)
+	assert.Equal(t, "phase_2", job.CurrentPhase)
+	assert.Equal(t, "step_2", job.CurrentStep)
+	assert.Equal(t, 1, job.Attempts)
+	assert.NotNil(t, job.FinishedAt)
+
+	jobs, err := service.ListJobs(ctx, resp.TaskID)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, job.ID, jobs[0].ID)
+
+	// 不存在的任务不创建作业
+	_, err = service.SubmitExecution(ctx, &ExecuteRequest{TaskID: "missing"})
+	assert.Error(t, err)
+}
+
+func TestServiceRecoversJobsAfterRestart(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "task_test_*")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(tmpDir) })
+	config := &TaskManagerConfig{StoragePath: tmpDir, RereadThreshold: 10}
+
+	model := llm.NewScriptedModel(
+		llm.Reply(scriptedPlan),
+		llm.ScriptStep{Content: `{"action": "complete", "message": "完成"}`, Repeat: true},
+	)
+	service, err := NewService(config, WithChatModel(model))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	resp, err := service.CreateTask(ctx, &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.NoError(t, err)
+	require.NoError(t, service.CompleteStep(ctx, resp.TaskID, "phase_1", "step_1", "已阅读"))
+	service.Close()
+
+	// 模拟上次进程在执行第二阶段时退出
+	started := time.Now().Add(-time.Minute)
+	interrupted := &Job{
+		ID:           "job_interrupted",
+		TaskID:       resp.TaskID,
+		Status:       JobStatusRunning,
+		CurrentPhase: "phase_2",
+		CurrentStep:  "step_1",
+		Attempts:     1,
+		CreatedAt:    started,
+		StartedAt:    &started,
+	}
+	require.NoError(t, service.manager.storage.SaveJob(interrupted))
+
+	restarted, err := NewService(config, WithChatModel(model))
+	require.NoError(t, err)
+	t.Cleanup(restarted.Close)
+
+	job := waitJob(t, restarted, interrupted.ID)
+	assert.Equal(t, JobStatusSucceeded, job.Status)
+	assert.Equal(t, 2, job.Attempts)
+	require.NotNil(t, job.Result)
+	assert.True(t, job.Result.Success)
+
+	// 已完成的第一阶段步骤不再调用模型
+	for _, call := range model.Calls()[1:] {
+		assert.NotContains(t, call[len(call)-1].Content, "阅读文档")
+	}
+}
+
+func TestJobQueueLimits(t *testing.T) {
+	storage, err := NewFileStorage(t.TempDir())
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	queue := NewJobQueue(storage, func(ctx context.Context, job *Job) (*ExecutionResult, error) {
+		<-release
+		return &ExecutionResult{Success: true}, nil
+	}, 1, 1)
+	queue.Start()
+	defer queue.Close()
+	defer close(release)
+
+	running := &Job{TaskID: "task_1"}
+	require.NoError(t, queue.Submit(running))
+	require.Eventually(t, func() bool {
+		job, _ := storage.LoadJob(running.ID)
+		return job != nil && job.Status == JobStatusRunning
+	}, time.Second, 5*time.Millisecond)
+
+	// 同一任务不并发执行
+	assert.ErrorIs(t, queue.Submit(&Job{TaskID: "task_1"}), ErrJobActive)
+
+	// 唯一的 worker 被占用，第二个作业排队，第三个超出上限
+	require.NoError(t, queue.Submit(&Job{TaskID: "task_2"}))
+	assert.ErrorIs(t, queue.Submit(&Job{TaskID: "task_3"}), ErrJobQueueFull)
+
+	jobs, err := storage.ListJobs("", JobStatusQueued)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "task_2", jobs[0].TaskID)
+}
+
+func TestServicePauseAndResume(t *testing.T) {
+	model := newGatedModel("阅读文档",
+		llm.Reply(scriptedPlan),
+		llm.ScriptStep{Content: `{"action": "complete", "message": "完成"}`, Repeat: true},
+	)
+	service := newScriptedService(t, model)
+	ctx := context.Background()
+
+	resp, err := service.CreateTask(ctx, &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.NoError(t, err)
+	job, err := service.SubmitExecution(ctx, &ExecuteRequest{TaskID: resp.TaskID})
+	require.NoError(t, err)
+	<-model.entered
+
+	// 执行中的作业在当前步骤完成后暂停
+	pausing, err := service.PauseTask(ctx, resp.TaskID)
+	require.NoError(t, err)
+	assert.Equal(t, job.ID, pausing.ID)
+	close(model.release)
+
+	job = waitJob(t, service, job.ID)
+	assert.Equal(t, JobStatusPaused, job.Status)
+	require.NotNil(t, job.Result)
+	assert.True(t, job.Result.Paused)
+
+	task, err := service.GetTask(ctx, resp.TaskID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusPaused, task.Status)
+	assert.Equal(t, &TaskCursor{PhaseID: "phase_2", StepID: "step_1"}, task.Cursor)
+	assert.True(t, task.Phases[0].Steps[0].Completed)
+	assert.False(t, task.Phases[1].Steps[0].Completed)
+
+	// 暂停的任务不能直接执行，需要恢复
+	_, err = service.SubmitExecution(ctx, &ExecuteRequest{TaskID: resp.TaskID})
+	assert.ErrorIs(t, err, ErrTaskPaused)
+	_, err = service.PauseTask(ctx, resp.TaskID)
+	assert.ErrorIs(t, err, ErrNoActiveJob)
+
+	calls := len(model.Calls())
+	resumed, err := service.ResumeTask(ctx, resp.TaskID, "")
+	require.NoError(t, err)
+	assert.NotEqual(t, job.ID, resumed.ID)
+
+	resumed = waitJob(t, service, resumed.ID)
+	assert.Equal(t, JobStatusSucceeded, resumed.Status)
+
+	// 从恢复位置继续，已执行的步骤不再调用模型
+	after := model.Calls()[calls:]
+	require.Len(t, after, 2)
+	assert.Contains(t, after[0][len(after[0])-1].Content, "实现接口")
+	assert.Contains(t, after[1][len(after[1])-1].Content, "补充测试")
+
+	task, err = service.GetTask(ctx, resp.TaskID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusCompleted, task.Status)
+	assert.Nil(t, task.Cursor)
+
+	_, err = service.ResumeTask(ctx, resp.TaskID, "")
+	assert.ErrorIs(t, err, ErrTaskNotPaused)
+}
+
+func TestServiceResumeKeepsPhaseScope(t *testing.T) {
+	model := newGatedModel("实现接口",
+		llm.Reply(scriptedPlan),
+		llm.ScriptStep{Content: `{"action": "complete", "message": "完成"}`, Repeat: true},
+	)
+	service := newScriptedService(t, model)
+	ctx := context.Background()
+
+	resp, err := service.CreateTask(ctx, &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.NoError(t, err)
+	job, err := service.SubmitExecution(ctx, &ExecuteRequest{TaskID: resp.TaskID, PhaseID: "phase_2"})
+	require.NoError(t, err)
+	<-model.entered
+
+	_, err = service.PauseTask(ctx, resp.TaskID)
+	require.NoError(t, err)
+	close(model.release)
+	job = waitJob(t, service, job.ID)
+	assert.Equal(t, JobStatusPaused, job.Status)
+
+	task, err := service.GetTask(ctx, resp.TaskID)
+	require.NoError(t, err)
+	assert.Equal(t, &TaskCursor{PhaseID: "phase_2", StepID: "step_2"}, task.Cursor)
+
+	// 恢复的作业仍只执行该阶段，从暂停的步骤继续
+	calls := len(model.Calls())
+	resumed, err := service.ResumeTask(ctx, resp.TaskID, "")
+	require.NoError(t, err)
+	assert.Equal(t, "phase_2", resumed.PhaseID)
+
+	resumed = waitJob(t, service, resumed.ID)
+	assert.Equal(t, JobStatusSucceeded, resumed.Status)
+
+	after := model.Calls()[calls:]
+	require.Len(t, after, 1)
+	assert.Contains(t, after[0][len(after[0])-1].Content, "补充测试")
+
+	task, err = service.GetTask(ctx, resp.TaskID)
+	require.NoError(t, err)
+	assert.False(t, task.Phases[0].Steps[0].Completed)
+	assert.True(t, task.Phases[1].Steps[1].Completed)
+	assert.Nil(t, task.Cursor)
+}
+
+func TestServiceResumeWhenCursorStepRemoved(t *testing.T) {
+	model := newGatedModel("实现接口",
+		llm.Reply(scriptedPlan),
+		llm.ScriptStep{Content: `{"action": "complete", "message": "完成"}`, Repeat: true},
+	)
+	service := newScriptedService(t, model)
+	ctx := context.Background()
+
+	resp, err := service.CreateTask(ctx, &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.NoError(t, err)
+	job, err := service.SubmitExecution(ctx, &ExecuteRequest{TaskID: resp.TaskID, PhaseID: "phase_2"})
+	require.NoError(t, err)
+	<-model.entered
+
+	_, err = service.PauseTask(ctx, resp.TaskID)
+	require.NoError(t, err)
+	close(model.release)
+	job = waitJob(t, service, job.ID)
+	require.Equal(t, JobStatusPaused, job.Status)
+
+	// 暂停期间修改计划，暂停的步骤不再存在
+	taskCtx, err := service.manager.GetTaskContext(ctx, resp.TaskID)
+	require.NoError(t, err)
+	require.Equal(t, &TaskCursor{PhaseID: "phase_2", StepID: "step_2"}, taskCtx.Task.Cursor)
+	taskCtx.Task.Phases[1].Steps[1].ID = "step_3"
+	require.NoError(t, service.manager.storage.SaveTask(taskCtx.Task))
+
+	// 从该阶段第一个未完成的步骤继续，而不是跳过所有步骤
+	calls := len(model.Calls())
+	resumed, err := service.ResumeTask(ctx, resp.TaskID, "")
+	require.NoError(t, err)
+	resumed = waitJob(t, service, resumed.ID)
+	assert.Equal(t, JobStatusSucceeded, resumed.Status)
+
+	after := model.Calls()[calls:]
+	require.Len(t, after, 1)
+	assert.Contains(t, after[0][len(after[0])-1].Content, "补充测试")
+
+	task, err := service.GetTask(ctx, resp.TaskID)
+	require.NoError(t, err)
+	assert.True(t, task.Phases[1].Steps[1].Completed)
+	assert.Nil(t, task.Cursor)
+}
+
+func TestServiceResumeKeepsStatusWhenJobActive(t *testing.T) {
+	model := newGatedModel("阅读文档",
+		llm.Reply(scriptedPlan),
+		llm.Reply(scriptedPlan),
+		llm.ScriptStep{Content: `{"action": "complete", "message": "完成"}`, Repeat: true},
+	)
+	tmpDir := t.TempDir()
+	service, err := NewService(&TaskManagerConfig{StoragePath: tmpDir, RereadThreshold: 10, JobWorkers: 1}, WithChatModel(model))
+	require.NoError(t, err)
+	t.Cleanup(service.Close)
+	ctx := context.Background()
+
+	first, err := service.CreateTask(ctx, &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.NoError(t, err)
+	second, err := service.CreateTask(ctx, &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个队列"})
+	require.NoError(t, err)
+
+	// 唯一的 worker 被第一个任务占用，第二个任务的作业排队
+	firstJob, err := service.SubmitExecution(ctx, &ExecuteRequest{TaskID: first.TaskID})
+	require.NoError(t, err)
+	<-model.entered
+	secondJob, err := service.SubmitExecution(ctx, &ExecuteRequest{TaskID: second.TaskID})
+	require.NoError(t, err)
+	require.NoError(t, service.manager.PauseTask(ctx, second.TaskID, nil))
+
+	// 任务已有作业时恢复被拒绝，状态不改回暂停，排队的作业仍可执行
+	_, err = service.ResumeTask(ctx, second.TaskID, "")
+	assert.ErrorIs(t, err, ErrJobActive)
+	task, err := service.GetTask(ctx, second.TaskID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusInProgress, task.Status)
+
+	close(model.release)
+	assert.Equal(t, JobStatusSucceeded, waitJob(t, service, firstJob.ID).Status)
+	assert.Equal(t, JobStatusSucceeded, waitJob(t, service, secondJob.ID).Status)
+}
+
+func TestServiceGuidanceResumesFailedStep(t *testing.T) {
+	model := llm.NewScriptedModel(
+		llm.Reply(scriptedPlan),
+		llm.ReplyWhen("改用内存实现", `{"action": "complete", "message": "已按指导完成"}`),
+		llm.ScriptStep{Match: llm.MatchContains("当前步骤: 阅读文档"), Content: `{"action": "fail", "message": "文档不存在"}`, Repeat: true},
+		llm.ScriptStep{Content: `{"action": "complete", "message": "完成"}`, Repeat: true},
+	)
+	service := newScriptedService(t, model)
+	ctx := context.Background()
+
+	resp, err := service.CreateTask(ctx, &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.NoError(t, err)
+	job, err := service.SubmitExecution(ctx, &ExecuteRequest{TaskID: resp.TaskID})
+	require.NoError(t, err)
+
+	// 重试用尽后任务进入等待指导的列表
+	job = waitJob(t, service, job.ID)
+	assert.Equal(t, JobStatusBlocked, job.Status)
+	require.NotNil(t, job.Result)
+	assert.True(t, job.Result.AwaitingInput)
+
+	inbox, err := service.ListTasks(ctx, "", "", TaskStatusAwaitingInput)
+	require.NoError(t, err)
+	require.Len(t, inbox, 1)
+	assert.Equal(t, resp.TaskID, inbox[0].ID)
+	require.NotNil(t, inbox[0].PendingInput)
+	assert.Len(t, inbox[0].PendingInput.Attempts, 3)
+
+	_, err = service.ResumeTask(ctx, resp.TaskID, "")
+	assert.ErrorIs(t, err, ErrTaskNotPaused)
+
+	// 指导注入失败步骤的提示词，从该步骤继续执行
+	resumed, err := service.SubmitGuidance(ctx, resp.TaskID, "改用内存实现", "")
+	require.NoError(t, err)
+	resumed = waitJob(t, service, resumed.ID)
+	assert.Equal(t, JobStatusSucceeded, resumed.Status)
+
+	task, err := service.GetTask(ctx, resp.TaskID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusCompleted, task.Status)
+	assert.Equal(t, "已按指导完成", task.Phases[0].Steps[0].Result)
+	assert.Nil(t, task.Guidance)
+	assert.Nil(t, task.Cursor)
+
+	inbox, err = service.ListTasks(ctx, "", "", TaskStatusAwaitingInput)
+	require.NoError(t, err)
+	assert.Empty(t, inbox)
+
+	_, err = service.SubmitGuidance(ctx, resp.TaskID, "改用内存实现", "")
+	assert.ErrorIs(t, err, ErrTaskNotAwaitingInput)
+}
+
+func TestServiceCancelRunningTask(t *testing.T) {
+	model := newGatedModel("阅读文档", llm.Reply(scriptedPlan))
+	service := newScriptedService(t, model)
+	ctx := context.Background()
+
+	resp, err := service.CreateTask(ctx, &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.NoError(t, err)
+	job, err := service.SubmitExecution(ctx, &ExecuteRequest{TaskID: resp.TaskID})
+	require.NoError(t, err)
+	<-model.entered
+
+	// 取消中断进行中的模型调用，release 始终不关闭
+	task, err := service.CancelTask(ctx, resp.TaskID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusCancelled, task.Status)
+
+	job = waitJob(t, service, job.ID)
+	assert.Equal(t, JobStatusCancelled, job.Status)
+
+	task, err = service.GetTask(ctx, resp.TaskID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusCancelled, task.Status)
+	assert.False(t, task.Phases[0].Steps[0].Completed)
+
+	_, err = service.SubmitExecution(ctx, &ExecuteRequest{TaskID: resp.TaskID})
+	assert.ErrorIs(t, err, ErrTaskCancelled)
+	_, err = service.ResumeTask(ctx, resp.TaskID, "")
+	assert.ErrorIs(t, err, ErrTaskNotPaused)
+	_, err = service.CancelTask(ctx, resp.TaskID)
+	assert.ErrorIs(t, err, ErrTaskFinished)
+}
+
+func TestJobQueueCancelQueued(t *testing.T) {
+	storage, err := NewFileStorage(t.TempDir())
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	queue := NewJobQueue(storage, func(ctx context.Context, job *Job) (*ExecutionResult, error) {
+		<-release
+		return &ExecutionResult{Success: true}, nil
+	}, 1, 2)
+	queue.Start()
+	defer queue.Close()
+	defer close(release)
+
+	running := &Job{TaskID: "task_1"}
+	require.NoError(t, queue.Submit(running))
+	require.Eventually(t, func() bool {
+		job, _ := storage.LoadJob(running.ID)
+		return job != nil && job.Status == JobStatusRunning
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(t, queue.Submit(&Job{TaskID: "task_2"}))
+	require.NoError(t, queue.Submit(&Job{TaskID: "task_3"}))
+
+	// 排队中的作业直接出队
+	cancelled := queue.Cancel("task_2")
+	require.NotNil(t, cancelled)
+	assert.Equal(t, JobStatusCancelled, cancelled.Status)
+	paused, stillRunning := queue.Pause("task_3")
+	assert.False(t, stillRunning)
+	assert.Equal(t, JobStatusPaused, paused.Status)
+
+	jobs, err := storage.ListJobs("", JobStatusQueued)
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+	assert.Nil(t, queue.Cancel("task_4"))
+}
+
+// slowJobStorage 保存指定任务的作业时阻塞，模拟慢速存储
+type slowJobStorage struct {
+	Storage
+	taskID  string
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *slowJobStorage) SaveJob(job *Job) error {
+	if job.TaskID == s.taskID {
+		select {
+		case s.entered <- struct{}{}:
+		default:
+		}
+		<-s.release
+	}
+	return s.Storage.SaveJob(job)
+}
+
+func TestJobQueueSlowStorageDoesNotBlock(t *testing.T) {
+	fileStorage, err := NewFileStorage(t.TempDir())
+	require.NoError(t, err)
+	storage := &slowJobStorage{Storage: fileStorage, taskID: "slow", entered: make(chan struct{}, 1), release: make(chan struct{})}
+
+	queue := NewJobQueue(storage, func(ctx context.Context, job *Job) (*ExecutionResult, error) {
+		return &ExecutionResult{Success: true}, nil
+	}, 1, 10)
+	queue.Start()
+	defer queue.Close()
+
+	submitted := make(chan error, 1)
+	go func() { submitted <- queue.Submit(&Job{TaskID: "slow"}) }()
+	<-storage.entered
+
+	// 写入存储期间其他任务的提交、取消与暂停不被阻塞，同一任务的重复提交被拒绝
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, queue.Submit(&Job{TaskID: "task_2"}))
+		assert.Nil(t, queue.Cancel("task_3"))
+		job, _ := queue.Pause("task_4")
+		assert.Nil(t, job)
+		assert.ErrorIs(t, queue.Submit(&Job{TaskID: "slow"}), ErrJobActive)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("queue blocked by a slow storage write")
+	}
+
+	close(storage.release)
+	require.NoError(t, <-submitted)
+}
+
+func TestServiceJobOperationsOnMissingTask(t *testing.T) {
+	service := newScriptedService(t, llm.NewScriptedModel())
+	ctx := context.Background()
+
+	_, err := service.SubmitExecution(ctx, &ExecuteRequest{TaskID: "missing"})
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+	_, err = service.CancelTask(ctx, "missing")
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+	_, err = service.PauseTask(ctx, "missing")
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+	_, err = service.ResumeTask(ctx, "missing", "")
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+	_, err = service.SubmitGuidance(ctx, "missing", "改用内存实现", "")
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+}
@@ -6,6 +6,9 @@ import (
 	"sync"
 	"time"
 
+	"ai_task/pkg/clients/llm"
+	"ai_task/pkg/retrieval"
+	"ai_task/pkg/usage"
 	"ai_task/repository/factory"
 
 	"github.com/google/uuid"
@@ -33,6 +36,9 @@ type ManagerOption func(*managerOptions)
 
 type managerOptions struct {
 	repoFactory factory.Factory
+	retriever   retrieval.Retriever
+	models      llm.Models
+	budget      usage.Budget
 }
 
 // WithRepositoryFactory 设置仓库工厂
@@ -42,6 +48,34 @@ func WithRepositoryFactory(f factory.Factory) ManagerOption {
 	}
 }
 
+// WithRetriever 设置规划时使用的检索器，为 nil 时不检索参考资料
+func WithRetriever(r retrieval.Retriever) ManagerOption {
+	return func(opts *managerOptions) {
+		opts.retriever = r
+	}
+}
+
+// WithChatModel 规划、执行、摘要统一使用同一个模型，为 nil 时按全局路由选择
+func WithChatModel(m llm.ChatModel) ManagerOption {
+	return func(opts *managerOptions) {
+		opts.models = llm.Single(m)
+	}
+}
+
+// WithModels 按用途选择规划、执行、摘要使用的模型，为 nil 时使用全局路由
+func WithModels(models llm.Models) ManagerOption {
+	return func(opts *managerOptions) {
+		opts.models = models
+	}
+}
+
+// WithBudget 设置任务执行的 token 预算，为 nil 时不限制
+func WithBudget(budget usage.Budget) ManagerOption {
+	return func(opts *managerOptions) {
+		opts.budget = budget
+	}
+}
+
 // 创建任务管理器
 func NewManager(config *TaskManagerConfig, opts ...ManagerOption) (*Manager, error) {
 	if config == nil {
@@ -346,6 +380,11 @@ func (m *Manager) CompleteStep(ctx context.Context, taskID, phaseID, stepID stri
 		}
 	}
 
+	// 人工指导只作用于对应的步骤
+	if stepGuidance(taskCtx.Task, phaseID, stepID) != nil {
+		taskCtx.Task.Guidance = nil
+	}
+
 	// 记录进度
 	taskCtx.Progress.Entries = append(taskCtx.Progress.Entries, ProgressEntry{
 		PhaseID:   phaseID,
@@ -486,7 +525,7 @@ func (m *Manager) GetTaskSummary(ctx context.Context, taskID string) (*TaskSumma
 	}
 
 	if taskCtx == nil || taskCtx.Task == nil {
-		return nil, fmt.Errorf("task not found: %s", taskID)
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
 	}
 
 	task := taskCtx.Task
@@ -543,7 +582,7 @@ func (m *Manager) CheckCompletion(ctx context.Context, taskID string) (bool, []s
 	}
 
 	if taskCtx == nil || taskCtx.Task == nil {
-		return false, nil, fmt.Errorf("task not found: %s", taskID)
+		return false, nil, fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
 	}
 
 	var incompletePhases []string
@@ -567,8 +606,8 @@ func (m *Manager) getOrLoadContext(taskID string) (*TaskContext, error) {
 		return nil, err
 	}
 
-	if taskCtx == nil {
-		return nil, fmt.Errorf("task not found: %s", taskID)
+	if taskCtx == nil || taskCtx.Task == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
 	}
 
 	m.activeTasks[taskID] = taskCtx
@@ -580,9 +619,9 @@ func (m *Manager) StartPhase(ctx context.Context, taskID, phaseID string) error
 	return m.UpdatePhaseStatus(ctx, taskID, phaseID, PhaseStatusInProgress)
 }
 
-// ListTasks 列出任务
-func (m *Manager) ListTasks(ctx context.Context, userID, sessionID string) ([]*Task, error) {
-	return m.storage.ListTasks(userID, sessionID)
+// ListTasks 列出任务，status 不为空时只返回该状态的任务
+func (m *Manager) ListTasks(ctx context.Context, userID, sessionID string, status TaskStatus) ([]*Task, error) {
+	return m.storage.ListTasks(userID, sessionID, status)
 }
 
 // DeleteTask 删除任务
@@ -596,6 +635,171 @@ func (m *Manager) DeleteTask(ctx context.Context, taskID string) error {
 	return m.storage.DeleteTask(taskID)
 }
 
+// UpdateTaskStatus 更新任务状态
+func (m *Manager) UpdateTaskStatus(ctx context.Context, taskID string, status TaskStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	taskCtx, err := m.getOrLoadContext(taskID)
+	if err != nil {
+		return err
+	}
+
+	taskCtx.Task.Status = status
+	taskCtx.Task.UpdatedAt = time.Now()
+	return m.storage.SaveTask(taskCtx.Task)
+}
+
+// PauseTask 暂停任务并记录恢复位置，cursor 为 nil 时从第一个未完成的步骤恢复
+func (m *Manager) PauseTask(ctx context.Context, taskID string, cursor *TaskCursor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	taskCtx, err := m.getOrLoadContext(taskID)
+	if err != nil {
+		return err
+	}
+
+	if cursor == nil {
+		cursor = nextCursor(taskCtx.Task)
+	}
+	taskCtx.Task.Status = TaskStatusPaused
+	taskCtx.Task.Cursor = cursor
+	if cursor != nil {
+		taskCtx.Task.CurrentPhase = cursor.PhaseID
+		taskCtx.Progress.Entries = append(taskCtx.Progress.Entries, ProgressEntry{
+			PhaseID:   cursor.PhaseID,
+			Action:    fmt.Sprintf("Task paused before step %s", cursor.StepID),
+			Timestamp: time.Now(),
+		})
+	}
+
+	return m.storage.SaveContext(taskCtx)
+}
+
+// CancelTask 取消任务，清除暂停位置
+func (m *Manager) CancelTask(ctx context.Context, taskID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	taskCtx, err := m.getOrLoadContext(taskID)
+	if err != nil {
+		return err
+	}
+
+	taskCtx.Task.Status = TaskStatusCancelled
+	taskCtx.Task.Cursor = nil
+	taskCtx.Task.PendingInput = nil
+	taskCtx.Progress.Entries = append(taskCtx.Progress.Entries, ProgressEntry{
+		PhaseID:   taskCtx.Task.CurrentPhase,
+		Action:    "Task cancelled",
+		Timestamp: time.Now(),
+	})
+
+	return m.storage.SaveContext(taskCtx)
+}
+
+// AwaitInput 步骤重试用尽后等待人工指导，记录问题并以失败的步骤为恢复位置
+func (m *Manager) AwaitInput(ctx context.Context, taskID string, pending *PendingInput) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	taskCtx, err := m.getOrLoadContext(taskID)
+	if err != nil {
+		return err
+	}
+
+	taskCtx.Task.Status = TaskStatusAwaitingInput
+	taskCtx.Task.PendingInput = pending
+	taskCtx.Task.Cursor = &TaskCursor{PhaseID: pending.PhaseID, StepID: pending.StepID}
+	taskCtx.Task.CurrentPhase = pending.PhaseID
+	taskCtx.Task.UpdatedAt = time.Now()
+	taskCtx.Progress.Entries = append(taskCtx.Progress.Entries, ProgressEntry{
+		PhaseID:   pending.PhaseID,
+		Action:    fmt.Sprintf("Awaiting guidance for step %s", pending.StepID),
+		Timestamp: time.Now(),
+	})
+
+	return m.storage.SaveContext(taskCtx)
+}
+
+// SubmitGuidance 回答等待中的问题：指导记录为决策并保留到对应步骤完成，
+// 任务转为暂停，从失败的步骤恢复执行
+func (m *Manager) SubmitGuidance(ctx context.Context, taskID, answer string) (*Guidance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	taskCtx, err := m.getOrLoadContext(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	task := taskCtx.Task
+	if task.Status != TaskStatusAwaitingInput || task.PendingInput == nil {
+		return nil, fmt.Errorf("%w: %s is %s", ErrTaskNotAwaitingInput, taskID, task.Status)
+	}
+
+	now := time.Now()
+	pending := task.PendingInput
+	guidance := &Guidance{
+		PhaseID:   pending.PhaseID,
+		StepID:    pending.StepID,
+		Question:  pending.Question,
+		Answer:    answer,
+		Timestamp: now,
+	}
+	task.Decisions = append(task.Decisions, Decision{
+		Decision:  answer,
+		Rationale: fmt.Sprintf("人工指导: %s", pending.Question),
+		Timestamp: now,
+		PhaseID:   pending.PhaseID,
+	})
+	task.Guidance = guidance
+	task.PendingInput = nil
+	task.Status = TaskStatusPaused
+	task.Cursor = &TaskCursor{PhaseID: pending.PhaseID, StepID: pending.StepID}
+	task.UpdatedAt = now
+	taskCtx.Progress.Entries = append(taskCtx.Progress.Entries, ProgressEntry{
+		PhaseID:   pending.PhaseID,
+		Action:    fmt.Sprintf("Guidance received for step %s", pending.StepID),
+		Timestamp: now,
+	})
+
+	if err := m.storage.SaveContext(taskCtx); err != nil {
+		return nil, err
+	}
+	return guidance, nil
+}
+
+// ClearCursor 清除暂停位置
+func (m *Manager) ClearCursor(ctx context.Context, taskID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	taskCtx, err := m.getOrLoadContext(taskID)
+	if err != nil {
+		return err
+	}
+
+	taskCtx.Task.Cursor = nil
+	return m.storage.SaveTask(taskCtx.Task)
+}
+
+// nextCursor 第一个未完成阶段中第一个未完成的步骤，全部完成时返回 nil
+func nextCursor(task *Task) *TaskCursor {
+	for _, phase := range task.Phases {
+		if phase.Status == PhaseStatusComplete {
+			continue
+		}
+		for _, step := range phase.Steps {
+			if !step.Completed {
+				return &TaskCursor{PhaseID: phase.ID, StepID: step.ID}
+			}
+		}
+	}
+	return nil
+}
+
 // MarkNeedsReread 标记需要重读计划
 func (m *Manager) MarkNeedsReread(ctx context.Context, taskID string) error {
 	m.mu.Lock()
@@ -426,7 +426,7 @@ func TestListTasks(t *testing.T) {
 	}
 
 	// 列出任务
-	tasks, err := manager.ListTasks(ctx, "user_123", "session_456")
+	tasks, err := manager.ListTasks(ctx, "user_123", "session_456", "")
 	require.NoError(t, err)
 	assert.Len(t, tasks, 3)
 }
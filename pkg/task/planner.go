@@ -1,9 +1,11 @@
 package task
 
 import (
-	"ai_task/pkg/clients/llm_model"
+	"ai_task/pkg/clients/llm"
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/retrieval"
+	"ai_task/pkg/usage"
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -14,13 +16,20 @@ import (
 // Planner 任务规划器
 // 使用 LLM 自动生成任务计划
 type Planner struct {
-	llmClient *llm_model.ClientChatModel
+	llmClient llm.ChatModel
+	retriever retrieval.Retriever // 可选，检索与目标相关的参考资料
 }
 
-// NewPlanner 创建规划器
-func NewPlanner() *Planner {
+// plannerReferenceLimit 规划时注入的参考资料条数
+const plannerReferenceLimit = 5
+
+// plannerReferenceThreshold 参考资料的最低相似度
+const plannerReferenceThreshold = 0.5
+
+// NewPlanner 创建规划器，llmClient 为 nil 时使用全局模型
+func NewPlanner(llmClient llm.ChatModel) *Planner {
 	return &Planner{
-		llmClient: llm_model.GetInstance(),
+		llmClient: llm.OrDefault(llmClient),
 	}
 }
 
@@ -29,9 +38,38 @@ func NewPlanner() *Planner {
 // PlannerResult 规划结果
 type PlannerResult struct {
 	Phases       []PlanPhase `json:"phases"`
-	KeyQuestions []string    `json:"key_questions"`
-	Estimate     string      `json:"estimate"`
-	Risks        []string    `json:"risks"`
+	KeyQuestions []string    `json:"key_questions" required:"false"`
+	Estimate     string      `json:"estimate" required:"false"`
+	Risks        []string    `json:"risks" required:"false"`
+}
+
+// Validate 至少一个阶段，阶段 ID 唯一且每个阶段至少一个步骤
+func (r *PlannerResult) Validate() error {
+	if len(r.Phases) == 0 {
+		return fmt.Errorf("$.phases: 至少需要一个阶段")
+	}
+
+	seen := make(map[string]bool, len(r.Phases))
+	for i, phase := range r.Phases {
+		switch {
+		case strings.TrimSpace(phase.ID) == "":
+			return fmt.Errorf("$.phases[%d].id: 不能为空", i)
+		case seen[phase.ID]:
+			return fmt.Errorf("$.phases[%d].id: 阶段 ID %s 重复", i, phase.ID)
+		case strings.TrimSpace(phase.Name) == "":
+			return fmt.Errorf("$.phases[%d].name: 不能为空", i)
+		case len(phase.Steps) == 0:
+			return fmt.Errorf("$.phases[%d].steps: 至少需要一个步骤", i)
+		}
+		seen[phase.ID] = true
+
+		for j, step := range phase.Steps {
+			if strings.TrimSpace(step.Description) == "" {
+				return fmt.Errorf("$.phases[%d].steps[%d].description: 不能为空", i, j)
+			}
+		}
+	}
+	return nil
 }
 
 // PlanPhase 规划阶段
@@ -50,123 +88,67 @@ type PlanStep struct {
 
 // GeneratePlan 生成任务计划
 func (p *Planner) GeneratePlan(ctx context.Context, req *PlanRequest) (*PlannerResult, error) {
-	// 构建用户提示
-	userPrompt := fmt.Sprintf("请为以下目标创建详细的执行计划：\n\n目标: %s", req.Goal)
-
-	if req.Context != "" {
-		userPrompt += fmt.Sprintf("\n\n上下文信息: %s", req.Context)
-	}
-
-	if len(req.Constraints) > 0 {
-		userPrompt += fmt.Sprintf("\n\n约束条件:\n- %s", strings.Join(req.Constraints, "\n- "))
+	system, err := prompt.Render(ctx, prompt.TaskPlannerSystem, nil)
+	if err != nil {
+		return nil, err
 	}
-
-	if len(req.Preferences) > 0 {
-		userPrompt += fmt.Sprintf("\n\n偏好设置:\n- %s", strings.Join(req.Preferences, "\n- "))
+	user, err := prompt.Render(ctx, prompt.TaskPlannerUser, map[string]any{
+		"Goal":        req.Goal,
+		"Context":     req.Context,
+		"Constraints": req.Constraints,
+		"Preferences": req.Preferences,
+		"References":  p.retrieveReferences(ctx, req),
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	messages := []openai.ChatCompletionMessage{
 		{
 			Role:    openai.ChatMessageRoleSystem,
-			Content: PromptPlannerSystem,
+			Content: system.Text,
 		},
 		{
 			Role:    openai.ChatMessageRoleUser,
-			Content: userPrompt,
+			Content: user.Text,
 		},
 	}
 
-	result, err := p.llmClient.PostChatCompletionsNonStreamContent(ctx, messages)
+	// 输出不符合 PlannerResult 时由模型修复，仍失败则返回 llm.ErrInvalidStructuredOutput
+	planResult, err := llm.GenerateJSON[PlannerResult](prompt.WithRendered(usage.WithRole(ctx, usage.RolePlanner), system, user), p.llmClient, messages, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate plan: %w", err)
 	}
 
-	// 解析结果
-	planResult, err := p.parsePlanResult(result)
-	if err != nil {
-		log.Warnf("Failed to parse plan result, using default plan: %v", err)
-		return p.createDefaultPlan(req.Goal), nil
-	}
-
 	return planResult, nil
 }
 
-// parsePlanResult 解析规划结果
-func (p *Planner) parsePlanResult(result string) (*PlannerResult, error) {
-	// 清理响应内容
-	result = strings.TrimSpace(result)
-	result = strings.TrimPrefix(result, "```json")
-	result = strings.TrimPrefix(result, "```")
-	result = strings.TrimSuffix(result, "```")
-	result = strings.TrimSpace(result)
-
-	var planResult PlannerResult
-	if err := json.Unmarshal([]byte(result), &planResult); err != nil {
-		return nil, fmt.Errorf("failed to parse plan JSON: %w", err)
+// retrieveReferences 检索与目标相关的参考资料，未配置检索器或检索失败时返回空字符串
+func (p *Planner) retrieveReferences(ctx context.Context, req *PlanRequest) string {
+	if p.retriever == nil {
+		return ""
 	}
 
-	return &planResult, nil
-}
+	results, err := p.retriever.Retrieve(ctx, &retrieval.Query{
+		Text:      strings.TrimSpace(req.Goal + "\n" + req.Context),
+		UserID:    req.UserID,
+		Limit:     plannerReferenceLimit,
+		Threshold: plannerReferenceThreshold,
+	})
+	if err != nil {
+		log.Warnf("Failed to retrieve plan references: %v", err)
+		return ""
+	}
 
-// createDefaultPlan 创建默认计划
-func (p *Planner) createDefaultPlan(goal string) *PlannerResult {
-	return &PlannerResult{
-		Phases: []PlanPhase{
-			{
-				ID:          "phase_1",
-				Name:        "需求与发现",
-				Description: "理解需求并收集信息",
-				Steps: []PlanStep{
-					{ID: "step_1_1", Description: "理解用户意图"},
-					{ID: "step_1_2", Description: "识别约束和需求"},
-					{ID: "step_1_3", Description: "记录发现"},
-				},
-			},
-			{
-				ID:          "phase_2",
-				Name:        "规划与设计",
-				Description: "确定技术方案",
-				Steps: []PlanStep{
-					{ID: "step_2_1", Description: "定义技术方案"},
-					{ID: "step_2_2", Description: "创建项目结构"},
-					{ID: "step_2_3", Description: "记录决策"},
-				},
-			},
-			{
-				ID:          "phase_3",
-				Name:        "实现",
-				Description: "执行实现",
-				Steps: []PlanStep{
-					{ID: "step_3_1", Description: "按步骤执行"},
-					{ID: "step_3_2", Description: "编写代码"},
-					{ID: "step_3_3", Description: "增量测试"},
-				},
-			},
-			{
-				ID:          "phase_4",
-				Name:        "测试与验证",
-				Description: "测试和验证",
-				Steps: []PlanStep{
-					{ID: "step_4_1", Description: "验证需求"},
-					{ID: "step_4_2", Description: "记录测试结果"},
-					{ID: "step_4_3", Description: "修复问题"},
-				},
-			},
-			{
-				ID:          "phase_5",
-				Name:        "交付",
-				Description: "交付和总结",
-				Steps: []PlanStep{
-					{ID: "step_5_1", Description: "审查输出"},
-					{ID: "step_5_2", Description: "确保完整"},
-					{ID: "step_5_3", Description: "交付用户"},
-				},
-			},
-		},
-		KeyQuestions: []string{},
-		Estimate:     "待评估",
-		Risks:        []string{},
+	lines := make([]string, 0, len(results))
+	for _, r := range results {
+		if r.Title != "" {
+			lines = append(lines, fmt.Sprintf("- 《%s》%s", r.Title, r.Content))
+			continue
+		}
+		lines = append(lines, "- "+r.Content)
 	}
+	return strings.Join(lines, "\n")
 }
 
 // ConvertToTaskPhases 将规划结果转换为任务阶段
@@ -196,6 +178,24 @@ func (p *Planner) ConvertToTaskPhases(planResult *PlannerResult) []TaskPhase {
 	return phases
 }
 
+// refinePhaseResult 阶段细化结果
+type refinePhaseResult struct {
+	Steps []PlanStep `json:"steps"`
+}
+
+// Validate 至少一个步骤且步骤描述不能为空
+func (r *refinePhaseResult) Validate() error {
+	if len(r.Steps) == 0 {
+		return fmt.Errorf("$.steps: 至少需要一个步骤")
+	}
+	for i, step := range r.Steps {
+		if strings.TrimSpace(step.Description) == "" {
+			return fmt.Errorf("$.steps[%d].description: 不能为空", i)
+		}
+	}
+	return nil
+}
+
 // RefinePhase 细化阶段
 // 当需要更详细的步骤时使用
 func (p *Planner) RefinePhase(ctx context.Context, taskContext *TaskContext, phaseID string) ([]TaskStep, error) {
@@ -216,36 +216,36 @@ func (p *Planner) RefinePhase(ctx context.Context, taskContext *TaskContext, pha
 		return nil, fmt.Errorf("phase not found: %s", phaseID)
 	}
 
-	refinePrompt := fmt.Sprintf(PromptRefinePhaseUserTemplate,
-		taskContext.Task.Goal, targetPhase.Name, targetPhase.Description, formatSteps(targetPhase.Steps))
+	system, err := prompt.Render(ctx, prompt.TaskRefinePhaseSystem, nil)
+	if err != nil {
+		return nil, err
+	}
+	user, err := prompt.Render(ctx, prompt.TaskRefinePhaseUser, map[string]any{
+		"Goal":             taskContext.Task.Goal,
+		"PhaseName":        targetPhase.Name,
+		"PhaseDescription": targetPhase.Description,
+		"Steps":            targetPhase.Steps,
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	messages := []openai.ChatCompletionMessage{
 		{
 			Role:    openai.ChatMessageRoleSystem,
-			Content: PromptRefinePhaseSystem,
+			Content: system.Text,
 		},
 		{
 			Role:    openai.ChatMessageRoleUser,
-			Content: refinePrompt,
+			Content: user.Text,
 		},
 	}
 
-	result, err := p.llmClient.PostChatCompletionsNonStreamContent(ctx, messages)
+	refineResult, err := llm.GenerateJSON[refinePhaseResult](prompt.WithRendered(usage.WithRole(ctx, usage.RolePlanner), system, user), p.llmClient, messages, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to refine phase: %w", err)
 	}
 
-	// 解析结果
-	result = cleanJSONResponse(result)
-
-	var refineResult struct {
-		Steps []PlanStep `json:"steps"`
-	}
-
-	if err := json.Unmarshal([]byte(result), &refineResult); err != nil {
-		return nil, fmt.Errorf("failed to parse refine result: %w", err)
-	}
-
 	// 转换为 TaskStep
 	steps := make([]TaskStep, len(refineResult.Steps))
 	for i, s := range refineResult.Steps {
@@ -259,19 +259,6 @@ func (p *Planner) RefinePhase(ctx context.Context, taskContext *TaskContext, pha
 	return steps, nil
 }
 
-// formatSteps 格式化步骤列表
-func formatSteps(steps []TaskStep) string {
-	var sb strings.Builder
-	for _, s := range steps {
-		status := "[ ]"
-		if s.Completed {
-			status = "[x]"
-		}
-		sb.WriteString(fmt.Sprintf("- %s %s\n", status, s.Description))
-	}
-	return sb.String()
-}
-
 // cleanJSONResponse 清理 JSON 响应
 func cleanJSONResponse(response string) string {
 	response = strings.TrimSpace(response)
@@ -1,7 +1,11 @@
 package task
 
 import (
+	"ai_task/pkg/clients/llm"
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/usage"
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -16,6 +20,8 @@ type Service struct {
 	planner         *Planner
 	executor        *Executor
 	contextEngineer *ContextEngineer
+	models          llm.Models
+	jobs            *JobQueue
 	sessions        map[string]*Session
 	mu              sync.RWMutex
 }
@@ -31,46 +37,67 @@ func NewService(config *TaskManagerConfig, opts ...ManagerOption) (*Service, err
 		return nil, fmt.Errorf("failed to create task manager: %w", err)
 	}
 
-	planner := NewPlanner()
-	executor := NewExecutor(manager, nil)
-	contextEngineer := NewContextEngineer(nil)
+	options := &managerOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	models := llm.OrDefaultModels(options.models)
+	planner := NewPlanner(models.Model(llm.RolePlanning))
+	planner.retriever = options.retriever
+	executor := NewExecutor(manager, nil, models.Model(llm.RoleExecution))
+	executor.budget = options.budget
+	contextEngineer := NewContextEngineer(nil, models.Model(llm.RoleSummarization))
 
-	return &Service{
+	service := &Service{
 		manager:         manager,
 		planner:         planner,
 		executor:        executor,
 		contextEngineer: contextEngineer,
+		models:          models,
 		sessions:        make(map[string]*Session),
-	}, nil
+	}
+
+	// 后台执行队列，启动时恢复上次未执行完的作业
+	service.jobs = NewJobQueue(manager.storage, service.runJob, config.JobWorkers, config.JobQueueSize)
+	service.jobs.Start()
+
+	return service, nil
+}
+
+// Close 停止后台执行队列，执行中的作业在下次启动时恢复
+func (s *Service) Close() {
+	s.jobs.Close()
 }
 
 // CreateTask 创建任务
 func (s *Service) CreateTask(ctx context.Context, req *PlanRequest) (*PlanResponse, error) {
-	// 使用 LLM 生成计划
-	planResult, err := s.planner.GeneratePlan(ctx, req)
+	// 先创建任务，规划的 token 用量计入该任务
+	task, err := s.manager.CreateTask(ctx, req)
 	if err != nil {
-		log.Warnf("Failed to generate plan with LLM, using default: %v", err)
+		return nil, fmt.Errorf("failed to create task: %w", err)
 	}
+	ctx = usage.WithTags(ctx, usage.Tags{UserID: task.UserID, SessionID: task.SessionID, TaskID: task.ID})
+	ctx = prompt.WithLocale(ctx, prompt.ResolveLocale(ctx, task.UserID, req.Locale))
 
-	// 创建任务
-	task, err := s.manager.CreateTask(ctx, req)
+	// 使用 LLM 生成计划，失败时任务标记为失败，输出修复用尽时错误包含 llm.ErrInvalidStructuredOutput
+	planResult, err := s.planner.GeneratePlan(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create task: %w", err)
+		_ = s.manager.RecordError(ctx, task.ID, err.Error(), 1, "规划失败")
+		_ = s.manager.UpdateTaskStatus(ctx, task.ID, TaskStatusFailed)
+		return nil, fmt.Errorf("failed to plan task %s: %w", task.ID, err)
 	}
 
-	// 如果有 LLM 生成的计划，更新阶段
-	if planResult != nil {
-		phases := s.planner.ConvertToTaskPhases(planResult)
-		if len(phases) > 0 {
-			// 更新任务阶段
-			taskCtx, _ := s.manager.GetTaskContext(ctx, task.ID)
-			if taskCtx != nil {
-				taskCtx.Task.Phases = phases
-				taskCtx.Task.KeyQuestions = planResult.KeyQuestions
-				taskCtx.Task.CurrentPhase = phases[0].ID
-				_ = s.manager.storage.SaveTask(taskCtx.Task)
-				task = taskCtx.Task
-			}
+	// 更新为 LLM 生成的阶段
+	phases := s.planner.ConvertToTaskPhases(planResult)
+	if len(phases) > 0 {
+		taskCtx, _ := s.manager.GetTaskContext(ctx, task.ID)
+		if taskCtx != nil {
+			taskCtx.Task.Phases = phases
+			taskCtx.Task.KeyQuestions = planResult.KeyQuestions
+			taskCtx.Task.CurrentPhase = phases[0].ID
+			_ = s.manager.storage.SaveTask(taskCtx.Task)
+			task = taskCtx.Task
 		}
 	}
 
@@ -92,53 +119,181 @@ func (s *Service) GetTaskContext(ctx context.Context, taskID string) (*TaskConte
 	return s.manager.GetTaskContext(ctx, taskID)
 }
 
-// ListTasks 列出任务
-func (s *Service) ListTasks(ctx context.Context, userID, sessionID string) ([]*Task, error) {
-	return s.manager.ListTasks(ctx, userID, sessionID)
+// ListTasks 列出任务，status 不为空时只返回该状态的任务
+func (s *Service) ListTasks(ctx context.Context, userID, sessionID string, status TaskStatus) ([]*Task, error) {
+	return s.manager.ListTasks(ctx, userID, sessionID, status)
 }
 
-// ExecuteTask 执行任务
-func (s *Service) ExecuteTask(ctx context.Context, req *ExecuteRequest) (*ExecuteResponse, error) {
+// SubmitExecution 提交后台执行作业，立即返回作业，执行进度与结果通过 GetJob 查询
+func (s *Service) SubmitExecution(ctx context.Context, req *ExecuteRequest) (*Job, error) {
 	task, err := s.manager.GetTask(ctx, req.TaskID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
 
 	if task == nil {
-		return nil, fmt.Errorf("task not found: %s", req.TaskID)
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, req.TaskID)
+	}
+	if err := checkRunnable(task); err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		TaskID:  task.ID,
+		PhaseID: req.PhaseID,
+		Locale:  req.Locale,
+	}
+	if err := s.jobs.Submit(job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// GetJob 获取执行作业，不存在时返回 nil
+func (s *Service) GetJob(ctx context.Context, jobID string) (*Job, error) {
+	return s.manager.storage.LoadJob(jobID)
+}
+
+// ListJobs 列出任务的执行作业，按提交时间升序
+func (s *Service) ListJobs(ctx context.Context, taskID string) ([]*Job, error) {
+	return s.manager.storage.ListJobs(taskID)
+}
+
+// runJob 在后台执行作业，与 ExecuteTask 相同地设置用量标签与提示词语言
+func (s *Service) runJob(ctx context.Context, job *Job) (*ExecutionResult, error) {
+	task, err := s.manager.GetTask(ctx, job.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	if task == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, job.TaskID)
+	}
+	ctx = usage.WithTags(ctx, usage.Tags{UserID: task.UserID, SessionID: task.SessionID, TaskID: task.ID})
+	ctx = prompt.WithLocale(ctx, prompt.ResolveLocale(ctx, task.UserID, job.Locale))
+
+	var result *ExecutionResult
+	if job.PhaseID != "" {
+		result, err = s.executor.ExecutePhase(ctx, job.TaskID, job.PhaseID)
+	} else {
+		result, err = s.executor.ExecuteTask(ctx, job.TaskID)
+	}
+
+	// 执行中被取消时，执行器在中断前可能写回了任务状态，这里再次标记为已取消
+	if errors.Is(context.Cause(ctx), ErrTaskCancelled) {
+		ctx = context.WithoutCancel(ctx)
+		if task, err := s.manager.GetTask(ctx, job.TaskID); err == nil && task != nil && task.Status != TaskStatusCancelled {
+			if err := s.manager.UpdateTaskStatus(ctx, job.TaskID, TaskStatusCancelled); err != nil {
+				log.Errorf("Failed to mark task %s cancelled: %v", job.TaskID, err)
+			}
+		}
+		return nil, ErrTaskCancelled
+	}
+	return result, err
+}
+
+// CancelTask 取消任务，执行中的作业立即中断，进行中的模型调用随之返回
+func (s *Service) CancelTask(ctx context.Context, taskID string) (*Task, error) {
+	task, err := s.manager.GetTask(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	if task == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	if task.Status == TaskStatusCompleted || task.Status == TaskStatusCancelled {
+		return nil, fmt.Errorf("%w: %s is %s", ErrTaskFinished, taskID, task.Status)
+	}
+
+	if job := s.jobs.Cancel(taskID); job != nil {
+		log.Infof("Task %s cancelled, job %s was %s", taskID, job.ID, job.Status)
+	}
+	if err := s.manager.CancelTask(ctx, taskID); err != nil {
+		return nil, fmt.Errorf("failed to cancel task: %w", err)
 	}
 
-	// 如果指定了阶段，执行该阶段
-	if req.PhaseID != "" {
-		result, err := s.executor.ExecutePhase(ctx, req.TaskID, req.PhaseID)
-		if err != nil {
-			return nil, err
+	return s.manager.GetTask(ctx, taskID)
+}
+
+// PauseTask 暂停任务的作业
+// 排队中的作业直接暂停，从第一个未完成的步骤恢复；执行中的作业在当前步骤完成后暂停，
+// 返回的作业此时仍为 running，暂停位置由执行器记录，通过 GetJob 查询作业变为 paused
+func (s *Service) PauseTask(ctx context.Context, taskID string) (*Job, error) {
+	task, err := s.manager.GetTask(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	if task == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+
+	job, running := s.jobs.Pause(taskID)
+	if job == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoActiveJob, taskID)
+	}
+
+	if !running {
+		if err := s.manager.PauseTask(ctx, taskID, nil); err != nil {
+			return nil, fmt.Errorf("failed to pause task: %w", err)
 		}
+	}
+
+	return job, nil
+}
+
+// ResumeTask 从暂停位置继续执行任务，提交新的后台作业，作业的阶段范围与暂停前相同
+func (s *Service) ResumeTask(ctx context.Context, taskID, locale string) (*Job, error) {
+	task, err := s.manager.GetTask(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
 
-		return &ExecuteResponse{
-			TaskID:       req.TaskID,
-			CurrentPhase: task.CurrentPhase,
-			Status:       task.Status,
-			Message:      result.Message,
-			NextAction:   result.NextAction,
-		}, nil
+	if task == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	if task.Status != TaskStatusPaused {
+		return nil, fmt.Errorf("%w: %s is %s", ErrTaskNotPaused, taskID, task.Status)
 	}
 
-	// 执行整个任务
-	result, err := s.executor.ExecuteTask(ctx, req.TaskID)
+	// 沿用最近一次作业的阶段范围，只执行指定阶段的作业恢复后不继续执行后续阶段
+	jobs, err := s.manager.storage.ListJobs(taskID)
 	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	phaseID := ""
+	if len(jobs) > 0 {
+		phaseID = jobs[len(jobs)-1].PhaseID
+	}
+
+	if err := s.manager.UpdateTaskStatus(ctx, taskID, TaskStatusInProgress); err != nil {
+		return nil, fmt.Errorf("failed to resume task: %w", err)
+	}
+
+	job := &Job{
+		TaskID:  taskID,
+		PhaseID: phaseID,
+		Locale:  locale,
+	}
+	if err := s.jobs.Submit(job); err != nil {
+		// 作业未入队时恢复为暂停，可以稍后重试；任务已有作业时由该作业继续执行，不修改状态
+		if !errors.Is(err, ErrJobActive) {
+			_ = s.manager.UpdateTaskStatus(ctx, taskID, TaskStatusPaused)
+		}
 		return nil, err
 	}
 
-	// 刷新任务状态
-	task, _ = s.manager.GetTask(ctx, req.TaskID)
+	return job, nil
+}
 
-	return &ExecuteResponse{
-		TaskID:       req.TaskID,
-		CurrentPhase: task.CurrentPhase,
-		Status:       task.Status,
-		Message:      result.Message,
-	}, nil
+// SubmitGuidance 回答等待人工指导的问题，并从失败的步骤继续执行
+// 指导记录为决策并注入该步骤的提示词；提交作业失败时任务保持暂停，可通过恢复接口重试
+func (s *Service) SubmitGuidance(ctx context.Context, taskID, answer, locale string) (*Job, error) {
+	if _, err := s.manager.SubmitGuidance(ctx, taskID, answer); err != nil {
+		return nil, err
+	}
+	return s.ResumeTask(ctx, taskID, locale)
 }
 
 // UpdatePhase 更新阶段状态
@@ -216,32 +371,32 @@ func (s *Service) GetSession(sessionID string) (*Session, bool) {
 	return session, ok
 }
 
-// ExecuteSession 执行会话
-func (s *Service) ExecuteSession(ctx context.Context, sessionID string) (*ExecuteResponse, error) {
+// SubmitSessionExecution 以后台作业执行会话中的任务
+func (s *Service) SubmitSessionExecution(ctx context.Context, sessionID string) (*Job, error) {
 	session, ok := s.GetSession(sessionID)
 	if !ok {
 		return nil, fmt.Errorf("session not found: %s", sessionID)
 	}
-
-	result, err := session.Execute(ctx)
+	if session.TaskID == "" {
+		return nil, fmt.Errorf("no task associated with session")
+	}
+	task, err := s.manager.GetTask(ctx, session.TaskID)
 	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	if err := checkRunnable(task); err != nil {
 		return nil, err
 	}
 
-	task, _ := s.manager.GetTask(ctx, session.TaskID)
-	status := TaskStatusPending
-	currentPhase := ""
-	if task != nil {
-		status = task.Status
-		currentPhase = task.CurrentPhase
+	job := &Job{
+		TaskID:    session.TaskID,
+		SessionID: sessionID,
+	}
+	if err := s.jobs.Submit(job); err != nil {
+		return nil, err
 	}
 
-	return &ExecuteResponse{
-		TaskID:       session.TaskID,
-		CurrentPhase: currentPhase,
-		Status:       status,
-		Message:      result.Message,
-	}, nil
+	return job, nil
 }
 
 // CheckSessionStop 检查会话是否可以停止
@@ -274,6 +429,11 @@ func (s *Service) SessionPostAction(ctx context.Context, sessionID, actionName s
 	return session.PostAction(ctx, actionName, actionType)
 }
 
+// ListToolCalls 列出任务的工具调用记录，limit>0 时只返回最近的 limit 条
+func (s *Service) ListToolCalls(ctx context.Context, taskID string, limit int) ([]*ToolCall, error) {
+	return s.manager.storage.ListToolCalls(taskID, limit)
+}
+
 // GetOptimizedContext 获取优化的上下文
 func (s *Service) GetOptimizedContext(ctx context.Context, taskID string, toolCalls []ToolCall) (*OptimizedContext, error) {
 	taskCtx, err := s.manager.GetTaskContext(ctx, taskID)
@@ -295,6 +455,9 @@ func (s *Service) RefinePhase(ctx context.Context, taskID, phaseID string) ([]Ta
 	if err != nil {
 		return nil, err
 	}
+	if taskCtx != nil && taskCtx.Task != nil {
+		ctx = prompt.WithLocale(ctx, prompt.ResolveLocale(ctx, taskCtx.Task.UserID, ""))
+	}
 
 	return s.planner.RefinePhase(ctx, taskCtx, phaseID)
 }
@@ -0,0 +1,275 @@
+package task
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"ai_task/pkg/clients/llm"
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/usage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const scriptedPlan = "```json\n" + `{
+  "phases": [
+    {"id": "phase_1", "name": "调研", "description": "收集资料", "steps": [{"id": "step_1", "description": "阅读文档"}]},
+    {"id": "phase_2", "name": "实现", "description": "编写代码", "steps": [{"id": "step_1", "description": "实现接口"}, {"id": "step_2", "description": "补充测试"}]}
+  ],
+  "key_questions": ["接口如何设计？"]
+}` + "\n```"
+
+func newScriptedService(t *testing.T, model llm.ChatModel) *Service {
+	tmpDir, err := os.MkdirTemp("", "task_test_*")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(tmpDir) })
+
+	service, err := NewService(&TaskManagerConfig{StoragePath: tmpDir, RereadThreshold: 10}, WithChatModel(model))
+	require.NoError(t, err)
+	t.Cleanup(service.Close)
+	return service
+}
+
+func TestServiceCreateTaskWithScriptedModel(t *testing.T) {
+	model := llm.NewScriptedModel(llm.ReplyWhen("实现一个缓存", scriptedPlan))
+	service := newScriptedService(t, model)
+
+	resp, err := service.CreateTask(context.Background(), &PlanRequest{
+		UserID:    "user_123",
+		SessionID: "session_456",
+		Goal:      "实现一个缓存",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, resp.Phases, 2)
+	assert.Equal(t, "调研", resp.Phases[0].Name)
+	assert.Len(t, resp.Phases[1].Steps, 2)
+	assert.Len(t, model.Calls(), 1)
+	assert.Equal(t, prompt.MustRender(context.Background(), prompt.TaskPlannerSystem, nil).Text, model.Calls()[0][0].Content)
+}
+
+func TestServiceCreateTaskWithLocale(t *testing.T) {
+	model := llm.NewScriptedModel(llm.ReplyWhen("Build a cache", scriptedPlan))
+	service := newScriptedService(t, model)
+
+	_, err := service.CreateTask(context.Background(), &PlanRequest{
+		UserID:    "user_123",
+		SessionID: "session_456",
+		Goal:      "Build a cache",
+		Locale:    "en-US",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, model.Calls(), 1)
+	system := prompt.MustRender(prompt.WithLocale(context.Background(), prompt.LocaleEN), prompt.TaskPlannerSystem, nil)
+	assert.Equal(t, prompt.LocaleEN, system.Locale)
+	assert.Equal(t, system.Text, model.Calls()[0][0].Content)
+	assert.Contains(t, model.Calls()[0][1].Content, "Goal: Build a cache")
+}
+
+func TestServiceExecutePhaseWithScriptedModel(t *testing.T) {
+	model := llm.NewScriptedModel(
+		llm.Reply(scriptedPlan),
+		llm.ReplyWhen("实现接口", `{"action": "complete", "message": "接口已实现", "rationale": "按计划实现"}`),
+		llm.ReplyWhen("补充测试", `{"action": "complete", "message": "测试已补充"}`),
+	)
+	service := newScriptedService(t, model)
+	ctx := context.Background()
+
+	resp, err := service.CreateTask(ctx, &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.NoError(t, err)
+
+	job, err := service.SubmitExecution(ctx, &ExecuteRequest{TaskID: resp.TaskID, PhaseID: "phase_2"})
+	require.NoError(t, err)
+	job = waitJob(t, service, job.ID)
+	assert.Equal(t, resp.TaskID, job.TaskID)
+	assert.Equal(t, JobStatusSucceeded, job.Status)
+	assert.Zero(t, model.Remaining())
+
+	task, err := service.GetTask(ctx, resp.TaskID)
+	require.NoError(t, err)
+	for _, step := range task.Phases[1].Steps {
+		assert.True(t, step.Completed, "step %s should be completed", step.ID)
+	}
+}
+
+func TestServiceCreateTaskFailsOnModelError(t *testing.T) {
+	service := newScriptedService(t, llm.NewScriptedModel())
+
+	_, err := service.CreateTask(context.Background(), &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, llm.ErrInvalidStructuredOutput)
+
+	// 规划失败的任务标记为失败，不再使用默认计划
+	tasks, err := service.ListTasks(context.Background(), "user_123", "session_456", "")
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, TaskStatusFailed, tasks[0].Status)
+	require.Len(t, tasks[0].Errors, 1)
+}
+
+func TestServiceRoutesModelsByRole(t *testing.T) {
+	planning := llm.NewScriptedModel(llm.Reply(scriptedPlan))
+	execution := llm.NewScriptedModel(llm.ScriptStep{Content: `{"action": "complete", "message": "完成"}`, Repeat: true})
+	models := llm.NewRouter(llm.NewScriptedModel(), map[llm.Role]llm.ChatModel{
+		llm.RolePlanning:  planning,
+		llm.RoleExecution: execution,
+	})
+
+	tmpDir, err := os.MkdirTemp("", "task_test_*")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	service, err := NewService(&TaskManagerConfig{StoragePath: tmpDir, RereadThreshold: 10}, WithModels(models))
+	require.NoError(t, err)
+	t.Cleanup(service.Close)
+
+	ctx := context.Background()
+	resp, err := service.CreateTask(ctx, &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.NoError(t, err)
+	job, err := service.SubmitExecution(ctx, &ExecuteRequest{TaskID: resp.TaskID, PhaseID: "phase_1"})
+	require.NoError(t, err)
+	assert.Equal(t, JobStatusSucceeded, waitJob(t, service, job.ID).Status)
+
+	assert.Len(t, planning.Calls(), 1)
+	assert.Len(t, execution.Calls(), 1)
+	assert.Equal(t, prompt.MustRender(context.Background(), prompt.TaskExecutorSystem, nil).Text, execution.Calls()[0][0].Content)
+}
+
+// exceededBudget 前 allow 次检查通过，之后任务用量超出限额
+type exceededBudget struct {
+	allow  int
+	checks int
+}
+
+func (b *exceededBudget) Check(ctx context.Context, userID, taskID string) error {
+	b.checks++
+	if b.checks <= b.allow {
+		return nil
+	}
+	return &usage.ExceededError{Scope: usage.ScopeTask, Used: 1200, Limit: 1000}
+}
+
+func TestServiceExecuteTaskStopsWhenBudgetExceeded(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "task_test_*")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(tmpDir) })
+
+	model := llm.NewScriptedModel(llm.Reply(scriptedPlan))
+	budget := &exceededBudget{}
+	service, err := NewService(&TaskManagerConfig{StoragePath: tmpDir, RereadThreshold: 10}, WithChatModel(model), WithBudget(budget))
+	require.NoError(t, err)
+	t.Cleanup(service.Close)
+	ctx := context.Background()
+
+	resp, err := service.CreateTask(ctx, &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.NoError(t, err)
+
+	job, err := service.SubmitExecution(ctx, &ExecuteRequest{TaskID: resp.TaskID})
+	require.NoError(t, err)
+	job = waitJob(t, service, job.ID)
+	assert.Equal(t, JobStatusBlocked, job.Status)
+	require.NotNil(t, job.Result)
+	assert.True(t, job.Result.BudgetExceeded)
+	assert.Contains(t, job.Result.Message, "task token budget exceeded")
+	task, err := service.GetTask(ctx, resp.TaskID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusBudgetExceeded, task.Status)
+	assert.Equal(t, 1, budget.checks)
+	assert.Len(t, model.Calls(), 1, "no step should run after the budget is exceeded")
+}
+
+func TestServiceExecuteTaskStopsWhenBudgetExceededMidStep(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "task_test_*")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(tmpDir) })
+
+	model := llm.NewScriptedModel(
+		llm.Reply(scriptedPlan),
+		llm.ScriptStep{Content: `{"action": "tool", "tool": "list_dir", "message": "查看目录"}`, Repeat: true},
+	)
+	// 阶段开始与第一轮决策前的检查通过，第二轮决策前超出
+	budget := &exceededBudget{allow: 2}
+	service, err := NewService(&TaskManagerConfig{StoragePath: tmpDir, RereadThreshold: 10}, WithChatModel(model), WithBudget(budget))
+	require.NoError(t, err)
+	t.Cleanup(service.Close)
+	ctx := context.Background()
+
+	resp, err := service.CreateTask(ctx, &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.NoError(t, err)
+
+	job, err := service.SubmitExecution(ctx, &ExecuteRequest{TaskID: resp.TaskID})
+	require.NoError(t, err)
+	job = waitJob(t, service, job.ID)
+	assert.Equal(t, JobStatusBlocked, job.Status)
+	require.NotNil(t, job.Result)
+	assert.True(t, job.Result.BudgetExceeded)
+	assert.Contains(t, job.Result.Message, "task token budget exceeded")
+	task, err := service.GetTask(ctx, resp.TaskID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusBudgetExceeded, task.Status)
+	assert.Equal(t, 3, budget.checks)
+	assert.Len(t, model.Calls(), 1+1)
+
+	// 预算超出不计入失败，也不转为等待人工指导
+	task, err = service.GetTask(ctx, resp.TaskID)
+	require.NoError(t, err)
+	assert.Empty(t, task.Errors)
+	assert.Nil(t, task.PendingInput)
+}
+
+func TestServiceExecuteTaskStopsOnInvalidDecision(t *testing.T) {
+	model := llm.NewScriptedModel(
+		llm.Reply(scriptedPlan),
+		llm.ReplyWhen("阅读文档", `{"action": "complete"}`),
+		llm.ReplyWhen("阅读文档", "已经完成"),
+		llm.ScriptStep{Match: llm.MatchContains("阅读文档"), Content: `{"action": "", "message": "完成"}`, Repeat: true},
+	)
+	service := newScriptedService(t, model)
+	ctx := context.Background()
+
+	resp, err := service.CreateTask(ctx, &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.NoError(t, err)
+
+	job, err := service.SubmitExecution(ctx, &ExecuteRequest{TaskID: resp.TaskID})
+	require.NoError(t, err)
+	job = waitJob(t, service, job.ID)
+	assert.Equal(t, JobStatusBlocked, job.Status)
+	require.NotNil(t, job.Result)
+	assert.Equal(t, "请提供进一步指导", job.Result.Message)
+
+	// 修复请求携带校验问题；每次尝试修复两次，3次尝试后升级
+	calls := model.Calls()
+	assert.Contains(t, calls[2][len(calls[2])-1].Content, "缺少必填字段 message")
+	assert.Len(t, calls, 1+3*3)
+
+	taskCtx, err := service.GetTaskContext(ctx, resp.TaskID)
+	require.NoError(t, err)
+	assert.False(t, taskCtx.Task.Phases[0].Steps[0].Completed, "step must not be marked complete")
+	require.Len(t, taskCtx.Task.Errors, 3)
+	assert.Contains(t, taskCtx.Task.Errors[0].Error, "invalid decision output")
+}
+
+func TestServiceCreateTaskRepairsPlan(t *testing.T) {
+	model := llm.NewScriptedModel(
+		llm.Reply(`{"phases": []}`),
+		llm.Reply(scriptedPlan),
+	)
+	service := newScriptedService(t, model)
+
+	resp, err := service.CreateTask(context.Background(), &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.NoError(t, err)
+	assert.Len(t, resp.Phases, 2)
+	assert.Contains(t, model.Calls()[1][len(model.Calls()[1])-1].Content, "至少需要一个阶段")
+}
+
+func TestServiceCreateTaskFailsOnInvalidPlan(t *testing.T) {
+	model := llm.NewScriptedModel(llm.ScriptStep{Content: `{"phases": []}`, Repeat: true})
+	service := newScriptedService(t, model)
+
+	_, err := service.CreateTask(context.Background(), &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	assert.ErrorIs(t, err, llm.ErrInvalidStructuredOutput)
+	assert.Len(t, model.Calls(), 1+llm.DefaultMaxRepairs)
+}
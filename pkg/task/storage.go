@@ -1,10 +1,13 @@
 package task
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
@@ -17,7 +20,7 @@ type Storage interface {
 	SaveTask(task *Task) error
 	LoadTask(taskID string) (*Task, error)
 	DeleteTask(taskID string) error
-	ListTasks(userID, sessionID string) ([]*Task, error)
+	ListTasks(userID, sessionID string, status TaskStatus) ([]*Task, error)
 
 	// 发现操作
 	SaveFindings(findings *TaskFindings) error
@@ -30,8 +33,24 @@ type Storage interface {
 	// 完整上下文操作
 	SaveContext(ctx *TaskContext) error
 	LoadContext(taskID string) (*TaskContext, error)
+
+	// 执行作业操作
+	SaveJob(job *Job) error
+	LoadJob(jobID string) (*Job, error)
+	ListJobs(taskID string, statuses ...JobStatus) ([]*Job, error) // taskID 为空时不按任务过滤，结果按创建时间升序
+
+	// 工具调用记录操作
+	SaveToolCall(taskID string, call *ToolCall) error
+	ListToolCalls(taskID string, limit int) ([]*ToolCall, error) // 按调用时间升序，limit>0 时只返回最近的 limit 条
 }
 
+const (
+	// jobsDir 执行作业文件所在目录，与任务目录并列
+	jobsDir = "_jobs"
+	// toolCallsFile 任务目录下的工具调用记录，每行一条 JSON
+	toolCallsFile = "tool_calls.jsonl"
+)
+
 // FileStorage 基于文件的任务存储实现
 // 遵循 Manus 原则：文件系统作为外部记忆
 type FileStorage struct {
@@ -125,8 +144,8 @@ func (fs *FileStorage) DeleteTask(taskID string) error {
 	return os.RemoveAll(dir)
 }
 
-// ListTasks 列出任务
-func (fs *FileStorage) ListTasks(userID, sessionID string) ([]*Task, error) {
+// ListTasks 列出任务，参数为空时不按该条件过滤
+func (fs *FileStorage) ListTasks(userID, sessionID string, status TaskStatus) ([]*Task, error) {
 	fs.mu.RLock()
 	defer fs.mu.RUnlock()
 
@@ -141,7 +160,7 @@ func (fs *FileStorage) ListTasks(userID, sessionID string) ([]*Task, error) {
 	}
 
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if !entry.IsDir() || entry.Name() == jobsDir {
 			continue
 		}
 
@@ -162,6 +181,9 @@ func (fs *FileStorage) ListTasks(userID, sessionID string) ([]*Task, error) {
 		if sessionID != "" && task.SessionID != sessionID {
 			continue
 		}
+		if status != "" && task.Status != status {
+			continue
+		}
 
 		tasks = append(tasks, task)
 	}
@@ -321,6 +343,166 @@ func (fs *FileStorage) LoadContext(taskID string) (*TaskContext, error) {
 	}, nil
 }
 
+// SaveJob 保存执行作业（_jobs/<job_id>.json）
+func (fs *FileStorage) SaveJob(job *Job) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	dir := filepath.Join(fs.basePath, jobsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	job.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, job.ID+".json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write job file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadJob 加载执行作业
+func (fs *FileStorage) LoadJob(jobID string) (*Job, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return fs.readJob(filepath.Join(fs.basePath, jobsDir, jobID+".json"))
+}
+
+// ListJobs 列出执行作业
+func (fs *FileStorage) ListJobs(taskID string, statuses ...JobStatus) ([]*Job, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	var jobs []*Job
+
+	dir := filepath.Join(fs.basePath, jobsDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return jobs, nil
+		}
+		return nil, fmt.Errorf("failed to read jobs directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		job, err := fs.readJob(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			log.Warnf("Failed to load job %s: %v", entry.Name(), err)
+			continue
+		}
+
+		if job == nil {
+			continue
+		}
+
+		// 过滤条件
+		if taskID != "" && job.TaskID != taskID {
+			continue
+		}
+		if len(statuses) > 0 && !slices.Contains(statuses, job.Status) {
+			continue
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	return jobs, nil
+}
+
+// SaveToolCall 追加工具调用记录（tool_calls.jsonl）
+func (fs *FileStorage) SaveToolCall(taskID string, call *ToolCall) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.ensureTaskDir(taskID); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(call)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tool call: %w", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(fs.getTaskDir(taskID), toolCallsFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open tool calls file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write tool call: %w", err)
+	}
+
+	return nil
+}
+
+// ListToolCalls 列出工具调用记录
+func (fs *FileStorage) ListToolCalls(taskID string, limit int) ([]*ToolCall, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	var calls []*ToolCall
+
+	data, err := os.ReadFile(filepath.Join(fs.getTaskDir(taskID), toolCallsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return calls, nil
+		}
+		return nil, fmt.Errorf("failed to read tool calls file: %w", err)
+	}
+
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var call ToolCall
+		if err := json.Unmarshal(line, &call); err != nil {
+			log.Warnf("Failed to unmarshal tool call of task %s: %v", taskID, err)
+			continue
+		}
+		calls = append(calls, &call)
+	}
+
+	if limit > 0 && len(calls) > limit {
+		calls = calls[len(calls)-limit:]
+	}
+
+	return calls, nil
+}
+
+// readJob 读取作业文件，文件不存在时返回 nil
+func (fs *FileStorage) readJob(path string) (*Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read job file: %w", err)
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+	}
+
+	return &job, nil
+}
+
 // taskToMarkdown 将任务转换为 Markdown 格式
 func (fs *FileStorage) taskToMarkdown(task *Task) string {
 	md := fmt.Sprintf(`# Task Plan: %s
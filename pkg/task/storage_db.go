@@ -131,8 +131,8 @@ func (ds *DBStorage) DeleteTask(taskID string) error {
 	return nil
 }
 
-// ListTasks 列出任务
-func (ds *DBStorage) ListTasks(userID, sessionID string) ([]*Task, error) {
+// ListTasks 列出任务，参数为空时不按该条件过滤
+func (ds *DBStorage) ListTasks(userID, sessionID string, status TaskStatus) ([]*Task, error) {
 	ds.mu.RLock()
 	defer ds.mu.RUnlock()
 
@@ -152,6 +152,10 @@ func (ds *DBStorage) ListTasks(userID, sessionID string) ([]*Task, error) {
 	if sessionID != "" {
 		condition.SessionID = &sessionID
 	}
+	if status != "" {
+		statusStr := string(status)
+		condition.Status = &statusStr
+	}
 
 	records, err := taskRepo.List(condition)
 	if err != nil {
@@ -337,6 +341,158 @@ func (ds *DBStorage) LoadContext(taskID string) (*TaskContext, error) {
 	}, nil
 }
 
+// SaveJob 保存执行作业
+func (ds *DBStorage) SaveJob(job *Job) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	ctx := context.Background()
+	session := ds.factory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	jobRepo, err := ds.factory.NewTaskJobRepository(session)
+	if err != nil {
+		return fmt.Errorf("failed to create job repository: %w", err)
+	}
+
+	job.UpdatedAt = time.Now()
+
+	req, err := ds.jobToCondition(job)
+	if err != nil {
+		return err
+	}
+
+	if err := jobRepo.Upsert(req); err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+
+	return nil
+}
+
+// LoadJob 加载执行作业
+func (ds *DBStorage) LoadJob(jobID string) (*Job, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	ctx := context.Background()
+	session := ds.factory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	jobRepo, err := ds.factory.NewTaskJobRepository(session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job repository: %w", err)
+	}
+
+	record, err := jobRepo.Get(jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job: %w", err)
+	}
+
+	if record == nil {
+		return nil, nil
+	}
+
+	return ds.entityToJob(record)
+}
+
+// ListJobs 列出执行作业
+func (ds *DBStorage) ListJobs(taskID string, statuses ...JobStatus) ([]*Job, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	ctx := context.Background()
+	session := ds.factory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	jobRepo, err := ds.factory.NewTaskJobRepository(session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job repository: %w", err)
+	}
+
+	condition := &model.TaskJobListCondition{}
+	if taskID != "" {
+		condition.TaskID = &taskID
+	}
+	for _, status := range statuses {
+		condition.Statuses = append(condition.Statuses, string(status))
+	}
+
+	records, err := jobRepo.List(condition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	jobs := make([]*Job, 0, len(records))
+	for _, record := range records {
+		job, err := ds.entityToJob(record)
+		if err != nil {
+			log.Warnf("Failed to convert job %s: %v", record.ID, err)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// SaveToolCall 保存工具调用记录
+func (ds *DBStorage) SaveToolCall(taskID string, call *ToolCall) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	ctx := context.Background()
+	session := ds.factory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	toolCallRepo, err := ds.factory.NewTaskToolCallRepository(session)
+	if err != nil {
+		return fmt.Errorf("failed to create tool call repository: %w", err)
+	}
+
+	req, err := ds.toolCallToCondition(taskID, call)
+	if err != nil {
+		return err
+	}
+
+	if err := toolCallRepo.Insert(req); err != nil {
+		return fmt.Errorf("failed to save tool call: %w", err)
+	}
+
+	return nil
+}
+
+// ListToolCalls 列出工具调用记录
+func (ds *DBStorage) ListToolCalls(taskID string, limit int) ([]*ToolCall, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	ctx := context.Background()
+	session := ds.factory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	toolCallRepo, err := ds.factory.NewTaskToolCallRepository(session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tool call repository: %w", err)
+	}
+
+	records, err := toolCallRepo.List(&model.TaskToolCallListCondition{TaskID: taskID, Limit: limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tool calls: %w", err)
+	}
+
+	calls := make([]*ToolCall, 0, len(records))
+	for _, record := range records {
+		call, err := ds.entityToToolCall(record)
+		if err != nil {
+			log.Warnf("Failed to convert tool call %s: %v", record.ID, err)
+			continue
+		}
+		calls = append(calls, call)
+	}
+
+	return calls, nil
+}
+
 // QueryTasks 高级查询（数据库特有功能）
 func (ds *DBStorage) QueryTasks(opts *TaskQueryOptions) ([]*Task, int64, error) {
 	ds.mu.RLock()
@@ -445,6 +601,34 @@ func (ds *DBStorage) taskToCondition(task *Task) (*model.UpsertTaskCondition, er
 		return nil, fmt.Errorf("failed to marshal errors: %w", err)
 	}
 
+	// 没有暂停位置时写入空字符串，清除上次的位置
+	cursorStr := ""
+	if task.Cursor != nil {
+		cursorJSON, err := json.Marshal(task.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal cursor: %w", err)
+		}
+		cursorStr = string(cursorJSON)
+	}
+
+	pendingStr := ""
+	if task.PendingInput != nil {
+		pendingJSON, err := json.Marshal(task.PendingInput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal pending input: %w", err)
+		}
+		pendingStr = string(pendingJSON)
+	}
+
+	guidanceStr := ""
+	if task.Guidance != nil {
+		guidanceJSON, err := json.Marshal(task.Guidance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal guidance: %w", err)
+		}
+		guidanceStr = string(guidanceJSON)
+	}
+
 	phasesStr := string(phasesJSON)
 	questionsStr := string(questionsJSON)
 	decisionsStr := string(decisionsJSON)
@@ -462,6 +646,9 @@ func (ds *DBStorage) taskToCondition(task *Task) (*model.UpsertTaskCondition, er
 		DecisionsJSON: &decisionsStr,
 		ErrorsJSON:    &errorsStr,
 		Status:        &statusStr,
+		CursorJSON:    &cursorStr,
+		PendingJSON:   &pendingStr,
+		GuidanceJSON:  &guidanceStr,
 		ToolCallCount: &task.ToolCallCount,
 		NeedsReread:   &task.NeedsReread,
 		CompletedAt:   task.CompletedAt,
@@ -497,6 +684,30 @@ func (ds *DBStorage) entityToTask(record *entity.Task) (*Task, error) {
 		}
 	}
 
+	var cursor *TaskCursor
+	if record.CursorJSON != "" {
+		cursor = &TaskCursor{}
+		if err := json.Unmarshal([]byte(record.CursorJSON), cursor); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal cursor: %w", err)
+		}
+	}
+
+	var pending *PendingInput
+	if record.PendingJSON != "" {
+		pending = &PendingInput{}
+		if err := json.Unmarshal([]byte(record.PendingJSON), pending); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal pending input: %w", err)
+		}
+	}
+
+	var guidance *Guidance
+	if record.GuidanceJSON != "" {
+		guidance = &Guidance{}
+		if err := json.Unmarshal([]byte(record.GuidanceJSON), guidance); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal guidance: %w", err)
+		}
+	}
+
 	return &Task{
 		ID:            record.ID,
 		UserID:        record.UserID,
@@ -508,6 +719,9 @@ func (ds *DBStorage) entityToTask(record *entity.Task) (*Task, error) {
 		Decisions:     decisions,
 		Errors:        errors,
 		Status:        TaskStatus(record.Status),
+		Cursor:        cursor,
+		PendingInput:  pending,
+		Guidance:      guidance,
 		ToolCallCount: record.ToolCallCount,
 		NeedsReread:   record.NeedsReread,
 		CreatedAt:     record.CreatedAt,
@@ -636,6 +850,102 @@ func (ds *DBStorage) entityToProgress(record *entity.TaskProgress) (*TaskProgres
 	}, nil
 }
 
+func (ds *DBStorage) jobToCondition(job *Job) (*model.UpsertTaskJobCondition, error) {
+	resultStr := ""
+	if job.Result != nil {
+		resultJSON, err := json.Marshal(job.Result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal job result: %w", err)
+		}
+		resultStr = string(resultJSON)
+	}
+
+	status := string(job.Status)
+
+	return &model.UpsertTaskJobCondition{
+		ID:           job.ID,
+		TaskID:       job.TaskID,
+		SessionID:    job.SessionID,
+		PhaseID:      job.PhaseID,
+		Locale:       job.Locale,
+		Status:       &status,
+		CurrentPhase: &job.CurrentPhase,
+		CurrentStep:  &job.CurrentStep,
+		ResultJSON:   &resultStr,
+		Error:        &job.Error,
+		Attempts:     &job.Attempts,
+		StartedAt:    job.StartedAt,
+		FinishedAt:   job.FinishedAt,
+	}, nil
+}
+
+func (ds *DBStorage) entityToJob(record *entity.TaskJob) (*Job, error) {
+	var result *ExecutionResult
+	if record.ResultJSON != "" {
+		result = &ExecutionResult{}
+		if err := json.Unmarshal([]byte(record.ResultJSON), result); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal job result: %w", err)
+		}
+	}
+
+	return &Job{
+		ID:           record.ID,
+		TaskID:       record.TaskID,
+		SessionID:    record.SessionID,
+		PhaseID:      record.PhaseID,
+		Locale:       record.Locale,
+		Status:       JobStatus(record.Status),
+		CurrentPhase: record.CurrentPhase,
+		CurrentStep:  record.CurrentStep,
+		Result:       result,
+		Error:        record.Error,
+		Attempts:     record.Attempts,
+		CreatedAt:    record.CreatedAt,
+		StartedAt:    record.StartedAt,
+		FinishedAt:   record.FinishedAt,
+		UpdatedAt:    record.UpdatedAt,
+	}, nil
+}
+
+func (ds *DBStorage) toolCallToCondition(taskID string, call *ToolCall) (*model.InsertTaskToolCallCondition, error) {
+	argsStr := ""
+	if len(call.Args) > 0 {
+		argsJSON, err := json.Marshal(call.Args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal tool call args: %w", err)
+		}
+		argsStr = string(argsJSON)
+	}
+
+	return &model.InsertTaskToolCallCondition{
+		ID:        call.ID,
+		TaskID:    taskID,
+		Name:      call.Name,
+		ArgsJSON:  argsStr,
+		Result:    call.Result,
+		Error:     call.Error,
+		CreatedAt: call.Timestamp,
+	}, nil
+}
+
+func (ds *DBStorage) entityToToolCall(record *entity.TaskToolCall) (*ToolCall, error) {
+	var args map[string]interface{}
+	if record.ArgsJSON != "" {
+		if err := json.Unmarshal([]byte(record.ArgsJSON), &args); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tool call args: %w", err)
+		}
+	}
+
+	return &ToolCall{
+		ID:        record.ID,
+		Name:      record.Name,
+		Args:      args,
+		Result:    record.Result,
+		Error:     record.Error,
+		Timestamp: record.CreatedAt,
+	}, nil
+}
+
 // TaskQueryOptions 任务查询选项
 type TaskQueryOptions struct {
 	UserID    string
@@ -0,0 +1,418 @@
+package task
+
+import (
+	"ai_task/pkg/sandbox"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// workspaceDir 任务目录下的工具工作目录
+const workspaceDir = "workspace"
+
+// ErrUnknownTool 工具没有注册实现
+var ErrUnknownTool = errors.New("unknown tool")
+
+// ToolFunc 工具实现，返回的文本写入 ToolCall.Result；出错时可同时返回已有的输出
+type ToolFunc func(ctx context.Context, env *ToolEnv, args map[string]interface{}) (string, error)
+
+// Tool 可执行的工具
+type Tool struct {
+	Name   string
+	Action ActionType // 计入 2 动作规则的动作类型
+	Run    ToolFunc
+}
+
+// ToolEnv 单次工具调用的运行环境
+type ToolEnv struct {
+	TaskID    string
+	Workspace *sandbox.Workspace
+	Config    *ToolConfig
+}
+
+// ToolRegistry 工具注册表
+// 为 ToolLoader 公布的工具提供实现，工具只能访问任务自己的工作目录，
+// 每次调用都经过 ActionTracker 的前后钩子并记录为 ToolCall
+type ToolRegistry struct {
+	manager *Manager
+	tracker *ActionTracker
+	config  ToolConfig
+	baseDir string
+
+	mu    sync.RWMutex
+	tools map[string]Tool
+}
+
+// NewToolRegistry 创建工具注册表并注册内置工具，工作目录位于任务存储路径下
+func NewToolRegistry(manager *Manager) *ToolRegistry {
+	config := manager.config.Tools
+	defaults := DefaultTaskManagerConfig().Tools
+	if config.MaxReadBytes <= 0 {
+		config.MaxReadBytes = defaults.MaxReadBytes
+	}
+	if config.MaxWriteBytes <= 0 {
+		config.MaxWriteBytes = defaults.MaxWriteBytes
+	}
+
+	r := &ToolRegistry{
+		manager: manager,
+		tracker: NewActionTracker(manager),
+		config:  config,
+		baseDir: manager.config.StoragePath,
+		tools:   make(map[string]Tool),
+	}
+	for _, tool := range builtinTools() {
+		r.Register(tool)
+	}
+	return r
+}
+
+// Register 注册工具，同名工具被替换
+func (r *ToolRegistry) Register(tool Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tools[tool.Name] = tool
+}
+
+// Has 工具是否有实现
+func (r *ToolRegistry) Has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.tools[name]
+	return ok
+}
+
+// Available 过滤出有实现的工具定义
+func (r *ToolRegistry) Available(defs []ToolDefinition) []ToolDefinition {
+	available := make([]ToolDefinition, 0, len(defs))
+	for _, def := range defs {
+		if r.Has(def.Name) {
+			available = append(available, def)
+		}
+	}
+	return available
+}
+
+// WorkspaceDir 任务的工作目录
+func (r *ToolRegistry) WorkspaceDir(taskID string) string {
+	return filepath.Join(r.baseDir, taskID, workspaceDir)
+}
+
+// Invoke 执行工具调用
+// 工具自身的错误（路径越界、命令不在白名单中等）写入 ToolCall.Error 返回给调用方观察，
+// 只有任务不存在或记录失败时返回 error
+func (r *ToolRegistry) Invoke(ctx context.Context, taskID, name string, args map[string]interface{}) (*ToolCall, error) {
+	if taskID == "" || !filepath.IsLocal(taskID) || strings.ContainsAny(taskID, `/\`) {
+		return nil, fmt.Errorf("invalid task id: %q", taskID)
+	}
+	if err := r.tracker.PreActionHook(ctx, taskID, name); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	tool, ok := r.tools[name]
+	r.mu.RUnlock()
+
+	call := &ToolCall{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Args:      args,
+		Timestamp: time.Now(),
+	}
+
+	var result string
+	var err error
+	if ok {
+		result, err = r.run(ctx, taskID, tool, args)
+	} else {
+		err = fmt.Errorf("%w: %s", ErrUnknownTool, name)
+		tool.Action = ActionTypeExecute
+	}
+	call.Result = result
+	if err != nil {
+		call.Error = err.Error()
+		log.Warnf("Task %s tool %s failed: %v", taskID, name, err)
+	}
+
+	if err := r.manager.storage.SaveToolCall(taskID, call); err != nil {
+		return call, fmt.Errorf("failed to record tool call: %w", err)
+	}
+	if err := r.tracker.PostActionHook(ctx, taskID, name, tool.Action); err != nil {
+		return call, err
+	}
+
+	return call, nil
+}
+
+// run 在任务工作目录中执行工具
+func (r *ToolRegistry) run(ctx context.Context, taskID string, tool Tool, args map[string]interface{}) (string, error) {
+	workspace, err := sandbox.OpenWorkspace(r.WorkspaceDir(taskID))
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = workspace.Close() }()
+
+	return tool.Run(ctx, &ToolEnv{TaskID: taskID, Workspace: workspace, Config: &r.config}, args)
+}
+
+// builtinTools 内置工具，名称与 ToolLoader 公布的工具一致
+func builtinTools() []Tool {
+	return []Tool{
+		{Name: "read_file", Action: ActionTypeView, Run: readFileTool},
+		{Name: "write_file", Action: ActionTypeWrite, Run: writeFileTool},
+		{Name: "list_dir", Action: ActionTypeView, Run: listDirTool},
+		{Name: "edit_file", Action: ActionTypeWrite, Run: editFileTool},
+		{Name: "run_command", Action: ActionTypeExecute, Run: runCommandTool},
+		{Name: "run_test", Action: ActionTypeExecute, Run: runTestTool},
+		{Name: "verify", Action: ActionTypeView, Run: verifyTool},
+	}
+}
+
+// readFileTool 读取文件，超过 MaxReadBytes 的部分截断
+func readFileTool(ctx context.Context, env *ToolEnv, args map[string]interface{}) (string, error) {
+	path, err := stringArg(args, "path", true)
+	if err != nil {
+		return "", err
+	}
+	data, truncated, err := env.Workspace.ReadFile(path, env.Config.MaxReadBytes)
+	if err != nil {
+		return "", err
+	}
+	if truncated {
+		return string(data) + fmt.Sprintf("\n...[truncated at %d bytes]", env.Config.MaxReadBytes), nil
+	}
+	return string(data), nil
+}
+
+// writeFileTool 写入文件，覆盖已有内容
+func writeFileTool(ctx context.Context, env *ToolEnv, args map[string]interface{}) (string, error) {
+	path, err := stringArg(args, "path", true)
+	if err != nil {
+		return "", err
+	}
+	content, err := stringArg(args, "content", false)
+	if err != nil {
+		return "", err
+	}
+	if int64(len(content)) > env.Config.MaxWriteBytes {
+		return "", fmt.Errorf("content exceeds %d bytes", env.Config.MaxWriteBytes)
+	}
+	if err := env.Workspace.WriteFile(path, []byte(content)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("wrote %d bytes to %s", len(content), path), nil
+}
+
+// listDirTool 列出目录，目录名以 / 结尾
+func listDirTool(ctx context.Context, env *ToolEnv, args map[string]interface{}) (string, error) {
+	path, err := stringArg(args, "path", false)
+	if err != nil {
+		return "", err
+	}
+	entries, err := env.Workspace.ListDir(path)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "(empty)", nil
+	}
+
+	var sb strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir {
+			sb.WriteString(entry.Name + "/\n")
+		} else {
+			sb.WriteString(fmt.Sprintf("%s (%d bytes)\n", entry.Name, entry.Size))
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// editFileTool 把文件中唯一出现的 old_text 替换为 new_text
+func editFileTool(ctx context.Context, env *ToolEnv, args map[string]interface{}) (string, error) {
+	path, err := stringArg(args, "path", true)
+	if err != nil {
+		return "", err
+	}
+	oldText, err := stringArg(args, "old_text", true)
+	if err != nil {
+		return "", err
+	}
+	newText, err := stringArg(args, "new_text", false)
+	if err != nil {
+		return "", err
+	}
+
+	data, truncated, err := env.Workspace.ReadFile(path, env.Config.MaxWriteBytes)
+	if err != nil {
+		return "", err
+	}
+	if truncated {
+		return "", fmt.Errorf("%s exceeds %d bytes", path, env.Config.MaxWriteBytes)
+	}
+
+	content := string(data)
+	switch n := strings.Count(content, oldText); n {
+	case 0:
+		return "", fmt.Errorf("old_text not found in %s", path)
+	case 1:
+	default:
+		return "", fmt.Errorf("old_text appears %d times in %s, include more context", n, path)
+	}
+
+	content = strings.Replace(content, oldText, newText, 1)
+	if int64(len(content)) > env.Config.MaxWriteBytes {
+		return "", fmt.Errorf("content exceeds %d bytes", env.Config.MaxWriteBytes)
+	}
+	if err := env.Workspace.WriteFile(path, []byte(content)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("edited %s", path), nil
+}
+
+// runCommandTool 执行白名单中的命令，command 按空白拆分，不经过 shell 解释
+func runCommandTool(ctx context.Context, env *ToolEnv, args map[string]interface{}) (string, error) {
+	command, err := stringArg(args, "command", true)
+	if err != nil {
+		return "", err
+	}
+	return runInWorkspace(ctx, env, args, strings.Fields(command))
+}
+
+// runTestTool 执行测试命令，未指定 command 时使用配置的 TestCommand
+func runTestTool(ctx context.Context, env *ToolEnv, args map[string]interface{}) (string, error) {
+	command, err := stringArg(args, "command", false)
+	if err != nil {
+		return "", err
+	}
+	argv := strings.Fields(command)
+	if len(argv) == 0 {
+		argv = env.Config.TestCommand
+	}
+	if len(argv) == 0 {
+		return "", fmt.Errorf("no test command configured, command is required")
+	}
+	return runInWorkspace(ctx, env, args, argv)
+}
+
+// runInWorkspace 在工作目录的 dir 子目录中执行命令，非零退出码作为错误返回，输出仍写入结果
+func runInWorkspace(ctx context.Context, env *ToolEnv, args map[string]interface{}, argv []string) (string, error) {
+	dir, err := stringArg(args, "dir", false)
+	if err != nil {
+		return "", err
+	}
+	result, err := env.Config.Command.Run(ctx, env.Workspace, dir, argv)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("exit_code: %d\n", result.ExitCode))
+	if result.Stdout != "" {
+		sb.WriteString("stdout:\n" + result.Stdout + "\n")
+	}
+	if result.Stderr != "" {
+		sb.WriteString("stderr:\n" + result.Stderr + "\n")
+	}
+	if result.Truncated {
+		sb.WriteString(fmt.Sprintf("[output truncated at %d bytes]\n", env.Config.Command.MaxOutputBytes))
+	}
+	output := strings.TrimSuffix(sb.String(), "\n")
+
+	switch {
+	case result.TimedOut:
+		return output, fmt.Errorf("command timed out after %s", env.Config.Command.Timeout)
+	case result.ExitCode != 0:
+		return output, fmt.Errorf("command exited with code %d", result.ExitCode)
+	}
+	return output, nil
+}
+
+// verifyTool 检查文件存在，指定 contains 时同时检查文件前 MaxReadBytes 字节包含该文本
+func verifyTool(ctx context.Context, env *ToolEnv, args map[string]interface{}) (string, error) {
+	paths, err := stringsArg(args, "paths")
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("paths is required")
+	}
+	contains, err := stringArg(args, "contains", false)
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	failed := 0
+	for _, path := range paths {
+		status := "ok"
+		if _, err := env.Workspace.Stat(path); err != nil {
+			status = "missing"
+		} else if contains != "" {
+			data, _, err := env.Workspace.ReadFile(path, env.Config.MaxReadBytes)
+			if err != nil || !strings.Contains(string(data), contains) {
+				status = "does not contain expected text"
+			}
+		}
+		if status != "ok" {
+			failed++
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", path, status))
+	}
+
+	output := strings.Join(lines, "\n")
+	if failed > 0 {
+		return output, fmt.Errorf("%d of %d checks failed", failed, len(paths))
+	}
+	return output, nil
+}
+
+// stringArg 读取字符串参数
+func stringArg(args map[string]interface{}, key string, required bool) (string, error) {
+	value, ok := args[key]
+	if !ok || value == nil {
+		if required {
+			return "", fmt.Errorf("%s is required", key)
+		}
+		return "", nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string", key)
+	}
+	if required && s == "" {
+		return "", fmt.Errorf("%s is required", key)
+	}
+	return s, nil
+}
+
+// stringsArg 读取字符串数组参数，单个字符串视为只有一个元素
+func stringsArg(args map[string]interface{}, key string) ([]string, error) {
+	switch value := args[key].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{value}, nil
+	case []string:
+		return value, nil
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be an array of strings", key)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("%s must be an array of strings", key)
+	}
+}
@@ -0,0 +1,133 @@
+package task
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"ai_task/pkg/sandbox"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newToolRegistry 创建使用临时目录的工具注册表与任务
+func newToolRegistry(t *testing.T, allowed ...string) (*ToolRegistry, *Manager, *Task) {
+	tmpDir, err := os.MkdirTemp("", "task_test_*")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(tmpDir) })
+
+	policy := sandbox.DefaultPolicy()
+	policy.AllowedCommands = allowed
+	manager, err := NewManager(&TaskManagerConfig{
+		StoragePath:     tmpDir,
+		RereadThreshold: 100,
+		Tools:           ToolConfig{MaxReadBytes: 16, Command: policy},
+	})
+	require.NoError(t, err)
+
+	task, err := manager.CreateTask(context.Background(), &PlanRequest{UserID: "user_123", SessionID: "session_456", Goal: "实现一个缓存"})
+	require.NoError(t, err)
+	return NewToolRegistry(manager), manager, task
+}
+
+func TestToolRegistryFileTools(t *testing.T) {
+	registry, manager, task := newToolRegistry(t)
+	ctx := context.Background()
+
+	invoke := func(name string, args map[string]interface{}) *ToolCall {
+		call, err := registry.Invoke(ctx, task.ID, name, args)
+		require.NoError(t, err)
+		return call
+	}
+
+	call := invoke("write_file", map[string]interface{}{"path": "src/cache.go", "content": "package cache\n\nvar size = 1\n"})
+	assert.Empty(t, call.Error)
+	assert.FileExists(t, filepath.Join(registry.WorkspaceDir(task.ID), "src", "cache.go"))
+
+	call = invoke("edit_file", map[string]interface{}{"path": "src/cache.go", "old_text": "size = 1", "new_text": "size = 2"})
+	assert.Empty(t, call.Error)
+	data, err := os.ReadFile(filepath.Join(registry.WorkspaceDir(task.ID), "src", "cache.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "size = 2")
+
+	// 超过 MaxReadBytes 的内容被截断
+	call = invoke("read_file", map[string]interface{}{"path": "src/cache.go"})
+	assert.Empty(t, call.Error)
+	assert.Equal(t, "package cache\n\nv\n...[truncated at 16 bytes]", call.Result)
+
+	call = invoke("list_dir", map[string]interface{}{})
+	assert.Equal(t, "src/", call.Result)
+
+	// verify 同样只检查前 MaxReadBytes 字节
+	call = invoke("verify", map[string]interface{}{"paths": []interface{}{"src/cache.go"}, "contains": "package cache"})
+	assert.Empty(t, call.Error)
+	call = invoke("verify", map[string]interface{}{"paths": []interface{}{"src/cache.go", "README.md"}})
+	assert.Equal(t, "src/cache.go: ok\nREADME.md: missing", call.Result)
+	assert.Equal(t, "1 of 2 checks failed", call.Error)
+
+	// 工具错误记录在 ToolCall 中，不中断调用方
+	call = invoke("read_file", map[string]interface{}{"path": "../task.json"})
+	assert.Contains(t, call.Error, sandbox.ErrPathEscape.Error())
+	call = invoke("web_search", map[string]interface{}{"query": "cache"})
+	assert.Contains(t, call.Error, ErrUnknownTool.Error())
+
+	calls, err := manager.storage.ListToolCalls(task.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, calls, 8)
+	assert.Equal(t, "write_file", calls[0].Name)
+	assert.Equal(t, "src/cache.go", calls[0].Args["path"])
+	assert.Equal(t, "web_search", calls[7].Name)
+
+	recent, err := manager.storage.ListToolCalls(task.ID, 2)
+	require.NoError(t, err)
+	require.Len(t, recent, 2)
+	assert.Equal(t, calls[6].ID, recent[0].ID)
+
+	// 每次调用都经过动作钩子计数
+	updated, err := manager.GetTask(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 8, updated.ToolCallCount)
+
+	_, err = registry.Invoke(ctx, "missing", "list_dir", nil)
+	assert.Error(t, err)
+	_, err = registry.Invoke(ctx, "../"+task.ID, "list_dir", nil)
+	assert.Error(t, err)
+}
+
+func TestToolRegistryRunCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires unix commands")
+	}
+	registry, _, task := newToolRegistry(t, "cat", "ls")
+	ctx := context.Background()
+
+	_, err := registry.Invoke(ctx, task.ID, "write_file", map[string]interface{}{"path": "notes.txt", "content": "hello"})
+	require.NoError(t, err)
+
+	call, err := registry.Invoke(ctx, task.ID, "run_command", map[string]interface{}{"command": "cat notes.txt"})
+	require.NoError(t, err)
+	assert.Empty(t, call.Error)
+	assert.Equal(t, "exit_code: 0\nstdout:\nhello", call.Result)
+
+	// 非零退出码作为错误，输出仍然保留
+	call, err = registry.Invoke(ctx, task.ID, "run_command", map[string]interface{}{"command": "cat missing.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, "command exited with code 1", call.Error)
+	assert.Contains(t, call.Result, "No such file")
+
+	call, err = registry.Invoke(ctx, task.ID, "run_command", map[string]interface{}{"command": "rm notes.txt"})
+	require.NoError(t, err)
+	assert.Contains(t, call.Error, sandbox.ErrCommandNotAllowed.Error())
+	assert.FileExists(t, filepath.Join(registry.WorkspaceDir(task.ID), "notes.txt"))
+
+	// 默认不配置测试命令，run_test 的命令同样受白名单限制
+	call, err = registry.Invoke(ctx, task.ID, "run_test", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "no test command configured, command is required", call.Error)
+	call, err = registry.Invoke(ctx, task.ID, "run_test", map[string]interface{}{"command": "go test ./..."})
+	require.NoError(t, err)
+	assert.Equal(t, sandbox.ErrCommandNotAllowed.Error()+": go", call.Error)
+}
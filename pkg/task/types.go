@@ -9,6 +9,7 @@ package task
 
 import (
 	"ai_task/constant"
+	"ai_task/pkg/sandbox"
 	"time"
 )
 
@@ -18,6 +19,7 @@ type (
 	PhaseStatus = constant.PhaseStatus
 	StorageType = constant.StorageType
 	ActionType  = constant.ActionType
+	JobStatus   = constant.JobStatus
 )
 
 // 常量别名，保持向后兼容
@@ -28,6 +30,10 @@ const (
 	TaskStatusFailed     = constant.TaskStatusFailed
 	TaskStatusCancelled  = constant.TaskStatusCancelled
 
+	TaskStatusBudgetExceeded = constant.TaskStatusBudgetExceeded
+	TaskStatusPaused         = constant.TaskStatusPaused
+	TaskStatusAwaitingInput  = constant.TaskStatusAwaitingInput
+
 	PhaseStatusPending    = constant.PhaseStatusPending
 	PhaseStatusInProgress = constant.PhaseStatusInProgress
 	PhaseStatusComplete   = constant.PhaseStatusComplete
@@ -37,6 +43,14 @@ const (
 	StorageTypeDB     = constant.StorageTypeDB
 	StorageTypeHybrid = constant.StorageTypeHybrid
 
+	JobStatusQueued    = constant.JobStatusQueued
+	JobStatusRunning   = constant.JobStatusRunning
+	JobStatusSucceeded = constant.JobStatusSucceeded
+	JobStatusFailed    = constant.JobStatusFailed
+	JobStatusCancelled = constant.JobStatusCancelled
+	JobStatusPaused    = constant.JobStatusPaused
+	JobStatusBlocked   = constant.JobStatusBlocked
+
 	ActionTypeView    = constant.ActionTypeView
 	ActionTypeBrowser = constant.ActionTypeBrowser
 	ActionTypeSearch  = constant.ActionTypeSearch
@@ -83,10 +97,10 @@ type Decision struct {
 
 // Finding 发现记录
 type Finding struct {
-	Category  string    `json:"category"`         // 发现类别（research/technical/visual/resource）
-	Content   string    `json:"content"`          // 发现内容
-	Source    string    `json:"source,omitempty"` // 发现来源（文件路径、URL等）
-	Timestamp time.Time `json:"timestamp"`        // 发现时间
+	Category  string    `json:"category"`                   // 发现类别（research/technical/visual/resource）
+	Content   string    `json:"content"`                    // 发现内容
+	Source    string    `json:"source,omitempty"`           // 发现来源（文件路径、URL等）
+	Timestamp time.Time `json:"timestamp" required:"false"` // 发现时间，模型输出时可省略
 }
 
 // ProgressEntry 进度条目
@@ -106,6 +120,30 @@ type TestResult struct {
 	Status   string `json:"status"`   // 测试状态（✓通过/✗失败/pending待测试）
 }
 
+// TaskCursor 任务暂停时记录的恢复位置，恢复后从该阶段的该步骤继续执行
+type TaskCursor struct {
+	PhaseID string `json:"phase_id"` // 阶段ID
+	StepID  string `json:"step_id"`  // 下一个要执行的步骤ID
+}
+
+// PendingInput 等待人工指导的问题，步骤重试用尽后记录，提交指导后清除
+type PendingInput struct {
+	PhaseID   string        `json:"phase_id"`   // 阶段ID
+	StepID    string        `json:"step_id"`    // 失败的步骤ID
+	Question  string        `json:"question"`   // 向人工提出的问题
+	Attempts  []ErrorRecord `json:"attempts"`   // 该步骤失败的各次尝试
+	CreatedAt time.Time     `json:"created_at"` // 提出时间
+}
+
+// Guidance 人工对等待问题的指导，恢复执行后注入该步骤的提示词，步骤完成后清除
+type Guidance struct {
+	PhaseID   string    `json:"phase_id"`  // 阶段ID
+	StepID    string    `json:"step_id"`   // 步骤ID
+	Question  string    `json:"question"`  // 对应的问题
+	Answer    string    `json:"answer"`    // 指导内容
+	Timestamp time.Time `json:"timestamp"` // 提交时间
+}
+
 // Task 任务（对应 task_plan.md）
 type Task struct {
 	ID           string        `json:"id"`                      // 任务唯一标识符
@@ -117,7 +155,10 @@ type Task struct {
 	KeyQuestions []string      `json:"key_questions,omitempty"` // 关键问题列表
 	Decisions    []Decision    `json:"decisions,omitempty"`     // 决策记录列表
 	Errors       []ErrorRecord `json:"errors,omitempty"`        // 错误记录列表
-	Status       TaskStatus    `json:"status"`                  // 任务状态（pending/in_progress/completed/failed/cancelled）
+	Status       TaskStatus    `json:"status"`                  // 任务状态（pending/in_progress/completed/failed/cancelled/budget_exceeded/paused/awaiting_input）
+	Cursor       *TaskCursor   `json:"cursor,omitempty"`        // 暂停位置，恢复执行从此处继续，执行结束后清除
+	PendingInput *PendingInput `json:"pending_input,omitempty"` // 等待人工指导的问题，状态为 awaiting_input 时存在
+	Guidance     *Guidance     `json:"guidance,omitempty"`      // 最近一次人工指导，对应步骤完成后清除
 	CreatedAt    time.Time     `json:"created_at"`              // 创建时间
 	UpdatedAt    time.Time     `json:"updated_at"`              // 更新时间
 	CompletedAt  *time.Time    `json:"completed_at,omitempty"`  // 完成时间
@@ -173,6 +214,22 @@ type TaskManagerConfig struct {
 	Compression          ContextCompression `json:"compression"`          // 上下文压缩配置（用于KV缓存优化）
 	MaxRetries           int                `json:"max_retries"`          // 最大重试次数（3次打击规则，默认3）
 	EnableAutoPlanning   bool               `json:"enable_auto_planning"` // 是否启用LLM自动规划
+
+	// 后台执行配置
+	JobWorkers   int `json:"job_workers"`    // 并发执行作业数，<=0 时使用默认值
+	JobQueueSize int `json:"job_queue_size"` // 排队作业上限，超出后拒绝提交，<=0 时使用默认值
+
+	// 工具配置
+	Tools ToolConfig `json:"tools"` // 工具运行环境配置
+}
+
+// ToolConfig 工具运行环境配置
+// 每个任务的工作目录为 <StoragePath>/<task_id>/workspace，文件工具只能访问工作目录内的文件
+type ToolConfig struct {
+	MaxReadBytes  int64          `json:"max_read_bytes"`  // read_file 返回的最大字节数，超出部分截断
+	MaxWriteBytes int64          `json:"max_write_bytes"` // write_file、edit_file 写入的最大字节数
+	TestCommand   []string       `json:"test_command"`    // run_test 未指定命令时执行的命令，同样受白名单限制，为空时必须指定命令
+	Command       sandbox.Policy `json:"command"`         // run_command 的白名单、超时、输出与资源限制
 }
 
 // DefaultTaskManagerConfig 返回默认配置
@@ -190,6 +247,13 @@ func DefaultTaskManagerConfig() *TaskManagerConfig {
 		},
 		MaxRetries:         constant.DefaultMaxRetries,
 		EnableAutoPlanning: true,
+		JobWorkers:         constant.DefaultJobWorkers,
+		JobQueueSize:       constant.DefaultJobQueueSize,
+		Tools: ToolConfig{
+			MaxReadBytes:  constant.DefaultToolMaxReadBytes,
+			MaxWriteBytes: constant.DefaultToolMaxWriteBytes,
+			Command:       sandbox.DefaultPolicy(),
+		},
 	}
 }
 
@@ -212,6 +276,7 @@ type PlanRequest struct {
 	Context     string   `json:"context,omitempty"`             // 额外上下文信息
 	Constraints []string `json:"constraints,omitempty"`         // 约束条件列表
 	Preferences []string `json:"preferences,omitempty"`         // 偏好设置列表
+	Locale      string   `json:"locale,omitempty"`              // 提示词语言 zh/en，未指定时使用用户的语言偏好
 }
 
 // PlanResponse 规划响应
@@ -227,15 +292,7 @@ type ExecuteRequest struct {
 	TaskID  string `json:"task_id" binding:"required"` // 任务ID（必填）
 	PhaseID string `json:"phase_id,omitempty"`         // 可选，指定执行特定阶段
 	StepID  string `json:"step_id,omitempty"`          // 可选，指定执行特定步骤
-}
-
-// ExecuteResponse 执行响应
-type ExecuteResponse struct {
-	TaskID       string     `json:"task_id"`               // 任务ID
-	CurrentPhase string     `json:"current_phase"`         // 当前执行阶段ID
-	Status       TaskStatus `json:"status"`                // 任务状态
-	Message      string     `json:"message"`               // 执行结果消息
-	NextAction   string     `json:"next_action,omitempty"` // 建议的下一步操作
+	Locale  string `json:"locale,omitempty"`           // 提示词语言 zh/en，未指定时使用任务所属用户的语言偏好
 }
 
 // TaskSummary 任务摘要（用于上下文压缩）
@@ -0,0 +1,106 @@
+package usage
+
+import (
+	"ai_task/config"
+	"context"
+	"fmt"
+	"time"
+)
+
+// 预算范围
+const (
+	ScopeTask      = "task"
+	ScopeUserDaily = "user_daily"
+)
+
+// ExceededError 超出 token 预算
+type ExceededError struct {
+	Scope string // task / user_daily
+	Used  int64
+	Limit int64
+}
+
+func (e *ExceededError) Error() string {
+	switch e.Scope {
+	case ScopeTask:
+		return fmt.Sprintf("task token budget exceeded: used %d of %d", e.Used, e.Limit)
+	case ScopeUserDaily:
+		return fmt.Sprintf("user daily token budget exceeded: used %d of %d", e.Used, e.Limit)
+	}
+	return fmt.Sprintf("%s token budget exceeded: used %d of %d", e.Scope, e.Used, e.Limit)
+}
+
+// Budget token 预算检查，超出时返回 *ExceededError
+type Budget interface {
+	Check(ctx context.Context, userID, taskID string) error
+}
+
+// Reader 已用 token 统计，userID / taskID 为空时不按该维度过滤
+type Reader interface {
+	SumTokens(ctx context.Context, userID, taskID string, since time.Time) (int64, error)
+}
+
+// TokenBudget 按任务累计和用户当日累计限制 token，限额 <= 0 表示不限制
+type TokenBudget struct {
+	reader          Reader
+	TaskTokens      int64
+	UserDailyTokens int64
+	now             func() time.Time
+}
+
+// NewTokenBudget 创建 token 预算，两个限额都不限制时返回 nil
+func NewTokenBudget(reader Reader, taskTokens, userDailyTokens int64) *TokenBudget {
+	if reader == nil || (taskTokens <= 0 && userDailyTokens <= 0) {
+		return nil
+	}
+	return &TokenBudget{
+		reader:          reader,
+		TaskTokens:      taskTokens,
+		UserDailyTokens: userDailyTokens,
+		now:             time.Now,
+	}
+}
+
+// LoadBudget 按 usage.budget 配置创建 token 预算，未配置限额时返回 nil
+func LoadBudget(reader Reader) Budget {
+	conf := config.GetInstance()
+	budget := NewTokenBudget(reader,
+		int64(conf.GetIntOrDefault(config.UsageBudgetTaskTokens, 0)),
+		int64(conf.GetIntOrDefault(config.UsageBudgetUserDailyTokens, 0)),
+	)
+	if budget == nil {
+		return nil
+	}
+	return budget
+}
+
+// Check 检查任务累计和用户当日累计是否已达到限额
+func (b *TokenBudget) Check(ctx context.Context, userID, taskID string) error {
+	if b == nil {
+		return nil
+	}
+
+	if b.TaskTokens > 0 && taskID != "" {
+		used, err := b.reader.SumTokens(ctx, "", taskID, time.Time{})
+		if err != nil {
+			return fmt.Errorf("failed to sum task tokens: %w", err)
+		}
+		if used >= b.TaskTokens {
+			return &ExceededError{Scope: ScopeTask, Used: used, Limit: b.TaskTokens}
+		}
+	}
+
+	if b.UserDailyTokens > 0 && userID != "" {
+		now := b.now()
+		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		used, err := b.reader.SumTokens(ctx, userID, "", startOfDay)
+		if err != nil {
+			return fmt.Errorf("failed to sum user tokens: %w", err)
+		}
+		if used >= b.UserDailyTokens {
+			return &ExceededError{Scope: ScopeUserDaily, Used: used, Limit: b.UserDailyTokens}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package usage
+
+import (
+	"ai_task/config"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Price 模型单价，单位为每百万 token
+type Price struct {
+	Model      string  `mapstructure:"model"`
+	Prompt     float64 `mapstructure:"prompt"`
+	Completion float64 `mapstructure:"completion"`
+	Cached     float64 `mapstructure:"cached"` // 命中缓存的输入单价，<= 0 时按 Prompt 计价
+}
+
+// Pricing 按模型名索引的单价表
+type Pricing map[string]Price
+
+// NewPricing 由单价列表创建单价表，忽略未填写模型名的条目
+func NewPricing(prices ...Price) Pricing {
+	pricing := make(Pricing, len(prices))
+	for _, price := range prices {
+		if price.Model != "" {
+			pricing[price.Model] = price
+		}
+	}
+	return pricing
+}
+
+// LoadPricing 读取 usage.pricing，配置缺失或格式错误时返回空表，即不计费
+func LoadPricing() Pricing {
+	var prices []Price
+	if err := config.GetInstance().UnmarshalKey(config.UsagePricing, &prices); err != nil {
+		log.Warnf("load %s failed, cost will be 0: %v", config.UsagePricing, err)
+		return Pricing{}
+	}
+	return NewPricing(prices...)
+}
+
+// Cost 按单价表估算一次调用的费用，未配置价格的模型返回 0
+func (p Pricing) Cost(record *Record) float64 {
+	price, ok := p[record.Model]
+	if !ok {
+		return 0
+	}
+	return price.Cost(record.PromptTokens, record.CompletionTokens, record.CachedTokens)
+}
+
+var (
+	pricingMu sync.RWMutex
+	pricing   Pricing
+)
+
+// SetPricing 设置上报用量时使用的单价表，为 nil 时不计费
+func SetPricing(p Pricing) {
+	pricingMu.Lock()
+	defer pricingMu.Unlock()
+	pricing = p
+}
+
+func getPricing() Pricing {
+	pricingMu.RLock()
+	defer pricingMu.RUnlock()
+	return pricing
+}
+
+// Cost 计算费用，cachedTokens 包含在 promptTokens 中
+func (p Price) Cost(promptTokens, completionTokens, cachedTokens int) float64 {
+	cachedPrice := p.Cached
+	if cachedPrice <= 0 {
+		cachedPrice = p.Prompt
+	}
+	if cachedTokens > promptTokens {
+		cachedTokens = promptTokens
+	}
+
+	cost := float64(promptTokens-cachedTokens)*p.Prompt +
+		float64(cachedTokens)*cachedPrice +
+		float64(completionTokens)*p.Completion
+	return cost / 1e6
+}
@@ -0,0 +1,74 @@
+package usage
+
+import (
+	"ai_task/model"
+	"ai_task/repository/factory"
+	"context"
+	"time"
+)
+
+// Store 基于数据库的用量记录器，同时提供预算所需的已用 token 统计
+type Store struct {
+	repositoryFactory factory.Factory
+}
+
+// NewStore 创建用量存储
+func NewStore(repositoryFactory factory.Factory) *Store {
+	return &Store{repositoryFactory: repositoryFactory}
+}
+
+// Record 写入一条用量记录
+func (s *Store) Record(ctx context.Context, record *Record) error {
+	session := s.repositoryFactory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	usageRepo, err := s.repositoryFactory.NewLLMUsageRepository(session)
+	if err != nil {
+		return err
+	}
+
+	return usageRepo.Create(&model.CreateLLMUsageCondition{
+		UserID:           record.UserID,
+		SessionID:        record.SessionID,
+		TaskID:           record.TaskID,
+		Role:             record.Role,
+		Kind:             record.Kind,
+		Model:            record.Model,
+		PromptTokens:     record.PromptTokens,
+		CompletionTokens: record.CompletionTokens,
+		CachedTokens:     record.CachedTokens,
+		TotalTokens:      record.TotalTokens,
+		Cost:             record.Cost,
+	})
+}
+
+// SumTokens 统计 since 之后的总 token 数，since 为零值时不限制时间
+func (s *Store) SumTokens(ctx context.Context, userID, taskID string, since time.Time) (int64, error) {
+	session := s.repositoryFactory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	usageRepo, err := s.repositoryFactory.NewLLMUsageRepository(session)
+	if err != nil {
+		return 0, err
+	}
+
+	condition := &model.AggregateLLMUsageCondition{}
+	if userID != "" {
+		condition.UserID = &userID
+	}
+	if taskID != "" {
+		condition.TaskID = &taskID
+	}
+	if !since.IsZero() {
+		condition.Since = &since
+	}
+
+	results, err := usageRepo.Aggregate(condition)
+	if err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+	return results[0].TotalTokens, nil
+}
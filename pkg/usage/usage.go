@@ -0,0 +1,179 @@
+// Package usage 记录每次 LLM / embedding 调用的 token 用量，并按用户、会话、任务做预算控制
+//
+// 调用方通过 WithTags 在 context 中标注 user_id、session_id、task_id 和调用角色，
+// 模型客户端在调用完成后通过 ReportLLM / ReportEmbedding 上报，由全局 Recorder 落库。
+package usage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	log "github.com/sirupsen/logrus"
+)
+
+// 调用角色
+const (
+	RolePlanner    = "planner"
+	RoleExecutor   = "executor"
+	RoleSummarizer = "summarizer"
+	RoleExtractor  = "extractor"
+	RoleChat       = "chat"
+	RoleEmbedding  = "embedding"
+)
+
+// 调用类型
+const (
+	KindLLM       = "llm"
+	KindEmbedding = "embedding"
+)
+
+// Tags 用量归属，空字段表示未知
+type Tags struct {
+	UserID    string
+	SessionID string
+	TaskID    string
+	Role      string
+}
+
+type tagsKey struct{}
+
+// WithTags 在 context 中合并用量归属，只覆盖 tags 中非空的字段
+func WithTags(ctx context.Context, tags Tags) context.Context {
+	merged := TagsFrom(ctx)
+	if tags.UserID != "" {
+		merged.UserID = tags.UserID
+	}
+	if tags.SessionID != "" {
+		merged.SessionID = tags.SessionID
+	}
+	if tags.TaskID != "" {
+		merged.TaskID = tags.TaskID
+	}
+	if tags.Role != "" {
+		merged.Role = tags.Role
+	}
+	return context.WithValue(ctx, tagsKey{}, merged)
+}
+
+// WithRole 在 context 中标注调用角色
+func WithRole(ctx context.Context, role string) context.Context {
+	return WithTags(ctx, Tags{Role: role})
+}
+
+// TagsFrom 读取 context 中的用量归属
+func TagsFrom(ctx context.Context) Tags {
+	if ctx == nil {
+		return Tags{}
+	}
+	tags, _ := ctx.Value(tagsKey{}).(Tags)
+	return tags
+}
+
+// Record 一次调用的用量
+type Record struct {
+	Tags
+	Kind             string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	CachedTokens     int // 命中提示词缓存的 token，包含在 PromptTokens 中
+	TotalTokens      int
+	Cost             float64
+	CreatedAt        time.Time
+}
+
+// Recorder 用量记录器
+type Recorder interface {
+	Record(ctx context.Context, record *Record) error
+}
+
+// RecorderFunc 函数形式的 Recorder
+type RecorderFunc func(ctx context.Context, record *Record) error
+
+func (f RecorderFunc) Record(ctx context.Context, record *Record) error {
+	return f(ctx, record)
+}
+
+var (
+	recorderMu sync.RWMutex
+	recorder   Recorder
+)
+
+// SetRecorder 设置全局用量记录器，为 nil 时不记录
+func SetRecorder(r Recorder) {
+	recorderMu.Lock()
+	defer recorderMu.Unlock()
+	recorder = r
+}
+
+func getRecorder() Recorder {
+	recorderMu.RLock()
+	defer recorderMu.RUnlock()
+	return recorder
+}
+
+// ReportLLM 上报一次模型调用的用量，角色未标注时记为 chat
+func ReportLLM(ctx context.Context, model string, u openai.Usage) {
+	cached := 0
+	if u.PromptTokensDetails != nil {
+		cached = u.PromptTokensDetails.CachedTokens
+	}
+	total := u.TotalTokens
+	if total == 0 {
+		total = u.PromptTokens + u.CompletionTokens
+	}
+
+	report(ctx, &Record{
+		Tags:             TagsFrom(ctx),
+		Kind:             KindLLM,
+		Model:            model,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		CachedTokens:     cached,
+		TotalTokens:      total,
+	})
+}
+
+// ReportEmbedding 上报一次 embedding 调用的用量
+func ReportEmbedding(ctx context.Context, model string, promptTokens, totalTokens int) {
+	if totalTokens == 0 {
+		totalTokens = promptTokens
+	}
+
+	record := &Record{
+		Tags:         TagsFrom(ctx),
+		Kind:         KindEmbedding,
+		Model:        model,
+		PromptTokens: promptTokens,
+		TotalTokens:  totalTokens,
+	}
+	record.Role = RoleEmbedding
+	report(ctx, record)
+}
+
+// report 补全角色、费用（按 SetPricing 设置的单价表）和时间后交给全局记录器，记录失败只打日志不影响调用方
+func report(ctx context.Context, record *Record) {
+	if record.TotalTokens == 0 {
+		return
+	}
+	r := getRecorder()
+	if r == nil {
+		return
+	}
+
+	if record.Role == "" {
+		record.Role = RoleChat
+	}
+	record.Cost = getPricing().Cost(record)
+	record.CreatedAt = time.Now()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// 调用方取消（如流式连接断开）时仍需记录已消耗的 token
+	if err := r.Record(context.WithoutCancel(ctx), record); err != nil {
+		log.Warnf("record %s usage of %s failed: %v", record.Kind, record.Model, err)
+	}
+}
@@ -0,0 +1,131 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestWithTagsMerges(t *testing.T) {
+	ctx := WithTags(context.Background(), Tags{UserID: "user_1", SessionID: "session_1", TaskID: "task_1"})
+	ctx = WithRole(ctx, RolePlanner)
+	ctx = WithTags(ctx, Tags{SessionID: "session_2"})
+
+	got := TagsFrom(ctx)
+	want := Tags{UserID: "user_1", SessionID: "session_2", TaskID: "task_1", Role: RolePlanner}
+	if got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestReportLLM(t *testing.T) {
+	var records []*Record
+	SetRecorder(RecorderFunc(func(ctx context.Context, record *Record) error {
+		if ctx.Err() != nil {
+			t.Error("Expected record context not to be canceled")
+		}
+		records = append(records, record)
+		return nil
+	}))
+	SetPricing(NewPricing(Price{Model: "priced-model", Prompt: 2, Completion: 8}))
+	t.Cleanup(func() {
+		SetRecorder(nil)
+		SetPricing(nil)
+	})
+
+	ctx, cancel := context.WithCancel(WithTags(context.Background(), Tags{UserID: "user_1", TaskID: "task_1"}))
+	cancel()
+
+	ReportLLM(ctx, "unpriced-model", openai.Usage{
+		PromptTokens:        100,
+		CompletionTokens:    20,
+		PromptTokensDetails: &openai.PromptTokensDetails{CachedTokens: 40},
+	})
+	ReportLLM(ctx, "unpriced-model", openai.Usage{}) // 没有用量时不记录
+	ReportEmbedding(WithRole(ctx, RoleExecutor), "embedding-model", 8, 0)
+	ReportLLM(ctx, "priced-model", openai.Usage{PromptTokens: 500_000, CompletionTokens: 100_000})
+
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	llmRecord := records[0]
+	if llmRecord.Role != RoleChat || llmRecord.Kind != KindLLM || llmRecord.UserID != "user_1" || llmRecord.TaskID != "task_1" {
+		t.Errorf("Unexpected llm record tags: %+v", llmRecord)
+	}
+	if llmRecord.TotalTokens != 120 || llmRecord.CachedTokens != 40 || llmRecord.Cost != 0 {
+		t.Errorf("Unexpected llm record tokens: %+v", llmRecord)
+	}
+	if records[1].Role != RoleEmbedding || records[1].Kind != KindEmbedding || records[1].TotalTokens != 8 {
+		t.Errorf("Unexpected embedding record: %+v", records[1])
+	}
+	// 500k 输入 * 2 + 100k 输出 * 8
+	if records[2].Cost != 1.8 {
+		t.Errorf("Expected cost 1.8 from the configured pricing, got %v", records[2].Cost)
+	}
+}
+
+func TestPriceCost(t *testing.T) {
+	price := Price{Prompt: 2, Completion: 8, Cached: 0.5}
+	// 600k 未命中缓存 * 2 + 400k 缓存 * 0.5 + 100k 输出 * 8
+	if got := price.Cost(1_000_000, 100_000, 400_000); got != 2.2 {
+		t.Errorf("Expected cost 2.2, got %v", got)
+	}
+
+	// 未配置缓存单价时按输入单价计价
+	if got := (Price{Prompt: 2}).Cost(1_000_000, 0, 400_000); got != 2 {
+		t.Errorf("Expected cost 2, got %v", got)
+	}
+}
+
+type fakeReader struct {
+	task, user int64
+	err        error
+	since      time.Time
+}
+
+func (r *fakeReader) SumTokens(ctx context.Context, userID, taskID string, since time.Time) (int64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if taskID != "" {
+		return r.task, nil
+	}
+	r.since = since
+	return r.user, nil
+}
+
+func TestTokenBudget(t *testing.T) {
+	if NewTokenBudget(&fakeReader{}, 0, 0) != nil {
+		t.Error("Expected nil budget when no limit is configured")
+	}
+
+	reader := &fakeReader{task: 500, user: 900}
+	budget := NewTokenBudget(reader, 1000, 1000)
+	budget.now = func() time.Time { return time.Date(2026, 10, 17, 15, 30, 0, 0, time.UTC) }
+
+	if err := budget.Check(context.Background(), "user_1", "task_1"); err != nil {
+		t.Fatalf("Expected budget to pass, got %v", err)
+	}
+	if want := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC); !reader.since.Equal(want) {
+		t.Errorf("Expected user budget to count from %v, got %v", want, reader.since)
+	}
+
+	reader.task = 1000
+	var exceeded *ExceededError
+	if err := budget.Check(context.Background(), "user_1", "task_1"); !errors.As(err, &exceeded) || exceeded.Scope != ScopeTask {
+		t.Errorf("Expected task budget exceeded, got %v", err)
+	}
+
+	reader.task, reader.user = 0, 1200
+	if err := budget.Check(context.Background(), "user_1", "task_1"); !errors.As(err, &exceeded) || exceeded.Scope != ScopeUserDaily {
+		t.Errorf("Expected user daily budget exceeded, got %v", err)
+	}
+
+	reader.err = errors.New("db down")
+	if err := budget.Check(context.Background(), "user_1", "task_1"); err == nil || errors.As(err, &exceeded) {
+		t.Errorf("Expected plain error when usage cannot be read, got %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+)
+
+// ChatMessageRepository 聊天消息仓库接口
+type ChatMessageRepository interface {
+	// Create 写入一条消息，返回带自增 ID 的记录
+	Create(req *model.CreateChatMessageCondition) (*entity.ChatMessage, error)
+	// ListRecent 获取会话中 id 大于 afterID 的最近 limit 条消息，按时间正序返回
+	ListRecent(userID, sessionID string, afterID int64, limit int) ([]*entity.ChatMessage, error)
+	// List 条件查询（支持分页、排序）
+	List(condition *model.GetChatMessagesCondition) ([]*entity.ChatMessage, error)
+	// Count 按条件统计消息条数
+	Count(condition *model.GetChatMessagesCondition) (int64, error)
+	// DeleteBySession 删除会话的全部消息，返回删除条数
+	DeleteBySession(userID, sessionID string) (int64, error)
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+)
+
+// ChatSessionRepository 聊天会话仓库接口
+type ChatSessionRepository interface {
+	// Create 创建会话，返回带自增 ID 的记录
+	Create(req *model.CreateChatSessionCondition) (*entity.ChatSession, error)
+	// Touch 会话新增消息时累加消息数并刷新最后活跃时间，会话不存在时创建
+	Touch(req *model.TouchChatSessionCondition) error
+	// Get 获取会话，不存在时返回 nil
+	Get(userID, sessionID string) (*entity.ChatSession, error)
+	// List 条件查询（支持分页、排序），默认按最后活跃时间倒序
+	List(condition *model.GetChatSessionsCondition) ([]*entity.ChatSession, error)
+	// Count 按条件统计会话数
+	Count(condition *model.GetChatSessionsCondition) (int64, error)
+	// Update 更新会话标题等信息
+	Update(id int64, req *model.UpdateChatSessionCondition) error
+	// Delete 删除会话记录
+	Delete(userID, sessionID string) error
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+)
+
+// ChatSummaryRepository 会话摘要仓库接口
+type ChatSummaryRepository interface {
+	// Get 获取会话当前摘要，不存在时返回 nil
+	Get(userID, sessionID string) (*entity.ChatSummary, error)
+	// Upsert 按 (user_id, session_id) 插入或更新摘要
+	Upsert(req *model.UpsertChatSummaryCondition) error
+	// Delete 删除会话摘要，返回是否存在
+	Delete(userID, sessionID string) (bool, error)
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+)
+
+// DocChunkRepository 文档分块仓库接口
+type DocChunkRepository interface {
+	// BatchCreate 批量写入分块及向量
+	BatchCreate(reqs []*model.CreateDocChunkCondition) error
+	List(condition *model.GetDocChunksCondition) ([]*entity.DocChunk, error)
+	// DeleteByDocID 删除文档的全部分块
+	DeleteByDocID(docID int64) error
+	// Search 按余弦相似度检索，只返回不低于阈值的分块，按相似度降序
+	Search(condition *model.SearchDocChunksCondition) ([]*model.DocChunkSearchResult, error)
+	// SearchLexical 按 Keywords 全文检索，按文本相关度降序，结果附带与 Embedding 的相似度，不应用阈值
+	SearchLexical(condition *model.SearchDocChunksCondition) ([]*model.DocChunkSearchResult, error)
+}
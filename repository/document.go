@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+)
+
+// DocumentRepository 知识文档仓库接口
+type DocumentRepository interface {
+	Create(req *model.CreateDocumentCondition) (*entity.Document, error)
+	// Get 按 ID 获取文档，不存在时返回 nil
+	Get(id int64) (*entity.Document, error)
+	List(condition *model.GetDocumentsCondition) ([]*entity.Document, error)
+	Update(id int64, req *model.UpdateDocumentCondition) error
+	Delete(id int64) error
+}
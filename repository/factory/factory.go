@@ -12,4 +12,13 @@ type Factory interface {
 	NewTaskRepository(session interfaces.Session) (repository.TaskRepository, error)
 	NewTaskFindingsRepository(session interfaces.Session) (repository.TaskFindingsRepository, error)
 	NewTaskProgressRepository(session interfaces.Session) (repository.TaskProgressRepository, error)
+	NewTaskJobRepository(session interfaces.Session) (repository.TaskJobRepository, error)
+	NewTaskToolCallRepository(session interfaces.Session) (repository.TaskToolCallRepository, error)
+	NewChatMessageRepository(session interfaces.Session) (repository.ChatMessageRepository, error)
+	NewMemoryChunkRepository(session interfaces.Session) (repository.MemoryChunkRepository, error)
+	NewChatSummaryRepository(session interfaces.Session) (repository.ChatSummaryRepository, error)
+	NewChatSessionRepository(session interfaces.Session) (repository.ChatSessionRepository, error)
+	NewDocumentRepository(session interfaces.Session) (repository.DocumentRepository, error)
+	NewDocChunkRepository(session interfaces.Session) (repository.DocChunkRepository, error)
+	NewLLMUsageRepository(session interfaces.Session) (repository.LLMUsageRepository, error)
 }
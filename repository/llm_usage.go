@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"ai_task/model"
+)
+
+// LLMUsageRepository 模型用量仓库接口
+type LLMUsageRepository interface {
+	// Create 记录一次调用的用量
+	Create(req *model.CreateLLMUsageCondition) error
+	// Aggregate 按条件过滤并按 GroupBy 分组汇总，GroupBy 为空时返回一行总计
+	Aggregate(condition *model.AggregateLLMUsageCondition) ([]*model.LLMUsageAggregate, error)
+}
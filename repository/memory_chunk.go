@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"ai_task/model"
+)
+
+// MemoryChunkRepository 语义记忆分块仓库接口
+type MemoryChunkRepository interface {
+	// BatchCreate 批量写入分块及向量
+	BatchCreate(reqs []*model.CreateMemoryChunkCondition) error
+	// Search 按余弦相似度检索，只返回不低于阈值的分块，按相似度降序
+	Search(condition *model.SearchMemoryChunksCondition) ([]*model.MemoryChunkSearchResult, error)
+	// SearchLexical 按 Keywords 全文检索，按文本相关度降序，结果附带与 Embedding 的相似度，不应用阈值
+	SearchLexical(condition *model.SearchMemoryChunksCondition) ([]*model.MemoryChunkSearchResult, error)
+	// DeleteBySession 删除会话的全部分块，返回删除条数
+	DeleteBySession(userID, sessionID string) (int64, error)
+}
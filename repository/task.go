@@ -42,3 +42,21 @@ type TaskProgressRepository interface {
 	// Delete 删除任务进度
 	Delete(taskID string) error
 }
+
+// TaskJobRepository 任务执行作业仓库接口
+type TaskJobRepository interface {
+	// Upsert 创建或更新执行作业
+	Upsert(req *model.UpsertTaskJobCondition) error
+	// Get 获取执行作业
+	Get(jobID string) (*entity.TaskJob, error)
+	// List 按任务和状态列出执行作业，按创建时间升序
+	List(condition *model.TaskJobListCondition) ([]*entity.TaskJob, error)
+}
+
+// TaskToolCallRepository 任务工具调用记录仓库接口
+type TaskToolCallRepository interface {
+	// Insert 写入工具调用记录
+	Insert(req *model.InsertTaskToolCallCondition) error
+	// List 列出任务的工具调用记录，按调用时间升序
+	List(condition *model.TaskToolCallListCondition) ([]*entity.TaskToolCall, error)
+}
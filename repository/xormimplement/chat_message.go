@@ -0,0 +1,158 @@
+package xormimplement
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+	"ai_task/repository"
+	"fmt"
+	"time"
+
+	"xorm.io/builder"
+)
+
+type ChatMessageRepository struct {
+	session *Session
+}
+
+func NewChatMessageRepository(session *Session) repository.ChatMessageRepository {
+	return &ChatMessageRepository{session: session}
+}
+
+func (r *ChatMessageRepository) Create(req *model.CreateChatMessageCondition) (*entity.ChatMessage, error) {
+	if req == nil {
+		return nil, fmt.Errorf("create request cannot be nil")
+	}
+	if req.UserID == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+
+	msg := &entity.ChatMessage{
+		UserID:    req.UserID,
+		SessionID: req.SessionID,
+		Role:      req.Role,
+		Content:   req.Content,
+		Truncated: req.Truncated,
+		CreatedAt: time.Now(),
+	}
+	_, err := r.session.Table(entity.TableNameChatMessage).Insert(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert chat_message: %w", err)
+	}
+
+	return msg, nil
+}
+
+func (r *ChatMessageRepository) ListRecent(userID, sessionID string, afterID int64, limit int) ([]*entity.ChatMessage, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+	if sessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	var results []*entity.ChatMessage
+	err := r.session.Table(entity.TableNameChatMessage).
+		Where(builder.Eq{
+			entity.ChatMessageFieldUserID:    userID,
+			entity.ChatMessageFieldSessionID: sessionID,
+		}.And(builder.Gt{entity.ChatMessageFieldID: afterID})).
+		Desc(entity.ChatMessageFieldID).
+		Limit(limit).
+		Find(&results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recent chat_messages: %w", err)
+	}
+
+	// 倒序取出后翻转为时间正序，便于直接拼接到 prompt
+	for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+		results[i], results[j] = results[j], results[i]
+	}
+
+	return results, nil
+}
+
+func (r *ChatMessageRepository) List(condition *model.GetChatMessagesCondition) ([]*entity.ChatMessage, error) {
+	if condition == nil {
+		return nil, fmt.Errorf("get condition cannot be nil")
+	}
+
+	session := r.session.Table(entity.TableNameChatMessage)
+	if conds := chatMessageConds(condition); len(conds) > 0 {
+		session = session.Where(builder.And(conds...))
+	}
+
+	pagerOrder(session, condition, WithDefaultOrderField(entity.ChatMessageFieldID))
+
+	var results []*entity.ChatMessage
+	err := session.Find(&results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat_messages: %w", err)
+	}
+
+	return results, nil
+}
+
+func (r *ChatMessageRepository) Count(condition *model.GetChatMessagesCondition) (int64, error) {
+	if condition == nil {
+		return 0, fmt.Errorf("count condition cannot be nil")
+	}
+
+	session := r.session.Table(entity.TableNameChatMessage)
+	if conds := chatMessageConds(condition); len(conds) > 0 {
+		session = session.Where(builder.And(conds...))
+	}
+
+	total, err := session.Count(&entity.ChatMessage{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count chat_messages: %w", err)
+	}
+
+	return total, nil
+}
+
+// chatMessageConds 构建消息查询条件
+func chatMessageConds(condition *model.GetChatMessagesCondition) []builder.Cond {
+	var conds []builder.Cond
+
+	if condition.UserID != nil && *condition.UserID != "" {
+		conds = append(conds, builder.Eq{entity.ChatMessageFieldUserID: *condition.UserID})
+	}
+	if condition.SessionID != nil && *condition.SessionID != "" {
+		conds = append(conds, builder.Eq{entity.ChatMessageFieldSessionID: *condition.SessionID})
+	}
+	if condition.AfterID != nil {
+		conds = append(conds, builder.Gt{entity.ChatMessageFieldID: *condition.AfterID})
+	}
+	if condition.UntilID != nil {
+		conds = append(conds, builder.Lte{entity.ChatMessageFieldID: *condition.UntilID})
+	}
+
+	return conds
+}
+
+func (r *ChatMessageRepository) DeleteBySession(userID, sessionID string) (int64, error) {
+	if userID == "" {
+		return 0, fmt.Errorf("user_id is required")
+	}
+	if sessionID == "" {
+		return 0, fmt.Errorf("session_id is required")
+	}
+
+	affected, err := r.session.Table(entity.TableNameChatMessage).
+		Where(builder.Eq{
+			entity.ChatMessageFieldUserID:    userID,
+			entity.ChatMessageFieldSessionID: sessionID,
+		}).
+		Delete(&entity.ChatMessage{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete chat_messages: %w", err)
+	}
+
+	return affected, nil
+}
@@ -0,0 +1,210 @@
+package xormimplement
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+	"ai_task/repository"
+	"fmt"
+	"time"
+
+	"xorm.io/builder"
+)
+
+type ChatSessionRepository struct {
+	session *Session
+}
+
+func NewChatSessionRepository(session *Session) repository.ChatSessionRepository {
+	return &ChatSessionRepository{session: session}
+}
+
+func (r *ChatSessionRepository) Create(req *model.CreateChatSessionCondition) (*entity.ChatSession, error) {
+	if req == nil {
+		return nil, fmt.Errorf("create request cannot be nil")
+	}
+	if req.UserID == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+	if req.SessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+
+	now := time.Now()
+	chatSession := &entity.ChatSession{
+		UserID:            req.UserID,
+		SessionID:         req.SessionID,
+		Title:             req.Title,
+		TitleSource:       req.TitleSource,
+		MessageCount:      req.MessageCount,
+		ForkedFromSession: req.ForkedFromSession,
+		ForkedFromMsgID:   req.ForkedFromMsgID,
+		LastActiveAt:      now,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	_, err := r.session.Table(entity.TableNameChatSession).Insert(chatSession)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert chat_session: %w", err)
+	}
+
+	return chatSession, nil
+}
+
+func (r *ChatSessionRepository) Touch(req *model.TouchChatSessionCondition) error {
+	if req == nil {
+		return fmt.Errorf("touch request cannot be nil")
+	}
+	if req.UserID == "" {
+		return fmt.Errorf("user_id is required")
+	}
+	if req.SessionID == "" {
+		return fmt.Errorf("session_id is required")
+	}
+
+	// 同一会话的首轮并发请求依赖 (user_id, session_id) 唯一约束合并为一条
+	sql := fmt.Sprintf(`INSERT INTO %[1]s (%[2]s, %[3]s, %[4]s, %[5]s, %[6]s, %[7]s, %[8]s, %[9]s) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (%[2]s, %[3]s) DO UPDATE SET %[6]s = %[1]s.%[6]s + EXCLUDED.%[6]s, %[7]s = EXCLUDED.%[7]s, %[9]s = EXCLUDED.%[9]s`,
+		entity.TableNameChatSession,
+		entity.ChatSessionFieldUserID,
+		entity.ChatSessionFieldSessionID,
+		entity.ChatSessionFieldTitle,
+		entity.ChatSessionFieldTitleSource,
+		entity.ChatSessionFieldMessageCount,
+		entity.ChatSessionFieldLastActiveAt,
+		entity.ChatSessionFieldCreatedAt,
+		entity.ChatSessionFieldUpdatedAt,
+	)
+
+	now := time.Now()
+	_, err := r.session.Exec(sql, req.UserID, req.SessionID, req.Title, entity.ChatSessionTitleAuto, req.MessageCount, now, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to touch chat_session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ChatSessionRepository) Get(userID, sessionID string) (*entity.ChatSession, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+	if sessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+
+	result := &entity.ChatSession{}
+	ok, err := r.session.Table(entity.TableNameChatSession).
+		Where(builder.Eq{
+			entity.ChatSessionFieldUserID:    userID,
+			entity.ChatSessionFieldSessionID: sessionID,
+		}).
+		Get(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat_session: %w", err)
+	}
+
+	if !ok {
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+func (r *ChatSessionRepository) List(condition *model.GetChatSessionsCondition) ([]*entity.ChatSession, error) {
+	if condition == nil {
+		return nil, fmt.Errorf("get condition cannot be nil")
+	}
+
+	session := r.session.Table(entity.TableNameChatSession)
+	if conds := chatSessionConds(condition); len(conds) > 0 {
+		session = session.Where(builder.And(conds...))
+	}
+
+	pagerOrder(session, condition, WithDefaultOrderField(entity.ChatSessionFieldLastActiveAt))
+
+	var results []*entity.ChatSession
+	err := session.Find(&results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat_sessions: %w", err)
+	}
+
+	return results, nil
+}
+
+func (r *ChatSessionRepository) Count(condition *model.GetChatSessionsCondition) (int64, error) {
+	if condition == nil {
+		return 0, fmt.Errorf("count condition cannot be nil")
+	}
+
+	session := r.session.Table(entity.TableNameChatSession)
+	if conds := chatSessionConds(condition); len(conds) > 0 {
+		session = session.Where(builder.And(conds...))
+	}
+
+	total, err := session.Count(&entity.ChatSession{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count chat_sessions: %w", err)
+	}
+
+	return total, nil
+}
+
+func (r *ChatSessionRepository) Update(id int64, req *model.UpdateChatSessionCondition) error {
+	if id <= 0 {
+		return fmt.Errorf("id is required")
+	}
+	if req == nil {
+		return fmt.Errorf("update request cannot be nil")
+	}
+
+	updateData := map[string]interface{}{
+		entity.ChatSessionFieldUpdatedAt: time.Now(),
+	}
+	if req.Title != nil {
+		updateData[entity.ChatSessionFieldTitle] = *req.Title
+	}
+	if req.TitleSource != nil {
+		updateData[entity.ChatSessionFieldTitleSource] = *req.TitleSource
+	}
+
+	_, err := r.session.Table(entity.TableNameChatSession).
+		Where(builder.Eq{entity.ChatSessionFieldID: id}).
+		Update(updateData)
+	if err != nil {
+		return fmt.Errorf("failed to update chat_session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ChatSessionRepository) Delete(userID, sessionID string) error {
+	if userID == "" {
+		return fmt.Errorf("user_id is required")
+	}
+	if sessionID == "" {
+		return fmt.Errorf("session_id is required")
+	}
+
+	_, err := r.session.Table(entity.TableNameChatSession).
+		Where(builder.Eq{
+			entity.ChatSessionFieldUserID:    userID,
+			entity.ChatSessionFieldSessionID: sessionID,
+		}).
+		Delete(&entity.ChatSession{})
+	if err != nil {
+		return fmt.Errorf("failed to delete chat_session: %w", err)
+	}
+
+	return nil
+}
+
+// chatSessionConds 构建会话查询条件
+func chatSessionConds(condition *model.GetChatSessionsCondition) []builder.Cond {
+	var conds []builder.Cond
+
+	if condition.UserID != nil && *condition.UserID != "" {
+		conds = append(conds, builder.Eq{entity.ChatSessionFieldUserID: *condition.UserID})
+	}
+
+	return conds
+}
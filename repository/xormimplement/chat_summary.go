@@ -0,0 +1,115 @@
+package xormimplement
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+	"ai_task/repository"
+	"fmt"
+	"time"
+
+	"xorm.io/builder"
+)
+
+type ChatSummaryRepository struct {
+	session *Session
+}
+
+func NewChatSummaryRepository(session *Session) repository.ChatSummaryRepository {
+	return &ChatSummaryRepository{session: session}
+}
+
+func (r *ChatSummaryRepository) Get(userID, sessionID string) (*entity.ChatSummary, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+	if sessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+
+	result := &entity.ChatSummary{}
+	ok, err := r.session.Table(entity.TableNameChatSummary).
+		Where(builder.Eq{
+			entity.ChatSummaryFieldUserID:    userID,
+			entity.ChatSummaryFieldSessionID: sessionID,
+		}).
+		Get(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat_summary: %w", err)
+	}
+
+	if !ok {
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+func (r *ChatSummaryRepository) Upsert(req *model.UpsertChatSummaryCondition) error {
+	if req == nil {
+		return fmt.Errorf("upsert request cannot be nil")
+	}
+	if req.UserID == "" {
+		return fmt.Errorf("user_id is required")
+	}
+	if req.SessionID == "" {
+		return fmt.Errorf("session_id is required")
+	}
+
+	existing, err := r.Get(req.UserID, req.SessionID)
+	if err != nil {
+		return fmt.Errorf("failed to check existing chat_summary: %w", err)
+	}
+
+	if existing != nil {
+		updateData := map[string]interface{}{
+			entity.ChatSummaryFieldContent:           req.Content,
+			entity.ChatSummaryFieldCoveredUntilMsgID: req.CoveredUntilMsgID,
+			entity.ChatSummaryFieldMessageCount:      req.MessageCount,
+			entity.ChatSummaryFieldUpdatedAt:         time.Now(),
+		}
+		_, err = r.session.Table(entity.TableNameChatSummary).
+			Where(builder.Eq{entity.ChatSummaryFieldID: existing.ID}).
+			Update(updateData)
+		if err != nil {
+			return fmt.Errorf("failed to update chat_summary: %w", err)
+		}
+		return nil
+	}
+
+	newSummary := &entity.ChatSummary{
+		UserID:            req.UserID,
+		SessionID:         req.SessionID,
+		Content:           req.Content,
+		CoveredUntilMsgID: req.CoveredUntilMsgID,
+		MessageCount:      req.MessageCount,
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
+	}
+	_, err = r.session.Table(entity.TableNameChatSummary).Insert(newSummary)
+	if err != nil {
+		return fmt.Errorf("failed to insert chat_summary: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ChatSummaryRepository) Delete(userID, sessionID string) (bool, error) {
+	if userID == "" {
+		return false, fmt.Errorf("user_id is required")
+	}
+	if sessionID == "" {
+		return false, fmt.Errorf("session_id is required")
+	}
+
+	affected, err := r.session.Table(entity.TableNameChatSummary).
+		Where(builder.Eq{
+			entity.ChatSummaryFieldUserID:    userID,
+			entity.ChatSummaryFieldSessionID: sessionID,
+		}).
+		Delete(&entity.ChatSummary{})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete chat_summary: %w", err)
+	}
+
+	return affected > 0, nil
+}
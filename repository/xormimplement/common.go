@@ -2,6 +2,7 @@ package xormimplement
 
 import (
 	"ai_task/model"
+	"fmt"
 	"strings"
 
 	"xorm.io/xorm"
@@ -51,3 +52,29 @@ func pagerOrder(session xorm.Interface, condition PaginationOrderCondition, arr
 		}
 	}
 }
+
+// escapeLike 转义 LIKE 通配符，用于前缀等精确匹配场景
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// fullTextSearchConfig 全文检索配置，simple 不做词干化和停用词过滤，保留错误码、ID 等标识符原样
+// 需与 full.sql 中的 GIN 表达式索引一致
+const fullTextSearchConfig = "simple"
+
+// fullTextSearchExpr 返回 content 列的 tsvector 表达式和查询 tsquery 表达式（一个 ? 占位符）
+func fullTextSearchExpr(column string) (tsvector, tsquery string) {
+	return fmt.Sprintf("to_tsvector('%s', %s)", fullTextSearchConfig, column),
+		fmt.Sprintf("websearch_to_tsquery('%s', ?)", fullTextSearchConfig)
+}
+
+// websearchQuery 将词元拼为 websearch_to_tsquery 的 OR 查询，词元中的引号会被去除
+func websearchQuery(keywords []string) string {
+	terms := make([]string, 0, len(keywords))
+	for _, k := range keywords {
+		if k = strings.TrimSpace(strings.ReplaceAll(k, `"`, "")); k != "" {
+			terms = append(terms, k)
+		}
+	}
+	return strings.Join(terms, " or ")
+}
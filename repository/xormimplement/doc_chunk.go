@@ -0,0 +1,221 @@
+package xormimplement
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+	"ai_task/pkg/clients/embedding"
+	"ai_task/repository"
+	"fmt"
+	"time"
+
+	"xorm.io/builder"
+)
+
+type DocChunkRepository struct {
+	session *Session
+}
+
+func NewDocChunkRepository(session *Session) repository.DocChunkRepository {
+	return &DocChunkRepository{session: session}
+}
+
+func (r *DocChunkRepository) BatchCreate(reqs []*model.CreateDocChunkCondition) error {
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	chunks := make([]*entity.DocChunk, 0, len(reqs))
+	for _, req := range reqs {
+		if req.DocID <= 0 {
+			return fmt.Errorf("doc_id is required")
+		}
+		if len(req.Embedding) == 0 {
+			return fmt.Errorf("embedding is required")
+		}
+		chunks = append(chunks, &entity.DocChunk{
+			DocID:     req.DocID,
+			UserID:    req.UserID,
+			Namespace: req.Namespace,
+			ChunkIdx:  req.ChunkIdx,
+			Content:   req.Content,
+			Embedding: embedding.VectorToString(req.Embedding),
+			CreatedAt: time.Now(),
+		})
+	}
+
+	_, err := r.session.Table(entity.TableNameDocChunk).Insert(&chunks)
+	if err != nil {
+		return fmt.Errorf("failed to insert doc_chunks: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DocChunkRepository) List(condition *model.GetDocChunksCondition) ([]*entity.DocChunk, error) {
+	if condition == nil {
+		return nil, fmt.Errorf("get condition cannot be nil")
+	}
+
+	// 列表不需要向量，避免读出大字段
+	session := r.session.Table(entity.TableNameDocChunk).Cols(
+		entity.DocChunkFieldID,
+		entity.DocChunkFieldDocID,
+		entity.DocChunkFieldUserID,
+		entity.DocChunkFieldNamespace,
+		entity.DocChunkFieldChunkIdx,
+		entity.DocChunkFieldContent,
+		entity.DocChunkFieldCreatedAt,
+	)
+	var conds []builder.Cond
+
+	if condition.DocID != nil {
+		conds = append(conds, builder.Eq{entity.DocChunkFieldDocID: *condition.DocID})
+	}
+	if len(condition.DocIDs) > 0 {
+		conds = append(conds, builder.In(entity.DocChunkFieldDocID, condition.DocIDs))
+	}
+	if condition.Content != nil && *condition.Content != "" {
+		conds = append(conds, builder.Like{entity.DocChunkFieldContent, *condition.Content})
+	}
+
+	if len(conds) > 0 {
+		session = session.Where(builder.And(conds...))
+	}
+
+	pagerOrder(session, condition, WithDefaultOrderField(entity.DocChunkFieldID))
+
+	var results []*entity.DocChunk
+	err := session.Find(&results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list doc_chunks: %w", err)
+	}
+
+	return results, nil
+}
+
+func (r *DocChunkRepository) DeleteByDocID(docID int64) error {
+	if docID <= 0 {
+		return fmt.Errorf("doc_id is required")
+	}
+
+	_, err := r.session.Table(entity.TableNameDocChunk).
+		Where(builder.Eq{entity.DocChunkFieldDocID: docID}).
+		Delete(&entity.DocChunk{})
+	if err != nil {
+		return fmt.Errorf("failed to delete doc_chunks: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DocChunkRepository) Search(condition *model.SearchDocChunksCondition) ([]*model.DocChunkSearchResult, error) {
+	whereSQL, whereArgs, err := docChunkSearchWhere(condition)
+	if err != nil || condition.Limit <= 0 {
+		return nil, err
+	}
+
+	// <=> 为 pgvector 余弦距离，相似度 = 1 - 距离
+	distance := fmt.Sprintf("(c.%s <=> ?::vector)", entity.DocChunkFieldEmbedding)
+	sql := fmt.Sprintf("SELECT %s, 1 - %s AS similarity FROM %s WHERE (%s) AND 1 - %s >= ? ORDER BY %s LIMIT ?",
+		docChunkSearchColumns,
+		distance,
+		docChunkSearchFrom,
+		whereSQL,
+		distance,
+		distance,
+	)
+
+	vector := embedding.VectorToString(condition.Embedding)
+	args := make([]interface{}, 0, len(whereArgs)+4)
+	args = append(args, vector)
+	args = append(args, whereArgs...)
+	args = append(args, vector, condition.Threshold, vector, condition.Limit)
+
+	var results []*model.DocChunkSearchResult
+	err = r.session.SQL(sql, args...).Find(&results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search doc_chunks: %w", err)
+	}
+
+	return results, nil
+}
+
+func (r *DocChunkRepository) SearchLexical(condition *model.SearchDocChunksCondition) ([]*model.DocChunkSearchResult, error) {
+	whereSQL, whereArgs, err := docChunkSearchWhere(condition)
+	if err != nil || condition.Limit <= 0 {
+		return nil, err
+	}
+	query := websearchQuery(condition.Keywords)
+	if query == "" {
+		return nil, nil
+	}
+
+	distance := fmt.Sprintf("(c.%s <=> ?::vector)", entity.DocChunkFieldEmbedding)
+	tsvector, tsquery := fullTextSearchExpr("c." + entity.DocChunkFieldContent)
+	sql := fmt.Sprintf("SELECT %s, 1 - %s AS similarity FROM %s WHERE (%s) AND %s @@ %s ORDER BY ts_rank_cd(%s, %s) DESC, c.%s DESC LIMIT ?",
+		docChunkSearchColumns,
+		distance,
+		docChunkSearchFrom,
+		whereSQL,
+		tsvector, tsquery,
+		tsvector, tsquery,
+		entity.DocChunkFieldID,
+	)
+
+	args := make([]interface{}, 0, len(whereArgs)+4)
+	args = append(args, embedding.VectorToString(condition.Embedding))
+	args = append(args, whereArgs...)
+	args = append(args, query, query, condition.Limit)
+
+	var results []*model.DocChunkSearchResult
+	err = r.session.SQL(sql, args...).Find(&results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search doc_chunks by keywords: %w", err)
+	}
+
+	return results, nil
+}
+
+// docChunkSearchColumns 检索结果列（不含向量），附带所属文档标题
+var docChunkSearchColumns = fmt.Sprintf("c.%s, c.%s, d.%s, c.%s, c.%s, c.%s",
+	entity.DocChunkFieldID,
+	entity.DocChunkFieldDocID,
+	entity.DocumentFieldTitle,
+	entity.DocChunkFieldNamespace,
+	entity.DocChunkFieldChunkIdx,
+	entity.DocChunkFieldContent,
+)
+
+var docChunkSearchFrom = fmt.Sprintf("%s c JOIN %s d ON d.%s = c.%s",
+	entity.TableNameDocChunk,
+	entity.TableNameDocument,
+	entity.DocumentFieldID,
+	entity.DocChunkFieldDocID,
+)
+
+// docChunkSearchWhere 校验检索条件并构建范围条件：本人上传的文档或指定命名空间下的共享文档
+func docChunkSearchWhere(condition *model.SearchDocChunksCondition) (string, []interface{}, error) {
+	if condition == nil {
+		return "", nil, fmt.Errorf("search condition cannot be nil")
+	}
+	if condition.UserID == "" && len(condition.Namespaces) == 0 {
+		return "", nil, fmt.Errorf("user_id or namespaces is required")
+	}
+	if len(condition.Embedding) == 0 {
+		return "", nil, fmt.Errorf("embedding is required")
+	}
+
+	scope := builder.NewCond()
+	if condition.UserID != "" {
+		scope = scope.Or(builder.Eq{"c." + entity.DocChunkFieldUserID: condition.UserID})
+	}
+	if len(condition.Namespaces) > 0 {
+		scope = scope.Or(builder.In("c."+entity.DocChunkFieldNamespace, condition.Namespaces))
+	}
+
+	whereSQL, whereArgs, err := builder.ToSQL(scope)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to build doc_chunks condition: %w", err)
+	}
+	return whereSQL, whereArgs, nil
+}
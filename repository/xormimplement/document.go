@@ -0,0 +1,145 @@
+package xormimplement
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+	"ai_task/repository"
+	"fmt"
+	"time"
+
+	"xorm.io/builder"
+)
+
+type DocumentRepository struct {
+	session *Session
+}
+
+func NewDocumentRepository(session *Session) repository.DocumentRepository {
+	return &DocumentRepository{session: session}
+}
+
+func (r *DocumentRepository) Create(req *model.CreateDocumentCondition) (*entity.Document, error) {
+	if req == nil {
+		return nil, fmt.Errorf("create request cannot be nil")
+	}
+	if req.UserID == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	now := time.Now()
+	doc := &entity.Document{
+		UserID:      req.UserID,
+		Namespace:   req.Namespace,
+		Title:       req.Title,
+		ContentType: req.ContentType,
+		Content:     req.Content,
+		Status:      req.Status,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	_, err := r.session.Table(entity.TableNameDocument).Insert(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert document: %w", err)
+	}
+
+	return doc, nil
+}
+
+func (r *DocumentRepository) Get(id int64) (*entity.Document, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("id is required")
+	}
+
+	result := &entity.Document{}
+	ok, err := r.session.Table(entity.TableNameDocument).
+		Where(builder.Eq{entity.DocumentFieldID: id}).
+		Get(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+
+	if !ok {
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+func (r *DocumentRepository) List(condition *model.GetDocumentsCondition) ([]*entity.Document, error) {
+	if condition == nil {
+		return nil, fmt.Errorf("get condition cannot be nil")
+	}
+
+	session := r.session.Table(entity.TableNameDocument)
+	var conds []builder.Cond
+
+	if condition.UserID != nil && *condition.UserID != "" {
+		conds = append(conds, builder.Eq{entity.DocumentFieldUserID: *condition.UserID})
+	}
+	if condition.Namespace != nil && *condition.Namespace != "" {
+		conds = append(conds, builder.Eq{entity.DocumentFieldNamespace: *condition.Namespace})
+	}
+	if condition.Status != nil && *condition.Status != "" {
+		conds = append(conds, builder.Eq{entity.DocumentFieldStatus: *condition.Status})
+	}
+
+	if len(conds) > 0 {
+		session = session.Where(builder.And(conds...))
+	}
+
+	pagerOrder(session, condition, WithDefaultOrderField(entity.DocumentFieldID))
+
+	var results []*entity.Document
+	err := session.Find(&results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	return results, nil
+}
+
+func (r *DocumentRepository) Update(id int64, req *model.UpdateDocumentCondition) error {
+	if id <= 0 {
+		return fmt.Errorf("id is required")
+	}
+	if req == nil {
+		return fmt.Errorf("update request cannot be nil")
+	}
+
+	updateData := map[string]interface{}{
+		entity.DocumentFieldUpdatedAt: time.Now(),
+	}
+	if req.Status != nil {
+		updateData[entity.DocumentFieldStatus] = *req.Status
+	}
+	if req.ChunkCount != nil {
+		updateData[entity.DocumentFieldChunkCount] = *req.ChunkCount
+	}
+	if req.Error != nil {
+		updateData[entity.DocumentFieldError] = *req.Error
+	}
+
+	_, err := r.session.Table(entity.TableNameDocument).
+		Where(builder.Eq{entity.DocumentFieldID: id}).
+		Update(updateData)
+	if err != nil {
+		return fmt.Errorf("failed to update document: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DocumentRepository) Delete(id int64) error {
+	if id <= 0 {
+		return fmt.Errorf("id is required")
+	}
+
+	_, err := r.session.Table(entity.TableNameDocument).
+		Where(builder.Eq{entity.DocumentFieldID: id}).
+		Delete(&entity.Document{})
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	return nil
+}
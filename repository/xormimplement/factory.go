@@ -98,3 +98,75 @@ func (f *Factory) NewTaskProgressRepository(session interfaces.Session) (reposit
 	}
 	return nil, fmt.Errorf("xorm session 结构解析失败")
 }
+
+// NewTaskJobRepository 创建任务执行作业仓库
+func (f *Factory) NewTaskJobRepository(session interfaces.Session) (repository.TaskJobRepository, error) {
+	if s, ok := session.(*Session); ok {
+		return NewTaskJobRepository(s), nil
+	}
+	return nil, fmt.Errorf("xorm session 结构解析失败")
+}
+
+// NewTaskToolCallRepository 创建任务工具调用记录仓库
+func (f *Factory) NewTaskToolCallRepository(session interfaces.Session) (repository.TaskToolCallRepository, error) {
+	if s, ok := session.(*Session); ok {
+		return NewTaskToolCallRepository(s), nil
+	}
+	return nil, fmt.Errorf("xorm session 结构解析失败")
+}
+
+// NewChatMessageRepository 创建聊天消息仓库
+func (f *Factory) NewChatMessageRepository(session interfaces.Session) (repository.ChatMessageRepository, error) {
+	if s, ok := session.(*Session); ok {
+		return NewChatMessageRepository(s), nil
+	}
+	return nil, fmt.Errorf("xorm session 结构解析失败")
+}
+
+// NewMemoryChunkRepository 创建语义记忆分块仓库
+func (f *Factory) NewMemoryChunkRepository(session interfaces.Session) (repository.MemoryChunkRepository, error) {
+	if s, ok := session.(*Session); ok {
+		return NewMemoryChunkRepository(s), nil
+	}
+	return nil, fmt.Errorf("xorm session 结构解析失败")
+}
+
+// NewChatSummaryRepository 创建会话摘要仓库
+func (f *Factory) NewChatSummaryRepository(session interfaces.Session) (repository.ChatSummaryRepository, error) {
+	if s, ok := session.(*Session); ok {
+		return NewChatSummaryRepository(s), nil
+	}
+	return nil, fmt.Errorf("xorm session 结构解析失败")
+}
+
+// NewChatSessionRepository 创建聊天会话仓库
+func (f *Factory) NewChatSessionRepository(session interfaces.Session) (repository.ChatSessionRepository, error) {
+	if s, ok := session.(*Session); ok {
+		return NewChatSessionRepository(s), nil
+	}
+	return nil, fmt.Errorf("xorm session 结构解析失败")
+}
+
+// NewDocumentRepository 创建知识文档仓库
+func (f *Factory) NewDocumentRepository(session interfaces.Session) (repository.DocumentRepository, error) {
+	if s, ok := session.(*Session); ok {
+		return NewDocumentRepository(s), nil
+	}
+	return nil, fmt.Errorf("xorm session 结构解析失败")
+}
+
+// NewDocChunkRepository 创建文档分块仓库
+func (f *Factory) NewDocChunkRepository(session interfaces.Session) (repository.DocChunkRepository, error) {
+	if s, ok := session.(*Session); ok {
+		return NewDocChunkRepository(s), nil
+	}
+	return nil, fmt.Errorf("xorm session 结构解析失败")
+}
+
+// NewLLMUsageRepository 创建模型用量仓库
+func (f *Factory) NewLLMUsageRepository(session interfaces.Session) (repository.LLMUsageRepository, error) {
+	if s, ok := session.(*Session); ok {
+		return NewLLMUsageRepository(s), nil
+	}
+	return nil, fmt.Errorf("xorm session 结构解析失败")
+}
@@ -0,0 +1,134 @@
+package xormimplement
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+	"ai_task/repository"
+	"fmt"
+	"strings"
+	"time"
+
+	"xorm.io/builder"
+)
+
+type LLMUsageRepository struct {
+	session *Session
+}
+
+func NewLLMUsageRepository(session *Session) repository.LLMUsageRepository {
+	return &LLMUsageRepository{session: session}
+}
+
+func (r *LLMUsageRepository) Create(req *model.CreateLLMUsageCondition) error {
+	if req == nil {
+		return fmt.Errorf("create request cannot be nil")
+	}
+
+	usage := &entity.LLMUsage{
+		UserID:           req.UserID,
+		SessionID:        req.SessionID,
+		TaskID:           req.TaskID,
+		Role:             req.Role,
+		Kind:             req.Kind,
+		Model:            req.Model,
+		PromptTokens:     req.PromptTokens,
+		CompletionTokens: req.CompletionTokens,
+		CachedTokens:     req.CachedTokens,
+		TotalTokens:      req.TotalTokens,
+		Cost:             req.Cost,
+		CreatedAt:        time.Now(),
+	}
+	_, err := r.session.Table(entity.TableNameLLMUsage).Insert(usage)
+	if err != nil {
+		return fmt.Errorf("failed to insert llm_usage: %w", err)
+	}
+
+	return nil
+}
+
+// llmUsageGroupColumns 分组维度到查询表达式，也用作 group_by 白名单
+var llmUsageGroupColumns = map[string]string{
+	model.UsageGroupUser:    entity.LLMUsageFieldUserID,
+	model.UsageGroupSession: entity.LLMUsageFieldSessionID,
+	model.UsageGroupTask:    entity.LLMUsageFieldTaskID,
+	model.UsageGroupRole:    entity.LLMUsageFieldRole,
+	model.UsageGroupKind:    entity.LLMUsageFieldKind,
+	model.UsageGroupModel:   entity.LLMUsageFieldModel,
+	model.UsageGroupDay:     fmt.Sprintf("TO_CHAR(%s, 'YYYY-MM-DD')", entity.LLMUsageFieldCreatedAt),
+}
+
+func (r *LLMUsageRepository) Aggregate(condition *model.AggregateLLMUsageCondition) ([]*model.LLMUsageAggregate, error) {
+	if condition == nil {
+		return nil, fmt.Errorf("aggregate condition cannot be nil")
+	}
+
+	selects := make([]string, 0, len(condition.GroupBy)+6)
+	groups := make([]string, 0, len(condition.GroupBy))
+	for _, group := range condition.GroupBy {
+		column, ok := llmUsageGroupColumns[group]
+		if !ok {
+			return nil, fmt.Errorf("unsupported group_by: %s", group)
+		}
+		selects = append(selects, fmt.Sprintf("%s AS %s", column, group))
+		groups = append(groups, column)
+	}
+	selects = append(selects,
+		"COUNT(*) AS calls",
+		fmt.Sprintf("COALESCE(SUM(%[1]s), 0) AS %[1]s", entity.LLMUsageFieldPromptTokens),
+		fmt.Sprintf("COALESCE(SUM(%[1]s), 0) AS %[1]s", entity.LLMUsageFieldCompletionTokens),
+		fmt.Sprintf("COALESCE(SUM(%[1]s), 0) AS %[1]s", entity.LLMUsageFieldCachedTokens),
+		fmt.Sprintf("COALESCE(SUM(%[1]s), 0) AS %[1]s", entity.LLMUsageFieldTotalTokens),
+		fmt.Sprintf("COALESCE(SUM(%[1]s), 0) AS %[1]s", entity.LLMUsageFieldCost),
+	)
+
+	sql := fmt.Sprintf("SELECT %s FROM %s", strings.Join(selects, ", "), entity.TableNameLLMUsage)
+	var args []interface{}
+	if conds := llmUsageConds(condition); len(conds) > 0 {
+		whereSQL, whereArgs, err := builder.ToSQL(builder.And(conds...))
+		if err != nil {
+			return nil, fmt.Errorf("failed to build llm_usage condition: %w", err)
+		}
+		sql += " WHERE " + whereSQL
+		args = whereArgs
+	}
+	if len(groups) > 0 {
+		sql += fmt.Sprintf(" GROUP BY %s ORDER BY %s", strings.Join(groups, ", "), strings.Join(groups, ", "))
+	}
+
+	var results []*model.LLMUsageAggregate
+	err := r.session.SQL(sql, args...).Find(&results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate llm_usage: %w", err)
+	}
+
+	return results, nil
+}
+
+// llmUsageConds 构建用量查询条件
+func llmUsageConds(condition *model.AggregateLLMUsageCondition) []builder.Cond {
+	var conds []builder.Cond
+
+	if condition.UserID != nil && *condition.UserID != "" {
+		conds = append(conds, builder.Eq{entity.LLMUsageFieldUserID: *condition.UserID})
+	}
+	if condition.SessionID != nil && *condition.SessionID != "" {
+		conds = append(conds, builder.Eq{entity.LLMUsageFieldSessionID: *condition.SessionID})
+	}
+	if condition.TaskID != nil && *condition.TaskID != "" {
+		conds = append(conds, builder.Eq{entity.LLMUsageFieldTaskID: *condition.TaskID})
+	}
+	if condition.Role != nil && *condition.Role != "" {
+		conds = append(conds, builder.Eq{entity.LLMUsageFieldRole: *condition.Role})
+	}
+	if condition.Kind != nil && *condition.Kind != "" {
+		conds = append(conds, builder.Eq{entity.LLMUsageFieldKind: *condition.Kind})
+	}
+	if condition.Since != nil {
+		conds = append(conds, builder.Gte{entity.LLMUsageFieldCreatedAt: *condition.Since})
+	}
+	if condition.Until != nil {
+		conds = append(conds, builder.Lt{entity.LLMUsageFieldCreatedAt: *condition.Until})
+	}
+
+	return conds
+}
@@ -0,0 +1,182 @@
+package xormimplement
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+	"ai_task/pkg/clients/embedding"
+	"ai_task/repository"
+	"fmt"
+	"strings"
+	"time"
+
+	"xorm.io/builder"
+)
+
+type MemoryChunkRepository struct {
+	session *Session
+}
+
+func NewMemoryChunkRepository(session *Session) repository.MemoryChunkRepository {
+	return &MemoryChunkRepository{session: session}
+}
+
+func (r *MemoryChunkRepository) BatchCreate(reqs []*model.CreateMemoryChunkCondition) error {
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	chunks := make([]*entity.MemoryChunk, 0, len(reqs))
+	for _, req := range reqs {
+		if req.UserID == "" {
+			return fmt.Errorf("user_id is required")
+		}
+		if len(req.Embedding) == 0 {
+			return fmt.Errorf("embedding is required")
+		}
+		chunks = append(chunks, &entity.MemoryChunk{
+			UserID:    req.UserID,
+			SessionID: req.SessionID,
+			MessageID: req.MessageID,
+			Role:      req.Role,
+			ChunkIdx:  req.ChunkIdx,
+			Content:   req.Content,
+			Embedding: embedding.VectorToString(req.Embedding),
+			CreatedAt: time.Now(),
+		})
+	}
+
+	_, err := r.session.Table(entity.TableNameMemoryChunk).Insert(&chunks)
+	if err != nil {
+		return fmt.Errorf("failed to insert memory_chunks: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MemoryChunkRepository) Search(condition *model.SearchMemoryChunksCondition) ([]*model.MemoryChunkSearchResult, error) {
+	whereSQL, whereArgs, err := memoryChunkSearchWhere(condition)
+	if err != nil || condition.Limit <= 0 {
+		return nil, err
+	}
+
+	// <=> 为 pgvector 余弦距离，相似度 = 1 - 距离
+	distance := fmt.Sprintf("(%s <=> ?::vector)", entity.MemoryChunkFieldEmbedding)
+	sql := fmt.Sprintf("SELECT %s, 1 - %s AS similarity FROM %s WHERE %s AND 1 - %s >= ? ORDER BY %s LIMIT ?",
+		memoryChunkSearchColumns,
+		distance,
+		entity.TableNameMemoryChunk,
+		whereSQL,
+		distance,
+		distance,
+	)
+
+	vector := embedding.VectorToString(condition.Embedding)
+	args := make([]interface{}, 0, len(whereArgs)+4)
+	args = append(args, vector)
+	args = append(args, whereArgs...)
+	args = append(args, vector, condition.Threshold, vector, condition.Limit)
+
+	var results []*model.MemoryChunkSearchResult
+	err = r.session.SQL(sql, args...).Find(&results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search memory_chunks: %w", err)
+	}
+
+	return results, nil
+}
+
+func (r *MemoryChunkRepository) SearchLexical(condition *model.SearchMemoryChunksCondition) ([]*model.MemoryChunkSearchResult, error) {
+	whereSQL, whereArgs, err := memoryChunkSearchWhere(condition)
+	if err != nil || condition.Limit <= 0 {
+		return nil, err
+	}
+	query := websearchQuery(condition.Keywords)
+	if query == "" {
+		return nil, nil
+	}
+
+	distance := fmt.Sprintf("(%s <=> ?::vector)", entity.MemoryChunkFieldEmbedding)
+	tsvector, tsquery := fullTextSearchExpr(entity.MemoryChunkFieldContent)
+	sql := fmt.Sprintf("SELECT %s, 1 - %s AS similarity FROM %s WHERE %s AND %s @@ %s ORDER BY ts_rank_cd(%s, %s) DESC, %s DESC LIMIT ?",
+		memoryChunkSearchColumns,
+		distance,
+		entity.TableNameMemoryChunk,
+		whereSQL,
+		tsvector, tsquery,
+		tsvector, tsquery,
+		entity.MemoryChunkFieldID,
+	)
+
+	args := make([]interface{}, 0, len(whereArgs)+4)
+	args = append(args, embedding.VectorToString(condition.Embedding))
+	args = append(args, whereArgs...)
+	args = append(args, query, query, condition.Limit)
+
+	var results []*model.MemoryChunkSearchResult
+	err = r.session.SQL(sql, args...).Find(&results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search memory_chunks by keywords: %w", err)
+	}
+
+	return results, nil
+}
+
+func (r *MemoryChunkRepository) DeleteBySession(userID, sessionID string) (int64, error) {
+	if userID == "" {
+		return 0, fmt.Errorf("user_id is required")
+	}
+	if sessionID == "" {
+		return 0, fmt.Errorf("session_id is required")
+	}
+
+	affected, err := r.session.Table(entity.TableNameMemoryChunk).
+		Where(builder.Eq{
+			entity.MemoryChunkFieldUserID:    userID,
+			entity.MemoryChunkFieldSessionID: sessionID,
+		}).
+		Delete(&entity.MemoryChunk{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete memory_chunks: %w", err)
+	}
+
+	return affected, nil
+}
+
+// memoryChunkSearchColumns 检索结果列（不含向量）
+var memoryChunkSearchColumns = strings.Join([]string{
+	entity.MemoryChunkFieldID,
+	entity.MemoryChunkFieldUserID,
+	entity.MemoryChunkFieldSessionID,
+	entity.MemoryChunkFieldMessageID,
+	entity.MemoryChunkFieldRole,
+	entity.MemoryChunkFieldChunkIdx,
+	entity.MemoryChunkFieldContent,
+	entity.MemoryChunkFieldCreatedAt,
+}, ", ")
+
+// memoryChunkSearchWhere 校验检索条件并构建 WHERE 子句
+func memoryChunkSearchWhere(condition *model.SearchMemoryChunksCondition) (string, []interface{}, error) {
+	if condition == nil {
+		return "", nil, fmt.Errorf("search condition cannot be nil")
+	}
+	if condition.UserID == "" {
+		return "", nil, fmt.Errorf("user_id is required")
+	}
+	if len(condition.Embedding) == 0 {
+		return "", nil, fmt.Errorf("embedding is required")
+	}
+
+	cond := builder.NewCond().And(builder.Eq{entity.MemoryChunkFieldUserID: condition.UserID})
+	if condition.SessionID != nil && *condition.SessionID != "" {
+		cond = cond.And(builder.Eq{entity.MemoryChunkFieldSessionID: *condition.SessionID})
+	}
+	if len(condition.ExcludeMessageIDs) > 0 {
+		cond = cond.And(builder.NotIn(entity.MemoryChunkFieldMessageID, condition.ExcludeMessageIDs))
+	}
+
+	whereSQL, whereArgs, err := builder.ToSQL(cond)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to build memory_chunks condition: %w", err)
+	}
+	return whereSQL, whereArgs, nil
+}
@@ -63,6 +63,15 @@ func (r *TaskRepository) Upsert(req *model.UpsertTaskCondition) error {
 		if req.Status != nil {
 			updateData[entity.TaskFieldStatus] = *req.Status
 		}
+		if req.CursorJSON != nil {
+			updateData[entity.TaskFieldCursorJSON] = *req.CursorJSON
+		}
+		if req.PendingJSON != nil {
+			updateData[entity.TaskFieldPendingJSON] = *req.PendingJSON
+		}
+		if req.GuidanceJSON != nil {
+			updateData[entity.TaskFieldGuidanceJSON] = *req.GuidanceJSON
+		}
 		if req.ToolCallCount != nil {
 			updateData[entity.TaskFieldToolCallCount] = *req.ToolCallCount
 		}
@@ -113,6 +122,15 @@ func (r *TaskRepository) Upsert(req *model.UpsertTaskCondition) error {
 		if req.ErrorsJSON != nil {
 			newTask.ErrorsJSON = *req.ErrorsJSON
 		}
+		if req.CursorJSON != nil {
+			newTask.CursorJSON = *req.CursorJSON
+		}
+		if req.PendingJSON != nil {
+			newTask.PendingJSON = *req.PendingJSON
+		}
+		if req.GuidanceJSON != nil {
+			newTask.GuidanceJSON = *req.GuidanceJSON
+		}
 		if req.ToolCallCount != nil {
 			newTask.ToolCallCount = *req.ToolCallCount
 		}
@@ -163,6 +181,9 @@ func (r *TaskRepository) List(condition *model.TaskListCondition) ([]*entity.Tas
 		if condition.SessionID != nil && *condition.SessionID != "" {
 			conds = append(conds, builder.Eq{entity.TaskFieldSessionID: *condition.SessionID})
 		}
+		if condition.Status != nil && *condition.Status != "" {
+			conds = append(conds, builder.Eq{entity.TaskFieldStatus: *condition.Status})
+		}
 	}
 
 	if len(conds) > 0 {
@@ -215,6 +236,15 @@ func (r *TaskRepository) Delete(taskID string) error {
 		return fmt.Errorf("failed to delete task progress: %w", err)
 	}
 
+	// 删除关联的工具调用记录
+	_, err = r.session.Table(entity.TableNameTaskToolCall).
+		Where(builder.Eq{entity.TaskToolCallFieldTaskID: taskID}).
+		Delete(&entity.TaskToolCall{})
+	if err != nil {
+		_ = r.session.Rollback()
+		return fmt.Errorf("failed to delete task tool calls: %w", err)
+	}
+
 	if err := r.session.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -552,3 +582,226 @@ func (r *TaskProgressRepository) Delete(taskID string) error {
 
 	return nil
 }
+
+// ========== TaskJobRepository 实现 ==========
+
+type TaskJobRepository struct {
+	session *Session
+}
+
+func NewTaskJobRepository(session *Session) repository.TaskJobRepository {
+	return &TaskJobRepository{session: session}
+}
+
+func (r *TaskJobRepository) Upsert(req *model.UpsertTaskJobCondition) error {
+	if req == nil {
+		return fmt.Errorf("upsert request cannot be nil")
+	}
+	if req.ID == "" {
+		return fmt.Errorf("job id is required")
+	}
+
+	// 先尝试获取现有记录
+	existing := &entity.TaskJob{}
+	has, err := r.session.Table(entity.TableNameTaskJob).
+		Where(builder.Eq{entity.TaskJobFieldID: req.ID}).
+		Get(existing)
+	if err != nil {
+		return fmt.Errorf("failed to check existing job: %w", err)
+	}
+
+	if has {
+		// 更新现有记录
+		updateData := make(map[string]interface{})
+		updateData[entity.TaskJobFieldUpdatedAt] = time.Now()
+
+		if req.Status != nil {
+			updateData[entity.TaskJobFieldStatus] = *req.Status
+		}
+		if req.CurrentPhase != nil {
+			updateData[entity.TaskJobFieldCurrentPhase] = *req.CurrentPhase
+		}
+		if req.CurrentStep != nil {
+			updateData[entity.TaskJobFieldCurrentStep] = *req.CurrentStep
+		}
+		if req.ResultJSON != nil {
+			updateData[entity.TaskJobFieldResultJSON] = *req.ResultJSON
+		}
+		if req.Error != nil {
+			updateData[entity.TaskJobFieldError] = *req.Error
+		}
+		if req.Attempts != nil {
+			updateData[entity.TaskJobFieldAttempts] = *req.Attempts
+		}
+		if req.StartedAt != nil {
+			updateData[entity.TaskJobFieldStartedAt] = *req.StartedAt
+		}
+		if req.FinishedAt != nil {
+			updateData[entity.TaskJobFieldFinishedAt] = *req.FinishedAt
+		}
+
+		_, err = r.session.Table(entity.TableNameTaskJob).
+			Where(builder.Eq{entity.TaskJobFieldID: req.ID}).
+			Update(updateData)
+		if err != nil {
+			return fmt.Errorf("failed to update job: %w", err)
+		}
+	} else {
+		// 插入新记录
+		if req.TaskID == "" {
+			return fmt.Errorf("task_id is required")
+		}
+
+		newJob := &entity.TaskJob{
+			ID:        req.ID,
+			TaskID:    req.TaskID,
+			SessionID: req.SessionID,
+			PhaseID:   req.PhaseID,
+			Locale:    req.Locale,
+			Status:    "queued",
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+
+		if req.Status != nil {
+			newJob.Status = *req.Status
+		}
+		if req.CurrentPhase != nil {
+			newJob.CurrentPhase = *req.CurrentPhase
+		}
+		if req.CurrentStep != nil {
+			newJob.CurrentStep = *req.CurrentStep
+		}
+		if req.ResultJSON != nil {
+			newJob.ResultJSON = *req.ResultJSON
+		}
+		if req.Error != nil {
+			newJob.Error = *req.Error
+		}
+		if req.Attempts != nil {
+			newJob.Attempts = *req.Attempts
+		}
+		if req.StartedAt != nil {
+			newJob.StartedAt = req.StartedAt
+		}
+		if req.FinishedAt != nil {
+			newJob.FinishedAt = req.FinishedAt
+		}
+
+		_, err = r.session.Table(entity.TableNameTaskJob).Insert(newJob)
+		if err != nil {
+			return fmt.Errorf("failed to insert job: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *TaskJobRepository) Get(jobID string) (*entity.TaskJob, error) {
+	if jobID == "" {
+		return nil, fmt.Errorf("job_id is required")
+	}
+
+	result := &entity.TaskJob{}
+	ok, err := r.session.Table(entity.TableNameTaskJob).
+		Where(builder.Eq{entity.TaskJobFieldID: jobID}).
+		Get(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	if !ok {
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+func (r *TaskJobRepository) List(condition *model.TaskJobListCondition) ([]*entity.TaskJob, error) {
+	session := r.session.Table(entity.TableNameTaskJob)
+	var conds []builder.Cond
+
+	if condition != nil {
+		if condition.TaskID != nil && *condition.TaskID != "" {
+			conds = append(conds, builder.Eq{entity.TaskJobFieldTaskID: *condition.TaskID})
+		}
+		if len(condition.Statuses) > 0 {
+			conds = append(conds, builder.In(entity.TaskJobFieldStatus, condition.Statuses))
+		}
+	}
+
+	if len(conds) > 0 {
+		session = session.Where(builder.And(conds...))
+	}
+
+	var results []*entity.TaskJob
+	err := session.Asc(entity.TaskJobFieldCreatedAt).Find(&results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	return results, nil
+}
+
+// ========== TaskToolCallRepository 实现 ==========
+
+type TaskToolCallRepository struct {
+	session *Session
+}
+
+func NewTaskToolCallRepository(session *Session) repository.TaskToolCallRepository {
+	return &TaskToolCallRepository{session: session}
+}
+
+func (r *TaskToolCallRepository) Insert(req *model.InsertTaskToolCallCondition) error {
+	if req == nil {
+		return fmt.Errorf("insert request cannot be nil")
+	}
+	if req.ID == "" || req.TaskID == "" {
+		return fmt.Errorf("id and task_id are required")
+	}
+
+	record := &entity.TaskToolCall{
+		ID:        req.ID,
+		TaskID:    req.TaskID,
+		Name:      req.Name,
+		ArgsJSON:  req.ArgsJSON,
+		Result:    req.Result,
+		Error:     req.Error,
+		CreatedAt: req.CreatedAt,
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+
+	_, err := r.session.Table(entity.TableNameTaskToolCall).Insert(record)
+	if err != nil {
+		return fmt.Errorf("failed to insert tool call: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TaskToolCallRepository) List(condition *model.TaskToolCallListCondition) ([]*entity.TaskToolCall, error) {
+	if condition == nil || condition.TaskID == "" {
+		return nil, fmt.Errorf("task_id is required")
+	}
+
+	// 倒序取出最近的记录后翻转为时间正序
+	session := r.session.Table(entity.TableNameTaskToolCall).
+		Where(builder.Eq{entity.TaskToolCallFieldTaskID: condition.TaskID}).
+		Desc(entity.TaskToolCallFieldCreatedAt)
+	if condition.Limit > 0 {
+		session = session.Limit(condition.Limit)
+	}
+
+	var results []*entity.TaskToolCall
+	if err := session.Find(&results); err != nil {
+		return nil, fmt.Errorf("failed to list tool calls: %w", err)
+	}
+	for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+		results[i], results[j] = results[j], results[i]
+	}
+
+	return results, nil
+}
@@ -54,9 +54,8 @@ func (r *UserProfileRepository) Upsert(req *model.UpsertUserProfileCondition) er
 			entity.UserProfileFieldConfidence: req.Confidence,
 			entity.UserProfileFieldUpdatedAt:  time.Now(),
 		}
-		if req.SourceMsgID != nil {
-			updateData[entity.UserProfileFieldSourceMsgID] = *req.SourceMsgID
-		}
+		// 来源随值一起更新，nil 时清空
+		updateData[entity.UserProfileFieldSourceMsgID] = req.SourceMsgID
 		if req.Meta != nil {
 			updateData[entity.UserProfileFieldMeta] = meta
 		}
@@ -130,13 +129,19 @@ func (r *UserProfileRepository) List(condition *model.GetUserProfileCondition) (
 	if condition.Key != nil && *condition.Key != "" {
 		conds = append(conds, builder.Eq{entity.UserProfileFieldKey: *condition.Key})
 	}
+	if condition.KeyPrefix != nil && *condition.KeyPrefix != "" {
+		conds = append(conds, builder.Like{entity.UserProfileFieldKey, escapeLike(*condition.KeyPrefix) + "%"})
+	}
+	if condition.MinConfidence != nil {
+		conds = append(conds, builder.Gte{entity.UserProfileFieldConfidence: *condition.MinConfidence})
+	}
 
 	if len(conds) > 0 {
 		session = session.Where(builder.And(conds...))
 	}
 
 	var results []*entity.UserProfile
-	err := session.Find(&results)
+	err := session.Asc(entity.UserProfileFieldKey).Find(&results)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list user_profile: %w", err)
 	}
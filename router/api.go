@@ -13,6 +13,30 @@ func addApiRouter(engine *gin.Engine) {
 	{
 		api.POST("/chat", controller.Chat)
 
+		// 用户画像 API
+		api.GET("/user/:user_id/profile", controller.ListUserProfile)
+		api.GET("/user/:user_id/profile/:key", controller.GetUserProfile)
+		api.PUT("/user/:user_id/profile/:key", controller.UpsertUserProfile)
+		api.DELETE("/user/:user_id/profile/:key", controller.DeleteUserProfile)
+
+		// 聊天会话 API
+		api.GET("/user/:user_id/chat-sessions", controller.ListChatSessions)
+		api.GET("/user/:user_id/chat-sessions/:session_id/messages", controller.ListChatSessionMessages)
+		api.PUT("/user/:user_id/chat-sessions/:session_id", controller.RenameChatSession)
+		api.POST("/user/:user_id/chat-sessions/:session_id/fork", controller.ForkChatSession)
+		api.DELETE("/user/:user_id/chat-sessions/:session_id", controller.DeleteChatSession)
+
+		// 知识文档 API
+		api.POST("/documents", controller.CreateDocument)
+		api.GET("/documents", controller.ListDocuments)
+		api.GET("/documents/:doc_id", controller.GetDocument)
+		api.POST("/documents/:doc_id/reindex", controller.ReindexDocument)
+		api.DELETE("/documents/:doc_id", controller.DeleteDocument)
+
+		// 模型用量 API
+		api.GET("/usage", controller.GetUsage)
+		api.GET("/usage/cache", controller.GetCacheMetrics)
+
 		// 任务管理 API
 		// 任务 CRUD
 		api.POST("/task", controller.CreateTask)
@@ -22,6 +46,13 @@ func addApiRouter(engine *gin.Engine) {
 
 		// 任务执行
 		api.POST("/task/execute", controller.ExecuteTask)
+		api.GET("/task/:task_id/jobs", controller.ListTaskJobs)
+		api.GET("/task/:task_id/tool-calls", controller.ListToolCalls)
+		api.POST("/task/:task_id/cancel", controller.CancelTask)
+		api.POST("/task/:task_id/pause", controller.PauseTask)
+		api.POST("/task/:task_id/resume", controller.ResumeTask)
+		api.POST("/task/:task_id/guidance", controller.SubmitGuidance)
+		api.GET("/job/:job_id", controller.GetJob)
 
 		// 任务上下文
 		api.GET("/task/:task_id/context", controller.GetTaskContext)
@@ -1,11 +1,25 @@
 package chat
 
 import (
+	"ai_task/entity"
 	"ai_task/model"
+	"ai_task/pkg/clients/embedding"
+	"ai_task/pkg/clients/llm"
 	"ai_task/pkg/clients/llm_model"
+	"ai_task/pkg/memory"
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/retrieval"
+	"ai_task/pkg/usage"
+	"ai_task/repository"
 	"ai_task/repository/factory"
+	"ai_task/repository/interfaces"
 	"context"
+	"fmt"
 	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sashabaranov/go-openai"
+	log "github.com/sirupsen/logrus"
 )
 
 var (
@@ -13,24 +27,349 @@ var (
 	instance    *Service
 )
 
+// 流式响应中先于增量内容推送的 SSE 事件名
+const (
+	streamEventCitations     = "citations"      // 引用来源
+	streamEventContextReport = "context_report" // 上下文裁剪报告
+)
+
+// streamingChatModel 支持以 SSE 流式推送的模型
+type streamingChatModel interface {
+	PostChatCompletions(c *context.Context, messages []openai.ChatCompletionMessage) (*llm_model.StreamResult, error)
+}
+
 type Service struct {
 	repositoryFactory factory.Factory
-	llmClient         *llm_model.ClientChatModel
+	llmClient         llm.ChatModel
+	embeddingClient   *embedding.Client // 为 nil 时不启用语义记忆
+	summarizer        *memory.Summarizer
+	memoryRetriever   retrieval.Retriever // 语义记忆混合检索，embedding 不可用时为 nil
+	documentRetriever retrieval.Retriever // 知识文档混合检索，embedding 不可用时为 nil
+	settings          chatSettings
 }
 
-func NewService(repositoryFactory factory.Factory) *Service {
+// NewService 创建聊天服务，按用途选择对话、摘要与事实提取的模型，models 为 nil 时使用全局路由
+func NewService(repositoryFactory factory.Factory, models llm.Models) *Service {
 	serviceOnce.Do(func() {
+		embeddingClient, err := embedding.GetInstance()
+		if err != nil {
+			log.Warnf("embedding client unavailable, semantic memory disabled: %v", err)
+			embeddingClient = nil
+		}
 
+		models = llm.OrDefaultModels(models)
 		instance = &Service{
 			repositoryFactory: repositoryFactory,
-			llmClient:         llm_model.GetInstance(),
+			llmClient:         models.Model(llm.RoleChat),
+			embeddingClient:   embeddingClient,
+			summarizer:        memory.NewSummarizer(models),
+			settings:          loadChatSettings(),
+		}
+		if embeddingClient != nil {
+			instance.memoryRetriever = retrieval.NewMemoryRetriever(repositoryFactory, embeddingClient)
+			instance.documentRetriever = retrieval.NewDocumentRetriever(repositoryFactory, embeddingClient)
 		}
 	})
 
 	return instance
 }
 
+// chatTurn 一轮对话的上下文：数据库会话、仓库以及拼装好的模型输入
+type chatTurn struct {
+	req          *model.ChatRequest
+	opts         *memoryOptions
+	session      interfaces.Session
+	messageRepo  repository.ChatMessageRepository
+	turnMessages []openai.ChatCompletionMessage
+	messages     []openai.ChatCompletionMessage
+	queryVector  []float64            // 本轮用户输入的向量，检索时按需计算
+	citations    []model.Citation     // 注入上下文的文档片段
+	report       *model.ContextReport // 超出 token 预算时被裁剪的内容
+	prompts      []prompt.Rendered    // 注入的上下文提示词，调用模型时记录模板版本
+}
+
 // Chat 处理聊天请求
+// 流程：加载会话记忆 -> 拼装上下文 -> 调用大模型 -> 持久化本轮 user/assistant 消息
 func (s *Service) Chat(ctx context.Context, req *model.ChatRequest, options *model.MemoryContextOptionsRequest) (*model.ChatResponse, *model.Error) {
-	return nil, nil
+	if modelErr := checkChatRequest(req); modelErr != nil {
+		return nil, modelErr
+	}
+	ctx = usage.WithTags(ctx, usage.Tags{UserID: req.UserID, SessionID: req.SessionID, Role: usage.RoleChat})
+	ctx = prompt.WithLocale(ctx, prompt.ResolveLocale(ctx, req.UserID, req.Locale))
+
+	turn, modelErr := s.prepareTurn(ctx, req, options)
+	if modelErr != nil {
+		return nil, modelErr
+	}
+	defer func() { _ = turn.session.Close() }()
+
+	content, err := s.llmClient.PostChatCompletionsNonStreamContent(prompt.WithRendered(ctx, turn.prompts...), turn.messages)
+	if err != nil {
+		return nil, model.NewError(model.ErrorLLM, err)
+	}
+
+	saved, err := s.saveTurn(turn, content, false)
+	if err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+	s.indexSemanticMemory(saved, turn.opts)
+	s.extractLongTermMemory(saved, turn.opts)
+	s.compressConversation(req, turn.opts)
+
+	return &model.ChatResponse{
+		Message:   content,
+		SessionID: req.SessionID,
+		MessageID: saved[len(saved)-1].ID,
+		Citations: turn.citations,
+		Context:   turn.report,
+	}, nil
+}
+
+// ChatStream 流式处理聊天请求，增量内容以 SSE 直接写入 gin 响应
+// 流结束后保存完整回复；客户端中途断开时保存已生成部分并标记为截断
+func (s *Service) ChatStream(ctx *gin.Context, req *model.ChatRequest, options *model.MemoryContextOptionsRequest) *model.Error {
+	if modelErr := checkChatRequest(req); modelErr != nil {
+		return modelErr
+	}
+
+	// 流式调用使用请求自身的 context，用量归属和提示词语言需要写入请求
+	reqCtx := usage.WithTags(ctx.Request.Context(), usage.Tags{UserID: req.UserID, SessionID: req.SessionID, Role: usage.RoleChat})
+	ctx.Request = ctx.Request.WithContext(prompt.WithLocale(reqCtx, prompt.ResolveLocale(reqCtx, req.UserID, req.Locale)))
+
+	// 客户端断开后请求 context 会被取消，落库不应受其影响
+	dbCtx := context.WithoutCancel(ctx.Request.Context())
+
+	streamer, ok := s.llmClient.(streamingChatModel)
+	if !ok {
+		return model.NewError(model.ErrorLLM, fmt.Errorf("chat model %T does not support streaming", s.llmClient))
+	}
+
+	turn, modelErr := s.prepareTurn(dbCtx, req, options)
+	if modelErr != nil {
+		return modelErr
+	}
+	defer func() { _ = turn.session.Close() }()
+
+	// 引用来源先于增量内容推送，客户端可在渲染回复时解析 [n]
+	if len(turn.citations) > 0 {
+		if err := llm_model.WriteStreamEvent(ctx, streamEventCitations, turn.citations); err != nil {
+			log.Warnf("write citations event error, user_id:%s, session_id:%s, err:%v", req.UserID, req.SessionID, err)
+		}
+	}
+	if turn.report != nil {
+		if err := llm_model.WriteStreamEvent(ctx, streamEventContextReport, turn.report); err != nil {
+			log.Warnf("write context report event error, user_id:%s, session_id:%s, err:%v", req.UserID, req.SessionID, err)
+		}
+	}
+
+	ctx.Request = ctx.Request.WithContext(prompt.WithRendered(ctx.Request.Context(), turn.prompts...))
+	var streamCtx context.Context = ctx
+	result, err := streamer.PostChatCompletions(&streamCtx, turn.messages)
+	if result == nil {
+		// 已推送过引用或裁剪报告事件时无法再返回 JSON 错误，以 error 事件结束流
+		if ctx.Writer.Written() && err != nil {
+			_ = llm_model.WriteStreamError(ctx, err)
+		}
+		return model.NewError(model.ErrorLLM, err)
+	}
+	if err != nil {
+		// 响应头已写出，错误事件已推送给客户端，这里只记录并保存已生成部分
+		log.Errorf("chat stream interrupted, user_id:%s, session_id:%s, err:%v", req.UserID, req.SessionID, err)
+	}
+
+	saved, err := s.saveTurn(turn, result.Content, result.Truncated)
+	if err != nil {
+		return model.NewError(model.ErrorDB, err)
+	}
+	s.indexSemanticMemory(saved, turn.opts)
+	s.extractLongTermMemory(saved, turn.opts)
+	s.compressConversation(req, turn.opts)
+
+	return nil
+}
+
+// checkChatRequest 校验请求必填字段，需在读取请求字段之前调用
+func checkChatRequest(req *model.ChatRequest) *model.Error {
+	if req == nil || req.UserID == "" || req.SessionID == "" {
+		return model.NewError(model.ErrorParams, fmt.Errorf("user_id and session_id are required"))
+	}
+	return nil
+}
+
+// prepareTurn 解析记忆选项、加载会话记忆并拼装模型输入
+// 返回的 chatTurn 持有数据库会话，调用方负责关闭
+func (s *Service) prepareTurn(ctx context.Context, req *model.ChatRequest, options *model.MemoryContextOptionsRequest) (*chatTurn, *model.Error) {
+	systemMessages, turnMessages := splitMessages(req.Messages)
+	if len(turnMessages) == 0 {
+		return nil, model.NewError(model.ErrorParams, fmt.Errorf("messages must contain at least one non-system message"))
+	}
+
+	opts := s.resolveMemoryOptions(options)
+
+	session := s.repositoryFactory.NewSession(ctx)
+
+	messageRepo, err := s.repositoryFactory.NewChatMessageRepository(session)
+	if err != nil {
+		_ = session.Close()
+		return nil, model.NewError(model.ErrorNewRepo, err)
+	}
+
+	// 摘要读取失败时退化为普通短期记忆窗口
+	summary, err := s.loadConversationSummary(session, req, opts)
+	if err != nil {
+		log.Warnf("load conversation summary error, user_id:%s, session_id:%s, err:%v", req.UserID, req.SessionID, err)
+		summary = nil
+	}
+
+	history, err := s.loadSessionMemory(messageRepo, req, opts, summary)
+	if err != nil {
+		_ = session.Close()
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+
+	turn := &chatTurn{
+		req:          req,
+		opts:         opts,
+		session:      session,
+		messageRepo:  messageRepo,
+		turnMessages: turnMessages,
+	}
+
+	// 记忆上下文检索失败不影响本轮对话，降级为仅使用短期记忆
+	// 注入顺序：画像 -> 文档 -> 语义记忆 -> 摘要
+	var sections []*contextSection
+	profileSection, err := s.loadLongTermMemory(turn)
+	if err != nil {
+		log.Warnf("load long term memory error, user_id:%s, err:%v", req.UserID, err)
+	} else if profileSection != nil {
+		sections = append(sections, profileSection)
+	}
+
+	documents, err := s.retrieveDocuments(ctx, turn)
+	if err != nil {
+		log.Warnf("retrieve documents error, user_id:%s, session_id:%s, err:%v", req.UserID, req.SessionID, err)
+		documents = nil
+	} else if section := documentSection(documents); section != nil {
+		sections = append(sections, section)
+	}
+
+	semanticSection, err := s.retrieveSemanticMemory(ctx, turn, history)
+	if err != nil {
+		log.Warnf("retrieve semantic memory error, user_id:%s, session_id:%s, err:%v", req.UserID, req.SessionID, err)
+	} else if semanticSection != nil {
+		sections = append(sections, semanticSection)
+	}
+
+	if section := conversationSummarySection(summary); section != nil {
+		sections = append(sections, section)
+	}
+
+	turn.messages = s.assemblePrompt(ctx, turn, systemMessages, sections, history, documents)
+	return turn, nil
+}
+
+// loadSessionMemory 加载 (user_id, session_id) 最近 SessionMemoryLimit 条消息作为短期记忆
+// 启用摘要时改为加载摘要之后尚未折叠的全部消息（压缩会将其控制在阈值附近），
+// 早期消息由摘要代替，避免窗口与摘要之间出现断档
+func (s *Service) loadSessionMemory(messageRepo repository.ChatMessageRepository, req *model.ChatRequest, opts *memoryOptions, summary *entity.ChatSummary) ([]*entity.ChatMessage, error) {
+	if !opts.EnableSessionMemory || opts.SessionMemoryLimit <= 0 {
+		return nil, nil
+	}
+
+	var afterID int64
+	limit := opts.SessionMemoryLimit
+	if s.summaryEnabled(opts) {
+		// 异步压缩可能滞后几轮，额外留出一个窗口的余量
+		limit = opts.CompressThreshold + opts.SessionMemoryLimit
+		if summary != nil {
+			afterID = summary.CoveredUntilMsgID
+		}
+	}
+
+	history, err := messageRepo.ListRecent(req.UserID, req.SessionID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session memory: %w", err)
+	}
+
+	return history, nil
+}
+
+// saveTurn 在同一事务中写入本轮请求消息和助手回复并刷新会话信息，返回全部落库记录（最后一条为助手回复）
+func (s *Service) saveTurn(turn *chatTurn, reply string, truncated bool) ([]*entity.ChatMessage, error) {
+	if err := turn.session.Begin(); err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	saved := make([]*entity.ChatMessage, 0, len(turn.turnMessages)+1)
+	for _, msg := range turn.turnMessages {
+		record, err := turn.messageRepo.Create(&model.CreateChatMessageCondition{
+			UserID:    turn.req.UserID,
+			SessionID: turn.req.SessionID,
+			Role:      msg.Role,
+			Content:   msg.Content,
+		})
+		if err != nil {
+			_ = turn.session.Rollback()
+			return nil, err
+		}
+		saved = append(saved, record)
+	}
+
+	assistantMessage, err := turn.messageRepo.Create(&model.CreateChatMessageCondition{
+		UserID:    turn.req.UserID,
+		SessionID: turn.req.SessionID,
+		Role:      openai.ChatMessageRoleAssistant,
+		Content:   reply,
+		Truncated: truncated,
+	})
+	if err != nil {
+		_ = turn.session.Rollback()
+		return nil, err
+	}
+	saved = append(saved, assistantMessage)
+
+	if err := s.touchSession(turn, saved); err != nil {
+		_ = turn.session.Rollback()
+		return nil, err
+	}
+
+	if err := turn.session.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return saved, nil
+}
+
+// splitMessages 将请求消息拆分为 system 指令和本轮对话消息
+// system 消息只作用于本次调用，不落库
+func splitMessages(messages []openai.ChatCompletionMessage) (system, turn []openai.ChatCompletionMessage) {
+	for _, msg := range messages {
+		if msg.Role == openai.ChatMessageRoleSystem {
+			system = append(system, msg)
+			continue
+		}
+		turn = append(turn, msg)
+	}
+	return system, turn
+}
+
+// buildMessages 按 system -> 记忆上下文 -> 历史消息 -> 本轮消息 的顺序拼装上下文
+// contextPrompts 为检索得到的记忆提示词，以 system 消息注入
+func buildMessages(system []openai.ChatCompletionMessage, contextPrompts []string, history []*entity.ChatMessage, turn []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	messages := make([]openai.ChatCompletionMessage, 0, len(system)+len(contextPrompts)+len(history)+len(turn))
+	messages = append(messages, system...)
+	for _, prompt := range contextPrompts {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: prompt,
+		})
+	}
+	for _, h := range history {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    h.Role,
+			Content: h.Content,
+		})
+	}
+	messages = append(messages, turn...)
+	return messages
 }
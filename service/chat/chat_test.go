@@ -0,0 +1,101 @@
+package chat
+
+import (
+	"ai_task/model"
+	"ai_task/pkg/clients/llm"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chatRequest 单条用户消息的聊天请求
+func chatRequest(content string) *model.ChatRequest {
+	return &model.ChatRequest{
+		UserID:    "user_1",
+		SessionID: "session_1",
+		Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: content}},
+	}
+}
+
+// contents 模型请求中各条消息的内容
+func contents(messages []openai.ChatCompletionMessage) []string {
+	result := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		result = append(result, msg.Content)
+	}
+	return result
+}
+
+func TestChatPersistsTurnAndLoadsHistory(t *testing.T) {
+	chatModel := llm.NewScriptedModel(llm.Reply("好的，用 LRU"), llm.Reply("容量 1000"))
+	service, store := newMemoryService(chatModel)
+	ctx := context.Background()
+	disabled := false
+	options := &model.MemoryContextOptionsRequest{EnableSummary: &disabled}
+
+	resp, modelErr := service.Chat(ctx, chatRequest("实现一个缓存"), options)
+	require.Nil(t, modelErr)
+	assert.Equal(t, "好的，用 LRU", resp.Message)
+
+	// 本轮 user/assistant 消息落库，首条用户消息作为会话标题
+	saved := store.sessionMessages("user_1", "session_1")
+	require.Len(t, saved, 2)
+	assert.Equal(t, openai.ChatMessageRoleUser, saved[0].Role)
+	assert.Equal(t, openai.ChatMessageRoleAssistant, saved[1].Role)
+	assert.Equal(t, saved[1].ID, resp.MessageID)
+	record, err := (&memorySessionRepository{store: store}).Get("user_1", "session_1")
+	require.NoError(t, err)
+	assert.Equal(t, "实现一个缓存", record.Title)
+	assert.Equal(t, 2, record.MessageCount)
+
+	// 下一轮以历史消息作为短期记忆
+	_, modelErr = service.Chat(ctx, chatRequest("容量多大"), options)
+	require.Nil(t, modelErr)
+	calls := chatModel.Calls()
+	require.Len(t, calls, 2)
+	assert.Subset(t, contents(calls[1]), []string{"实现一个缓存", "好的，用 LRU", "容量多大"})
+	assert.Len(t, store.sessionMessages("user_1", "session_1"), 4)
+	assert.Equal(t, 4, record.MessageCount)
+}
+
+func TestChatReplacesSummarizedHistory(t *testing.T) {
+	chatModel := llm.NewScriptedModel(llm.Reply("1000 条"))
+	service, store := newMemoryService(chatModel)
+	ctx := context.Background()
+
+	messages := store.addMessages("user_1", "session_1", "实现一个缓存", "好的", "用什么淘汰策略", "LRU")
+	require.NoError(t, (&memorySummaryRepository{store: store}).Upsert(&model.UpsertChatSummaryCondition{
+		UserID: "user_1", SessionID: "session_1", Content: "用户要实现一个缓存", CoveredUntilMsgID: messages[1].ID, MessageCount: 2,
+	}))
+
+	enabled, threshold := true, 20
+	_, modelErr := service.Chat(ctx, chatRequest("容量多大"), &model.MemoryContextOptionsRequest{EnableSummary: &enabled, CompressThreshold: &threshold})
+	require.Nil(t, modelErr)
+
+	// 摘要覆盖的消息由摘要代替，之后的消息仍作为历史
+	calls := chatModel.Calls()
+	require.Len(t, calls, 1)
+	sent := contents(calls[0])
+	assert.NotContains(t, sent, "实现一个缓存")
+	assert.Subset(t, sent, []string{"用什么淘汰策略", "LRU", "容量多大"})
+	assert.True(t, slices.ContainsFunc(calls[0], func(msg openai.ChatCompletionMessage) bool {
+		return msg.Role == openai.ChatMessageRoleSystem && strings.Contains(msg.Content, "用户要实现一个缓存")
+	}), "summary should be injected as a system message")
+}
+
+func TestChatRejectsMissingRequest(t *testing.T) {
+	service, _ := newMemoryService(llm.NewScriptedModel())
+
+	_, modelErr := service.Chat(context.Background(), nil, nil)
+	require.NotNil(t, modelErr)
+	assert.Equal(t, model.ErrorParams, modelErr.Code)
+
+	_, modelErr = service.Chat(context.Background(), &model.ChatRequest{UserID: "user_1"}, nil)
+	require.NotNil(t, modelErr)
+	assert.Equal(t, model.ErrorParams, modelErr.Code)
+}
@@ -0,0 +1,139 @@
+package chat
+
+import (
+	"ai_task/model"
+	"ai_task/pkg/memory"
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/retrieval"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// 配置缺失时的兜底默认值，与 config.yaml 中 documents.* 保持一致
+const (
+	defaultDocumentSearchLimit     = 5
+	defaultDocumentSearchThreshold = 0.5
+	citationSnippetRunes           = 120
+)
+
+// documentSearchLimit 每轮引用的文档分块条数，检索器不可用时为 0
+func (s *Service) documentSearchLimit() int {
+	if s.documentRetriever == nil {
+		return 0
+	}
+	return s.settings.documentSearchLimit
+}
+
+// retrieveDocuments 以本轮用户输入混合检索本人文档及请求指定命名空间下的文档分块，无结果时返回 nil
+func (s *Service) retrieveDocuments(ctx context.Context, turn *chatTurn) ([]*retrieval.Result, error) {
+	limit := s.documentSearchLimit()
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	vector, err := s.queryEmbedding(ctx, turn)
+	if err != nil || vector == nil {
+		return nil, err
+	}
+
+	return s.documentRetriever.Retrieve(ctx, &retrieval.Query{
+		Text:       lastUserContent(turn.turnMessages),
+		Embedding:  vector,
+		UserID:     turn.req.UserID,
+		Namespaces: normalizeNamespaces(turn.req.Namespaces),
+		Limit:      limit,
+		Threshold:  s.settings.documentSearchThreshold,
+	})
+}
+
+// documentSection 将文档检索结果转换为带 [n] 编号的参考资料上下文
+func documentSection(results []*retrieval.Result) *contextSection {
+	if len(results) == 0 {
+		return nil
+	}
+
+	items := make([]memory.PromptItem, 0, len(results))
+	for i, r := range results {
+		items = append(items, memory.PromptItem{
+			Key:  fmt.Sprintf("doc:%d#%d", r.DocID, r.ChunkIdx),
+			Text: fmt.Sprintf("[%d] 《%s》\n%s", i+1, r.Title, r.Content),
+		})
+	}
+
+	return &contextSection{
+		name:      memory.SectionDocuments,
+		prompt:    prompt.ChatDocumentContext,
+		separator: "\n\n",
+		items:     items,
+	}
+}
+
+// buildCitations 生成与参考资料编号对应的引用列表
+func buildCitations(results []*retrieval.Result) []model.Citation {
+	if len(results) == 0 {
+		return nil
+	}
+
+	citations := make([]model.Citation, 0, len(results))
+	for i, r := range results {
+		citations = append(citations, model.Citation{
+			Index:      i + 1,
+			DocID:      r.DocID,
+			Title:      r.Title,
+			ChunkID:    r.ID,
+			ChunkIdx:   r.ChunkIdx,
+			Similarity: r.Similarity,
+			Snippet:    snippet(r.Content, citationSnippetRunes),
+		})
+	}
+	return citations
+}
+
+// queryEmbedding 本轮检索使用的查询向量，语义记忆与文档检索共用，每轮只计算一次
+// 本轮没有用户输入时返回 nil
+func (s *Service) queryEmbedding(ctx context.Context, turn *chatTurn) ([]float64, error) {
+	if turn.queryVector != nil {
+		return turn.queryVector, nil
+	}
+
+	query := lastUserContent(turn.turnMessages)
+	if query == "" {
+		return nil, nil
+	}
+
+	vector, err := s.embeddingClient.GetTextEmbedding(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	turn.queryVector = vector
+	return vector, nil
+}
+
+// normalizeNamespaces 去除空值和重复的命名空间
+func normalizeNamespaces(namespaces []string) []string {
+	seen := make(map[string]struct{}, len(namespaces))
+	result := make([]string, 0, len(namespaces))
+	for _, ns := range namespaces {
+		ns = strings.TrimSpace(ns)
+		if ns == "" {
+			continue
+		}
+		if _, ok := seen[ns]; ok {
+			continue
+		}
+		seen[ns] = struct{}{}
+		result = append(result, ns)
+	}
+	return result
+}
+
+// snippet 截取前 n 个字符作为引用摘录
+func snippet(content string, n int) string {
+	runes := []rune(strings.TrimSpace(content))
+	if len(runes) <= n {
+		return string(runes)
+	}
+	return string(runes[:n]) + "..."
+}
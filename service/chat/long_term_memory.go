@@ -0,0 +1,157 @@
+package chat
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+	"ai_task/pkg/memory"
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/usage"
+	"ai_task/repository"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	log "github.com/sirupsen/logrus"
+)
+
+// profileMinConfidence 注入对话的画像属性最低置信度，过低的推测信息不干扰回答
+const profileMinConfidence float32 = 0.5
+
+// loadLongTermMemory 读取用户画像作为长期记忆上下文，无可用属性时返回 nil
+func (s *Service) loadLongTermMemory(turn *chatTurn) (*contextSection, error) {
+	profileRepo, err := s.repositoryFactory.NewUserProfileRepository(turn.session)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := turn.req.UserID
+	profiles, err := profileRepo.List(&model.GetUserProfileCondition{UserID: &userID})
+	if err != nil {
+		return nil, err
+	}
+
+	return longTermMemorySection(profiles), nil
+}
+
+// extractLongTermMemory 从本轮对话中提取关键事实并合并到用户画像
+// 在请求结束后异步执行，失败只记录日志
+func (s *Service) extractLongTermMemory(saved []*entity.ChatMessage, opts *memoryOptions) {
+	if !opts.EnableAutoExtract || s.summarizer == nil || len(saved) == 0 {
+		return
+	}
+
+	var sourceMsgID *int64
+	messages := make([]openai.ChatCompletionMessage, 0, len(saved))
+	for _, msg := range saved {
+		if msg.Truncated || strings.TrimSpace(msg.Content) == "" {
+			continue
+		}
+		if msg.Role == openai.ChatMessageRoleUser {
+			id := msg.ID
+			sourceMsgID = &id
+		}
+		messages = append(messages, openai.ChatCompletionMessage{Role: msg.Role, Content: msg.Content})
+	}
+	// 事实以用户发言为准，没有用户消息时不提取
+	if sourceMsgID == nil {
+		return
+	}
+
+	userID, sessionID := saved[0].UserID, saved[0].SessionID
+
+	go func() {
+		ctx := usage.WithTags(context.Background(), usage.Tags{UserID: userID, SessionID: sessionID})
+
+		facts, err := s.summarizer.ExtractKeyFacts(ctx, messages)
+		if err != nil {
+			log.Errorf("extract long term memory error, user_id:%s, err:%v", userID, err)
+			return
+		}
+		if len(facts) == 0 {
+			return
+		}
+
+		session := s.repositoryFactory.NewSession(ctx)
+		defer func() { _ = session.Close() }()
+
+		profileRepo, err := s.repositoryFactory.NewUserProfileRepository(session)
+		if err != nil {
+			log.Errorf("extract long term memory new repository error: %v", err)
+			return
+		}
+
+		for _, fact := range facts {
+			if err := mergeProfileFact(profileRepo, userID, fact, sourceMsgID); err != nil {
+				log.Errorf("merge profile fact error, user_id:%s, key:%s, err:%v", userID, fact.Key, err)
+			}
+		}
+	}()
+}
+
+// mergeProfileFact 将单条事实与已有画像值合并后写回
+func mergeProfileFact(profileRepo repository.UserProfileRepository, userID string, fact memory.KeyFact, sourceMsgID *int64) error {
+	record, err := profileRepo.Get(userID, fact.Key)
+	if err != nil {
+		return err
+	}
+
+	var existing *memory.ProfileValue
+	if record != nil {
+		existing = &memory.ProfileValue{
+			Value:       record.Value,
+			Confidence:  record.Confidence,
+			SourceMsgID: record.SourceMsgID,
+			Meta:        memory.ParseProfileMeta(record.Meta),
+		}
+	}
+
+	merged := memory.MergeFact(existing, fact, sourceMsgID, time.Now())
+	meta := merged.Meta.String()
+
+	return profileRepo.Upsert(&model.UpsertUserProfileCondition{
+		UserID:      userID,
+		Key:         fact.Key,
+		Value:       merged.Value,
+		Confidence:  merged.Confidence,
+		SourceMsgID: merged.SourceMsgID,
+		Meta:        &meta,
+	})
+}
+
+// longTermMemorySection 按置信度从高到低输出达标的画像属性，预算不足时先丢弃置信度低的
+func longTermMemorySection(profiles []*entity.UserProfile) *contextSection {
+	filtered := make([]*entity.UserProfile, 0, len(profiles))
+	for _, p := range profiles {
+		if p.Confidence >= profileMinConfidence && strings.TrimSpace(p.Value) != "" {
+			filtered = append(filtered, p)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if filtered[i].Confidence != filtered[j].Confidence {
+			return filtered[i].Confidence > filtered[j].Confidence
+		}
+		return filtered[i].Key < filtered[j].Key
+	})
+
+	items := make([]memory.PromptItem, 0, len(filtered))
+	for _, p := range filtered {
+		items = append(items, memory.PromptItem{
+			Key:  "profile:" + p.Key,
+			Text: fmt.Sprintf("- %s: %s", p.Key, p.Value),
+		})
+	}
+
+	return &contextSection{
+		name:      memory.SectionProfile,
+		prompt:    prompt.ChatLongTermMemory,
+		separator: "\n",
+		items:     items,
+	}
+}
@@ -0,0 +1,173 @@
+package chat
+
+import (
+	"ai_task/config"
+	"ai_task/model"
+	"ai_task/pkg/memory"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// 配置缺失时的兜底默认值，与 config.yaml 中 memory.* 保持一致
+const (
+	defaultSessionMemoryLimit  = 10
+	defaultSemanticMemoryLimit = 5
+	defaultSemanticThreshold   = 0.7
+	defaultCompressThreshold   = 20
+	defaultChunkMaxSize        = 1000
+	defaultChunkOverlap        = 100
+	defaultChunkMinSize        = 200
+	defaultChunkStrategy       = "paragraph"
+)
+
+// memoryOptions 合并配置文件与请求参数后的记忆选项
+type memoryOptions struct {
+	EnableSessionMemory bool
+	EnableChunking      bool
+	SessionMemoryLimit  int
+	SemanticMemoryLimit int
+	SemanticThreshold   float64
+	CompressThreshold   int
+	EnableSummary       bool
+	EnableAutoExtract   bool
+	ChunkMaxSize        int
+	ChunkOverlap        int
+	ChunkMinSize        int
+	ChunkStrategy       string
+}
+
+// chatSettings 聊天服务的配置项，创建服务时从 config.yaml 读取一次
+type chatSettings struct {
+	memory                  memoryOptions  // 记忆选项默认值，请求参数在其副本上覆盖
+	promptBudget            int            // 提示词 token 预算：模型上下文窗口扣除为回复预留的 maxTokens
+	sectionBudgets          map[string]int // 各段 token 预算
+	trimOrder               []string       // 超出预算时各段的裁剪顺序
+	documentSearchLimit     int            // 每轮引用的文档分块条数
+	documentSearchThreshold float64        // 文档分块的相似度阈值
+}
+
+// defaultChatSettings 兜底默认值，不读取配置
+func defaultChatSettings() chatSettings {
+	return chatSettings{
+		memory: memoryOptions{
+			EnableSessionMemory: true,
+			EnableChunking:      true,
+			SessionMemoryLimit:  defaultSessionMemoryLimit,
+			SemanticMemoryLimit: defaultSemanticMemoryLimit,
+			SemanticThreshold:   defaultSemanticThreshold,
+			CompressThreshold:   defaultCompressThreshold,
+			ChunkMaxSize:        defaultChunkMaxSize,
+			ChunkOverlap:        defaultChunkOverlap,
+			ChunkMinSize:        defaultChunkMinSize,
+			ChunkStrategy:       defaultChunkStrategy,
+		},
+		promptBudget: defaultContextWindow - defaultCompletionTokens,
+		sectionBudgets: map[string]int{
+			memory.SectionProfile:   defaultProfileBudget,
+			memory.SectionDocuments: defaultDocumentsBudget,
+			memory.SectionSemantic:  defaultSemanticBudget,
+			memory.SectionSummary:   defaultSummaryBudget,
+			memory.SectionHistory:   defaultHistoryBudget,
+		},
+		trimOrder:               memory.DefaultTrimOrder,
+		documentSearchLimit:     defaultDocumentSearchLimit,
+		documentSearchThreshold: defaultDocumentSearchThreshold,
+	}
+}
+
+// loadChatSettings 从 config.yaml 的 memory.*、documents.* 与 clients.llmModel.* 读取配置，缺失时使用兜底默认值
+func loadChatSettings() chatSettings {
+	conf := config.GetInstance()
+	settings := defaultChatSettings()
+	settings.memory = memoryOptions{
+		EnableSessionMemory: conf.GetBoolOrDefault(config.MemoryEnableSessionMemory, true),
+		EnableChunking:      conf.GetBoolOrDefault(config.MemoryEnableChunking, true),
+		SessionMemoryLimit:  conf.GetIntOrDefault(config.MemorySessionMemoryLimit, defaultSessionMemoryLimit),
+		SemanticMemoryLimit: conf.GetIntOrDefault(config.MemorySemanticMemoryLimit, defaultSemanticMemoryLimit),
+		SemanticThreshold:   conf.GetFloat64OrDefault(config.MemorySemanticThreshold, defaultSemanticThreshold),
+		CompressThreshold:   conf.GetIntOrDefault(config.MemoryCompressThreshold, defaultCompressThreshold),
+		EnableSummary:       conf.GetBoolOrDefault(config.MemoryEnableSummary, false),
+		EnableAutoExtract:   conf.GetBoolOrDefault(config.MemoryEnableAutoExtract, false),
+		ChunkMaxSize:        conf.GetIntOrDefault(config.MemoryChunkMaxSize, defaultChunkMaxSize),
+		ChunkOverlap:        conf.GetIntOrDefault(config.MemoryChunkOverlap, defaultChunkOverlap),
+		ChunkMinSize:        conf.GetIntOrDefault(config.MemoryChunkMinSize, defaultChunkMinSize),
+		ChunkStrategy:       conf.GetStringOrDefault(config.MemoryChunkStrategy, defaultChunkStrategy),
+	}
+
+	window := conf.GetIntOrDefault(config.ClientChatModelContextWindow, defaultContextWindow)
+	settings.promptBudget = window - conf.GetIntOrDefault(config.ClientChatModelMaxTokens, defaultCompletionTokens)
+	if settings.promptBudget <= 0 {
+		log.Warnf("clients.llmModel.maxTokens exceeds contextWindow %d, use the whole window as prompt budget", window)
+		settings.promptBudget = window
+	}
+	settings.sectionBudgets = map[string]int{
+		memory.SectionProfile:   conf.GetIntOrDefault(config.MemoryPromptBudgetProfile, defaultProfileBudget),
+		memory.SectionDocuments: conf.GetIntOrDefault(config.MemoryPromptBudgetDocuments, defaultDocumentsBudget),
+		memory.SectionSemantic:  conf.GetIntOrDefault(config.MemoryPromptBudgetSemantic, defaultSemanticBudget),
+		memory.SectionSummary:   conf.GetIntOrDefault(config.MemoryPromptBudgetSummary, defaultSummaryBudget),
+		memory.SectionHistory:   conf.GetIntOrDefault(config.MemoryPromptBudgetHistory, defaultHistoryBudget),
+	}
+	settings.trimOrder = conf.GetStringSliceOrDefault(config.MemoryPromptBudgetTrimOrder, memory.DefaultTrimOrder)
+	settings.documentSearchLimit = conf.GetIntOrDefault(config.DocumentsSearchLimit, defaultDocumentSearchLimit)
+	settings.documentSearchThreshold = conf.GetFloat64OrDefault(config.DocumentsSearchThreshold, defaultDocumentSearchThreshold)
+	return settings
+}
+
+// resolveMemoryOptions 以配置为默认值，用请求中显式传入的字段覆盖
+func (s *Service) resolveMemoryOptions(req *model.MemoryContextOptionsRequest) *memoryOptions {
+	defaults := s.settings.memory
+	opts := &defaults
+	if req == nil {
+		return opts
+	}
+
+	if req.EnableSessionMemory != nil {
+		opts.EnableSessionMemory = *req.EnableSessionMemory
+	}
+	if req.EnableChunking != nil {
+		opts.EnableChunking = *req.EnableChunking
+	}
+	if req.SessionMemoryLimit != nil && *req.SessionMemoryLimit >= 0 {
+		opts.SessionMemoryLimit = *req.SessionMemoryLimit
+	}
+	if req.SemanticMemoryLimit != nil && *req.SemanticMemoryLimit >= 0 {
+		opts.SemanticMemoryLimit = *req.SemanticMemoryLimit
+	}
+	if req.SemanticThreshold != nil {
+		opts.SemanticThreshold = *req.SemanticThreshold
+	}
+	if req.CompressThreshold != nil && *req.CompressThreshold > 0 {
+		opts.CompressThreshold = *req.CompressThreshold
+	}
+	if req.EnableSummary != nil {
+		opts.EnableSummary = *req.EnableSummary
+	}
+	if req.EnableAutoExtract != nil {
+		opts.EnableAutoExtract = *req.EnableAutoExtract
+	}
+	// 分块参数：0 或空值表示沿用默认值
+	if req.ChunkMaxSize != nil && *req.ChunkMaxSize > 0 {
+		opts.ChunkMaxSize = *req.ChunkMaxSize
+	}
+	if req.ChunkOverlap != nil && *req.ChunkOverlap > 0 {
+		opts.ChunkOverlap = *req.ChunkOverlap
+	}
+	if req.ChunkMinSize != nil && *req.ChunkMinSize > 0 {
+		opts.ChunkMinSize = *req.ChunkMinSize
+	}
+	if req.ChunkStrategy != nil && *req.ChunkStrategy != "" {
+		opts.ChunkStrategy = *req.ChunkStrategy
+	}
+
+	return opts
+}
+
+// chunkConfig 转换为分块器配置
+func (o *memoryOptions) chunkConfig() memory.ChunkConfig {
+	return memory.ChunkConfig{
+		MaxSize:  o.ChunkMaxSize,
+		Overlap:  o.ChunkOverlap,
+		MinSize:  o.ChunkMinSize,
+		Strategy: o.ChunkStrategy,
+	}
+}
@@ -0,0 +1,119 @@
+package chat
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+	"ai_task/pkg/memory"
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/retrieval"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+	log "github.com/sirupsen/logrus"
+)
+
+// 配置缺失时的兜底默认值，与 config.yaml 中 clients.llmModel.* 和 memory.prompt_budget.* 保持一致
+const (
+	defaultContextWindow    = 32768
+	defaultCompletionTokens = 1500
+	defaultProfileBudget    = 500
+	defaultDocumentsBudget  = 3000
+	defaultSemanticBudget   = 1500
+	defaultSummaryBudget    = 1000
+	defaultHistoryBudget    = 6000
+)
+
+// contextSection 一段记忆上下文：参与预算裁剪的条目及渲染为 system 提示词的方式
+type contextSection struct {
+	name      string
+	prompt    string // 提示词模板名，Content 为保留条目拼接后的文本
+	separator string
+	items     []memory.PromptItem
+}
+
+// render 按 ctx 中的语言将保留的条目渲染为提示词
+func (c *contextSection) render(ctx context.Context, kept []memory.PromptItem) prompt.Rendered {
+	texts := make([]string, 0, len(kept))
+	for _, item := range kept {
+		texts = append(texts, item.Text)
+	}
+	return prompt.MustRender(ctx, c.prompt, map[string]any{"Content": strings.Join(texts, c.separator)})
+}
+
+// assemblePrompt 按 token 预算组装本轮模型输入
+// sections 按注入顺序排列；超出预算时按配置的裁剪顺序丢弃低优先级条目，历史消息从最早的开始丢弃，
+// system 指令和本轮消息只会被截断。被裁剪的内容记录在 turn.report 中
+func (s *Service) assemblePrompt(ctx context.Context, turn *chatTurn, system []openai.ChatCompletionMessage, sections []*contextSection, history []*entity.ChatMessage, documents []*retrieval.Result) []openai.ChatCompletionMessage {
+	budgets := s.settings.sectionBudgets
+
+	promptSections := make([]*memory.PromptSection, 0, len(sections)+1)
+	for _, section := range sections {
+		promptSections = append(promptSections, &memory.PromptSection{
+			Name:     section.name,
+			Budget:   budgets[section.name],
+			Overhead: memory.MessageTokenOverhead + memory.CountTokens(section.render(ctx, nil).Text),
+			Items:    section.items,
+		})
+	}
+
+	// 历史消息越新越重要，倒序传入使裁剪从最早的消息开始
+	historyItems := make([]memory.PromptItem, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		historyItems = append(historyItems, memory.PromptItem{
+			Key:  fmt.Sprintf("message:%d", history[i].ID),
+			Text: history[i].Content,
+		})
+	}
+	promptSections = append(promptSections, &memory.PromptSection{
+		Name:         memory.SectionHistory,
+		Budget:       budgets[memory.SectionHistory],
+		ItemOverhead: memory.MessageTokenOverhead,
+		Items:        historyItems,
+	})
+
+	required := make([]openai.ChatCompletionMessage, 0, len(system)+len(turn.turnMessages))
+	required = append(required, system...)
+	required = append(required, turn.turnMessages...)
+
+	assembly := memory.NewPromptAssembler(s.settings.promptBudget, s.settings.trimOrder).Assemble(required, promptSections)
+
+	// 条目只会从末尾裁剪，保留的总是原列表的前缀，文档编号与引用保持一致
+	var contextPrompts []string
+	for _, section := range sections {
+		if kept := assembly.Sections[section.name]; len(kept) > 0 {
+			rendered := section.render(ctx, kept)
+			turn.prompts = append(turn.prompts, rendered)
+			contextPrompts = append(contextPrompts, rendered.Text)
+		}
+	}
+	turn.citations = buildCitations(documents[:len(assembly.Sections[memory.SectionDocuments])])
+	keptHistory := history[len(history)-len(assembly.Sections[memory.SectionHistory]):]
+
+	if len(assembly.Dropped) > 0 {
+		turn.report = toContextReport(assembly)
+		log.Infof("prompt trimmed to fit token budget, user_id:%s, session_id:%s, tokens:%d, budget:%d, dropped:%d",
+			turn.req.UserID, turn.req.SessionID, assembly.Tokens, assembly.Budget, len(assembly.Dropped))
+	}
+
+	return buildMessages(assembly.Required[:len(system)], contextPrompts, keptHistory, assembly.Required[len(system):])
+}
+
+// toContextReport 转换为响应中的上下文裁剪报告
+func toContextReport(assembly *memory.Assembly) *model.ContextReport {
+	dropped := make([]model.ContextDroppedItem, 0, len(assembly.Dropped))
+	for _, d := range assembly.Dropped {
+		dropped = append(dropped, model.ContextDroppedItem{
+			Section: d.Section,
+			Key:     d.Key,
+			Tokens:  d.Tokens,
+			Reason:  d.Reason,
+		})
+	}
+	return &model.ContextReport{
+		Tokens:  assembly.Tokens,
+		Budget:  assembly.Budget,
+		Dropped: dropped,
+	}
+}
@@ -0,0 +1,390 @@
+package chat
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+	"ai_task/pkg/clients/llm"
+	"ai_task/pkg/memory"
+	"ai_task/repository"
+	"ai_task/repository/factory"
+	"ai_task/repository/interfaces"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// newMemoryService 创建使用内存仓库与脚本模型的聊天服务，不启用语义记忆与文档检索
+func newMemoryService(model *llm.ScriptedModel) (*Service, *memoryStore) {
+	store := newMemoryStore()
+	return &Service{
+		repositoryFactory: &memoryFactory{store: store},
+		llmClient:         model,
+		summarizer:        memory.NewSummarizer(llm.Single(model)),
+		settings:          defaultChatSettings(),
+	}, store
+}
+
+// memoryStore 内存中的聊天数据，供假仓库共享；事务不做隔离，Begin/Commit/Rollback 只计数
+type memoryStore struct {
+	mu        sync.Mutex
+	nextID    int64
+	messages  []*entity.ChatMessage
+	summaries map[string]*entity.ChatSummary
+	sessions  map[string]*entity.ChatSession
+	chunks    map[string]int64 // 每个会话的语义记忆分块条数
+	commits   int
+	rollbacks int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		summaries: make(map[string]*entity.ChatSummary),
+		sessions:  make(map[string]*entity.ChatSession),
+		chunks:    make(map[string]int64),
+	}
+}
+
+func sessionKey(userID, sessionID string) string {
+	return userID + "\x00" + sessionID
+}
+
+// addMessages 直接写入消息，返回带 ID 的记录
+func (s *memoryStore) addMessages(userID, sessionID string, contents ...string) []*entity.ChatMessage {
+	repo := &memoryMessageRepository{store: s}
+	records := make([]*entity.ChatMessage, 0, len(contents))
+	for i, content := range contents {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		record, _ := repo.Create(&model.CreateChatMessageCondition{UserID: userID, SessionID: sessionID, Role: role, Content: content})
+		records = append(records, record)
+	}
+	return records
+}
+
+// sessionMessages 会话的全部消息，按 ID 升序
+func (s *memoryStore) sessionMessages(userID, sessionID string) []*entity.ChatMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []*entity.ChatMessage
+	for _, msg := range s.messages {
+		if msg.UserID == userID && msg.SessionID == sessionID {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
+
+func (s *memoryStore) summary(userID, sessionID string) *entity.ChatSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.summaries[sessionKey(userID, sessionID)]
+}
+
+// memoryFactory 使用 memoryStore 的仓库工厂，聊天服务用不到的仓库未实现
+type memoryFactory struct {
+	factory.Factory
+	store *memoryStore
+}
+
+func (f *memoryFactory) NewSession(ctx context.Context) interfaces.Session {
+	return &memorySession{store: f.store}
+}
+
+func (f *memoryFactory) NewChatMessageRepository(session interfaces.Session) (repository.ChatMessageRepository, error) {
+	return &memoryMessageRepository{store: f.store}, nil
+}
+
+func (f *memoryFactory) NewChatSummaryRepository(session interfaces.Session) (repository.ChatSummaryRepository, error) {
+	return &memorySummaryRepository{store: f.store}, nil
+}
+
+func (f *memoryFactory) NewChatSessionRepository(session interfaces.Session) (repository.ChatSessionRepository, error) {
+	return &memorySessionRepository{store: f.store}, nil
+}
+
+func (f *memoryFactory) NewMemoryChunkRepository(session interfaces.Session) (repository.MemoryChunkRepository, error) {
+	return &memoryChunkRepository{store: f.store}, nil
+}
+
+func (f *memoryFactory) NewUserProfileRepository(session interfaces.Session) (repository.UserProfileRepository, error) {
+	return &memoryProfileRepository{}, nil
+}
+
+type memorySession struct {
+	store *memoryStore
+}
+
+func (s *memorySession) Begin() error { return nil }
+func (s *memorySession) Close() error { return nil }
+
+func (s *memorySession) Commit() error {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	s.store.commits++
+	return nil
+}
+
+func (s *memorySession) Rollback() error {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	s.store.rollbacks++
+	return nil
+}
+
+type memoryMessageRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryMessageRepository) Create(req *model.CreateChatMessageCondition) (*entity.ChatMessage, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.nextID++
+	record := &entity.ChatMessage{
+		ID:        r.store.nextID,
+		UserID:    req.UserID,
+		SessionID: req.SessionID,
+		Role:      req.Role,
+		Content:   req.Content,
+		Truncated: req.Truncated,
+		CreatedAt: time.Now(),
+	}
+	r.store.messages = append(r.store.messages, record)
+	return record, nil
+}
+
+func (r *memoryMessageRepository) ListRecent(userID, sessionID string, afterID int64, limit int) ([]*entity.ChatMessage, error) {
+	messages, err := r.List(&model.GetChatMessagesCondition{
+		UserID:    &userID,
+		SessionID: &sessionID,
+		AfterID:   &afterID,
+		Order:     &model.Order{OrderBy: entity.ChatMessageFieldID, OrderAsc: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+	return messages, nil
+}
+
+// List 未指定升序时按 ID 倒序
+func (r *memoryMessageRepository) List(condition *model.GetChatMessagesCondition) ([]*entity.ChatMessage, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var result []*entity.ChatMessage
+	for _, msg := range r.store.messages {
+		if matchMessage(msg, condition) {
+			result = append(result, msg)
+		}
+	}
+	if condition.Order == nil || !condition.Order.OrderAsc {
+		slices.Reverse(result)
+	}
+	if pager := condition.Pager; pager != nil {
+		result = result[min(pager.Offset, len(result)):]
+		if pager.Limit > 0 && len(result) > pager.Limit {
+			result = result[:pager.Limit]
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryMessageRepository) Count(condition *model.GetChatMessagesCondition) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var count int64
+	for _, msg := range r.store.messages {
+		if matchMessage(msg, condition) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryMessageRepository) DeleteBySession(userID, sessionID string) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	before := len(r.store.messages)
+	r.store.messages = slices.DeleteFunc(r.store.messages, func(msg *entity.ChatMessage) bool {
+		return msg.UserID == userID && msg.SessionID == sessionID
+	})
+	return int64(before - len(r.store.messages)), nil
+}
+
+func matchMessage(msg *entity.ChatMessage, condition *model.GetChatMessagesCondition) bool {
+	switch {
+	case condition.UserID != nil && msg.UserID != *condition.UserID,
+		condition.SessionID != nil && msg.SessionID != *condition.SessionID,
+		condition.AfterID != nil && msg.ID <= *condition.AfterID,
+		condition.UntilID != nil && msg.ID > *condition.UntilID:
+		return false
+	}
+	return true
+}
+
+type memorySummaryRepository struct {
+	store *memoryStore
+}
+
+func (r *memorySummaryRepository) Get(userID, sessionID string) (*entity.ChatSummary, error) {
+	return r.store.summary(userID, sessionID), nil
+}
+
+func (r *memorySummaryRepository) Upsert(req *model.UpsertChatSummaryCondition) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key := sessionKey(req.UserID, req.SessionID)
+	summary, ok := r.store.summaries[key]
+	if !ok {
+		r.store.nextID++
+		summary = &entity.ChatSummary{ID: r.store.nextID, UserID: req.UserID, SessionID: req.SessionID, CreatedAt: time.Now()}
+		r.store.summaries[key] = summary
+	}
+	summary.Content = req.Content
+	summary.CoveredUntilMsgID = req.CoveredUntilMsgID
+	summary.MessageCount = req.MessageCount
+	summary.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *memorySummaryRepository) Delete(userID, sessionID string) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key := sessionKey(userID, sessionID)
+	_, ok := r.store.summaries[key]
+	delete(r.store.summaries, key)
+	return ok, nil
+}
+
+type memorySessionRepository struct {
+	store *memoryStore
+}
+
+func (r *memorySessionRepository) Create(req *model.CreateChatSessionCondition) (*entity.ChatSession, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.nextID++
+	now := time.Now()
+	record := &entity.ChatSession{
+		ID:                r.store.nextID,
+		UserID:            req.UserID,
+		SessionID:         req.SessionID,
+		Title:             req.Title,
+		TitleSource:       req.TitleSource,
+		MessageCount:      req.MessageCount,
+		ForkedFromSession: req.ForkedFromSession,
+		ForkedFromMsgID:   req.ForkedFromMsgID,
+		LastActiveAt:      now,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	r.store.sessions[sessionKey(req.UserID, req.SessionID)] = record
+	return record, nil
+}
+
+func (r *memorySessionRepository) Touch(req *model.TouchChatSessionCondition) error {
+	r.store.mu.Lock()
+	record, ok := r.store.sessions[sessionKey(req.UserID, req.SessionID)]
+	r.store.mu.Unlock()
+	if !ok {
+		_, err := r.Create(&model.CreateChatSessionCondition{
+			UserID:       req.UserID,
+			SessionID:    req.SessionID,
+			Title:        req.Title,
+			TitleSource:  entity.ChatSessionTitleAuto,
+			MessageCount: req.MessageCount,
+		})
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	record.MessageCount += req.MessageCount
+	record.LastActiveAt = time.Now()
+	return nil
+}
+
+func (r *memorySessionRepository) Get(userID, sessionID string) (*entity.ChatSession, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return r.store.sessions[sessionKey(userID, sessionID)], nil
+}
+
+func (r *memorySessionRepository) List(condition *model.GetChatSessionsCondition) ([]*entity.ChatSession, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var result []*entity.ChatSession
+	for _, record := range r.store.sessions {
+		if condition.UserID == nil || record.UserID == *condition.UserID {
+			result = append(result, record)
+		}
+	}
+	slices.SortFunc(result, func(a, b *entity.ChatSession) int { return b.LastActiveAt.Compare(a.LastActiveAt) })
+	return result, nil
+}
+
+func (r *memorySessionRepository) Count(condition *model.GetChatSessionsCondition) (int64, error) {
+	result, err := r.List(condition)
+	return int64(len(result)), err
+}
+
+func (r *memorySessionRepository) Update(id int64, req *model.UpdateChatSessionCondition) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, record := range r.store.sessions {
+		if record.ID != id {
+			continue
+		}
+		if req.Title != nil {
+			record.Title = *req.Title
+		}
+		if req.TitleSource != nil {
+			record.TitleSource = *req.TitleSource
+		}
+	}
+	return nil
+}
+
+func (r *memorySessionRepository) Delete(userID, sessionID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	delete(r.store.sessions, sessionKey(userID, sessionID))
+	return nil
+}
+
+// memoryChunkRepository 只记录分块条数，检索未实现
+type memoryChunkRepository struct {
+	repository.MemoryChunkRepository
+	store *memoryStore
+}
+
+func (r *memoryChunkRepository) DeleteBySession(userID, sessionID string) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key := sessionKey(userID, sessionID)
+	count := r.store.chunks[key]
+	delete(r.store.chunks, key)
+	return count, nil
+}
+
+// memoryProfileRepository 没有任何画像
+type memoryProfileRepository struct {
+	repository.UserProfileRepository
+}
+
+func (r *memoryProfileRepository) List(condition *model.GetUserProfileCondition) ([]*entity.UserProfile, error) {
+	return nil, nil
+}
@@ -0,0 +1,173 @@
+package chat
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+	"ai_task/pkg/memory"
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/retrieval"
+	"ai_task/pkg/usage"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+	log "github.com/sirupsen/logrus"
+)
+
+// semanticMemoryEnabled 语义记忆需要 embedding 客户端可用且检索条数大于 0
+func (s *Service) semanticMemoryEnabled(opts *memoryOptions) bool {
+	return s.embeddingClient != nil && opts.SemanticMemoryLimit > 0
+}
+
+// retrieveSemanticMemory 以本轮用户输入为查询，混合检索（向量 + 全文）历史分块，融合后按相似度阈值过滤
+// 已在短期记忆窗口中的消息会被排除，无结果时返回 nil
+func (s *Service) retrieveSemanticMemory(ctx context.Context, turn *chatTurn, history []*entity.ChatMessage) (*contextSection, error) {
+	if !s.semanticMemoryEnabled(turn.opts) || s.memoryRetriever == nil {
+		return nil, nil
+	}
+
+	vector, err := s.queryEmbedding(ctx, turn)
+	if err != nil || vector == nil {
+		return nil, err
+	}
+
+	excludeIDs := make([]int64, 0, len(history))
+	for _, h := range history {
+		excludeIDs = append(excludeIDs, h.ID)
+	}
+
+	results, err := s.memoryRetriever.Retrieve(ctx, &retrieval.Query{
+		Text:              lastUserContent(turn.turnMessages),
+		Embedding:         vector,
+		UserID:            turn.req.UserID,
+		ExcludeMessageIDs: excludeIDs,
+		Limit:             turn.opts.SemanticMemoryLimit,
+		Threshold:         turn.opts.SemanticThreshold,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return semanticMemorySection(results), nil
+}
+
+// indexSemanticMemory 将本轮落库的消息分块、向量化并写入语义记忆
+// 在请求结束后异步执行，失败只记录日志，不影响对话
+func (s *Service) indexSemanticMemory(messages []*entity.ChatMessage, opts *memoryOptions) {
+	if !s.semanticMemoryEnabled(opts) || len(messages) == 0 {
+		return
+	}
+
+	go func() {
+		ctx := usage.WithTags(context.Background(), usage.Tags{UserID: messages[0].UserID, SessionID: messages[0].SessionID})
+
+		reqs := buildMemoryChunks(messages, opts)
+		if len(reqs) == 0 {
+			return
+		}
+
+		texts := make([]string, len(reqs))
+		for i, req := range reqs {
+			texts[i] = req.Content
+		}
+		vectors, err := s.embeddingClient.GetTextEmbeddingBatch(ctx, texts)
+		if err != nil {
+			log.Errorf("index semantic memory embedding error: %v", err)
+			return
+		}
+		for i := range reqs {
+			if i < len(vectors) {
+				reqs[i].Embedding = vectors[i]
+			}
+		}
+
+		session := s.repositoryFactory.NewSession(ctx)
+		defer func() { _ = session.Close() }()
+
+		chunkRepo, err := s.repositoryFactory.NewMemoryChunkRepository(session)
+		if err != nil {
+			log.Errorf("index semantic memory new repository error: %v", err)
+			return
+		}
+		if err := chunkRepo.BatchCreate(reqs); err != nil {
+			log.Errorf("index semantic memory save error: %v", err)
+		}
+	}()
+}
+
+// buildMemoryChunks 按配置的分块策略切分消息；未开启分块时整条消息作为一个分块
+// 截断的回复内容不完整，不进入语义记忆
+func buildMemoryChunks(messages []*entity.ChatMessage, opts *memoryOptions) []*model.CreateMemoryChunkCondition {
+	var chunker memory.ChunkStrategy
+	if opts.EnableChunking {
+		chunker = memory.NewChunker(opts.chunkConfig())
+	}
+
+	reqs := make([]*model.CreateMemoryChunkCondition, 0, len(messages))
+	for _, msg := range messages {
+		content := strings.TrimSpace(msg.Content)
+		if content == "" || msg.Truncated {
+			continue
+		}
+
+		texts := []string{content}
+		if chunker != nil {
+			texts = texts[:0]
+			for _, chunk := range chunker.Chunk(content, opts.ChunkMaxSize, opts.ChunkOverlap) {
+				if text := strings.TrimSpace(chunk.Text); text != "" {
+					texts = append(texts, text)
+				}
+			}
+		}
+
+		for i, text := range texts {
+			reqs = append(reqs, &model.CreateMemoryChunkCondition{
+				UserID:    msg.UserID,
+				SessionID: msg.SessionID,
+				MessageID: msg.ID,
+				Role:      msg.Role,
+				ChunkIdx:  i,
+				Content:   text,
+			})
+		}
+	}
+
+	return reqs
+}
+
+// semanticMemorySection 将检索结果转换为语义记忆上下文，保持检索排序
+func semanticMemorySection(results []*retrieval.Result) *contextSection {
+	if len(results) == 0 {
+		return nil
+	}
+
+	items := make([]memory.PromptItem, 0, len(results))
+	for _, r := range results {
+		role := "用户"
+		if r.Role == openai.ChatMessageRoleAssistant {
+			role = "助手"
+		}
+		items = append(items, memory.PromptItem{
+			Key:  fmt.Sprintf("chunk:%d", r.ID),
+			Text: fmt.Sprintf("- %s: %s", role, r.Content),
+		})
+	}
+
+	return &contextSection{
+		name:      memory.SectionSemantic,
+		prompt:    prompt.ChatSemanticMemory,
+		separator: "\n",
+		items:     items,
+	}
+}
+
+// lastUserContent 取本轮最后一条用户消息作为检索查询
+func lastUserContent(messages []openai.ChatCompletionMessage) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == openai.ChatMessageRoleUser {
+			return strings.TrimSpace(messages[i].Content)
+		}
+	}
+	return ""
+}
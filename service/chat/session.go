@@ -0,0 +1,374 @@
+package chat
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+	"ai_task/repository"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	// sessionTitleRunes 自动生成标题截取首条用户消息的字符数
+	sessionTitleRunes = 30
+	// defaultSessionPageSize 会话和消息列表未指定分页大小时的默认值
+	defaultSessionPageSize = 20
+)
+
+// ListSessions 按最后活跃时间倒序列出用户的会话
+func (s *Service) ListSessions(ctx context.Context, userID string, req *model.ListChatSessionsRequest) ([]*model.ChatSessionResponse, int64, *model.Error) {
+	if userID == "" {
+		return nil, 0, model.NewError(model.ErrorParams, fmt.Errorf("user_id is required"))
+	}
+	if req == nil {
+		req = &model.ListChatSessionsRequest{}
+	}
+
+	session := s.repositoryFactory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	sessionRepo, err := s.repositoryFactory.NewChatSessionRepository(session)
+	if err != nil {
+		return nil, 0, model.NewError(model.ErrorNewRepo, err)
+	}
+
+	condition := &model.GetChatSessionsCondition{UserID: &userID}
+	total, err := sessionRepo.Count(condition)
+	if err != nil {
+		return nil, 0, model.NewError(model.ErrorDB, err)
+	}
+
+	condition.Pager = sessionPager(req.Limit, req.Offset)
+	sessions, err := sessionRepo.List(condition)
+	if err != nil {
+		return nil, 0, model.NewError(model.ErrorDB, err)
+	}
+
+	results := make([]*model.ChatSessionResponse, 0, len(sessions))
+	for _, cs := range sessions {
+		results = append(results, toChatSessionResponse(cs))
+	}
+	return results, total, nil
+}
+
+// ListSessionMessages 按消息先后顺序分页列出会话消息，会话不存在时返回 nil
+func (s *Service) ListSessionMessages(ctx context.Context, userID, sessionID string, req *model.ListChatMessagesRequest) ([]*model.ChatMessageResponse, int64, *model.Error) {
+	if userID == "" || sessionID == "" {
+		return nil, 0, model.NewError(model.ErrorParams, fmt.Errorf("user_id and session_id are required"))
+	}
+	if req == nil {
+		req = &model.ListChatMessagesRequest{}
+	}
+
+	session := s.repositoryFactory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	messageRepo, err := s.repositoryFactory.NewChatMessageRepository(session)
+	if err != nil {
+		return nil, 0, model.NewError(model.ErrorNewRepo, err)
+	}
+
+	condition := &model.GetChatMessagesCondition{UserID: &userID, SessionID: &sessionID}
+	total, err := messageRepo.Count(condition)
+	if err != nil {
+		return nil, 0, model.NewError(model.ErrorDB, err)
+	}
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	condition.Pager = sessionPager(req.Limit, req.Offset)
+	condition.Order = &model.Order{OrderBy: entity.ChatMessageFieldID, OrderAsc: true}
+	messages, err := messageRepo.List(condition)
+	if err != nil {
+		return nil, 0, model.NewError(model.ErrorDB, err)
+	}
+
+	results := make([]*model.ChatMessageResponse, 0, len(messages))
+	for _, msg := range messages {
+		results = append(results, &model.ChatMessageResponse{
+			ID:        msg.ID,
+			Role:      msg.Role,
+			Content:   msg.Content,
+			Truncated: msg.Truncated,
+			CreatedAt: msg.CreatedAt,
+		})
+	}
+	return results, total, nil
+}
+
+// RenameSession 重命名会话，之后不再自动生成标题，会话不存在时返回 nil
+func (s *Service) RenameSession(ctx context.Context, userID, sessionID string, req *model.RenameChatSessionRequest) (*model.ChatSessionResponse, *model.Error) {
+	title := ""
+	if req != nil {
+		title = strings.TrimSpace(req.Title)
+	}
+	if userID == "" || sessionID == "" || title == "" {
+		return nil, model.NewError(model.ErrorParams, fmt.Errorf("user_id, session_id and title are required"))
+	}
+
+	session := s.repositoryFactory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	sessionRepo, err := s.repositoryFactory.NewChatSessionRepository(session)
+	if err != nil {
+		return nil, model.NewError(model.ErrorNewRepo, err)
+	}
+
+	record, err := sessionRepo.Get(userID, sessionID)
+	if err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+	if record == nil {
+		return nil, nil
+	}
+
+	titleSource := entity.ChatSessionTitleManual
+	if err := sessionRepo.Update(record.ID, &model.UpdateChatSessionCondition{Title: &title, TitleSource: &titleSource}); err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+
+	record.Title = title
+	record.TitleSource = titleSource
+	return toChatSessionResponse(record), nil
+}
+
+// ForkSession 从指定消息分叉会话：该消息及之前的消息复制到新会话，原会话不受影响
+// 消息不属于该会话时返回 nil；语义记忆和摘要不复制，新会话后续对话按需重新生成
+func (s *Service) ForkSession(ctx context.Context, userID, sessionID string, req *model.ForkChatSessionRequest) (*model.ChatSessionResponse, *model.Error) {
+	if userID == "" || sessionID == "" || req == nil || req.MessageID <= 0 {
+		return nil, model.NewError(model.ErrorParams, fmt.Errorf("user_id, session_id and message_id are required"))
+	}
+
+	newSessionID := strings.TrimSpace(req.NewSessionID)
+	if newSessionID == "" {
+		newSessionID = uuid.New().String()
+	}
+	if newSessionID == sessionID {
+		return nil, model.NewError(model.ErrorParams, fmt.Errorf("new_session_id must differ from session_id"))
+	}
+
+	session := s.repositoryFactory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	messageRepo, err := s.repositoryFactory.NewChatMessageRepository(session)
+	if err != nil {
+		return nil, model.NewError(model.ErrorNewRepo, err)
+	}
+	sessionRepo, err := s.repositoryFactory.NewChatSessionRepository(session)
+	if err != nil {
+		return nil, model.NewError(model.ErrorNewRepo, err)
+	}
+
+	messages, err := messageRepo.List(&model.GetChatMessagesCondition{
+		UserID:    &userID,
+		SessionID: &sessionID,
+		UntilID:   &req.MessageID,
+		Order:     &model.Order{OrderBy: entity.ChatMessageFieldID, OrderAsc: true},
+	})
+	if err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+	if len(messages) == 0 || messages[len(messages)-1].ID != req.MessageID {
+		return nil, nil
+	}
+
+	existing, err := sessionRepo.Get(userID, newSessionID)
+	if err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+	if existing != nil {
+		return nil, model.NewError(model.ErrorParams, fmt.Errorf("session %s already exists", newSessionID))
+	}
+
+	source, err := sessionRepo.Get(userID, sessionID)
+	if err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+
+	title, titleSource := strings.TrimSpace(req.Title), entity.ChatSessionTitleManual
+	if title == "" {
+		titleSource = entity.ChatSessionTitleAuto
+		if source != nil && source.Title != "" {
+			title = source.Title
+		} else {
+			title = sessionTitle(messages)
+		}
+	}
+
+	if err := session.Begin(); err != nil {
+		return nil, model.NewError(model.ErrorDB, fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	for _, msg := range messages {
+		_, err := messageRepo.Create(&model.CreateChatMessageCondition{
+			UserID:    userID,
+			SessionID: newSessionID,
+			Role:      msg.Role,
+			Content:   msg.Content,
+			Truncated: msg.Truncated,
+		})
+		if err != nil {
+			_ = session.Rollback()
+			return nil, model.NewError(model.ErrorDB, err)
+		}
+	}
+	forked, err := sessionRepo.Create(&model.CreateChatSessionCondition{
+		UserID:            userID,
+		SessionID:         newSessionID,
+		Title:             title,
+		TitleSource:       titleSource,
+		MessageCount:      len(messages),
+		ForkedFromSession: sessionID,
+		ForkedFromMsgID:   req.MessageID,
+	})
+	if err != nil {
+		_ = session.Rollback()
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+	if err := session.Commit(); err != nil {
+		return nil, model.NewError(model.ErrorDB, fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	return toChatSessionResponse(forked), nil
+}
+
+// DeleteSession 在同一事务中删除会话的消息、语义记忆分块、摘要和会话记录，用于用户删除数据的请求
+// 会话不存在时返回 nil；用户画像属于用户级数据，不随会话删除
+func (s *Service) DeleteSession(ctx context.Context, userID, sessionID string) (*model.DeleteChatSessionResponse, *model.Error) {
+	if userID == "" || sessionID == "" {
+		return nil, model.NewError(model.ErrorParams, fmt.Errorf("user_id and session_id are required"))
+	}
+
+	session := s.repositoryFactory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	sessionRepo, err := s.repositoryFactory.NewChatSessionRepository(session)
+	if err != nil {
+		return nil, model.NewError(model.ErrorNewRepo, err)
+	}
+	messageRepo, err := s.repositoryFactory.NewChatMessageRepository(session)
+	if err != nil {
+		return nil, model.NewError(model.ErrorNewRepo, err)
+	}
+	chunkRepo, err := s.repositoryFactory.NewMemoryChunkRepository(session)
+	if err != nil {
+		return nil, model.NewError(model.ErrorNewRepo, err)
+	}
+	summaryRepo, err := s.repositoryFactory.NewChatSummaryRepository(session)
+	if err != nil {
+		return nil, model.NewError(model.ErrorNewRepo, err)
+	}
+
+	if err := session.Begin(); err != nil {
+		return nil, model.NewError(model.ErrorDB, fmt.Errorf("failed to begin transaction: %w", err))
+	}
+
+	result, err := deleteSessionData(sessionRepo, messageRepo, chunkRepo, summaryRepo, userID, sessionID)
+	if err != nil {
+		_ = session.Rollback()
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+	if result == nil {
+		_ = session.Rollback()
+		return nil, nil
+	}
+
+	if err := session.Commit(); err != nil {
+		return nil, model.NewError(model.ErrorDB, fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	return result, nil
+}
+
+// deleteSessionData 删除会话的全部数据，会话记录和消息都不存在时返回 nil
+func deleteSessionData(sessionRepo repository.ChatSessionRepository, messageRepo repository.ChatMessageRepository, chunkRepo repository.MemoryChunkRepository, summaryRepo repository.ChatSummaryRepository, userID, sessionID string) (*model.DeleteChatSessionResponse, error) {
+	record, err := sessionRepo.Get(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	deletedMessages, err := messageRepo.DeleteBySession(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil && deletedMessages == 0 {
+		return nil, nil
+	}
+
+	deletedChunks, err := chunkRepo.DeleteBySession(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	deletedSummary, err := summaryRepo.Delete(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := sessionRepo.Delete(userID, sessionID); err != nil {
+		return nil, err
+	}
+
+	return &model.DeleteChatSessionResponse{
+		SessionID:       sessionID,
+		DeletedMessages: deletedMessages,
+		DeletedChunks:   deletedChunks,
+		DeletedSummary:  deletedSummary,
+	}, nil
+}
+
+// touchSession 在保存本轮消息的事务中刷新会话活跃信息，新会话以首条用户消息生成标题
+func (s *Service) touchSession(turn *chatTurn, saved []*entity.ChatMessage) error {
+	sessionRepo, err := s.repositoryFactory.NewChatSessionRepository(turn.session)
+	if err != nil {
+		return err
+	}
+
+	return sessionRepo.Touch(&model.TouchChatSessionCondition{
+		UserID:       turn.req.UserID,
+		SessionID:    turn.req.SessionID,
+		Title:        sessionTitle(saved),
+		MessageCount: len(saved),
+	})
+}
+
+// sessionTitle 取第一条用户消息的首行作为会话标题
+func sessionTitle(messages []*entity.ChatMessage) string {
+	for _, msg := range messages {
+		if msg.Role != openai.ChatMessageRoleUser {
+			continue
+		}
+		content := strings.TrimSpace(msg.Content)
+		if content == "" {
+			continue
+		}
+		if idx := strings.IndexByte(content, '\n'); idx >= 0 {
+			content = content[:idx]
+		}
+		return snippet(strings.Join(strings.Fields(content), " "), sessionTitleRunes)
+	}
+	return ""
+}
+
+// sessionPager 分页参数，未指定时使用默认分页大小
+func sessionPager(limit, offset int) *model.Pager {
+	if limit <= 0 {
+		limit = defaultSessionPageSize
+	}
+	return &model.Pager{Limit: limit, Offset: offset}
+}
+
+func toChatSessionResponse(cs *entity.ChatSession) *model.ChatSessionResponse {
+	return &model.ChatSessionResponse{
+		SessionID:         cs.SessionID,
+		Title:             cs.Title,
+		TitleSource:       cs.TitleSource,
+		MessageCount:      cs.MessageCount,
+		ForkedFromSession: cs.ForkedFromSession,
+		ForkedFromMsgID:   cs.ForkedFromMsgID,
+		LastActiveAt:      cs.LastActiveAt,
+		CreatedAt:         cs.CreatedAt,
+	}
+}
@@ -0,0 +1,78 @@
+package chat
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+	"ai_task/pkg/clients/llm"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteSessionCascades(t *testing.T) {
+	service, store := newMemoryService(llm.NewScriptedModel())
+	ctx := context.Background()
+
+	messages := store.addMessages("user_1", "session_1", "实现一个缓存", "好的", "用什么淘汰策略", "LRU")
+	store.addMessages("user_1", "session_2", "另一个会话")
+	sessionRepo := &memorySessionRepository{store: store}
+	_, err := sessionRepo.Create(&model.CreateChatSessionCondition{UserID: "user_1", SessionID: "session_1", MessageCount: len(messages)})
+	require.NoError(t, err)
+	require.NoError(t, (&memorySummaryRepository{store: store}).Upsert(&model.UpsertChatSummaryCondition{
+		UserID: "user_1", SessionID: "session_1", Content: "用户在实现缓存", CoveredUntilMsgID: messages[1].ID, MessageCount: 2,
+	}))
+	store.chunks[sessionKey("user_1", "session_1")] = 3
+
+	result, modelErr := service.DeleteSession(ctx, "user_1", "session_1")
+	require.Nil(t, modelErr)
+	assert.Equal(t, &model.DeleteChatSessionResponse{
+		SessionID:       "session_1",
+		DeletedMessages: 4,
+		DeletedChunks:   3,
+		DeletedSummary:  true,
+	}, result)
+
+	assert.Empty(t, store.sessionMessages("user_1", "session_1"))
+	assert.Nil(t, store.summary("user_1", "session_1"))
+	record, err := sessionRepo.Get("user_1", "session_1")
+	require.NoError(t, err)
+	assert.Nil(t, record)
+	assert.Equal(t, 1, store.commits)
+
+	// 其他会话不受影响，再次删除时会话已不存在
+	assert.Len(t, store.sessionMessages("user_1", "session_2"), 1)
+	result, modelErr = service.DeleteSession(ctx, "user_1", "session_1")
+	assert.Nil(t, modelErr)
+	assert.Nil(t, result)
+	assert.Equal(t, 1, store.rollbacks)
+}
+
+func TestForkSessionCopiesMessagesUntilForkPoint(t *testing.T) {
+	service, store := newMemoryService(llm.NewScriptedModel())
+	ctx := context.Background()
+
+	messages := store.addMessages("user_1", "session_1", "实现一个缓存\n细节稍后补充", "好的", "用什么淘汰策略", "LRU")
+
+	forked, modelErr := service.ForkSession(ctx, "user_1", "session_1", &model.ForkChatSessionRequest{MessageID: messages[1].ID, NewSessionID: "session_2"})
+	require.Nil(t, modelErr)
+	require.NotNil(t, forked)
+	assert.Equal(t, "session_2", forked.SessionID)
+	assert.Equal(t, "实现一个缓存", forked.Title)
+	assert.Equal(t, entity.ChatSessionTitleAuto, forked.TitleSource)
+	assert.Equal(t, 2, forked.MessageCount)
+	assert.Equal(t, messages[1].ID, forked.ForkedFromMsgID)
+
+	copied := store.sessionMessages("user_1", "session_2")
+	require.Len(t, copied, 2)
+	assert.Equal(t, "好的", copied[1].Content)
+	assert.Len(t, store.sessionMessages("user_1", "session_1"), 4)
+
+	// 消息不属于该会话时返回 nil，新会话已存在时报错
+	forked, modelErr = service.ForkSession(ctx, "user_1", "session_2", &model.ForkChatSessionRequest{MessageID: messages[3].ID})
+	assert.Nil(t, modelErr)
+	assert.Nil(t, forked)
+	_, modelErr = service.ForkSession(ctx, "user_1", "session_1", &model.ForkChatSessionRequest{MessageID: messages[1].ID, NewSessionID: "session_2"})
+	assert.NotNil(t, modelErr)
+}
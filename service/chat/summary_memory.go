@@ -0,0 +1,175 @@
+package chat
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+	"ai_task/pkg/memory"
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/usage"
+	"ai_task/repository/interfaces"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+	log "github.com/sirupsen/logrus"
+)
+
+// compressingSessions 正在压缩的会话（进程内），避免同一会话并发压缩导致重复折叠
+var compressingSessions sync.Map
+
+// summaryEnabled 摘要压缩依赖短期记忆，且需要有效的压缩阈值
+func (s *Service) summaryEnabled(opts *memoryOptions) bool {
+	return s.summarizer != nil && opts.EnableSummary && opts.EnableSessionMemory && opts.CompressThreshold > 0
+}
+
+// loadConversationSummary 读取会话当前摘要，未启用或不存在时返回 nil
+func (s *Service) loadConversationSummary(session interfaces.Session, req *model.ChatRequest, opts *memoryOptions) (*entity.ChatSummary, error) {
+	if !s.summaryEnabled(opts) {
+		return nil, nil
+	}
+
+	summaryRepo, err := s.repositoryFactory.NewChatSummaryRepository(session)
+	if err != nil {
+		return nil, err
+	}
+
+	return summaryRepo.Get(req.UserID, req.SessionID)
+}
+
+// compressConversation 会话中未折叠的消息超过 CompressThreshold 时，将最早的消息与旧摘要合并成新摘要
+// 保留最近的窗口不折叠；原始消息不删除，仍可按会话查询审计
+// 在请求结束后异步执行，失败只记录日志，下次对话会重新尝试
+func (s *Service) compressConversation(req *model.ChatRequest, opts *memoryOptions) {
+	if !s.summaryEnabled(opts) {
+		return
+	}
+
+	key := req.UserID + "\x00" + req.SessionID
+	if _, running := compressingSessions.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+
+	userID, sessionID := req.UserID, req.SessionID
+	threshold, keep := opts.CompressThreshold, summaryKeepWindow(opts)
+
+	go func() {
+		defer compressingSessions.Delete(key)
+
+		ctx := usage.WithTags(context.Background(), usage.Tags{UserID: userID, SessionID: sessionID})
+		if err := s.doCompressConversation(ctx, userID, sessionID, threshold, keep); err != nil {
+			log.Errorf("compress conversation error, user_id:%s, session_id:%s, err:%v", userID, sessionID, err)
+		}
+	}()
+}
+
+func (s *Service) doCompressConversation(ctx context.Context, userID, sessionID string, threshold, keep int) error {
+	session := s.repositoryFactory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	summaryRepo, err := s.repositoryFactory.NewChatSummaryRepository(session)
+	if err != nil {
+		return err
+	}
+	messageRepo, err := s.repositoryFactory.NewChatMessageRepository(session)
+	if err != nil {
+		return err
+	}
+
+	summary, err := summaryRepo.Get(userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	var coveredUntil int64
+	var coveredCount int
+	if summary != nil {
+		coveredUntil = summary.CoveredUntilMsgID
+		coveredCount = summary.MessageCount
+	}
+
+	pending, err := messageRepo.Count(&model.GetChatMessagesCondition{
+		UserID:    &userID,
+		SessionID: &sessionID,
+		AfterID:   &coveredUntil,
+	})
+	if err != nil {
+		return err
+	}
+	if pending <= int64(threshold) {
+		return nil
+	}
+
+	folded, err := messageRepo.List(&model.GetChatMessagesCondition{
+		UserID:    &userID,
+		SessionID: &sessionID,
+		AfterID:   &coveredUntil,
+		Pager:     &model.Pager{Limit: int(pending) - keep},
+		Order:     &model.Order{OrderBy: entity.ChatMessageFieldID, OrderAsc: true},
+	})
+	if err != nil {
+		return err
+	}
+	if len(folded) == 0 {
+		return nil
+	}
+
+	messages := make([]openai.ChatCompletionMessage, 0, len(folded)+1)
+	if summary != nil && strings.TrimSpace(summary.Content) != "" {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: prompt.MustRender(ctx, prompt.ChatPreviousSummary, map[string]any{"Content": summary.Content}).Text,
+		})
+	}
+	for _, msg := range folded {
+		if strings.TrimSpace(msg.Content) == "" {
+			continue
+		}
+		messages = append(messages, openai.ChatCompletionMessage{Role: msg.Role, Content: msg.Content})
+	}
+
+	content, err := s.summarizer.SummarizeConversation(ctx, messages)
+	if err != nil {
+		return err
+	}
+	// 摘要为空时不推进覆盖位置，避免早期对话信息丢失
+	if content == "" {
+		return fmt.Errorf("empty summary generated")
+	}
+
+	log.Infof("compressed conversation, user_id:%s, session_id:%s, folded:%d, covered_until:%d",
+		userID, sessionID, len(folded), folded[len(folded)-1].ID)
+
+	return summaryRepo.Upsert(&model.UpsertChatSummaryCondition{
+		UserID:            userID,
+		SessionID:         sessionID,
+		Content:           content,
+		CoveredUntilMsgID: folded[len(folded)-1].ID,
+		MessageCount:      coveredCount + len(folded),
+	})
+}
+
+// summaryKeepWindow 压缩后保留的最近消息条数，须小于压缩阈值才能真正折叠
+func summaryKeepWindow(opts *memoryOptions) int {
+	keep := opts.SessionMemoryLimit
+	if keep >= opts.CompressThreshold {
+		keep = opts.CompressThreshold / 2
+	}
+	return keep
+}
+
+// conversationSummarySection 将会话摘要转换为上下文，无摘要时返回 nil
+func conversationSummarySection(summary *entity.ChatSummary) *contextSection {
+	if summary == nil || strings.TrimSpace(summary.Content) == "" {
+		return nil
+	}
+	return &contextSection{
+		name:   memory.SectionSummary,
+		prompt: prompt.ChatConversationSummary,
+		items: []memory.PromptItem{{
+			Key:  fmt.Sprintf("summary:%d", summary.ID),
+			Text: summary.Content,
+		}},
+	}
+}
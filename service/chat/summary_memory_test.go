@@ -0,0 +1,67 @@
+package chat
+
+import (
+	"ai_task/pkg/clients/llm"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressConversationAdvancesSummary(t *testing.T) {
+	model := llm.NewScriptedModel(
+		llm.Reply("用户在实现缓存"),
+		llm.ReplyWhen("用户在实现缓存", "用户在实现缓存，选择了 LRU"),
+	)
+	service, store := newMemoryService(model)
+	ctx := context.Background()
+
+	messages := store.addMessages("user_1", "session_1", "实现一个缓存", "好的", "用什么淘汰策略", "LRU", "容量多大", "1000")
+
+	// 未超过阈值时不压缩
+	require.NoError(t, service.doCompressConversation(ctx, "user_1", "session_1", 6, 2))
+	assert.Nil(t, store.summary("user_1", "session_1"))
+	assert.Empty(t, model.Calls())
+
+	// 折叠最早的消息，保留最近 keep 条
+	require.NoError(t, service.doCompressConversation(ctx, "user_1", "session_1", 4, 2))
+	summary := store.summary("user_1", "session_1")
+	require.NotNil(t, summary)
+	assert.Equal(t, "用户在实现缓存", summary.Content)
+	assert.Equal(t, messages[3].ID, summary.CoveredUntilMsgID)
+	assert.Equal(t, 4, summary.MessageCount)
+
+	// 再次压缩时与旧摘要合并，只折叠摘要之后的消息
+	messages = append(messages, store.addMessages("user_1", "session_1", "需要过期时间吗", "需要")...)
+	require.NoError(t, service.doCompressConversation(ctx, "user_1", "session_1", 3, 2))
+	summary = store.summary("user_1", "session_1")
+	assert.Equal(t, "用户在实现缓存，选择了 LRU", summary.Content)
+	assert.Equal(t, messages[5].ID, summary.CoveredUntilMsgID)
+	assert.Equal(t, 6, summary.MessageCount)
+
+	calls := model.Calls()
+	require.Len(t, calls, 2)
+	assert.NotContains(t, calls[1][1].Content, "实现一个缓存")
+	assert.Contains(t, calls[1][1].Content, "容量多大")
+}
+
+func TestCompressConversationKeepsCoverageOnEmptySummary(t *testing.T) {
+	model := llm.NewScriptedModel(llm.Reply("用户在实现缓存"), llm.Reply("  "))
+	service, store := newMemoryService(model)
+	ctx := context.Background()
+
+	messages := store.addMessages("user_1", "session_1", "实现一个缓存", "好的", "用什么淘汰策略", "LRU")
+	require.NoError(t, service.doCompressConversation(ctx, "user_1", "session_1", 3, 2))
+	require.Equal(t, messages[1].ID, store.summary("user_1", "session_1").CoveredUntilMsgID)
+
+	// 空摘要返回错误，覆盖位置与旧摘要保持不变
+	store.addMessages("user_1", "session_1", "容量多大", "1000")
+	err := service.doCompressConversation(ctx, "user_1", "session_1", 3, 2)
+	assert.ErrorContains(t, err, "empty summary")
+
+	summary := store.summary("user_1", "session_1")
+	assert.Equal(t, "用户在实现缓存", summary.Content)
+	assert.Equal(t, messages[1].ID, summary.CoveredUntilMsgID)
+	assert.Equal(t, 2, summary.MessageCount)
+}
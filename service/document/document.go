@@ -0,0 +1,411 @@
+package document
+
+import (
+	"ai_task/config"
+	"ai_task/entity"
+	"ai_task/model"
+	"ai_task/pkg/clients/embedding"
+	docextract "ai_task/pkg/document"
+	"ai_task/pkg/memory"
+	"ai_task/repository/factory"
+	"ai_task/repository/interfaces"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// 配置缺失时的兜底默认值，与 config.yaml 中 documents.* 保持一致
+const (
+	defaultChunkMaxSize   = 800
+	defaultChunkOverlap   = 100
+	defaultChunkMinSize   = 200
+	defaultChunkStrategy  = "paragraph"
+	defaultMaxContentSize = 2 << 20
+)
+
+var (
+	serviceOnce sync.Once
+	instance    *Service
+)
+
+// Service 知识文档服务：上传、分块向量化、重建索引和删除
+type Service struct {
+	repositoryFactory factory.Factory
+	embeddingClient   *embedding.Client // 为 nil 时无法建立索引
+}
+
+func NewService(repositoryFactory factory.Factory) *Service {
+	serviceOnce.Do(func() {
+		embeddingClient, err := embedding.GetInstance()
+		if err != nil {
+			log.Warnf("embedding client unavailable, document indexing disabled: %v", err)
+			embeddingClient = nil
+		}
+
+		instance = &Service{
+			repositoryFactory: repositoryFactory,
+			embeddingClient:   embeddingClient,
+		}
+	})
+
+	return instance
+}
+
+// MaxContentSize 单个文档允许的最大字节数
+func (s *Service) MaxContentSize() int {
+	return config.GetInstance().GetIntOrDefault(config.DocumentsMaxContentSize, defaultMaxContentSize)
+}
+
+// Create 保存文档并同步建立索引
+// 索引失败时文档仍会保留（status=failed），可修复后调用 Reindex 重试
+func (s *Service) Create(ctx context.Context, req *model.CreateDocumentRequest) (*model.DocumentResponse, *model.Error) {
+	if req == nil || req.UserID == "" {
+		return nil, model.NewError(model.ErrorParams, fmt.Errorf("user_id is required"))
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		return nil, model.NewError(model.ErrorParams, fmt.Errorf("content is required"))
+	}
+	if len(req.Content) > s.MaxContentSize() {
+		return nil, model.NewError(model.ErrorParams, fmt.Errorf("content exceeds %d bytes", s.MaxContentSize()))
+	}
+	if s.embeddingClient == nil {
+		return nil, model.NewError(model.ErrorEmbedding, fmt.Errorf("embedding client unavailable"))
+	}
+
+	contentType, err := docextract.NormalizeContentType(req.ContentType, req.Filename)
+	if err != nil {
+		return nil, model.NewError(model.ErrorParams, err)
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = req.Filename
+	}
+
+	session := s.repositoryFactory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	docRepo, err := s.repositoryFactory.NewDocumentRepository(session)
+	if err != nil {
+		return nil, model.NewError(model.ErrorNewRepo, err)
+	}
+
+	doc, err := docRepo.Create(&model.CreateDocumentCondition{
+		UserID:      req.UserID,
+		Namespace:   strings.TrimSpace(req.Namespace),
+		Title:       title,
+		ContentType: contentType,
+		Content:     req.Content,
+		Status:      entity.DocumentStatusIndexing,
+	})
+	if err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+
+	if modelErr := s.indexDocument(ctx, session, doc); modelErr != nil {
+		return nil, modelErr
+	}
+
+	return s.reload(session, doc.ID)
+}
+
+// List 列出文档（不含原文），按 ID 倒序
+func (s *Service) List(ctx context.Context, req *model.ListDocumentsRequest) ([]*model.DocumentResponse, *model.Error) {
+	if req == nil || (req.UserID == "" && req.Namespace == "") {
+		return nil, model.NewError(model.ErrorParams, fmt.Errorf("user_id or namespace is required"))
+	}
+
+	session := s.repositoryFactory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	docRepo, err := s.repositoryFactory.NewDocumentRepository(session)
+	if err != nil {
+		return nil, model.NewError(model.ErrorNewRepo, err)
+	}
+
+	condition := &model.GetDocumentsCondition{}
+	if req.UserID != "" {
+		condition.UserID = &req.UserID
+	}
+	if req.Namespace != "" {
+		condition.Namespace = &req.Namespace
+	}
+	if req.Status != "" {
+		condition.Status = &req.Status
+	}
+	if req.Limit > 0 {
+		condition.Pager = &model.Pager{Limit: req.Limit, Offset: req.Offset}
+	}
+
+	docs, err := docRepo.List(condition)
+	if err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+
+	results := make([]*model.DocumentResponse, 0, len(docs))
+	for _, doc := range docs {
+		results = append(results, toDocumentResponse(doc, false))
+	}
+	return results, nil
+}
+
+// Get 获取文档详情（含原文），不存在时返回 nil
+func (s *Service) Get(ctx context.Context, docID int64) (*model.DocumentResponse, *model.Error) {
+	if docID <= 0 {
+		return nil, model.NewError(model.ErrorEmptyId, fmt.Errorf("doc_id is required"))
+	}
+
+	session := s.repositoryFactory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	docRepo, err := s.repositoryFactory.NewDocumentRepository(session)
+	if err != nil {
+		return nil, model.NewError(model.ErrorNewRepo, err)
+	}
+
+	doc, err := docRepo.Get(docID)
+	if err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+	if doc == nil {
+		return nil, nil
+	}
+
+	return toDocumentResponse(doc, true), nil
+}
+
+// Reindex 按当前分块配置和 embedding 模型重建文档索引，不存在时返回 nil
+// 新分块在事务中整体替换旧分块，失败时旧索引保持可用
+func (s *Service) Reindex(ctx context.Context, docID int64) (*model.DocumentResponse, *model.Error) {
+	if docID <= 0 {
+		return nil, model.NewError(model.ErrorEmptyId, fmt.Errorf("doc_id is required"))
+	}
+	if s.embeddingClient == nil {
+		return nil, model.NewError(model.ErrorEmbedding, fmt.Errorf("embedding client unavailable"))
+	}
+
+	session := s.repositoryFactory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	docRepo, err := s.repositoryFactory.NewDocumentRepository(session)
+	if err != nil {
+		return nil, model.NewError(model.ErrorNewRepo, err)
+	}
+
+	doc, err := docRepo.Get(docID)
+	if err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+	if doc == nil {
+		return nil, nil
+	}
+
+	if modelErr := s.indexDocument(ctx, session, doc); modelErr != nil {
+		return nil, modelErr
+	}
+
+	return s.reload(session, doc.ID)
+}
+
+// Delete 删除文档及其全部分块，返回删除前是否存在
+func (s *Service) Delete(ctx context.Context, docID int64) (bool, *model.Error) {
+	if docID <= 0 {
+		return false, model.NewError(model.ErrorEmptyId, fmt.Errorf("doc_id is required"))
+	}
+
+	session := s.repositoryFactory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	docRepo, err := s.repositoryFactory.NewDocumentRepository(session)
+	if err != nil {
+		return false, model.NewError(model.ErrorNewRepo, err)
+	}
+	chunkRepo, err := s.repositoryFactory.NewDocChunkRepository(session)
+	if err != nil {
+		return false, model.NewError(model.ErrorNewRepo, err)
+	}
+
+	doc, err := docRepo.Get(docID)
+	if err != nil {
+		return false, model.NewError(model.ErrorDB, err)
+	}
+	if doc == nil {
+		return false, nil
+	}
+
+	if err := session.Begin(); err != nil {
+		return false, model.NewError(model.ErrorDB, fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	if err := chunkRepo.DeleteByDocID(docID); err != nil {
+		_ = session.Rollback()
+		return false, model.NewError(model.ErrorDB, err)
+	}
+	if err := docRepo.Delete(docID); err != nil {
+		_ = session.Rollback()
+		return false, model.NewError(model.ErrorDB, err)
+	}
+	if err := session.Commit(); err != nil {
+		return false, model.NewError(model.ErrorDB, fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	return true, nil
+}
+
+// indexDocument 提取正文、分块并向量化，再在事务中替换文档的全部分块并标记为 ready
+// 向量化在事务外完成，避免长时间持有事务；失败时文档标记为 failed 并记录原因
+func (s *Service) indexDocument(ctx context.Context, session interfaces.Session, doc *entity.Document) *model.Error {
+	reqs, code, err := s.buildDocChunks(ctx, doc)
+	if err == nil {
+		code, err = s.replaceDocChunks(session, doc, reqs)
+	}
+	if err != nil {
+		s.markFailed(session, doc.ID, err)
+		return model.NewError(code, err)
+	}
+
+	log.Infof("indexed document, doc_id:%d, chunks:%d", doc.ID, len(reqs))
+	return nil
+}
+
+// buildDocChunks 将文档正文分块并向量化
+func (s *Service) buildDocChunks(ctx context.Context, doc *entity.Document) ([]*model.CreateDocChunkCondition, int, error) {
+	text, err := docextract.ExtractText(doc.ContentType, doc.Content)
+	if err != nil {
+		return nil, model.ErrorParams, err
+	}
+
+	conf := chunkConfig()
+	var texts []string
+	for _, chunk := range memory.NewChunker(conf).Chunk(text, conf.MaxSize, conf.Overlap) {
+		if t := strings.TrimSpace(chunk.Text); t != "" {
+			texts = append(texts, t)
+		}
+	}
+	if len(texts) == 0 {
+		return nil, model.ErrorParams, fmt.Errorf("document has no indexable text")
+	}
+
+	vectors, err := s.embeddingClient.GetTextEmbeddingBatch(ctx, texts)
+	if err != nil {
+		return nil, model.ErrorEmbedding, err
+	}
+	if len(vectors) != len(texts) {
+		return nil, model.ErrorEmbedding, fmt.Errorf("embedding count mismatch, expected %d, got %d", len(texts), len(vectors))
+	}
+
+	reqs := make([]*model.CreateDocChunkCondition, 0, len(texts))
+	for i, t := range texts {
+		reqs = append(reqs, &model.CreateDocChunkCondition{
+			DocID:     doc.ID,
+			UserID:    doc.UserID,
+			Namespace: doc.Namespace,
+			ChunkIdx:  i,
+			Content:   t,
+			Embedding: vectors[i],
+		})
+	}
+	return reqs, 0, nil
+}
+
+func (s *Service) replaceDocChunks(session interfaces.Session, doc *entity.Document, reqs []*model.CreateDocChunkCondition) (int, error) {
+	docRepo, err := s.repositoryFactory.NewDocumentRepository(session)
+	if err != nil {
+		return model.ErrorNewRepo, err
+	}
+	chunkRepo, err := s.repositoryFactory.NewDocChunkRepository(session)
+	if err != nil {
+		return model.ErrorNewRepo, err
+	}
+
+	if err := session.Begin(); err != nil {
+		return model.ErrorDB, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := chunkRepo.DeleteByDocID(doc.ID); err != nil {
+		_ = session.Rollback()
+		return model.ErrorDB, err
+	}
+	if err := chunkRepo.BatchCreate(reqs); err != nil {
+		_ = session.Rollback()
+		return model.ErrorDB, err
+	}
+
+	status, count, errMsg := entity.DocumentStatusReady, len(reqs), ""
+	err = docRepo.Update(doc.ID, &model.UpdateDocumentCondition{
+		Status:     &status,
+		ChunkCount: &count,
+		Error:      &errMsg,
+	})
+	if err != nil {
+		_ = session.Rollback()
+		return model.ErrorDB, err
+	}
+	if err := session.Commit(); err != nil {
+		return model.ErrorDB, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return 0, nil
+}
+
+// markFailed 记录索引失败原因，已有分块保持不变
+func (s *Service) markFailed(session interfaces.Session, docID int64, cause error) {
+	docRepo, err := s.repositoryFactory.NewDocumentRepository(session)
+	if err != nil {
+		log.Errorf("mark document failed new repository error, doc_id:%d, err:%v", docID, err)
+		return
+	}
+
+	status, errMsg := entity.DocumentStatusFailed, cause.Error()
+	if err := docRepo.Update(docID, &model.UpdateDocumentCondition{Status: &status, Error: &errMsg}); err != nil {
+		log.Errorf("mark document failed error, doc_id:%d, err:%v", docID, err)
+	}
+}
+
+// reload 重新读取文档，返回最新的索引状态
+func (s *Service) reload(session interfaces.Session, docID int64) (*model.DocumentResponse, *model.Error) {
+	docRepo, err := s.repositoryFactory.NewDocumentRepository(session)
+	if err != nil {
+		return nil, model.NewError(model.ErrorNewRepo, err)
+	}
+
+	doc, err := docRepo.Get(docID)
+	if err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+	if doc == nil {
+		return nil, model.NewError(model.ErrorDB, fmt.Errorf("document %d not found after indexing", docID))
+	}
+
+	return toDocumentResponse(doc, false), nil
+}
+
+// chunkConfig 从 config.yaml 的 documents.* 读取文档分块配置
+func chunkConfig() memory.ChunkConfig {
+	conf := config.GetInstance()
+	return memory.ChunkConfig{
+		MaxSize:  conf.GetIntOrDefault(config.DocumentsChunkMaxSize, defaultChunkMaxSize),
+		Overlap:  conf.GetIntOrDefault(config.DocumentsChunkOverlap, defaultChunkOverlap),
+		MinSize:  conf.GetIntOrDefault(config.DocumentsChunkMinSize, defaultChunkMinSize),
+		Strategy: conf.GetStringOrDefault(config.DocumentsChunkStrategy, defaultChunkStrategy),
+	}
+}
+
+func toDocumentResponse(doc *entity.Document, withContent bool) *model.DocumentResponse {
+	resp := &model.DocumentResponse{
+		ID:          doc.ID,
+		UserID:      doc.UserID,
+		Namespace:   doc.Namespace,
+		Title:       doc.Title,
+		ContentType: doc.ContentType,
+		Status:      doc.Status,
+		ChunkCount:  doc.ChunkCount,
+		Error:       doc.Error,
+		CreatedAt:   doc.CreatedAt,
+		UpdatedAt:   doc.UpdatedAt,
+	}
+	if withContent {
+		resp.Content = doc.Content
+	}
+	return resp
+}
@@ -1,10 +1,21 @@
 package factory
 
 import (
+	"ai_task/config"
+	"ai_task/pkg/clients/embedding"
+	"ai_task/pkg/clients/llm"
+	"ai_task/pkg/prompt"
+	"ai_task/pkg/retrieval"
+	"ai_task/pkg/usage"
 	"ai_task/repository/factory"
 	"ai_task/repository/xormimplement"
 	"ai_task/service/chat"
+	"ai_task/service/document"
+	"ai_task/service/profile"
+	usageservice "ai_task/service/usage"
 	"sync"
+
+	log "github.com/sirupsen/logrus"
 )
 
 var instance *Factory
@@ -13,12 +24,25 @@ var once sync.Once
 // 创建
 type Factory struct {
 	repositoryFactory factory.Factory
+	usageStore        *usage.Store
 }
 
-// 实例化instance
+// 实例化instance，模型与 embedding 调用的用量写入 llm_usage，提示词语言按配置与用户画像确定
 func init() {
 	once.Do(func() {
-		instance = &Factory{repositoryFactory: xormimplement.GetRepositoryFactoryInstance()}
+		repositoryFactory := xormimplement.GetRepositoryFactoryInstance()
+		instance = &Factory{
+			repositoryFactory: repositoryFactory,
+			usageStore:        usage.NewStore(repositoryFactory),
+		}
+		usage.SetRecorder(instance.usageStore)
+		usage.SetPricing(usage.LoadPricing())
+
+		conf := config.GetInstance()
+		if err := prompt.Setup(conf.GetString(config.PromptDefaultLocale), conf.GetString(config.PromptOverrideDir)); err != nil {
+			log.Errorf("setup prompts error, use embedded prompts: %v", err)
+		}
+		prompt.SetUserLocaleResolver(profile.NewService(repositoryFactory).Language)
 	})
 }
 
@@ -29,5 +53,39 @@ func GetServiceFactory() *Factory {
 
 // NewChatService 获取聊天服务
 func (f *Factory) NewChatService() *chat.Service {
-	return chat.NewService(f.repositoryFactory)
+	return chat.NewService(f.repositoryFactory, f.NewModels())
+}
+
+// NewModels 获取按用途路由的模型
+func (f *Factory) NewModels() llm.Models {
+	return llm.GetRouter()
+}
+
+// NewProfileService 获取用户画像服务
+func (f *Factory) NewProfileService() *profile.Service {
+	return profile.NewService(f.repositoryFactory)
+}
+
+// NewDocumentService 获取知识文档服务
+func (f *Factory) NewDocumentService() *document.Service {
+	return document.NewService(f.repositoryFactory)
+}
+
+// NewDocumentRetriever 获取知识文档检索器，embedding 不可用时返回 nil
+func (f *Factory) NewDocumentRetriever() retrieval.Retriever {
+	embeddingClient, err := embedding.GetInstance()
+	if err != nil {
+		return nil
+	}
+	return retrieval.NewDocumentRetriever(f.repositoryFactory, embeddingClient)
+}
+
+// NewUsageService 获取模型用量查询服务
+func (f *Factory) NewUsageService() *usageservice.Service {
+	return usageservice.NewService(f.repositoryFactory)
+}
+
+// NewUsageBudget 获取任务执行的 token 预算，未配置限额时返回 nil
+func (f *Factory) NewUsageBudget() usage.Budget {
+	return usage.LoadBudget(f.usageStore)
 }
@@ -0,0 +1,217 @@
+package profile
+
+import (
+	"ai_task/entity"
+	"ai_task/model"
+	"ai_task/pkg/memory"
+	"ai_task/repository"
+	"ai_task/repository/factory"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	serviceOnce sync.Once
+	instance    *Service
+)
+
+// KeyLanguage 用户语言偏好的画像属性，与事实提取使用的 key 一致
+const KeyLanguage = "preference_language"
+
+// Service 用户画像管理服务，供客服查看和修正助手对用户的认知
+type Service struct {
+	repositoryFactory factory.Factory
+}
+
+func NewService(repositoryFactory factory.Factory) *Service {
+	serviceOnce.Do(func() {
+		instance = &Service{
+			repositoryFactory: repositoryFactory,
+		}
+	})
+
+	return instance
+}
+
+// List 列出用户画像，支持 key 前缀和最低置信度过滤
+func (s *Service) List(ctx context.Context, userID string, req *model.ListUserProfileRequest) ([]*model.UserProfileResponse, *model.Error) {
+	if userID == "" {
+		return nil, model.NewError(model.ErrorParams, fmt.Errorf("user_id is required"))
+	}
+
+	profileRepo, closeFn, modelErr := s.newProfileRepository(ctx)
+	if modelErr != nil {
+		return nil, modelErr
+	}
+	defer closeFn()
+
+	condition := &model.GetUserProfileCondition{UserID: &userID}
+	if req != nil {
+		if req.KeyPrefix != "" {
+			condition.KeyPrefix = &req.KeyPrefix
+		}
+		condition.MinConfidence = req.MinConfidence
+	}
+
+	profiles, err := profileRepo.List(condition)
+	if err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+
+	results := make([]*model.UserProfileResponse, 0, len(profiles))
+	for _, p := range profiles {
+		results = append(results, toProfileResponse(p))
+	}
+	return results, nil
+}
+
+// Get 获取单个画像属性，不存在时返回 nil
+func (s *Service) Get(ctx context.Context, userID, key string) (*model.UserProfileResponse, *model.Error) {
+	if userID == "" || key == "" {
+		return nil, model.NewError(model.ErrorParams, fmt.Errorf("user_id and key are required"))
+	}
+
+	profileRepo, closeFn, modelErr := s.newProfileRepository(ctx)
+	if modelErr != nil {
+		return nil, modelErr
+	}
+	defer closeFn()
+
+	record, err := profileRepo.Get(userID, key)
+	if err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+	if record == nil {
+		return nil, nil
+	}
+
+	return toProfileResponse(record), nil
+}
+
+// Language 查询用户的语言偏好，不存在或查询失败时返回空字符串
+func (s *Service) Language(ctx context.Context, userID string) string {
+	record, modelErr := s.Get(ctx, userID, KeyLanguage)
+	if modelErr != nil {
+		log.Warnf("get user language error, user_id:%s, err:%v", userID, modelErr)
+		return ""
+	}
+	if record == nil {
+		return ""
+	}
+	return record.Value
+}
+
+// Upsert 人工设置或修正画像属性，原值保留在 meta.history 中
+func (s *Service) Upsert(ctx context.Context, userID, key string, req *model.UpsertUserProfileRequest) (*model.UserProfileResponse, *model.Error) {
+	key = strings.TrimSpace(key)
+	if userID == "" || key == "" || req == nil || strings.TrimSpace(req.Value) == "" {
+		return nil, model.NewError(model.ErrorParams, fmt.Errorf("user_id, key and value are required"))
+	}
+
+	profileRepo, closeFn, modelErr := s.newProfileRepository(ctx)
+	if modelErr != nil {
+		return nil, modelErr
+	}
+	defer closeFn()
+
+	record, err := profileRepo.Get(userID, key)
+	if err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+
+	var existing *memory.ProfileValue
+	if record != nil {
+		existing = &memory.ProfileValue{
+			Value:       record.Value,
+			Confidence:  record.Confidence,
+			SourceMsgID: record.SourceMsgID,
+			Meta:        memory.ParseProfileMeta(record.Meta),
+		}
+	}
+
+	merged := memory.OverrideValue(existing, strings.TrimSpace(req.Value), req.Confidence, req.Operator, time.Now())
+	meta := merged.Meta.String()
+
+	err = profileRepo.Upsert(&model.UpsertUserProfileCondition{
+		UserID:      userID,
+		Key:         key,
+		Value:       merged.Value,
+		Confidence:  merged.Confidence,
+		SourceMsgID: merged.SourceMsgID,
+		Meta:        &meta,
+	})
+	if err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+
+	saved, err := profileRepo.Get(userID, key)
+	if err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+	if saved == nil {
+		return nil, model.NewError(model.ErrorDB, fmt.Errorf("user_profile %s not found after upsert", key))
+	}
+
+	return toProfileResponse(saved), nil
+}
+
+// Delete 删除画像属性，返回删除前是否存在
+func (s *Service) Delete(ctx context.Context, userID, key string) (bool, *model.Error) {
+	if userID == "" || key == "" {
+		return false, model.NewError(model.ErrorParams, fmt.Errorf("user_id and key are required"))
+	}
+
+	profileRepo, closeFn, modelErr := s.newProfileRepository(ctx)
+	if modelErr != nil {
+		return false, modelErr
+	}
+	defer closeFn()
+
+	record, err := profileRepo.Get(userID, key)
+	if err != nil {
+		return false, model.NewError(model.ErrorDB, err)
+	}
+	if record == nil {
+		return false, nil
+	}
+
+	if err := profileRepo.Delete(userID, key); err != nil {
+		return false, model.NewError(model.ErrorDB, err)
+	}
+	return true, nil
+}
+
+// newProfileRepository 打开数据库会话并创建画像仓库，返回会话关闭函数
+func (s *Service) newProfileRepository(ctx context.Context) (repository.UserProfileRepository, func(), *model.Error) {
+	session := s.repositoryFactory.NewSession(ctx)
+	closeFn := func() { _ = session.Close() }
+
+	profileRepo, err := s.repositoryFactory.NewUserProfileRepository(session)
+	if err != nil {
+		closeFn()
+		return nil, nil, model.NewError(model.ErrorNewRepo, err)
+	}
+	return profileRepo, closeFn, nil
+}
+
+func toProfileResponse(p *entity.UserProfile) *model.UserProfileResponse {
+	meta := json.RawMessage("{}")
+	if strings.TrimSpace(p.Meta) != "" && json.Valid([]byte(p.Meta)) {
+		meta = json.RawMessage(p.Meta)
+	}
+
+	return &model.UserProfileResponse{
+		Key:         p.Key,
+		Value:       p.Value,
+		Confidence:  p.Confidence,
+		SourceMsgID: p.SourceMsgID,
+		Meta:        meta,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
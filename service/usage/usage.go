@@ -0,0 +1,146 @@
+package usage
+
+import (
+	"ai_task/model"
+	"ai_task/repository/factory"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+var (
+	serviceOnce sync.Once
+	instance    *Service
+)
+
+// Service 模型用量查询服务，按用户、会话、任务、角色、模型或日期汇总 token 与费用
+type Service struct {
+	repositoryFactory factory.Factory
+}
+
+func NewService(repositoryFactory factory.Factory) *Service {
+	serviceOnce.Do(func() {
+		instance = &Service{
+			repositoryFactory: repositoryFactory,
+		}
+	})
+
+	return instance
+}
+
+// Get 汇总用量，group_by 为空时只返回总计
+func (s *Service) Get(ctx context.Context, req *model.GetUsageRequest) (*model.GetUsageResponse, *model.Error) {
+	if req == nil {
+		req = &model.GetUsageRequest{}
+	}
+
+	condition, err := buildAggregateCondition(req)
+	if err != nil {
+		return nil, model.NewError(model.ErrorParams, err)
+	}
+
+	session := s.repositoryFactory.NewSession(ctx)
+	defer func() { _ = session.Close() }()
+
+	usageRepo, err := s.repositoryFactory.NewLLMUsageRepository(session)
+	if err != nil {
+		return nil, model.NewError(model.ErrorNewRepo, err)
+	}
+
+	resp := &model.GetUsageResponse{Groups: []*model.LLMUsageAggregate{}}
+	if len(condition.GroupBy) > 0 {
+		groups, err := usageRepo.Aggregate(condition)
+		if err != nil {
+			return nil, model.NewError(model.ErrorDB, err)
+		}
+		resp.Groups = groups
+	}
+
+	totalCondition := *condition
+	totalCondition.GroupBy = nil
+	totals, err := usageRepo.Aggregate(&totalCondition)
+	if err != nil {
+		return nil, model.NewError(model.ErrorDB, err)
+	}
+	resp.Total = &model.LLMUsageAggregate{}
+	if len(totals) > 0 {
+		resp.Total = totals[0]
+	}
+
+	return resp, nil
+}
+
+// buildAggregateCondition 校验分组维度并解析日期范围，日期格式的结束时间包含当天
+func buildAggregateCondition(req *model.GetUsageRequest) (*model.AggregateLLMUsageCondition, error) {
+	condition := &model.AggregateLLMUsageCondition{}
+	if req.UserID != "" {
+		condition.UserID = &req.UserID
+	}
+	if req.SessionID != "" {
+		condition.SessionID = &req.SessionID
+	}
+	if req.TaskID != "" {
+		condition.TaskID = &req.TaskID
+	}
+	if req.Role != "" {
+		condition.Role = &req.Role
+	}
+	if req.Kind != "" {
+		condition.Kind = &req.Kind
+	}
+
+	if req.Start != "" {
+		start, _, err := parseUsageTime(req.Start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start: %w", err)
+		}
+		condition.Since = &start
+	}
+	if req.End != "" {
+		end, dateOnly, err := parseUsageTime(req.End)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end: %w", err)
+		}
+		if dateOnly {
+			end = end.AddDate(0, 0, 1)
+		}
+		condition.Until = &end
+	}
+	if condition.Since != nil && condition.Until != nil && !condition.Since.Before(*condition.Until) {
+		return nil, fmt.Errorf("start must be before end")
+	}
+
+	seen := make(map[string]bool)
+	for _, group := range strings.Split(req.GroupBy, ",") {
+		group = strings.TrimSpace(group)
+		if group == "" || seen[group] {
+			continue
+		}
+		switch group {
+		case model.UsageGroupUser, model.UsageGroupSession, model.UsageGroupTask, model.UsageGroupRole,
+			model.UsageGroupKind, model.UsageGroupModel, model.UsageGroupDay:
+		default:
+			return nil, fmt.Errorf("unsupported group_by: %s", group)
+		}
+		seen[group] = true
+		condition.GroupBy = append(condition.GroupBy, group)
+	}
+
+	return condition, nil
+}
+
+// parseUsageTime 解析 YYYY-MM-DD（本地时区零点）或 RFC3339，返回是否为日期格式
+func parseUsageTime(value string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation(dateLayout, value, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("expect YYYY-MM-DD or RFC3339, got %q", value)
+	}
+	return t, false, nil
+}